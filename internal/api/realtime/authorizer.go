@@ -0,0 +1,339 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// defaultAuthzCacheTTL bounds how long a read decision is reused. Rules that
+	// call get()/exists() on other documents may lag by up to this long.
+	defaultAuthzCacheTTL = 30 * time.Second
+
+	// defaultAuthzCacheSize caps the number of memoized read decisions.
+	defaultAuthzCacheSize = 100000
+
+	// authzEvalTimeout bounds a single rule evaluation for a client request.
+	authzEvalTimeout = 2 * time.Second
+
+	// readEvalTimeout bounds a read rule evaluation for a delivered event. An
+	// evaluation that runs out denies the event.
+	readEvalTimeout = 250 * time.Millisecond
+
+	// listProbeID stands in for the document ID when checking list permission
+	// on a collection, so rules written against /collection/{id} still match.
+	listProbeID = "*"
+
+	// topicsPath is the rules path prefix of ephemeral topics.
+	topicsPath = "topics"
+
+	// presencePath is the rules path prefix of presence channels.
+	presencePath = "presence"
+)
+
+// principal is the authorization identity attached to a realtime client.
+type principal struct {
+	key  string
+	auth identity.Auth
+}
+
+func newPrincipal(tenant string, claims *identity.Claims, uid, username string, roles []string) principal {
+	auth := identity.Auth{
+		Username: username,
+		Roles:    append([]string{}, roles...),
+	}
+	if uid != "" {
+		auth.UID = uid
+	}
+	if claims != nil {
+		if auth.UID == nil && claims.UserID != "" {
+			auth.UID = claims.UserID
+		}
+		if auth.Username == "" {
+			auth.Username = claims.Username
+		}
+		if len(auth.Roles) == 0 {
+			auth.Roles = append([]string{}, claims.Roles...)
+		}
+		auth.Claims = claimsToMap(claims)
+	}
+
+	sortedRoles := append([]string{}, auth.Roles...)
+	sort.Strings(sortedRoles)
+	uidStr, _ := auth.UID.(string)
+
+	return principal{
+		key:  tenant + "|" + uidStr + "|" + strings.Join(sortedRoles, ","),
+		auth: auth,
+	}
+}
+
+func principalFromContext(ctx context.Context, tenant string) principal {
+	var (
+		claims   *identity.Claims
+		uid      string
+		username string
+		roles    []string
+	)
+	if ctx != nil {
+		claims, _ = ctx.Value(identity.ContextKeyClaims).(*identity.Claims)
+		uid, _ = ctx.Value(identity.ContextKeyUserID).(string)
+		username, _ = ctx.Value(identity.ContextKeyUsername).(string)
+		roles, _ = ctx.Value(identity.ContextKeyRoles).([]string)
+	}
+	return newPrincipal(tenant, claims, uid, username, roles)
+}
+
+func principalFromClaims(claims *identity.Claims) principal {
+	if claims == nil {
+		return newPrincipal("", nil, "", "", nil)
+	}
+	return newPrincipal(claims.TenantID, claims, "", "", nil)
+}
+
+func claimsToMap(claims *identity.Claims) map[string]interface{} {
+	if claims == nil {
+		return nil
+	}
+
+	toTime := func(nd *jwt.NumericDate) interface{} {
+		if nd == nil {
+			return nil
+		}
+		return nd.Time
+	}
+
+	return map[string]interface{}{
+		"sub":      claims.Subject,
+		"tid":      claims.TenantID,
+		"oid":      claims.UserID,
+		"username": claims.Username,
+		"roles":    append([]string{}, claims.Roles...),
+		"disabled": claims.Disabled,
+		"aud":      claims.Audience,
+		"iss":      claims.Issuer,
+		"jti":      claims.ID,
+		"nbf":      toTime(claims.NotBefore),
+		"exp":      toTime(claims.ExpiresAt),
+		"iat":      toTime(claims.IssuedAt),
+	}
+}
+
+type decisionKey struct {
+	principal string
+	path      string
+	version   int64
+}
+
+type decision struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+// authorizer evaluates authz rules for subscriptions and delivered documents.
+// Read decisions are memoized per (principal, document path, version) so the
+// hub does not re-run CEL programs for every subscriber of a hot document.
+type authorizer struct {
+	authz   identity.AuthZ
+	ttl     time.Duration
+	maxSize int
+
+	mu    sync.Mutex
+	cache map[decisionKey]decision
+	rules *identity.RuleSet // rules the cached decisions were made under
+	now   func() time.Time
+}
+
+func newAuthorizer(authz identity.AuthZ, cfg Config) *authorizer {
+	if authz == nil {
+		return nil
+	}
+	ttl := cfg.AuthzCacheTTL
+	if ttl <= 0 {
+		ttl = defaultAuthzCacheTTL
+	}
+	size := cfg.AuthzCacheSize
+	if size <= 0 {
+		size = defaultAuthzCacheSize
+	}
+	return &authorizer{
+		authz:   authz,
+		ttl:     ttl,
+		maxSize: size,
+		cache:   make(map[decisionKey]decision),
+		rules:   authz.GetRules(),
+		now:     time.Now,
+	}
+}
+
+// canList reports whether p may list the given collection. An empty collection
+// (subscribe to everything) can never be authorized by rules.
+func (a *authorizer) canList(ctx context.Context, p principal, collection string) (bool, error) {
+	collection = strings.Trim(collection, "/")
+	if collection == "" {
+		return false, nil
+	}
+	req := identity.AuthzRequest{Auth: p.auth, Time: a.now()}
+	return a.authz.Evaluate(ctx, collection+"/"+listProbeID, "list", req, nil)
+}
+
+// canTopic evaluates action on the rules path /topics/{topic}. Published data
+// is passed as resource.data when it is a JSON object.
+func (a *authorizer) canTopic(ctx context.Context, p principal, topic, action string, data json.RawMessage) (bool, error) {
+	req := identity.AuthzRequest{Auth: p.auth, Time: a.now()}
+	var res *identity.Resource
+	if len(data) > 0 {
+		var fields map[string]interface{}
+		if json.Unmarshal(data, &fields) == nil {
+			res = &identity.Resource{Data: fields, ID: topic}
+			req.Resource = res
+		}
+	}
+	return a.authz.Evaluate(ctx, topicsPath+"/"+topic, action, req, res)
+}
+
+// canPresence evaluates action on the rules path /presence/{channel}. The
+// member metadata is passed as resource.data when there is any.
+func (a *authorizer) canPresence(ctx context.Context, p principal, channel, action string, meta map[string]interface{}) (bool, error) {
+	req := identity.AuthzRequest{Auth: p.auth, Time: a.now()}
+	var res *identity.Resource
+	if meta != nil {
+		res = &identity.Resource{Data: meta, ID: channel}
+		req.Resource = res
+	}
+	return a.authz.Evaluate(ctx, presencePath+"/"+channel, action, req, res)
+}
+
+// canRead reports whether p may see evt. Deletes are authorized by the path
+// of the deleted document, since the store may no longer hold its data; the
+// before-image is passed as resource.data when the event carries one.
+// Evaluation errors deny access.
+func (a *authorizer) canRead(p principal, evt storage.Event) bool {
+	tenant, fullpath, version, fields, ok := readTarget(evt)
+	if !ok {
+		return false
+	}
+	return a.canReadPath(p, tenant, fullpath, version, fields)
+}
+
+// cachedRead returns the cached read decision on evt without evaluating any
+// rule; ok is false on a miss.
+func (a *authorizer) cachedRead(p principal, evt storage.Event) (allowed, ok bool) {
+	tenant, fullpath, version, _, ok := readTarget(evt)
+	if !ok || fullpath == "" {
+		return false, true
+	}
+	key := decisionKey{principal: p.key, path: tenant + "/" + fullpath, version: version}
+	now := a.now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.checkRulesLocked()
+	if d, ok := a.cache[key]; ok && now.Before(d.expiresAt) {
+		return d.allowed, true
+	}
+	return false, false
+}
+
+// readTarget returns the document canRead evaluates for evt; ok is false for
+// an event without one.
+func readTarget(evt storage.Event) (tenant, fullpath string, version int64, fields map[string]interface{}, ok bool) {
+	doc := eventDocument(evt)
+	if evt.Type == storage.EventDelete {
+		version = -1
+		if doc != nil {
+			version, fields = doc.Version, doc.Data
+		}
+		return determineEventTenant(evt), eventPath(evt), version, fields, true
+	}
+	if doc == nil {
+		return "", "", 0, nil, false
+	}
+	return doc.TenantID, doc.Fullpath, doc.Version, doc.Data, true
+}
+
+// canReadPath evaluates get permission on the document at fullpath with the
+// given data as resource.data.
+func (a *authorizer) canReadPath(p principal, tenant, fullpath string, version int64, fields map[string]interface{}) bool {
+	if fullpath == "" {
+		return false
+	}
+
+	key := decisionKey{principal: p.key, path: tenant + "/" + fullpath, version: version}
+	now := a.now()
+
+	a.mu.Lock()
+	a.checkRulesLocked()
+	if d, ok := a.cache[key]; ok && now.Before(d.expiresAt) {
+		a.mu.Unlock()
+		return d.allowed
+	}
+	a.mu.Unlock()
+
+	data := model.Document{}
+	for k, v := range fields {
+		data[k] = v
+	}
+	data.StripProtectedFields()
+	res := &identity.Resource{Data: data, ID: extractIDFromFullpath(fullpath)}
+	req := identity.AuthzRequest{Auth: p.auth, Time: now}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readEvalTimeout)
+	defer cancel()
+
+	allowed, err := a.authz.Evaluate(ctx, fullpath, "get", req, res)
+	if err != nil {
+		log.Printf("[Warning][Realtime] authz evaluation failed path=%s: %v", fullpath, err)
+		allowed = false
+	}
+
+	a.mu.Lock()
+	if len(a.cache) >= a.maxSize {
+		a.evictLocked(now)
+	}
+	a.cache[key] = decision{allowed: allowed, expiresAt: now.Add(a.ttl)}
+	a.mu.Unlock()
+
+	return allowed
+}
+
+// checkRulesLocked drops all decisions once the rules have been replaced,
+// e.g. through UpdateRules, so that no client keeps seeing documents under
+// rules that no longer apply.
+func (a *authorizer) checkRulesLocked() {
+	if rules := a.authz.GetRules(); rules != a.rules {
+		a.rules = rules
+		a.cache = make(map[decisionKey]decision)
+	}
+}
+
+// evictLocked drops expired decisions, falling back to a full reset when the
+// cache is still at capacity.
+func (a *authorizer) evictLocked(now time.Time) {
+	for k, d := range a.cache {
+		if !now.Before(d.expiresAt) {
+			delete(a.cache, k)
+		}
+	}
+	if len(a.cache) >= a.maxSize {
+		a.cache = make(map[decisionKey]decision)
+	}
+}
+
+func extractIDFromFullpath(fullpath string) string {
+	if idx := strings.LastIndex(fullpath, "/"); idx != -1 {
+		return fullpath[idx+1:]
+	}
+	return fullpath
+}
@@ -0,0 +1,367 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ownerAuthz allows list on "rooms", get on documents whose owner matches
+// the caller's UID and get on anything under "public".
+type ownerAuthz struct {
+	calls atomic.Int32
+	rules *identity.RuleSet
+}
+
+func (a *ownerAuthz) Evaluate(ctx context.Context, path string, action string, req identity.AuthzRequest, res *identity.Resource) (bool, error) {
+	a.calls.Add(1)
+	switch action {
+	case "list":
+		return path == "rooms/"+listProbeID, nil
+	case "get":
+		if strings.HasPrefix(path, "public/") {
+			return true, nil
+		}
+		if res == nil {
+			return false, nil
+		}
+		return res.Data["owner"] == req.Auth.UID, nil
+	}
+	return false, nil
+}
+
+func (a *ownerAuthz) GetRules() *identity.RuleSet      { return a.rules }
+func (a *ownerAuthz) UpdateRules(content []byte) error { return nil }
+func (a *ownerAuthz) LoadRules(path string) error      { return nil }
+
+func TestAuthorizer_CanList(t *testing.T) {
+	a := newAuthorizer(&ownerAuthz{}, Config{})
+	p := newPrincipal("default", nil, "u1", "", nil)
+
+	ok, err := a.canList(context.Background(), p, "rooms")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = a.canList(context.Background(), p, "secrets")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = a.canList(context.Background(), p, "")
+	require.NoError(t, err)
+	assert.False(t, ok, "wildcard subscriptions cannot be authorized by rules")
+}
+
+func TestAuthorizer_CanRead_Caches(t *testing.T) {
+	authz := &ownerAuthz{}
+	a := newAuthorizer(authz, Config{AuthzCacheTTL: time.Minute})
+	now := time.Now()
+	a.now = func() time.Time { return now }
+
+	p := newPrincipal("default", nil, "u1", "", nil)
+	doc := &storage.Document{TenantID: "default", Fullpath: "rooms/r1", Version: 1, Data: map[string]interface{}{"owner": "u1"}}
+
+	assert.True(t, a.canRead(p, storage.Event{Document: doc}))
+	assert.True(t, a.canRead(p, storage.Event{Document: doc}))
+	assert.Equal(t, int32(1), authz.calls.Load())
+
+	// A new version is re-evaluated.
+	doc2 := &storage.Document{TenantID: "default", Fullpath: "rooms/r1", Version: 2, Data: map[string]interface{}{"owner": "u2"}}
+	assert.False(t, a.canRead(p, storage.Event{Document: doc2}))
+	assert.Equal(t, int32(2), authz.calls.Load())
+
+	// Expired entries are re-evaluated.
+	now = now.Add(2 * time.Minute)
+	assert.True(t, a.canRead(p, storage.Event{Document: doc}))
+	assert.Equal(t, int32(3), authz.calls.Load())
+
+	assert.False(t, a.canRead(p, storage.Event{}))
+}
+
+func TestAuthorizer_CanRead_Delete(t *testing.T) {
+	a := newAuthorizer(&ownerAuthz{}, Config{})
+	p := newPrincipal("default", nil, "u1", "", nil)
+
+	// Without a before-image the delete is authorized by its path alone.
+	assert.True(t, a.canRead(p, storage.Event{Type: storage.EventDelete, Id: "public/p1", TenantID: "default"}))
+	assert.False(t, a.canRead(p, storage.Event{Type: storage.EventDelete, Id: "rooms/r1", TenantID: "default"}))
+	assert.False(t, a.canRead(p, storage.Event{Type: storage.EventDelete, Id: "default:hash", TenantID: "default"}))
+
+	before := &storage.Document{TenantID: "default", Fullpath: "rooms/r1", Version: 3, Data: map[string]interface{}{"owner": "u1"}}
+	assert.True(t, a.canRead(p, storage.Event{Type: storage.EventDelete, Id: "default:hash", TenantID: "default", Before: before}))
+}
+
+func TestAuthorizer_UpdateRules_ClearsCache(t *testing.T) {
+	authz := &ownerAuthz{rules: &identity.RuleSet{}}
+	a := newAuthorizer(authz, Config{AuthzCacheTTL: time.Minute})
+	p := newPrincipal("default", nil, "u1", "", nil)
+	doc := &storage.Document{TenantID: "default", Fullpath: "rooms/r1", Version: 1, Data: map[string]interface{}{"owner": "u1"}}
+
+	assert.True(t, a.canRead(p, storage.Event{Document: doc}))
+	assert.True(t, a.canRead(p, storage.Event{Document: doc}))
+	assert.Equal(t, int32(1), authz.calls.Load())
+
+	authz.rules = &identity.RuleSet{}
+	assert.True(t, a.canRead(p, storage.Event{Document: doc}))
+	assert.Equal(t, int32(2), authz.calls.Load())
+}
+
+func TestAuthorizer_Eviction(t *testing.T) {
+	a := newAuthorizer(&ownerAuthz{}, Config{AuthzCacheSize: 2})
+	p := newPrincipal("default", nil, "u1", "", nil)
+	for i, path := range []string{"rooms/a", "rooms/b", "rooms/c"} {
+		a.canRead(p, storage.Event{Document: &storage.Document{Fullpath: path, Version: int64(i)}})
+	}
+	assert.LessOrEqual(t, len(a.cache), 2)
+}
+
+func TestHub_Broadcast_FiltersUnreadableDocuments(t *testing.T) {
+	hub := NewHub()
+	hub.authorizer = newAuthorizer(&ownerAuthz{}, Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	newClient := func(uid string, allowAll bool) *Client {
+		c := &Client{
+			hub:             hub,
+			send:            make(chan BaseMessage, 10),
+			tenant:          "default",
+			allowAllTenants: allowAll,
+			principal:       newPrincipal("default", nil, uid, "", nil),
+			subscriptions: map[string]Subscription{
+				"s1": {Query: model.Query{Collection: "rooms"}},
+			},
+		}
+		require.True(t, hub.Register(c))
+		return c
+	}
+	owner := newClient("u1", false)
+	other := newClient("u2", false)
+	system := newClient("", true)
+
+	hub.Broadcast(storage.Event{
+		Type:     storage.EventUpdate,
+		Id:       "default:hash",
+		TenantID: "default",
+		Document: &storage.Document{TenantID: "default", Fullpath: "rooms/r1", Collection: "rooms", Version: 1, Data: map[string]interface{}{"owner": "u1"}},
+	})
+
+	for _, c := range []*Client{owner, system} {
+		select {
+		case msg := <-c.send:
+			assert.Equal(t, TypeEvent, msg.Type)
+		case <-time.After(time.Second):
+			t.Fatal("expected event")
+		}
+	}
+	select {
+	case msg := <-other.send:
+		t.Fatalf("unexpected message %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHub_Broadcast_DeliversReadableDeletes(t *testing.T) {
+	hub := NewHub()
+	hub.authorizer = newAuthorizer(&ownerAuthz{}, Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	c := &Client{
+		hub:       hub,
+		send:      make(chan BaseMessage, 10),
+		tenant:    "default",
+		principal: newPrincipal("default", nil, "u1", "", nil),
+		subscriptions: map[string]Subscription{
+			"s1": {Query: model.Query{Collection: "public"}},
+		},
+	}
+	require.True(t, hub.Register(c))
+
+	hub.Broadcast(storage.Event{Type: storage.EventDelete, Id: "public/p1", TenantID: "default"})
+
+	select {
+	case msg := <-c.send:
+		assert.Equal(t, TypeEvent, msg.Type)
+	case <-time.After(time.Second):
+		t.Fatal("expected delete event")
+	}
+}
+
+func TestClient_Subscribe_PermissionDenied(t *testing.T) {
+	hub := NewHub()
+	hub.authorizer = newAuthorizer(&ownerAuthz{}, Config{})
+	c := &Client{
+		hub:           hub,
+		send:          make(chan BaseMessage, 10),
+		subscriptions: make(map[string]Subscription),
+		tenant:        "default",
+		authenticated: true,
+		principal:     newPrincipal("default", nil, "u1", "", nil),
+	}
+
+	payload, _ := json.Marshal(SubscribePayload{Query: model.Query{Collection: "secrets"}})
+	c.handleMessage(BaseMessage{ID: "sub1", Type: TypeSubscribe, Payload: payload})
+
+	msg := <-c.send
+	assert.Equal(t, TypeError, msg.Type)
+	var errPayload ErrorPayload
+	require.NoError(t, json.Unmarshal(msg.Payload, &errPayload))
+	assert.Equal(t, "permission_denied", errPayload.Code)
+	assert.Empty(t, c.subscriptions)
+
+	payload, _ = json.Marshal(SubscribePayload{Query: model.Query{Collection: "rooms"}})
+	c.handleMessage(BaseMessage{ID: "sub2", Type: TypeSubscribe, Payload: payload})
+	msg = <-c.send
+	assert.Equal(t, TypeSubscribeAck, msg.Type)
+	assert.Contains(t, c.subscriptions, "sub2")
+}
+
+// blockingAuthz allows get on everything, but holds evaluations of paths
+// under "slow" until release is closed or the evaluation times out.
+type blockingAuthz struct {
+	ownerAuthz
+	release chan struct{}
+}
+
+func (a *blockingAuthz) Evaluate(ctx context.Context, path string, action string, req identity.AuthzRequest, res *identity.Resource) (bool, error) {
+	a.calls.Add(1)
+	if strings.HasPrefix(path, "slow/") {
+		select {
+		case <-a.release:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	return true, nil
+}
+
+func TestHub_Broadcast_SlowAuthzDoesNotBlockOtherClients(t *testing.T) {
+	authz := &blockingAuthz{release: make(chan struct{})}
+	hub := NewHub()
+	hub.authorizer = newAuthorizer(authz, Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	newClient := func(uid, collection string) *Client {
+		c := &Client{
+			hub:       hub,
+			send:      make(chan BaseMessage, 10),
+			tenant:    "default",
+			principal: newPrincipal("default", nil, uid, "", nil),
+			subscriptions: map[string]Subscription{
+				"s1": {Query: model.Query{Collection: collection}},
+			},
+		}
+		require.True(t, hub.Register(c))
+		return c
+	}
+	slow := newClient("u1", "slow")
+	fast := newClient("u2", "public")
+
+	doc := func(path string) storage.Event {
+		return storage.Event{Type: storage.EventCreate, Id: path, TenantID: "default", Document: &storage.Document{
+			TenantID: "default", Fullpath: path, Collection: strings.Split(path, "/")[0], Version: 1, Data: map[string]interface{}{},
+		}}
+	}
+	hub.Broadcast(doc("slow/a"))
+	hub.Broadcast(doc("public/b"))
+
+	select {
+	case msg := <-fast.send:
+		assert.Equal(t, TypeEvent, msg.Type)
+	case <-time.After(readEvalTimeout / 2):
+		t.Fatal("a slow rule for one client held up the hub")
+	}
+
+	close(authz.release)
+	select {
+	case msg := <-slow.send:
+		assert.Equal(t, TypeEvent, msg.Type)
+	case <-time.After(time.Second):
+		t.Fatal("expected the event once its rule resolved")
+	}
+}
+
+func TestHub_Broadcast_AuthzTimeoutDropsEvent(t *testing.T) {
+	hub := NewHub()
+	hub.authorizer = newAuthorizer(&blockingAuthz{release: make(chan struct{})}, Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	c := &Client{
+		hub:       hub,
+		send:      make(chan BaseMessage, 10),
+		tenant:    "default",
+		principal: newPrincipal("default", nil, "u1", "", nil),
+		subscriptions: map[string]Subscription{
+			"s1": {Query: model.Query{Collection: "slow"}},
+		},
+	}
+	require.True(t, hub.Register(c))
+
+	hub.Broadcast(storage.Event{Type: storage.EventCreate, Id: "slow/a", TenantID: "default", Document: &storage.Document{
+		TenantID: "default", Fullpath: "slow/a", Collection: "slow", Version: 1, Data: map[string]interface{}{},
+	}})
+	hub.Broadcast(storage.Event{Type: storage.EventDelete, Id: "slow/b", TenantID: "default"})
+
+	select {
+	case msg := <-c.send:
+		t.Fatalf("unexpected message after a timed out rule: %+v", msg)
+	case <-time.After(3 * readEvalTimeout):
+	}
+	assert.False(t, c.evicted.Load())
+}
+
+func TestHub_Broadcast_AuthzResolvedAfterUnregister(t *testing.T) {
+	authz := &blockingAuthz{release: make(chan struct{})}
+	hub := NewHub()
+	hub.authorizer = newAuthorizer(authz, Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	c := &Client{
+		hub:       hub,
+		send:      make(chan BaseMessage, 10),
+		tenant:    "default",
+		principal: newPrincipal("default", nil, "u1", "", nil),
+		subscriptions: map[string]Subscription{
+			"s1": {Query: model.Query{Collection: "slow"}},
+		},
+	}
+	require.True(t, hub.Register(c))
+
+	hub.Broadcast(storage.Event{Type: storage.EventCreate, Id: "slow/a", TenantID: "default", Document: &storage.Document{
+		TenantID: "default", Fullpath: "slow/a", Collection: "slow", Version: 1, Data: map[string]interface{}{},
+	}})
+	require.Eventually(t, func() bool { return authz.calls.Load() == 1 }, time.Second, time.Millisecond)
+	hub.Unregister(c)
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.unregistered
+	}, time.Second, time.Millisecond)
+
+	// The decision arrives after send was closed and must not be delivered.
+	close(authz.release)
+	require.Eventually(t, func() bool {
+		c.authzMu.Lock()
+		defer c.authzMu.Unlock()
+		return len(c.authzQueue) == 0
+	}, time.Second, time.Millisecond)
+	_, open := <-c.send
+	assert.False(t, open)
+}
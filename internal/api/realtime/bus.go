@@ -0,0 +1,44 @@
+package realtime
+
+import (
+	"github.com/nats-io/nats.go"
+)
+
+// NATS subjects the gateways share realtime state on.
+const (
+	DefaultPresenceSubject = "syntrix.realtime.presence"
+	DefaultTopicSubject    = "syntrix.realtime.topics"
+
+	DefaultRevocationSubject = "syntrix.realtime.revocations"
+)
+
+// Bus carries realtime messages between gateway nodes. Every node receives
+// the messages it publishes as well.
+type Bus interface {
+	Publish(data []byte) error
+	Subscribe(handler func(data []byte)) (unsubscribe func(), err error)
+}
+
+type natsBus struct {
+	nc      *nats.Conn
+	subject string
+}
+
+// NewNATSBus returns a Bus on a NATS core publish/subscribe subject.
+func NewNATSBus(nc *nats.Conn, subject string) Bus {
+	return &natsBus{nc: nc, subject: subject}
+}
+
+func (b *natsBus) Publish(data []byte) error {
+	return b.nc.Publish(b.subject, data)
+}
+
+func (b *natsBus) Subscribe(handler func(data []byte)) (func(), error) {
+	sub, err := b.nc.Subscribe(b.subject, func(msg *nats.Msg) {
+		handler(msg.Data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() { _ = sub.Unsubscribe() }, nil
+}
@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/codetrek/syntrix/internal/engine"
@@ -79,15 +81,15 @@ func safeCheckOrigin(r *http.Request) bool {
 	return strings.EqualFold(originHost, requestHost)
 }
 
-// Client is a middleman between the websocket connection and the hub.
+// Client is a middleman between a realtime connection and the hub.
 type Client struct {
 	hub          *Hub
 	queryService engine.Service
 	auth         identity.AuthN
 	cfg          Config
 
-	// The websocket connection.
-	conn *websocket.Conn
+	// The connection: a WebSocket, an SSE response or an HTTP/2 stream.
+	conn transport
 
 	// Buffered channel of outbound messages.
 	send chan BaseMessage
@@ -99,15 +101,53 @@ type Client struct {
 	tenant          string
 	authenticated   bool
 	allowAllTenants bool
+	principal       principal
+
+	// rate limits inbound messages; nil allows all.
+	rate *rateLimiter
+
+	// shard is the hub broadcast worker that delivers to this client.
+	shard int
+
+	// pending parks event messages under the coalesce overflow policy.
+	pending *coalesceQueue
+
+	// authzQueue holds events waiting for a read decision, oldest first; the
+	// head stays queued until it has been delivered. See Hub.deliver.
+	authzMu    sync.Mutex
+	authzQueue []*eventDeltas
+
+	// unregistered is set under mu before the hub closes send.
+	unregistered bool
+
+	// evicted is set once the client has been disconnected for falling
+	// behind; closed is closed at the same time to stop an SSE or HTTP/2
+	// stream.
+	evicted   atomic.Bool
+	closeOnce sync.Once
+	closed    chan struct{}
 }
 
 type Subscription struct {
 	Query       model.Query
 	IncludeData bool
 	CelProgram  cel.Program
+
+	// Patches sends the changed fields of updates instead of the whole
+	// document when they are known.
+	Patches bool
+
+	// pattern replaces Query.Collection for path and pattern subscriptions.
+	pattern *pathPattern
+
+	// snapshot is non-nil while the initial snapshot is being delivered.
+	snapshot *snapshotBuffer
+
+	// view is the tracked result window of a view subscription.
+	view *queryView
 }
 
-// readPump pumps messages from the websocket connection to the hub.
+// readPump pumps messages from the connection to the hub.
 //
 // The application runs readPump in a per-connection goroutine. The application
 // ensures that there is at most one reader on a connection by executing all
@@ -115,30 +155,32 @@ type Subscription struct {
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.Unregister(c)
-		c.conn.Close()
+		c.conn.close()
 	}()
-	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
-	log.Println("[Info][WS] WebSocket connection established")
+	log.Println("[Info][WS] Connection established")
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		msg, err := c.conn.read()
+		if errors.Is(err, errMalformedMessage) {
+			log.Printf("[Warning][WS] unmarshalling message: %v", err)
+			continue
+		}
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("[Warning][WS] Websocket connection closed: %v", err)
+				log.Printf("[Warning][WS] Connection closed: %v", err)
 			} else {
-				log.Println("[Info][WS] WebSocket connection closed")
+				log.Println("[Info][WS] Connection closed")
 			}
 			break
 		}
 
-		var msg BaseMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("[Warning][WS] unmarshalling message: %v", err)
+		if !c.rate.allow() {
+			limitRejections.WithLabelValues("messages").Inc()
+			c.enqueue(BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: codeRateLimited, Message: "too many messages"})}, "\x00"+codeRateLimited, c.hub.overflow)
 			continue
 		}
 
+		c.hub.presence.touch(c)
 		c.handleMessage(msg)
 	}
 }
@@ -158,6 +200,10 @@ func (c *Client) handleMessage(msg BaseMessage) {
 			log.Printf("[Error][WS] unmarshalling subscribe payload: %v", err)
 			return
 		}
+		if payload.Topic != "" {
+			c.handleTopicSubscribe(msg, payload.Topic)
+			return
+		}
 
 		// Compile CEL filters
 		prg, err := compileFiltersToCEL(payload.Query.Filters)
@@ -172,51 +218,68 @@ func (c *Client) handleMessage(msg BaseMessage) {
 			return
 		}
 
-		c.mu.Lock()
-		c.subscriptions[msg.ID] = Subscription{
+		pattern, err := subscriptionPattern(payload)
+		if err != nil {
+			c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "invalid_subscription", Message: err.Error()})}
+			return
+		}
+		if pattern != nil {
+			if !c.authorizePattern(pattern) {
+				c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "permission_denied", Message: "read not allowed on path"})}
+				return
+			}
+		} else if !c.authorizeSubscription(payload.Query.Collection) {
+			c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "permission_denied", Message: "list not allowed on collection"})}
+			return
+		}
+		if !c.allowSubscription(msg.ID) {
+			return
+		}
+
+		sub := Subscription{
 			Query:       payload.Query,
 			IncludeData: payload.IncludeData,
 			CelProgram:  prg,
+			Patches:     payload.Patches,
+			pattern:     pattern,
 		}
+		if payload.View {
+			sub.view = newQueryView(payload.Query)
+			payload.SendSnapshot = true
+			payload.ResumeAfter = ""
+		}
+		if payload.SendSnapshot || payload.ResumeAfter != "" {
+			// Hold back live events until the snapshot or replay has been
+			// delivered.
+			sub.snapshot = newSnapshotBuffer(c.cfg.SnapshotBufferSize)
+		}
+
+		c.hub.topics.unsubscribe(c, msg.ID)
+		c.mu.Lock()
+		if old, ok := c.subscriptions[msg.ID]; ok {
+			c.subIndex().remove(c, old)
+		}
+		c.subscriptions[msg.ID] = sub
+		c.subIndex().add(c, sub)
 		c.mu.Unlock()
-		log.Printf("[Info][WS] Subscribed to collection=%s id=%s includeData=%v", payload.Query.Collection, msg.ID, payload.IncludeData)
+		if pattern != nil {
+			log.Printf("[Info][WS] Subscribed to path=%s id=%s includeData=%v", pattern.raw, msg.ID, payload.IncludeData)
+		} else {
+			log.Printf("[Info][WS] Subscribed to collection=%s id=%s includeData=%v", payload.Query.Collection, msg.ID, payload.IncludeData)
+		}
 
 		// Send Ack
 		c.send <- BaseMessage{ID: msg.ID, Type: TypeSubscribeAck}
 
-		if payload.SendSnapshot {
-			// Fetch snapshot
-			req := storage.ReplicationPullRequest{
-				Collection: payload.Query.Collection,
-				Checkpoint: 0,    // From beginning
-				Limit:      1000, // Reasonable limit for snapshot
-			}
-			// Use a background context or create one with timeout
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-
-			resp, err := c.queryService.Pull(ctx, c.tenant, req)
-			if err != nil {
-				log.Printf("[Error][WS] Snapshot pull failed: %v", err)
-				return
-			}
-
-			flatDocs := make([]map[string]interface{}, len(resp.Documents))
-			for i, doc := range resp.Documents {
-				flatDocs[i] = flattenDocument(doc)
-			}
-
-			snapshotPayload := SnapshotPayload{
-				SubID:     msg.ID,
-				Documents: flatDocs,
-			}
-
-			c.send <- BaseMessage{
-				ID:      msg.ID,
-				Type:    TypeSnapshot,
-				Payload: mustMarshal(snapshotPayload),
-			}
+		if payload.ResumeAfter != "" {
+			c.resumeSubscription(msg.ID, payload.ResumeAfter)
+		} else if payload.SendSnapshot {
+			c.snapshotSubscription(msg.ID, sub)
 		}
+	case TypePublish:
+		c.handlePublish(msg)
+	case TypePresenceJoin, TypePresenceUpdate, TypePresenceLeave, TypePresenceSubscribe, TypePresenceUnsubscribe:
+		c.handlePresence(msg)
 	case TypeUnsubscribe:
 		if !c.authenticated {
 			c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "unauthorized", Message: "auth required"})}
@@ -228,8 +291,12 @@ func (c *Client) handleMessage(msg BaseMessage) {
 			return
 		}
 		c.mu.Lock()
-		delete(c.subscriptions, payload.ID)
+		if sub, ok := c.subscriptions[payload.ID]; ok {
+			delete(c.subscriptions, payload.ID)
+			c.subIndex().remove(c, sub)
+		}
 		c.mu.Unlock()
+		c.hub.topics.unsubscribe(c, payload.ID)
 		log.Printf("[Info][WS] Unsubscribed id=%s", payload.ID)
 		c.send <- BaseMessage{ID: msg.ID, Type: TypeUnsubscribeAck}
 	}
@@ -253,17 +320,137 @@ func (c *Client) handleAuth(msg BaseMessage) {
 		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "unauthorized", Message: "invalid token"})}
 		return
 	}
+	p := principalFromClaims(claims)
+
+	// A fresh token keeps an authenticated connection alive but cannot move
+	// it to another user.
+	c.mu.Lock()
+	user, _ := c.principal.auth.UID.(string)
+	switched := c.authenticated && user != "" && (c.tenant != claims.TenantID || user != claims.UserID)
+	c.mu.Unlock()
+	if switched {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "unauthorized", Message: "token is for a different user"})}
+		return
+	}
+
+	if c.hub != nil {
+		if !c.hub.limits.acquire(c, newConnOwner(claims.TenantID, p)) {
+			c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: codeConnectionLimit, Message: "too many connections"})}
+			return
+		}
+		if !c.hub.sessions.track(c, claims) {
+			c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: codeTokenRevoked, Message: "token revoked"})}
+			return
+		}
+	}
 
 	c.mu.Lock()
 	c.tenant = claims.TenantID
 	c.allowAllTenants = hasSystemRoleFromClaims(claims)
 	c.authenticated = true
+	c.principal = p
+	c.subIndex().reindexLocked(c)
 	c.mu.Unlock()
 
 	c.send <- BaseMessage{ID: msg.ID, Type: TypeAuthAck}
 }
 
-// writePump pumps messages from the hub to the websocket connection.
+// authorizeSubscription checks list permission on collection. Clients that
+// bypass tenant isolation (system role or auth disabled) are not subject to
+// rules.
+func (c *Client) authorizeSubscription(collection string) bool {
+	if c.hub == nil || c.hub.authorizer == nil {
+		return true
+	}
+	c.mu.Lock()
+	bypass, p := c.allowAllTenants, c.principal
+	c.mu.Unlock()
+	if bypass {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), authzEvalTimeout)
+	defer cancel()
+	allowed, err := c.hub.authorizer.canList(ctx, p, collection)
+	if err != nil {
+		log.Printf("[Warning][WS] authz list evaluation failed collection=%s: %v", collection, err)
+		return false
+	}
+	return allowed
+}
+
+// authorizePattern checks a path or pattern subscription: get permission on
+// the document of a path, list permission on the collections of a pattern.
+// Wildcards are probed as listProbeID, so the rule blocks matching any value
+// of them decide.
+func (c *Client) authorizePattern(pattern *pathPattern) bool {
+	if c.hub == nil || c.hub.authorizer == nil {
+		return true
+	}
+	c.mu.Lock()
+	bypass, p, tenant := c.allowAllTenants, c.principal, c.tenant
+	c.mu.Unlock()
+	if bypass {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), authzEvalTimeout)
+	defer cancel()
+	if !pattern.document {
+		allowed, err := c.hub.authorizer.canList(ctx, p, pattern.listCollection())
+		if err != nil {
+			log.Printf("[Warning][WS] authz list evaluation failed pattern=%s: %v", pattern.raw, err)
+			return false
+		}
+		return allowed
+	}
+
+	// Rules of a document see its current data, if it exists.
+	var fields model.Document
+	version := int64(-1)
+	if c.queryService != nil {
+		doc, err := c.queryService.GetDocument(ctx, tenant, pattern.raw)
+		switch {
+		case err == nil:
+			fields, version = doc, documentVersion(doc)
+		case !errors.Is(err, model.ErrNotFound):
+			log.Printf("[Warning][WS] reading document for authz failed path=%s: %v", pattern.raw, err)
+			return false
+		}
+	}
+	return c.hub.authorizer.canReadPath(p, tenant, pattern.raw, version, fields)
+}
+
+// canReadDocument checks read permission on a flattened document for this
+// client.
+func (c *Client) canReadDocument(path string, version int64, doc model.Document) bool {
+	if c.hub == nil || c.hub.authorizer == nil {
+		return true
+	}
+	c.mu.Lock()
+	bypass, p, tenant := c.allowAllTenants, c.principal, c.tenant
+	c.mu.Unlock()
+	if bypass {
+		return true
+	}
+	return c.hub.authorizer.canReadPath(p, tenant, path, version, doc)
+}
+
+// canReadEvent checks read permission on the document an event refers to.
+func (c *Client) canReadEvent(evt storage.Event) bool {
+	if c.hub == nil || c.hub.authorizer == nil {
+		return true
+	}
+	c.mu.Lock()
+	bypass, p := c.allowAllTenants, c.principal
+	c.mu.Unlock()
+	if bypass {
+		return true
+	}
+	return c.hub.authorizer.canRead(p, evt)
+}
+
+// writePump pumps messages from the hub to the connection.
 //
 // A goroutine running writePump is started for each connection. The
 // application ensures that there is at most one writer to a connection by
@@ -274,41 +461,73 @@ func (c *Client) writePump() {
 	defer func() {
 		pingTicker.Stop()
 		heartbeatTicker.Stop()
-		c.conn.Close()
+		c.conn.close()
 	}()
 	for {
 		select {
 		case message, ok := <-c.send:
 			log.Printf("[Debug] Client writePump: received message type=%s id=%s", message.Type, message.ID)
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// The hub closed the channel.
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.conn.writeClose("")
+				return
+			}
+			if message.Type == typeClose {
+				c.writeClose(message)
 				return
 			}
 
-			if err := c.conn.WriteJSON(message); err != nil {
+			if err := c.writeMessage(message); err != nil {
 				return
 			}
 
+		case <-c.wakeC():
+			for _, message := range c.takePending() {
+				if message.Type == typeClose {
+					c.writeClose(message)
+					return
+				}
+				if err := c.writeMessage(message); err != nil {
+					return
+				}
+			}
+
+		case <-c.closed:
+			// Evicted; streams without a connection to close end here.
+			return
+
 		case <-pingTicker.C:
-			// WebSocket protocol-level ping (browser auto-responds with pong)
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			// Transport-level ping, e.g. a WebSocket ping frame (browser auto-responds with pong)
+			if err := c.conn.ping(); err != nil {
 				return
 			}
 
 		case <-heartbeatTicker.C:
 			// Application-level heartbeat (visible to browser's onmessage handler)
 			// This keeps SDK's activity timer updated since browsers don't expose ping/pong frames
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteJSON(BaseMessage{Type: TypeHeartbeat}); err != nil {
+			if err := c.writeMessage(BaseMessage{Type: TypeHeartbeat}); err != nil {
 				return
 			}
 		}
 	}
 }
 
+// writeMessage writes msg to the connection.
+func (c *Client) writeMessage(msg BaseMessage) error {
+	err := c.conn.write(msg)
+	if err != nil {
+		log.Printf("[Warning][WS] writing message type=%s: %v", msg.Type, err)
+	}
+	return err
+}
+
+// claimsFromContext returns the token claims the request was authenticated
+// with, if any.
+func claimsFromContext(ctx context.Context) *identity.Claims {
+	claims, _ := ctx.Value(identity.ContextKeyClaims).(*identity.Claims)
+	return claims
+}
+
 func tenantFromContext(ctx context.Context) (string, bool) {
 	if ctx == nil {
 		return "", false
@@ -424,7 +643,11 @@ func checkAllowedOrigin(origin string, reqHost string, cfg Config, credentialed
 func ServeWs(hub *Hub, qs engine.Service, auth identity.AuthN, cfg Config, w http.ResponseWriter, r *http.Request) {
 	r = r.WithContext(r.Context())
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// Clients pick the encoding through Sec-WebSocket-Protocol.
+	up := upgrader
+	up.Subprotocols = subprotocols
+	up.EnableCompression = cfg.EnableCompression
+	conn, err := up.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
@@ -437,15 +660,39 @@ func ServeWs(hub *Hub, qs engine.Service, auth identity.AuthN, cfg Config, w htt
 		queryService:    qs,
 		auth:            auth,
 		cfg:             cfg,
-		conn:            conn,
-		send:            make(chan BaseMessage, 256),
+		conn:            newWSTransport(conn, codecFor(conn.Subprotocol())),
+		send:            make(chan BaseMessage, cfg.sendQueueSize()),
 		subscriptions:   make(map[string]Subscription),
 		tenant:          tenant,
 		authenticated:   !cfg.EnableAuth || tenant != "",
 		allowAllTenants: allowAll || !cfg.EnableAuth,
+		principal:       principalFromContext(r.Context(), tenant),
+		rate:            newRateLimiter(cfg.MessageRate, cfg.MessageBurst),
+	}
+	if hub.overflow == OverflowCoalesce {
+		client.pending = newCoalesceQueue(cfg.sendQueueSize())
+	}
+
+	// Browsers cannot read the status of a failed handshake, so rejected
+	// connections are told why on the socket before it closes.
+	reject := func(code, message string) {
+		_ = client.writeMessage(BaseMessage{Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: code, Message: message})})
+		client.conn.writeClose(code)
+		client.conn.close()
+	}
+	if !hub.limits.acquire(client, newConnOwner(client.tenant, client.principal)) {
+		reject(codeConnectionLimit, "too many connections")
+		return
+	}
+	if !hub.sessions.track(client, claimsFromContext(r.Context())) {
+		hub.limits.release(client)
+		reject(codeTokenRevoked, "token revoked")
+		return
 	}
 
 	if !client.hub.Register(client) {
+		hub.limits.release(client)
+		hub.sessions.removeClient(client)
 		conn.Close()
 		return
 	}
@@ -481,7 +728,6 @@ func ServeSSE(hub *Hub, qs engine.Service, auth identity.AuthN, cfg Config, w ht
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
 	w.Header().Add("Vary", "Origin")
 
-	// Create client without websocket connection
 	tenant := ""
 	allowAll := false
 	if cfg.EnableAuth {
@@ -499,28 +745,77 @@ func ServeSSE(hub *Hub, qs engine.Service, auth identity.AuthN, cfg Config, w ht
 		queryService:    qs,
 		auth:            auth,
 		cfg:             cfg,
-		conn:            nil,
-		send:            make(chan BaseMessage, 256),
+		conn:            &sseTransport{ctx: ctx, w: w, flusher: flusher},
+		send:            make(chan BaseMessage, cfg.sendQueueSize()),
 		subscriptions:   make(map[string]Subscription),
 		tenant:          tenant,
 		authenticated:   !cfg.EnableAuth || tenant != "",
 		allowAllTenants: allowAll || !cfg.EnableAuth,
+		principal:       principalFromContext(ctx, tenant),
+		closed:          make(chan struct{}),
+	}
+	if hub.overflow == OverflowCoalesce {
+		client.pending = newCoalesceQueue(cfg.sendQueueSize())
 	}
 
-	// Handle initial subscription from query params
+	// Handle initial subscription from query params. A topic subscribes to
+	// ephemeral messages instead of document changes.
+	topic := r.URL.Query().Get("topic")
 	collection := r.URL.Query().Get("collection")
-	// If collection is provided, subscribe to it.
-	// If not provided, we subscribe to everything (empty string matches all in Hub).
-	// We use "default" as the subscription ID.
-	client.subscriptions["default"] = Subscription{
-		Query:       model.Query{Collection: collection},
-		IncludeData: true, // SSE clients typically expect data
+	if topic != "" {
+		if !validTopic(topic) {
+			http.Error(w, "invalid topic name", http.StatusBadRequest)
+			return
+		}
+		if !client.authorizeTopic(topic, "get", nil) {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		log.Printf("[Info][SSE] connection established. Subscribed to topic=%s", topic)
+	} else if path, pattern := r.URL.Query().Get("path"), r.URL.Query().Get("pattern"); path != "" || pattern != "" {
+		p, err := subscriptionPattern(SubscribePayload{Query: model.Query{Collection: collection}, Path: path, Pattern: pattern})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !client.authorizePattern(p) {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		client.subscriptions["default"] = Subscription{IncludeData: true, pattern: p}
+		log.Printf("[Info][SSE] connection established. Subscribed to path=%s", p.raw)
+	} else {
+		if !client.authorizeSubscription(collection) {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		// If collection is provided, subscribe to it.
+		// If not provided, we subscribe to everything (empty string matches all in Hub).
+		// We use "default" as the subscription ID.
+		client.subscriptions["default"] = Subscription{
+			Query:       model.Query{Collection: collection},
+			IncludeData: true, // SSE clients typically expect data
+		}
+		log.Printf("[Info][SSE] connection established. Subscribed to collection=%s", collection)
 	}
-	log.Printf("[Info][SSE] connection established. Subscribed to collection=%s", collection)
 
+	if !hub.limits.acquire(client, newConnOwner(client.tenant, client.principal)) {
+		writeLimitError(w, codeConnectionLimit, "too many connections")
+		return
+	}
+	if !hub.sessions.track(client, claimsFromContext(ctx)) {
+		hub.limits.release(client)
+		http.Error(w, "token revoked", http.StatusUnauthorized)
+		return
+	}
 	if !client.hub.Register(client) {
+		hub.limits.release(client)
+		hub.sessions.removeClient(client)
 		return
 	}
+	if topic != "" {
+		client.hub.topics.subscribe(client, "default", client.topicTenant(), topic)
+	}
 
 	// Unregister on exit
 	defer func() {
@@ -541,26 +836,49 @@ func ServeSSE(hub *Hub, qs engine.Service, auth identity.AuthN, cfg Config, w ht
 		case <-ctx.Done():
 			log.Println("[Info][SSE] context cancelled, closing connection")
 			return
+		case <-client.closed:
+			log.Println("[Info][SSE] client fell behind, closing connection")
+			return
 		case <-ticker.C:
-			if _, err := fmt.Fprintf(w, ": heartbeat\n\n"); err != nil {
+			if err := client.conn.ping(); err != nil {
 				log.Println("[Warning][SSE] heartbeat error:", err)
 				return
 			}
-			flusher.Flush()
 		case message, ok := <-client.send:
 			if !ok {
 				log.Println("[Info][SSE] send channel closed")
 				return
 			}
-			data, err := json.Marshal(message)
-			if err != nil {
-				continue
+			if message.Type == typeClose {
+				log.Println("[Info][SSE] closed by server")
+				return
 			}
-			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			if err := client.conn.write(message); err != nil {
 				log.Println("[Error][SSE] write error:", err)
 				return
 			}
-			flusher.Flush()
+		case <-client.wakeC():
+			for _, message := range client.takePending() {
+				if message.Type == typeClose {
+					log.Println("[Info][SSE] closed by server")
+					return
+				}
+				if err := client.conn.write(message); err != nil {
+					log.Println("[Error][SSE] write error:", err)
+					return
+				}
+			}
 		}
 	}
 }
+
+// writeSSE writes one message as an SSE data frame. Messages that cannot be
+// encoded are skipped.
+func writeSSE(w io.Writer, message BaseMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
@@ -268,7 +268,7 @@ func TestClient_WritePump_WriteError(t *testing.T) {
 	require.NoError(t, err)
 
 	client := &Client{
-		conn: conn,
+		conn: newWSTransport(conn, jsonCodec{}),
 		send: make(chan BaseMessage, 10),
 	}
 
@@ -364,7 +364,7 @@ func TestClient_HandleSubscribe_SnapshotError(t *testing.T) {
 	defer cancel()
 
 	mockQS := &MockQueryService{}
-	mockQS.On("Pull", mock.Anything, mock.Anything, mock.Anything).Return(nil, assert.AnError)
+	mockQS.On("ExecuteQuery", mock.Anything, mock.Anything, mock.Anything).Return(nil, assert.AnError)
 
 	client := &Client{
 		hub:           hub,
@@ -420,7 +420,7 @@ func TestClient_WritePump_PingError(t *testing.T) {
 	require.NoError(t, err)
 
 	client := &Client{
-		conn: conn,
+		conn: newWSTransport(conn, jsonCodec{}),
 		send: make(chan BaseMessage, 10),
 	}
 
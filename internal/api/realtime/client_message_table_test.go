@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/codetrek/syntrix/internal/identity"
-	"github.com/codetrek/syntrix/internal/storage"
 	"github.com/codetrek/syntrix/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -131,13 +130,7 @@ func TestClientHandleMessage_TableDriven(t *testing.T) {
 				return BaseMessage{Type: TypeSubscribe, ID: "sub", Payload: b}
 			}(),
 			setupQuery: func(m *MockQueryService) {
-				// Mock Pull for Snapshot
-				// Note: The actual implementation calls Pull. We need to mock it.
-				// However, MockQueryService in this package might need adjustment or we use the one from rest package?
-				// Looking at client_message_test.go, it uses a local setupMockQuery helper.
-				// Let's assume MockQueryService has Pull method mocked.
-				// The previous test used:
-				// m.On("Pull", mock.Anything, mock.Anything, mock.Anything).Return(&storage.ReplicationPullResponse{...}, nil).Maybe()
+				// The default ExecuteQuery mock returns an empty snapshot.
 			},
 			expectedType: TypeSubscribeAck,
 			// Note: Snapshot message follows immediately. This test structure checks the first response.
@@ -180,8 +173,8 @@ func TestClientHandleMessage_TableDriven(t *testing.T) {
 			t.Parallel()
 			hub := NewHub()
 			qs := new(MockQueryService)
-			// Default mock behavior for Pull if not specified
-			qs.On("Pull", mock.Anything, mock.Anything, mock.Anything).Return(&storage.ReplicationPullResponse{}, nil).Maybe()
+			// Default mock behavior for snapshot queries if not specified
+			qs.On("ExecuteQuery", mock.Anything, mock.Anything, mock.Anything).Return([]model.Document{}, nil).Maybe()
 
 			if tt.setupQuery != nil {
 				tt.setupQuery(qs)
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/codetrek/syntrix/internal/identity"
-	"github.com/codetrek/syntrix/internal/storage"
 	"github.com/codetrek/syntrix/pkg/model"
 
 	"github.com/gorilla/websocket"
@@ -245,7 +244,7 @@ func TestWritePump_StopsOnChannelClose(t *testing.T) {
 		if err != nil {
 			return
 		}
-		c := &Client{conn: conn, send: make(chan BaseMessage, 1)}
+		c := &Client{conn: newWSTransport(conn, jsonCodec{}), send: make(chan BaseMessage, 1)}
 		clientCh <- c
 		go c.writePump()
 	}))
@@ -291,7 +290,7 @@ func TestWritePump_SendsPing(t *testing.T) {
 		if err != nil {
 			return
 		}
-		c := &Client{conn: conn, send: make(chan BaseMessage, 1)}
+		c := &Client{conn: newWSTransport(conn, jsonCodec{}), send: make(chan BaseMessage, 1)}
 		clientCh <- c
 		go c.writePump()
 	}))
@@ -354,7 +353,7 @@ func TestWritePump_SendsHeartbeat(t *testing.T) {
 		if err != nil {
 			return
 		}
-		c := &Client{conn: conn, send: make(chan BaseMessage, 1)}
+		c := &Client{conn: newWSTransport(conn, jsonCodec{}), send: make(chan BaseMessage, 1)}
 		clientCh <- c
 		go c.writePump()
 	}))
@@ -434,9 +433,9 @@ func TestServeWs_ReadWriteCycle(t *testing.T) {
 
 func setupMockQuery() *MockQueryService {
 	m := new(MockQueryService)
-	// Mock Pull for Snapshot
-	m.On("Pull", mock.Anything, mock.Anything, mock.Anything).Return(&storage.ReplicationPullResponse{
-		Documents: []*storage.Document{{Id: "1", Data: map[string]interface{}{"name": "test"}}},
+	// Mock ExecuteQuery for Snapshot
+	m.On("ExecuteQuery", mock.Anything, mock.Anything, mock.Anything).Return([]model.Document{
+		{"id": "1", "collection": "users", "version": int64(1), "name": "test"},
 	}, nil).Maybe()
 	return m
 }
@@ -0,0 +1,158 @@
+package realtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// WebSocket subprotocols selecting the encoding of messages. Connections that
+// request none use JSON text frames.
+const (
+	SubprotocolJSON    = "syntrix.json"
+	SubprotocolMsgPack = "syntrix.msgpack"
+	SubprotocolCBOR    = "syntrix.cbor"
+)
+
+// subprotocols lists the supported subprotocols in order of preference.
+var subprotocols = []string{SubprotocolMsgPack, SubprotocolCBOR, SubprotocolJSON}
+
+// codec encodes messages for one connection. Binary codecs carry the same
+// envelope as JSON with the payload encoded natively instead of as a string.
+type codec interface {
+	frameType() int
+	encode(msg BaseMessage) ([]byte, error)
+	decode(data []byte) (BaseMessage, error)
+}
+
+// codecFor returns the codec of a negotiated subprotocol.
+func codecFor(subprotocol string) codec {
+	switch subprotocol {
+	case SubprotocolMsgPack:
+		return msgpackCodec{}
+	case SubprotocolCBOR:
+		return cborCodec{}
+	default:
+		return jsonCodec{}
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) frameType() int { return websocket.TextMessage }
+
+func (jsonCodec) encode(msg BaseMessage) ([]byte, error) { return json.Marshal(msg) }
+
+func (jsonCodec) decode(data []byte) (BaseMessage, error) {
+	var msg BaseMessage
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+// binaryMessage is the envelope of binary codecs.
+type binaryMessage struct {
+	ID      string      `msgpack:"id,omitempty" cbor:"id,omitempty"`
+	Type    string      `msgpack:"type" cbor:"type"`
+	Payload interface{} `msgpack:"payload,omitempty" cbor:"payload,omitempty"`
+}
+
+// toBinary converts the JSON payload of msg into plain values. Numbers stay
+// integers where JSON has no fraction.
+func toBinary(msg BaseMessage) (binaryMessage, error) {
+	out := binaryMessage{ID: msg.ID, Type: msg.Type}
+	if len(msg.Payload) == 0 {
+		return out, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(msg.Payload))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return out, err
+	}
+	out.Payload = fromJSONNumbers(v)
+	return out, nil
+}
+
+func fromJSONNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = fromJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = fromJSONNumbers(e)
+		}
+	}
+	return v
+}
+
+// fromBinary converts a decoded binary envelope back into a BaseMessage.
+func fromBinary(in binaryMessage) (BaseMessage, error) {
+	msg := BaseMessage{ID: in.ID, Type: in.Type}
+	if in.Payload == nil {
+		return msg, nil
+	}
+	payload, err := json.Marshal(in.Payload)
+	if err != nil {
+		return msg, fmt.Errorf("payload: %w", err)
+	}
+	msg.Payload = payload
+	return msg, nil
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) frameType() int { return websocket.BinaryMessage }
+
+func (msgpackCodec) encode(msg BaseMessage) ([]byte, error) {
+	out, err := toBinary(msg)
+	if err != nil {
+		return nil, err
+	}
+	return msgpack.Marshal(out)
+}
+
+func (msgpackCodec) decode(data []byte) (BaseMessage, error) {
+	var in binaryMessage
+	if err := msgpack.Unmarshal(data, &in); err != nil {
+		return BaseMessage{}, err
+	}
+	return fromBinary(in)
+}
+
+// cborDecMode decodes maps with string keys so payloads convert to JSON.
+var cborDecMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+}.DecMode()
+
+type cborCodec struct{}
+
+func (cborCodec) frameType() int { return websocket.BinaryMessage }
+
+func (cborCodec) encode(msg BaseMessage) ([]byte, error) {
+	out, err := toBinary(msg)
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(out)
+}
+
+func (cborCodec) decode(data []byte) (BaseMessage, error) {
+	var in binaryMessage
+	if err := cborDecMode.Unmarshal(data, &in); err != nil {
+		return BaseMessage{}, err
+	}
+	return fromBinary(in)
+}
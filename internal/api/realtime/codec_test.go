@@ -0,0 +1,101 @@
+package realtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	msg := BaseMessage{ID: "1", Type: TypeSubscribe, Payload: mustMarshal(map[string]interface{}{
+		"query":       map[string]interface{}{"collection": "users", "limit": 10},
+		"includeData": true,
+		"ratio":       0.5,
+		"tags":        []interface{}{"a", nil},
+	})}
+
+	for _, proto := range subprotocols {
+		t.Run(proto, func(t *testing.T) {
+			c := codecFor(proto)
+			data, err := c.encode(msg)
+			require.NoError(t, err)
+			got, err := c.decode(data)
+			require.NoError(t, err)
+			assert.Equal(t, msg.ID, got.ID)
+			assert.Equal(t, msg.Type, got.Type)
+			assert.JSONEq(t, string(msg.Payload), string(got.Payload))
+		})
+	}
+
+	assert.Equal(t, websocket.TextMessage, codecFor("").frameType())
+	assert.Equal(t, websocket.BinaryMessage, codecFor(SubprotocolCBOR).frameType())
+
+	// Messages without a payload stay without one.
+	data, err := codecFor(SubprotocolMsgPack).encode(BaseMessage{Type: TypeHeartbeat})
+	require.NoError(t, err)
+	got, err := codecFor(SubprotocolMsgPack).decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, BaseMessage{Type: TypeHeartbeat}, got)
+
+	_, err = codecFor(SubprotocolCBOR).decode([]byte{0xff})
+	assert.Error(t, err)
+}
+
+func TestServeWs_NegotiatesEncoding(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	qs := &MockQueryService{}
+	cfg := Config{EnableCompression: true}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, qs, nil, cfg, w, r)
+	}))
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	dialer := websocket.Dialer{
+		Subprotocols:      []string{"other", SubprotocolCBOR},
+		EnableCompression: true,
+	}
+	conn, resp, err := dialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, SubprotocolCBOR, conn.Subprotocol())
+	assert.Contains(t, resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+
+	c := codecFor(SubprotocolCBOR)
+	data, err := c.encode(BaseMessage{ID: "s1", Type: TypeSubscribe, Payload: mustMarshal(SubscribePayload{Query: model.Query{Collection: "users"}})})
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, data))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	frame, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, frame)
+	ack, err := c.decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, TypeSubscribeAck, ack.Type)
+	assert.Equal(t, "s1", ack.ID)
+
+	// Clients that ask for no subprotocol keep JSON text frames.
+	plain, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer plain.Close()
+	assert.Empty(t, plain.Subprotocol())
+	require.NoError(t, plain.WriteJSON(BaseMessage{ID: "s2", Type: TypeSubscribe, Payload: mustMarshal(SubscribePayload{Query: model.Query{Collection: "users"}})}))
+	require.NoError(t, plain.SetReadDeadline(time.Now().Add(time.Second)))
+	frame, data, err = plain.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, frame)
+	assert.Contains(t, string(data), TypeSubscribeAck)
+}
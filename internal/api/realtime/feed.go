@@ -0,0 +1,199 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/puller"
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+)
+
+const (
+	// feedSaveInterval is how often the feed persists its progress marker.
+	feedSaveInterval = time.Second
+
+	feedSaveTimeout = 5 * time.Second
+
+	feedRetryMin = 100 * time.Millisecond
+	feedRetryMax = 10 * time.Second
+)
+
+// EventSource is a puller subscription the hub can be fed from. It is
+// satisfied by puller.Service, both in-process and over gRPC.
+type EventSource interface {
+	Subscribe(ctx context.Context, consumerID string, after string) (<-chan *puller.Event, error)
+}
+
+// ProgressStore persists the puller progress marker of a gateway.
+type ProgressStore interface {
+	LoadProgress(ctx context.Context) (string, error)
+	SaveProgress(ctx context.Context, marker string) error
+}
+
+// documentProgressStore keeps the marker in a system document, next to the
+// trigger evaluator checkpoints.
+type documentProgressStore struct {
+	store storage.DocumentStore
+	path  string
+}
+
+const progressTenant = "default"
+
+// NewProgressStore returns a ProgressStore that keeps the marker of
+// consumerID in the document store.
+func NewProgressStore(store storage.DocumentStore, consumerID string) ProgressStore {
+	return &documentProgressStore{
+		store: store,
+		path:  fmt.Sprintf("sys/checkpoints/realtime_gateway/%s", consumerID),
+	}
+}
+
+func (s *documentProgressStore) LoadProgress(ctx context.Context) (string, error) {
+	doc, err := s.store.Get(ctx, progressTenant, s.path)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	marker, _ := doc.Data["progress"].(string)
+	return marker, nil
+}
+
+func (s *documentProgressStore) SaveProgress(ctx context.Context, marker string) error {
+	data := map[string]interface{}{
+		"progress":  marker,
+		"updatedAt": time.Now().Unix(),
+	}
+	err := s.store.Update(ctx, progressTenant, s.path, data, model.Filters{})
+	if errors.Is(err, model.ErrNotFound) {
+		doc := storage.NewDocument(progressTenant, s.path, "sys/checkpoints/realtime_gateway", data)
+		return s.store.Create(ctx, progressTenant, doc)
+	}
+	return err
+}
+
+// pullerFeed delivers the puller's change events for the data collection to
+// the hub. Its position is persisted so that a restarted gateway or a broken
+// puller stream continues where it stopped instead of skipping events.
+type pullerFeed struct {
+	source     EventSource
+	progress   ProgressStore
+	consumerID string
+	backend    string
+	collection string
+
+	saveInterval time.Duration
+}
+
+// run feeds hub until ctx is cancelled, resubscribing with backoff whenever
+// the puller stream ends.
+func (f *pullerFeed) run(ctx context.Context, hub *Hub) {
+	marker := ""
+	if f.progress != nil {
+		m, err := f.progress.LoadProgress(ctx)
+		if err != nil {
+			log.Printf("[Warning][Realtime] Failed to load feed progress, starting from now: %v", err)
+		}
+		marker = m
+	}
+
+	saved := marker
+	backoff := feedRetryMin
+	for {
+		stream, err := f.source.Subscribe(ctx, f.consumerID, marker)
+		switch {
+		case errors.Is(err, puller.ErrPositionExpired):
+			// The events in between are gone; clients resuming across the
+			// gap are reset by their own replay.
+			log.Printf("[Warning][Realtime] Feed progress is no longer buffered by the puller, continuing from now")
+			marker = ""
+			continue
+		case err != nil:
+			log.Printf("[Warning][Realtime] Puller subscription failed: %v", err)
+		default:
+			log.Printf("[Realtime] Started puller feed consumer=%s", f.consumerID)
+			backoff = feedRetryMin
+			marker = f.consume(ctx, hub, stream, marker, &saved)
+		}
+
+		if ctx.Err() != nil {
+			f.save(marker, &saved)
+			log.Println("[Realtime] Context cancelled, stopping puller feed")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			f.save(marker, &saved)
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > feedRetryMax {
+			backoff = feedRetryMax
+		}
+	}
+}
+
+// consume broadcasts events until the stream ends and returns the last
+// progress marker received.
+func (f *pullerFeed) consume(ctx context.Context, hub *Hub, stream <-chan *puller.Event, marker string, saved *string) string {
+	interval := f.saveInterval
+	if interval <= 0 {
+		interval = feedSaveInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return marker
+		case <-ticker.C:
+			f.save(marker, saved)
+		case pe, ok := <-stream:
+			if !ok {
+				log.Println("[Realtime] Puller feed closed")
+				return marker
+			}
+			evt, ok := changeToEvent(pe.Change, f.backend, f.collection)
+			if ok && isSystemPath(eventPath(evt)) {
+				// System documents, among them the progress marker itself,
+				// are neither delivered nor advance the marker, so that
+				// saving it does not cause another save.
+				continue
+			}
+			if ok {
+				hub.Broadcast(evt)
+			}
+			if pe.Progress != "" {
+				marker = pe.Progress
+			}
+		}
+	}
+}
+
+// isSystemPath reports whether path is a system document under "sys", which
+// realtime clients never see.
+func isSystemPath(path string) bool {
+	return path == "sys" || strings.HasPrefix(path, "sys/")
+}
+
+// save persists marker if it moved since the last save.
+func (f *pullerFeed) save(marker string, saved *string) {
+	if f.progress == nil || marker == *saved {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), feedSaveTimeout)
+	defer cancel()
+	if err := f.progress.SaveProgress(ctx, marker); err != nil {
+		log.Printf("[Warning][Realtime] Failed to save feed progress: %v", err)
+		return
+	}
+	*saved = marker
+}
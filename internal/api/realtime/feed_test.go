@@ -0,0 +1,229 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/puller"
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEventSource struct {
+	mu      sync.Mutex
+	afters  []string
+	streams []chan *puller.Event
+	errs    []error
+}
+
+func (f *fakeEventSource) Subscribe(ctx context.Context, consumerID string, after string) (<-chan *puller.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := len(f.afters)
+	f.afters = append(f.afters, after)
+	if n < len(f.errs) && f.errs[n] != nil {
+		return nil, f.errs[n]
+	}
+	ch := make(chan *puller.Event, 10)
+	f.streams = append(f.streams, ch)
+	return ch, nil
+}
+
+func (f *fakeEventSource) subscriptions() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.afters...)
+}
+
+func (f *fakeEventSource) stream(i int) chan *puller.Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.streams[i]
+}
+
+type memoryProgressStore struct {
+	mu     sync.Mutex
+	marker string
+}
+
+func (m *memoryProgressStore) LoadProgress(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.marker, nil
+}
+
+func (m *memoryProgressStore) SaveProgress(ctx context.Context, marker string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.marker = marker
+	return nil
+}
+
+func (m *memoryProgressStore) load() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.marker
+}
+
+func TestPullerFeed_BroadcastsAndPersistsProgress(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	c := &Client{
+		hub:           hub,
+		send:          make(chan BaseMessage, 10),
+		tenant:        "default",
+		subscriptions: map[string]Subscription{"s1": {Query: model.Query{Collection: "rooms"}}},
+	}
+	require.True(t, hub.Register(c))
+
+	src := &fakeEventSource{}
+	progress := &memoryProgressStore{marker: "m0"}
+	feed := &pullerFeed{
+		source:       src,
+		progress:     progress,
+		consumerID:   "gw-1",
+		backend:      "main",
+		collection:   "documents",
+		saveInterval: 10 * time.Millisecond,
+	}
+	feedCtx, stopFeed := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		feed.run(feedCtx, hub)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return len(src.subscriptions()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "m0", src.subscriptions()[0], "resumes from the persisted marker")
+
+	src.stream(0) <- &puller.Event{
+		Change:   changeEvent("e1", puller.OperationInsert, &storage.Document{TenantID: "default", Fullpath: "rooms/r1", Collection: "rooms", Version: 1}),
+		Progress: "m1",
+	}
+
+	msg := <-c.send
+	var payload EventPayload
+	require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+	assert.Equal(t, "s1", payload.SubID)
+	assert.Equal(t, "default:e1", payload.Delta.ID)
+
+	require.Eventually(t, func() bool { return progress.load() == "m1" }, time.Second, 5*time.Millisecond)
+
+	// A broken stream is resubscribed from the last marker.
+	close(src.stream(0))
+	require.Eventually(t, func() bool { return len(src.subscriptions()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "m1", src.subscriptions()[1])
+
+	src.stream(1) <- &puller.Event{Progress: "m2"}
+	stopFeed()
+	<-done
+	assert.Equal(t, "m2", progress.load(), "progress is saved on shutdown")
+}
+
+func TestPullerFeed_SkipsSystemDocuments(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	c := &Client{
+		hub:             hub,
+		send:            make(chan BaseMessage, 10),
+		allowAllTenants: true,
+		subscriptions:   map[string]Subscription{"s1": {}},
+	}
+	require.True(t, hub.Register(c))
+
+	src := &fakeEventSource{}
+	progress := &memoryProgressStore{marker: "m0"}
+	feed := &pullerFeed{source: src, progress: progress, consumerID: "gw-1", backend: "main", collection: "documents"}
+	feedCtx, stopFeed := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		feed.run(feedCtx, hub)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return len(src.subscriptions()) == 1 }, time.Second, 5*time.Millisecond)
+
+	checkpoint := &storage.Document{TenantID: "default", Fullpath: "sys/checkpoints/realtime_gateway/gw-1", Collection: "sys/checkpoints/realtime_gateway", Version: 2}
+	src.stream(0) <- &puller.Event{Change: changeEvent("e1", puller.OperationUpdate, checkpoint), Progress: "m1"}
+	src.stream(0) <- &puller.Event{
+		Change:   changeEvent("e2", puller.OperationInsert, &storage.Document{TenantID: "default", Fullpath: "rooms/r1", Collection: "rooms", Version: 1}),
+		Progress: "m2",
+	}
+	src.stream(0) <- &puller.Event{Change: changeEvent("e3", puller.OperationUpdate, checkpoint), Progress: "m3"}
+
+	var payload EventPayload
+	require.NoError(t, json.Unmarshal((<-c.send).Payload, &payload))
+	assert.Equal(t, "default:e2", payload.Delta.ID)
+
+	stopFeed()
+	<-done
+	assert.Empty(t, c.send)
+	assert.Equal(t, "m2", progress.load(), "system documents do not advance the marker")
+}
+
+func TestPullerFeed_ExpiredProgressContinuesFromNow(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	src := &fakeEventSource{errs: []error{puller.ErrPositionExpired}}
+	feed := &pullerFeed{source: src, progress: &memoryProgressStore{marker: "old"}, consumerID: "gw-1"}
+	go feed.run(ctx, hub)
+
+	require.Eventually(t, func() bool { return len(src.subscriptions()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"old", ""}, src.subscriptions())
+}
+
+// progressDocStore keeps documents in memory for the progress store.
+type progressDocStore struct {
+	storage.DocumentStore
+	docs map[string]*storage.Document
+}
+
+func (s *progressDocStore) Get(ctx context.Context, tenant, path string) (*storage.Document, error) {
+	if doc, ok := s.docs[tenant+"/"+path]; ok {
+		return doc, nil
+	}
+	return nil, model.ErrNotFound
+}
+
+func (s *progressDocStore) Create(ctx context.Context, tenant string, doc *storage.Document) error {
+	s.docs[tenant+"/"+doc.Fullpath] = doc
+	return nil
+}
+
+func (s *progressDocStore) Update(ctx context.Context, tenant, path string, data map[string]interface{}, pred model.Filters) error {
+	doc, ok := s.docs[tenant+"/"+path]
+	if !ok {
+		return model.ErrNotFound
+	}
+	doc.Data = data
+	return nil
+}
+
+func TestDocumentProgressStore(t *testing.T) {
+	store := NewProgressStore(&progressDocStore{docs: map[string]*storage.Document{}}, "gw-1")
+	ctx := context.Background()
+
+	marker, err := store.LoadProgress(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, marker)
+
+	require.NoError(t, store.SaveProgress(ctx, "m1"))
+	require.NoError(t, store.SaveProgress(ctx, "m2"))
+
+	marker, err = store.LoadProgress(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "m2", marker)
+}
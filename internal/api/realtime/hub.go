@@ -2,7 +2,11 @@ package realtime
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +16,11 @@ import (
 
 // Hub maintains the set of active clients and broadcasts messages to the
 // clients.
+//
+// Subscriptions are indexed by (tenant, collection) so an event is only
+// matched against clients that can receive it. Matching and delivery run on
+// sharded workers; every client is pinned to one shard, which keeps its
+// events in order.
 type Hub struct {
 	// Registered clients.
 	clients map[*Client]bool
@@ -27,22 +36,108 @@ type Hub struct {
 
 	mu sync.RWMutex
 
+	// index maps (tenant, collection) to subscribed clients.
+	index *subscriptionIndex
+
+	// workers is the number of broadcast shards; nextShard assigns clients
+	// to shards round-robin.
+	workers   int
+	nextShard int
+
+	// overflow decides what happens when a client's queue is full.
+	overflow OverflowPolicy
+
+	// authorizer filters delivered documents by read rules. Nil disables
+	// document-level authorization.
+	authorizer *authorizer
+
+	// resume issues resume tokens and replays missed events. Nil disables
+	// resumable subscriptions.
+	resume *resumer
+
+	// node identifies this gateway on the realtime buses.
+	node string
+
+	// presence tracks presence channel membership.
+	presence *presenceTracker
+
+	// topics routes ephemeral topic messages.
+	topics *topicRouter
+
+	// limits counts connections per tenant and user.
+	limits *connLimits
+
+	// sessions tracks token expiry and revocation of connections.
+	sessions *sessionTracker
+
 	runCtx   context.Context
 	runCtxMu sync.RWMutex
 }
 
+// shardWork is one event to deliver to the clients of a shard.
+type shardWork struct {
+	deltas  *eventDeltas
+	clients []*Client
+	done    *sync.WaitGroup
+}
+
 func NewHub() *Hub {
+	node := newNodeID()
 	return &Hub{
 		broadcast:  make(chan storage.Event),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		clients:    make(map[*Client]bool),
+		index:      newSubscriptionIndex(),
+		workers:    runtime.GOMAXPROCS(0),
+		overflow:   OverflowDrop,
+		node:       node,
+		presence:   newPresenceTracker(node),
+		topics:     newTopicRouter(node),
+		limits:     newConnLimits(Config{}),
+		sessions:   newSessionTracker(node),
+	}
+}
+
+func newNodeID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// configure applies the fan-out settings of cfg. It must be called before
+// Run.
+func (h *Hub) configure(cfg Config) {
+	if cfg.BroadcastWorkers > 0 {
+		h.workers = cfg.BroadcastWorkers
+	}
+	if cfg.OverflowPolicy != "" {
+		h.overflow = cfg.OverflowPolicy
+	}
+	h.presence.overflow = h.overflow
+	h.topics.overflow = h.overflow
+	if cfg.PresenceTTL > 0 {
+		h.presence.ttl = cfg.PresenceTTL
+	}
+	h.limits = newConnLimits(cfg)
+	if cfg.ReauthWindow > 0 {
+		h.sessions.window = cfg.ReauthWindow
 	}
 }
 
 func (h *Hub) Run(ctx context.Context) {
 	h.setRunCtx(ctx)
 
+	shards := make([]chan shardWork, h.workers)
+	for i := range shards {
+		shards[i] = make(chan shardWork)
+		go h.runShard(ctx, shards[i])
+	}
+	perShard := make([][]*Client, len(shards))
+	var wg sync.WaitGroup
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -51,87 +146,370 @@ func (h *Hub) Run(ctx context.Context) {
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
+			client.shard = h.nextShard % len(shards)
+			h.nextShard++
 			h.mu.Unlock()
+			h.index.addClient(client)
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				h.index.removeClient(client)
+				h.presence.removeClient(client)
+				h.topics.removeClient(client)
+				h.limits.release(client)
+				h.sessions.removeClient(client)
+				client.closeSend()
 			}
 			h.mu.Unlock()
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				client.mu.Lock()
-				msgTenant := determineEventTenant(message)
-				if !client.allowAllTenants {
-					if client.tenant == "" || msgTenant == "" || msgTenant != client.tenant {
-						client.mu.Unlock()
-						continue
-					}
+			candidates := h.index.lookup(determineEventTenant(message), eventCollection(message), eventPath(message))
+			if len(candidates) == 0 {
+				continue
+			}
+			deltas := newEventDeltas(message, h.resume.tokenFor(message))
+
+			for i := range perShard {
+				perShard[i] = perShard[i][:0]
+			}
+			for _, client := range candidates {
+				perShard[client.shard] = append(perShard[client.shard], client)
+			}
+			// Wait for every shard so that registration changes and the next
+			// event never race with delivery.
+			for i, clients := range perShard {
+				if len(clients) == 0 {
+					continue
 				}
+				wg.Add(1)
+				select {
+				case shards[i] <- shardWork{deltas: deltas, clients: clients, done: &wg}:
+				case <-ctx.Done():
+					wg.Done()
+				}
+			}
+			wg.Wait()
+		}
+	}
+}
+
+func (h *Hub) runShard(ctx context.Context, work <-chan shardWork) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case w := <-work:
+			for _, client := range w.clients {
+				h.deliver(client, w.deltas)
+			}
+			w.done.Done()
+		}
+	}
+}
+
+// maxAuthzBacklog bounds the events a client can have waiting for a read
+// decision; a client that falls further behind is disconnected.
+const maxAuthzBacklog = 1024
+
+// readDecision is a client's read permission on one event, resolved before
+// the event is delivered.
+type readDecision struct {
+	principal string // key of the principal the decision was made for
+	checked   bool
+	allowed   bool
+}
+
+// deliver hands one event to a client. Read permission is taken from the
+// authorizer's cache; on a miss the event waits in the client's authz queue
+// for resolveAuthz, so a slow rule delays only that client and never its
+// shard. Later events queue behind it to keep their order.
+func (h *Hub) deliver(client *Client, deltas *eventDeltas) {
+	if client.evicted.Load() {
+		return
+	}
+
+	client.mu.Lock()
+	if !client.acceptsLocked(deltas.evt) {
+		client.mu.Unlock()
+		return
+	}
+	p, needsRead := client.principal, h.needsReadLocked(client, deltas.evt)
+	client.mu.Unlock()
+
+	client.authzMu.Lock()
+	if len(client.authzQueue) == 0 {
+		d := readDecision{principal: p.key}
+		ok := true
+		if needsRead {
+			d.allowed, ok = h.authorizer.cachedRead(p, deltas.evt)
+			d.checked = ok
+		}
+		if ok {
+			client.authzMu.Unlock()
+			h.deliverDecided(client, deltas, d)
+			return
+		}
+	}
+	if len(client.authzQueue) >= maxAuthzBacklog {
+		client.authzMu.Unlock()
+		client.disconnect("authz_backlog")
+		return
+	}
+	client.authzQueue = append(client.authzQueue, deltas)
+	start := len(client.authzQueue) == 1
+	client.authzMu.Unlock()
+
+	if start {
+		go h.resolveAuthz(client)
+	}
+}
+
+// resolveAuthz works through a client's authz queue, evaluating read
+// permission outside the hub and delivering each event in order. An
+// evaluation that times out denies its event.
+func (h *Hub) resolveAuthz(client *Client) {
+	for {
+		client.authzMu.Lock()
+		deltas := client.authzQueue[0]
+		client.authzMu.Unlock()
+
+		if !client.evicted.Load() {
+			client.mu.Lock()
+			p, needsRead := client.principal, h.needsReadLocked(client, deltas.evt)
+			client.mu.Unlock()
+
+			d := readDecision{principal: p.key}
+			if needsRead {
+				d.checked, d.allowed = true, h.authorizer.canRead(p, deltas.evt)
+			}
+			h.deliverDecided(client, deltas, d)
+		}
+
+		client.authzMu.Lock()
+		client.authzQueue[0] = nil
+		client.authzQueue = client.authzQueue[1:]
+		done := len(client.authzQueue) == 0
+		client.authzMu.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// closeSend closes the client's send channel once the hub has dropped it.
+// Deliveries from the authz queue check unregistered under mu, so they never
+// send on the closed channel.
+func (c *Client) closeSend() {
+	c.mu.Lock()
+	c.unregistered = true
+	c.mu.Unlock()
+	close(c.send)
+}
+
+// needsReadLocked reports whether delivering evt to the client depends on a
+// read decision, i.e. rules apply and some subscription matches it.
+func (h *Hub) needsReadLocked(client *Client, evt storage.Event) bool {
+	if h.authorizer == nil || client.allowAllTenants {
+		return false
+	}
+	for _, sub := range client.subscriptions {
+		if sub.matches(evt) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsLocked reports whether evt belongs to the client's tenant.
+func (c *Client) acceptsLocked(evt storage.Event) bool {
+	if c.allowAllTenants {
+		return true
+	}
+	msgTenant := determineEventTenant(evt)
+	return c.tenant != "" && msgTenant != "" && msgTenant == c.tenant
+}
+
+// deliverDecided matches one event against a client's subscriptions and
+// queues the resulting messages. The event is readable only under d, and not
+// at all if the client re-authenticated as someone else in the meantime.
+func (h *Hub) deliverDecided(client *Client, deltas *eventDeltas, d readDecision) {
+	message, token := deltas.evt, deltas.token
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
 
-				for subID, sub := range client.subscriptions {
-					// Determine collection from event
-					eventCollection := ""
-					if message.Document != nil {
-						eventCollection = message.Document.Collection
-					} else {
-						parts := strings.Split(message.Id, "/")
-						if len(parts) >= 2 {
-							eventCollection = parts[len(parts)-2]
-						}
-					}
-
-					if sub.Query.Collection == "" || eventCollection == sub.Query.Collection {
-						if sub.CelProgram != nil {
-							if message.Document == nil {
-								continue
-							}
-							out, _, err := sub.CelProgram.Eval(map[string]interface{}{
-								"doc": message.Document.Data,
-							})
-							if err != nil {
-								continue
-							}
-							if val, ok := out.Value().(bool); !ok || !val {
-								continue
-							}
-						}
-
-						var doc map[string]interface{}
-						if sub.IncludeData {
-							doc = flattenDocument(message.Document)
-						}
-
-						payload := EventPayload{
-							SubID: subID,
-							Delta: PublicEvent{
-								Type:      message.Type,
-								Document:  doc,
-								ID:        message.Id,
-								Timestamp: message.Timestamp,
-							},
-						}
-						msg := BaseMessage{Type: TypeEvent, Payload: mustMarshal(payload)}
-
-						select {
-						case client.send <- msg:
-						default:
-							select {
-							case client.send <- msg:
-							case <-time.After(50 * time.Millisecond):
-							}
-						}
-					}
+	if client.unregistered || !client.acceptsLocked(message) {
+		return
+	}
+
+	readable := func() bool {
+		if h.authorizer == nil || client.allowAllTenants {
+			return true
+		}
+		return d.checked && d.allowed && d.principal == client.principal.key
+	}
+
+	for subID, sub := range client.subscriptions {
+		if sub.view != nil {
+			if sub.snapshot != nil {
+				if message.Document != nil && message.Document.Collection != sub.Query.Collection {
+					continue
 				}
-				client.mu.Unlock()
+				sub.snapshot.add(bufferedEvent{
+					path:     eventPath(message),
+					version:  eventVersion(message),
+					delete:   message.Type == storage.EventDelete,
+					evt:      message,
+					readable: message.Type != storage.EventDelete && sub.matches(message) && readable(),
+					token:    token,
+				})
+				continue
+			}
+			for _, msg := range client.applyViewEvent(subID, sub, message, readable, token) {
+				// Dropping or merging a view change would corrupt the
+				// client's window, so these are never dropped.
+				client.enqueue(msg, "", h.overflow)
 			}
-			h.mu.RUnlock()
+			continue
+		}
+
+		if !sub.matches(message) || !readable() {
+			continue
 		}
+		msg := deltas.message(subID, sub.deltaMode())
+
+		key := subID + "\x00" + message.Id
+		if sub.snapshot != nil {
+			sub.snapshot.add(bufferedEvent{
+				msg:     msg,
+				key:     key,
+				path:    eventPath(message),
+				version: eventVersion(message),
+				delete:  message.Type == storage.EventDelete,
+			})
+			continue
+		}
+
+		client.enqueue(msg, key, h.overflow)
 	}
 }
 
+// matches reports whether evt belongs to the subscription's collection, path
+// or pattern and satisfies its filters.
+func (s Subscription) matches(evt storage.Event) bool {
+	if s.pattern != nil {
+		if !s.pattern.matches(eventPath(evt)) {
+			return false
+		}
+	} else if s.Query.Collection != "" && eventCollection(evt) != s.Query.Collection {
+		return false
+	}
+	if s.CelProgram != nil {
+		if evt.Document == nil {
+			return false
+		}
+		out, _, err := s.CelProgram.Eval(map[string]interface{}{
+			"doc": evt.Document.Data,
+		})
+		if err != nil {
+			return false
+		}
+		if val, ok := out.Value().(bool); !ok || !val {
+			return false
+		}
+	}
+	return true
+}
+
+// eventMessage builds the event message delivered to subscription subID.
+func (s Subscription) eventMessage(subID string, evt storage.Event, token string) BaseMessage {
+	return newEventDeltas(evt, token).message(subID, s.deltaMode())
+}
+
+// Variants of the public form of an event.
+const (
+	deltaBare  = iota // no document data
+	deltaFull         // the whole document
+	deltaPatch        // changed fields of an update, the whole document otherwise
+	deltaModes
+)
+
+// deltaMode returns the variant of event deltas the subscription receives.
+func (s Subscription) deltaMode() int {
+	switch {
+	case !s.IncludeData:
+		return deltaBare
+	case s.Patches:
+		return deltaPatch
+	default:
+		return deltaFull
+	}
+}
+
+// eventDeltas encodes the public form of one event at most once per variant,
+// so fanning it out to many subscriptions does not marshal it again for each.
+type eventDeltas struct {
+	evt   storage.Event
+	token string
+	once  [deltaModes]sync.Once
+	raw   [deltaModes][]byte
+}
+
+func newEventDeltas(evt storage.Event, token string) *eventDeltas {
+	return &eventDeltas{evt: evt, token: token}
+}
+
+func (d *eventDeltas) delta(mode int) []byte {
+	if mode == deltaPatch && (d.evt.Type != storage.EventUpdate || d.evt.Patch == nil) {
+		mode = deltaFull
+	}
+	d.once[mode].Do(func() {
+		pub := PublicEvent{
+			Type:        d.evt.Type,
+			ID:          d.evt.Id,
+			Timestamp:   d.evt.Timestamp,
+			ResumeToken: d.token,
+		}
+		switch mode {
+		case deltaFull:
+			pub.Document = flattenDocument(d.evt.Document)
+		case deltaPatch:
+			pub.Document = documentHeader(d.evt.Document)
+			pub.Patch = d.evt.Patch
+		}
+		d.raw[mode] = mustMarshal(pub)
+	})
+	return d.raw[mode]
+}
+
+// encodedEventPayload is the wire form of EventPayload with a pre-encoded
+// delta.
+type encodedEventPayload struct {
+	SubID string          `json:"subId"`
+	Delta json.RawMessage `json:"delta"`
+}
+
+// message builds the EventPayload message for subID.
+func (d *eventDeltas) message(subID string, mode int) BaseMessage {
+	return BaseMessage{Type: TypeEvent, Payload: mustMarshal(encodedEventPayload{
+		SubID: subID,
+		Delta: d.delta(mode),
+	})}
+}
+
+// eventCollection determines the collection of an event, falling back to the
+// event ID for deletes without a document.
+func eventCollection(evt storage.Event) string {
+	if doc := eventDocument(evt); doc != nil {
+		return doc.Collection
+	}
+	parts := strings.Split(evt.Id, "/")
+	if len(parts) >= 2 {
+		return parts[len(parts)-2]
+	}
+	return ""
+}
+
 func determineEventTenant(evt storage.Event) string {
 	if evt.TenantID != "" {
 		return evt.TenantID
@@ -145,6 +523,34 @@ func determineEventTenant(evt storage.Event) string {
 	return ""
 }
 
+// eventDocument returns the document state used to authorize an event: the
+// post-image when present, otherwise the before-image of a delete.
+func eventDocument(evt storage.Event) *storage.Document {
+	if evt.Document != nil {
+		return evt.Document
+	}
+	return evt.Before
+}
+
+// eventPath returns the path of the document of evt. A delete without a
+// before-image falls back to the event ID when it is a path.
+func eventPath(evt storage.Event) string {
+	if doc := eventDocument(evt); doc != nil {
+		return doc.Fullpath
+	}
+	if strings.Contains(evt.Id, "/") {
+		return evt.Id
+	}
+	return ""
+}
+
+func eventVersion(evt storage.Event) int64 {
+	if evt.Document != nil {
+		return evt.Document.Version
+	}
+	return -1
+}
+
 func (h *Hub) Broadcast(event storage.Event) {
 	select {
 	case <-h.Done():
@@ -199,6 +605,26 @@ func flattenDocument(doc *storage.Document) map[string]interface{} {
 	return flat
 }
 
+// documentHeader returns the ID and system fields of doc without its data,
+// for events that carry a patch instead.
+func documentHeader(doc *storage.Document) map[string]interface{} {
+	if doc == nil {
+		return nil
+	}
+	header := map[string]interface{}{
+		"version":    doc.Version,
+		"updatedAt":  doc.UpdatedAt,
+		"createdAt":  doc.CreatedAt,
+		"collection": doc.Collection,
+	}
+	if id, ok := doc.Data["id"]; ok {
+		header["id"] = id
+	} else if idx := strings.LastIndex(doc.Fullpath, "/"); idx != -1 {
+		header["id"] = doc.Fullpath[idx+1:]
+	}
+	return header
+}
+
 func mustMarshal(v interface{}) []byte {
 	b, _ := json.Marshal(v) // Should not fail for internal types
 	return b
@@ -223,7 +649,8 @@ func (h *Hub) shutdownClients() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	for client := range h.clients {
-		close(client.send)
+		h.index.removeClient(client)
+		client.closeSend()
 		delete(h.clients, client)
 	}
 }
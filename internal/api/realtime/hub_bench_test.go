@@ -0,0 +1,103 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+)
+
+// BenchmarkHub_Broadcast measures fan-out throughput with 10k connected
+// clients. "spread" distributes clients over 100 tenants and 10 collections,
+// so the index narrows every event to 10 clients; "hot" subscribes every
+// client to the same collection, so every event reaches all of them.
+func BenchmarkHub_Broadcast(b *testing.B) {
+	const clients = 10000
+
+	workerCounts := []int{1, 4}
+	if n := runtime.GOMAXPROCS(0); n != 1 && n != 4 {
+		workerCounts = append(workerCounts, n)
+	}
+
+	for _, layout := range []struct {
+		name        string
+		tenants     int
+		collections int
+	}{
+		{"spread", 100, 10},
+		{"hot", 1, 1},
+	} {
+		for _, workers := range workerCounts {
+			b.Run(fmt.Sprintf("%s/clients=%d/workers=%d", layout.name, clients, workers), func(b *testing.B) {
+				benchmarkBroadcast(b, clients, layout.tenants, layout.collections, workers)
+			})
+		}
+	}
+}
+
+func benchmarkBroadcast(b *testing.B, clients, tenants, collections, workers int) {
+	hub := NewHub()
+	hub.configure(Config{BroadcastWorkers: workers})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	var delivered atomic.Int64
+	var drained sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		tenant := fmt.Sprintf("t%d", i%tenants)
+		c := &Client{
+			hub:    hub,
+			send:   make(chan BaseMessage, defaultSendQueueSize),
+			tenant: tenant,
+			subscriptions: map[string]Subscription{
+				"s": {Query: model.Query{Collection: fmt.Sprintf("c%d", (i/tenants)%collections)}, IncludeData: true},
+			},
+		}
+		if !hub.Register(c) {
+			b.Fatal("register failed")
+		}
+		drained.Add(1)
+		go func() {
+			defer drained.Done()
+			for range c.send {
+				delivered.Add(1)
+			}
+		}()
+	}
+
+	events := make([]storage.Event, tenants*collections)
+	for i := range events {
+		tenant := fmt.Sprintf("t%d", i%tenants)
+		collection := fmt.Sprintf("c%d", (i/tenants)%collections)
+		path := collection + "/doc"
+		events[i] = storage.Event{
+			Id:       storage.CalculateTenantID(tenant, path),
+			TenantID: tenant,
+			Type:     storage.EventUpdate,
+			Document: &storage.Document{
+				TenantID:   tenant,
+				Collection: collection,
+				Fullpath:   path,
+				Version:    1,
+				Data:       map[string]interface{}{"value": 1},
+			},
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hub.Broadcast(events[i%len(events)])
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(delivered.Load())/b.Elapsed().Seconds(), "deliveries/s")
+	cancel()
+	drained.Wait()
+}
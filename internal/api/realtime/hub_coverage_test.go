@@ -124,7 +124,7 @@ func TestHub_Broadcast_BackpressureFallback(t *testing.T) {
 		allowAllTenants: true,
 	}
 
-	// Fill the channel to force default branch in non-blocking send.
+	// Fill the channel so the event overflows the client's queue.
 	client.send <- BaseMessage{Type: "primed"}
 
 	require.True(t, hub.Register(client))
@@ -140,10 +140,10 @@ func TestHub_Broadcast_BackpressureFallback(t *testing.T) {
 
 	hub.Broadcast(evt)
 
-	// Wait for the fallback select (with time.After) to trigger while channel remains full.
+	// Let the shard worker process the event while the channel remains full.
 	time.Sleep(100 * time.Millisecond)
 
-	// No new message should be enqueued because channel stayed full and fallback timeout fired.
+	// The default drop policy discards the event.
 	assert.Equal(t, 1, len(client.send))
 
 	cancel()
@@ -0,0 +1,235 @@
+package realtime
+
+import "sync"
+
+// anyKey stands for "every tenant" or "every collection" in the index.
+const anyKey = "*"
+
+type indexKey struct {
+	tenant     string
+	collection string
+	pattern    string
+}
+
+// subscriptionIndex maps (tenant, collection) to the clients holding at least
+// one subscription for it, so the hub only matches an event against clients
+// that can possibly receive it. Path and pattern subscriptions are keyed by
+// their pattern instead, and found through a per-tenant trie of patterns.
+//
+// Clients that bypass tenant isolation are indexed under anyKey as tenant, and
+// subscriptions without a collection under anyKey as collection. The index is
+// updated while holding the client's mu; it never takes a client lock itself.
+type subscriptionIndex struct {
+	mu       sync.RWMutex
+	byKey    map[indexKey]map[*Client]struct{}
+	byTenant map[string]map[indexKey]struct{}
+	clients  map[*Client]map[indexKey]int
+	patterns map[string]*pathNode
+}
+
+func newSubscriptionIndex() *subscriptionIndex {
+	return &subscriptionIndex{
+		byKey:    make(map[indexKey]map[*Client]struct{}),
+		byTenant: make(map[string]map[indexKey]struct{}),
+		clients:  make(map[*Client]map[indexKey]int),
+		patterns: make(map[string]*pathNode),
+	}
+}
+
+// indexKeyFor returns the index key of a subscription. Callers must hold c.mu.
+func (c *Client) indexKeyFor(sub Subscription) indexKey {
+	k := indexKey{tenant: c.tenant, collection: sub.Query.Collection}
+	if c.allowAllTenants {
+		k.tenant = anyKey
+	}
+	if sub.pattern != nil {
+		k.collection = ""
+		k.pattern = sub.pattern.raw
+	} else if k.collection == "" {
+		k.collection = anyKey
+	}
+	return k
+}
+
+// subIndex returns the index of the client's hub, or nil for a client
+// without one.
+func (c *Client) subIndex() *subscriptionIndex {
+	if c.hub == nil {
+		return nil
+	}
+	return c.hub.index
+}
+
+// addClient indexes every current subscription of c. It takes c.mu.
+func (x *subscriptionIndex) addClient(c *Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	x.reindexLocked(c)
+}
+
+// reindexLocked rebuilds the entries of c from its subscriptions, e.g. after
+// its tenant changed. Callers must hold c.mu.
+func (x *subscriptionIndex) reindexLocked(c *Client) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(c)
+	x.clients[c] = make(map[indexKey]int)
+	for _, sub := range c.subscriptions {
+		x.addLocked(c, c.indexKeyFor(sub))
+	}
+}
+
+// add indexes one subscription of a registered client. Callers must hold
+// c.mu.
+func (x *subscriptionIndex) add(c *Client, sub Subscription) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.clients[c]; !ok {
+		// Not registered (yet); addClient picks the subscription up.
+		return
+	}
+	x.addLocked(c, c.indexKeyFor(sub))
+}
+
+// remove drops one subscription of a client. Callers must hold c.mu.
+func (x *subscriptionIndex) remove(c *Client, sub Subscription) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	keys, ok := x.clients[c]
+	if !ok {
+		return
+	}
+	k := c.indexKeyFor(sub)
+	if keys[k]--; keys[k] <= 0 {
+		delete(keys, k)
+		x.unlinkLocked(c, k)
+	}
+}
+
+// removeClient drops every entry of c.
+func (x *subscriptionIndex) removeClient(c *Client) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(c)
+}
+
+func (x *subscriptionIndex) addLocked(c *Client, k indexKey) {
+	keys := x.clients[c]
+	if keys[k]++; keys[k] > 1 {
+		return
+	}
+	set, ok := x.byKey[k]
+	if !ok {
+		set = make(map[*Client]struct{})
+		x.byKey[k] = set
+		if x.byTenant[k.tenant] == nil {
+			x.byTenant[k.tenant] = make(map[indexKey]struct{})
+		}
+		x.byTenant[k.tenant][k] = struct{}{}
+		if k.pattern != "" {
+			x.insertPattern(k)
+		}
+	}
+	set[c] = struct{}{}
+}
+
+func (x *subscriptionIndex) insertPattern(k indexKey) {
+	p, err := parsePathPattern(k.pattern)
+	if err != nil {
+		return
+	}
+	root, ok := x.patterns[k.tenant]
+	if !ok {
+		root = &pathNode{}
+		x.patterns[k.tenant] = root
+	}
+	root.insert(p)
+}
+
+func (x *subscriptionIndex) removePattern(k indexKey) {
+	p, err := parsePathPattern(k.pattern)
+	if err != nil {
+		return
+	}
+	if root, ok := x.patterns[k.tenant]; ok && root.remove(p.trieSegments()) {
+		delete(x.patterns, k.tenant)
+	}
+}
+
+func (x *subscriptionIndex) unlinkLocked(c *Client, k indexKey) {
+	set := x.byKey[k]
+	delete(set, c)
+	if len(set) == 0 {
+		delete(x.byKey, k)
+		delete(x.byTenant[k.tenant], k)
+		if k.pattern != "" {
+			x.removePattern(k)
+		}
+		if len(x.byTenant[k.tenant]) == 0 {
+			delete(x.byTenant, k.tenant)
+		}
+	}
+}
+
+func (x *subscriptionIndex) removeLocked(c *Client) {
+	for k := range x.clients[c] {
+		x.unlinkLocked(c, k)
+	}
+	delete(x.clients, c)
+}
+
+// lookup returns the clients that may receive an event of tenant and
+// collection about the document at path. An empty collection (a delete
+// without a document) matches every collection of the tenant.
+func (x *subscriptionIndex) lookup(tenant, collection, path string) []*Client {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var out []*Client
+	seen := make(map[*Client]struct{})
+	collect := func(k indexKey) {
+		for c := range x.byKey[k] {
+			if _, ok := seen[c]; !ok {
+				seen[c] = struct{}{}
+				out = append(out, c)
+			}
+		}
+	}
+
+	tenants := []string{anyKey}
+	if tenant != "" && tenant != anyKey {
+		tenants = append(tenants, tenant)
+	}
+	for _, t := range tenants {
+		if collection == "" {
+			for k := range x.byTenant[t] {
+				collect(k)
+			}
+			continue
+		}
+		collect(indexKey{tenant: t, collection: collection})
+		collect(indexKey{tenant: t, collection: anyKey})
+		if root, ok := x.patterns[t]; ok && path != "" {
+			root.match(path, func(pattern string) {
+				collect(indexKey{tenant: t, pattern: pattern})
+			})
+		}
+	}
+	return out
+}
+
+// size returns the number of indexed clients.
+func (x *subscriptionIndex) size() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.clients)
+}
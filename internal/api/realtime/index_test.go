@@ -0,0 +1,69 @@
+package realtime
+
+import (
+	"testing"
+
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func indexedClient(tenant string, allowAll bool, collections ...string) *Client {
+	c := &Client{tenant: tenant, allowAllTenants: allowAll, subscriptions: make(map[string]Subscription)}
+	for i, coll := range collections {
+		c.subscriptions[string(rune('a'+i))] = Subscription{Query: model.Query{Collection: coll}}
+	}
+	return c
+}
+
+func TestSubscriptionIndex_Lookup(t *testing.T) {
+	x := newSubscriptionIndex()
+
+	users := indexedClient("t1", false, "users")
+	rooms := indexedClient("t1", false, "rooms")
+	all := indexedClient("t1", false, "")
+	otherTenant := indexedClient("t2", false, "users")
+	system := indexedClient("", true, "users")
+	for _, c := range []*Client{users, rooms, all, otherTenant, system} {
+		x.addClient(c)
+	}
+
+	assert.ElementsMatch(t, []*Client{users, all, system}, x.lookup("t1", "users", ""))
+	assert.ElementsMatch(t, []*Client{rooms, all}, x.lookup("t1", "rooms", ""))
+	assert.ElementsMatch(t, []*Client{otherTenant, system}, x.lookup("t2", "users", ""))
+	assert.Empty(t, x.lookup("t3", "rooms", ""))
+
+	// Without a collection every subscription of the tenant is a candidate.
+	assert.ElementsMatch(t, []*Client{users, rooms, all, system}, x.lookup("t1", "", ""))
+}
+
+func TestSubscriptionIndex_AddRemove(t *testing.T) {
+	x := newSubscriptionIndex()
+	c := indexedClient("t1", false)
+
+	// Subscriptions of unregistered clients are picked up by addClient.
+	x.add(c, Subscription{Query: model.Query{Collection: "users"}})
+	assert.Empty(t, x.lookup("t1", "users", ""))
+	x.addClient(c)
+
+	// Two subscriptions on one collection are reference counted.
+	x.add(c, Subscription{Query: model.Query{Collection: "users"}})
+	x.add(c, Subscription{Query: model.Query{Collection: "users"}})
+	x.remove(c, Subscription{Query: model.Query{Collection: "users"}})
+	assert.Equal(t, []*Client{c}, x.lookup("t1", "users", ""))
+	x.remove(c, Subscription{Query: model.Query{Collection: "users"}})
+	assert.Empty(t, x.lookup("t1", "users", ""))
+	assert.Empty(t, x.byKey)
+	assert.Empty(t, x.byTenant)
+
+	// Re-authenticating moves the client to its new tenant.
+	c.subscriptions["s"] = Subscription{Query: model.Query{Collection: "users"}}
+	x.addClient(c)
+	c.tenant = "t2"
+	x.reindexLocked(c)
+	assert.Empty(t, x.lookup("t1", "users", ""))
+	assert.Equal(t, []*Client{c}, x.lookup("t2", "users", ""))
+
+	x.removeClient(c)
+	assert.Empty(t, x.lookup("t2", "users", ""))
+	assert.Equal(t, 0, x.size())
+}
@@ -0,0 +1,173 @@
+package realtime
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Error codes of limit violations.
+const (
+	codeConnectionLimit   = "connection_limit"
+	codeSubscriptionLimit = "subscription_limit"
+	codeRateLimited       = "rate_limited"
+)
+
+// connOwner identifies whom a connection counts against.
+type connOwner struct {
+	tenant string
+	user   string
+}
+
+// connLimits counts open connections per tenant and per user.
+type connLimits struct {
+	mu        sync.Mutex
+	maxTenant int
+	maxUser   int
+	tenants   map[string]int
+	users     map[connOwner]int
+	owners    map[*Client]connOwner
+}
+
+func newConnLimits(cfg Config) *connLimits {
+	return &connLimits{
+		maxTenant: cfg.MaxConnectionsPerTenant,
+		maxUser:   cfg.MaxConnectionsPerUser,
+		tenants:   make(map[string]int),
+		users:     make(map[connOwner]int),
+		owners:    make(map[*Client]connOwner),
+	}
+}
+
+func newConnOwner(tenant string, p principal) connOwner {
+	user, _ := p.auth.UID.(string)
+	return connOwner{tenant: tenantOrDefault(tenant), user: user}
+}
+
+// acquire counts c against owner, moving it from whatever it counted
+// against before. It returns false, leaving the count of c unchanged, if
+// that would exceed a limit.
+func (l *connLimits) acquire(c *Client, owner connOwner) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	old, had := l.owners[c]
+	if had && old == owner {
+		return true
+	}
+	if l.maxTenant > 0 && (!had || old.tenant != owner.tenant) && l.tenants[owner.tenant] >= l.maxTenant {
+		limitRejections.WithLabelValues("tenant_connections").Inc()
+		return false
+	}
+	if l.maxUser > 0 && owner.user != "" && l.users[owner] >= l.maxUser {
+		limitRejections.WithLabelValues("user_connections").Inc()
+		return false
+	}
+	if had {
+		l.releaseLocked(c, old)
+	}
+	l.owners[c] = owner
+	l.tenants[owner.tenant]++
+	if owner.user != "" {
+		l.users[owner]++
+	}
+	return true
+}
+
+// release stops counting c.
+func (l *connLimits) release(c *Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if owner, ok := l.owners[c]; ok {
+		l.releaseLocked(c, owner)
+	}
+}
+
+func (l *connLimits) releaseLocked(c *Client, owner connOwner) {
+	delete(l.owners, c)
+	if l.tenants[owner.tenant]--; l.tenants[owner.tenant] <= 0 {
+		delete(l.tenants, owner.tenant)
+	}
+	if owner.user != "" {
+		if l.users[owner]--; l.users[owner] <= 0 {
+			delete(l.users, owner)
+		}
+	}
+}
+
+// rateLimiter is a token bucket for the inbound messages of one client.
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newRateLimiter allows rate messages per second with bursts of burst. A
+// non-positive rate returns nil, which allows everything.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = int(rate)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// allow takes a token if there is one.
+func (r *rateLimiter) allow() bool {
+	if r == nil {
+		return true
+	}
+	now := r.now()
+	if !r.last.IsZero() {
+		r.tokens += now.Sub(r.last).Seconds() * r.rate
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+	}
+	r.last = now
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// subscriptionCount returns the number of query and topic subscriptions of
+// c.
+func (c *Client) subscriptionCount() int {
+	c.mu.Lock()
+	n := len(c.subscriptions)
+	c.mu.Unlock()
+	return n + c.hub.topics.count(c)
+}
+
+// allowSubscription reports whether c may add subscription subID. Replacing
+// an existing subscription is always allowed.
+func (c *Client) allowSubscription(subID string) bool {
+	limit := c.cfg.MaxSubscriptionsPerConnection
+	if limit <= 0 {
+		return true
+	}
+	c.mu.Lock()
+	_, exists := c.subscriptions[subID]
+	c.mu.Unlock()
+	if exists || c.hub.topics.has(c, subID) || c.subscriptionCount() < limit {
+		return true
+	}
+	limitRejections.WithLabelValues("subscriptions").Inc()
+	c.send <- BaseMessage{ID: subID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: codeSubscriptionLimit, Message: "too many subscriptions on this connection"})}
+	return false
+}
+
+// writeLimitError rejects an HTTP request that exceeds a limit.
+func writeLimitError(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_, _ = w.Write(mustMarshal(ErrorPayload{Code: code, Message: message}))
+}
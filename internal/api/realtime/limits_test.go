@@ -0,0 +1,221 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func errorCode(t *testing.T, msg BaseMessage) string {
+	t.Helper()
+	var p ErrorPayload
+	require.NoError(t, json.Unmarshal(msg.Payload, &p))
+	return p.Code
+}
+
+func TestConnLimits(t *testing.T) {
+	l := newConnLimits(Config{MaxConnectionsPerTenant: 3, MaxConnectionsPerUser: 2})
+	a1, a2, a3, b1, c1 := &Client{}, &Client{}, &Client{}, &Client{}, &Client{}
+	alice := connOwner{tenant: "t1", user: "alice"}
+
+	assert.True(t, l.acquire(a1, alice))
+	assert.True(t, l.acquire(a2, alice))
+	assert.False(t, l.acquire(a3, alice), "user limit")
+	assert.True(t, l.acquire(b1, connOwner{tenant: "t1", user: "bob"}))
+	assert.False(t, l.acquire(c1, connOwner{tenant: "t1"}), "tenant limit")
+	assert.True(t, l.acquire(c1, connOwner{tenant: "t2"}))
+
+	// Moving a connection keeps its old slot on failure and frees it on
+	// success.
+	assert.False(t, l.acquire(c1, connOwner{tenant: "t1", user: "carol"}))
+	assert.Equal(t, connOwner{tenant: "t2"}, l.owners[c1])
+	assert.True(t, l.acquire(a1, alice), "re-acquiring the same owner")
+	assert.True(t, l.acquire(a2, connOwner{tenant: "t1", user: "dave"}))
+	assert.False(t, l.acquire(a3, alice), "tenant limit")
+	l.release(b1)
+	assert.True(t, l.acquire(a3, alice))
+
+	for _, c := range []*Client{a1, a2, a3, c1} {
+		l.release(c)
+	}
+	assert.Empty(t, l.tenants)
+	assert.Empty(t, l.users)
+	assert.Empty(t, l.owners)
+}
+
+func TestRateLimiter(t *testing.T) {
+	assert.Nil(t, newRateLimiter(0, 5))
+	assert.True(t, (*rateLimiter)(nil).allow())
+
+	now := time.Unix(1000, 0)
+	r := newRateLimiter(2, 3)
+	r.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		assert.True(t, r.allow())
+	}
+	assert.False(t, r.allow())
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, r.allow())
+	assert.False(t, r.allow())
+
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(t, r.allow())
+	}
+	assert.False(t, r.allow(), "burst caps refill")
+}
+
+func TestClient_SubscriptionLimit(t *testing.T) {
+	h := NewHub()
+	c := newPresenceClient(h, "t1")
+	c.cfg.MaxSubscriptionsPerConnection = 2
+
+	subscribe := func(id string) {
+		c.handleMessage(BaseMessage{ID: id, Type: TypeSubscribe, Payload: mustMarshal(SubscribePayload{Query: model.Query{Collection: "users"}})})
+	}
+	subscribe("s1")
+	nextMessage(t, c, TypeSubscribeAck)
+	topicSubscribe(c, "s2", "room")
+	nextMessage(t, c, TypeSubscribeAck)
+
+	subscribe("s3")
+	assert.Equal(t, codeSubscriptionLimit, errorCode(t, nextMessage(t, c, TypeError)))
+	topicSubscribe(c, "s3", "room")
+	assert.Equal(t, codeSubscriptionLimit, errorCode(t, nextMessage(t, c, TypeError)))
+
+	// Replacing a subscription is not a new one.
+	subscribe("s2")
+	nextMessage(t, c, TypeSubscribeAck)
+	topicSubscribe(c, "s1", "room")
+	nextMessage(t, c, TypeSubscribeAck)
+
+	c.handleMessage(BaseMessage{ID: "u1", Type: TypeUnsubscribe, Payload: mustMarshal(UnsubscribePayload{ID: "s1"})})
+	nextMessage(t, c, TypeUnsubscribeAck)
+	subscribe("s3")
+	nextMessage(t, c, TypeSubscribeAck)
+}
+
+func TestClient_Auth_ConnectionLimit(t *testing.T) {
+	h := NewHub()
+	h.limits = newConnLimits(Config{MaxConnectionsPerUser: 1})
+	auth := new(MockAuthService)
+	auth.On("ValidateToken", "tok").Return(&identity.Claims{TenantID: "t1", UserID: "alice"}, nil)
+
+	first, second := newPresenceClient(h, ""), newPresenceClient(h, "")
+	for _, c := range []*Client{first, second} {
+		c.auth = auth
+		c.authenticated = false
+	}
+
+	first.handleMessage(BaseMessage{ID: "a1", Type: TypeAuth, Payload: mustMarshal(AuthPayload{Token: "tok"})})
+	nextMessage(t, first, TypeAuthAck)
+
+	second.handleMessage(BaseMessage{ID: "a2", Type: TypeAuth, Payload: mustMarshal(AuthPayload{Token: "tok"})})
+	assert.Equal(t, codeConnectionLimit, errorCode(t, nextMessage(t, second, TypeError)))
+	assert.False(t, second.authenticated)
+}
+
+func TestServeWs_ConnectionLimit(t *testing.T) {
+	hub := NewHub()
+	cfg := Config{MaxConnectionsPerTenant: 1}
+	hub.configure(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, &MockQueryService{}, nil, cfg, w, r)
+	}))
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	first, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer second.Close()
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(time.Second)))
+	var msg BaseMessage
+	require.NoError(t, second.ReadJSON(&msg))
+	assert.Equal(t, TypeError, msg.Type)
+	assert.Equal(t, codeConnectionLimit, errorCode(t, msg))
+	_, _, err = second.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "%v", err)
+
+	// Closing the first connection frees its slot.
+	first.Close()
+	require.Eventually(t, func() bool {
+		hub.limits.mu.Lock()
+		defer hub.limits.mu.Unlock()
+		return len(hub.limits.owners) == 0
+	}, time.Second, 10*time.Millisecond)
+	third, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer third.Close()
+	require.NoError(t, third.WriteJSON(BaseMessage{ID: "s1", Type: TypeSubscribe, Payload: mustMarshal(SubscribePayload{Query: model.Query{Collection: "users"}})}))
+	require.NoError(t, third.SetReadDeadline(time.Now().Add(time.Second)))
+	require.NoError(t, third.ReadJSON(&msg))
+	assert.Equal(t, TypeSubscribeAck, msg.Type)
+}
+
+func TestServeWs_RateLimit(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	cfg := Config{MessageRate: 0.001, MessageBurst: 1}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, &MockQueryService{}, nil, cfg, w, r)
+	}))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, id := range []string{"s1", "s2"} {
+		require.NoError(t, conn.WriteJSON(BaseMessage{ID: id, Type: TypeSubscribe, Payload: mustMarshal(SubscribePayload{Query: model.Query{Collection: "users"}})}))
+	}
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	var msg BaseMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, TypeSubscribeAck, msg.Type)
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, TypeError, msg.Type)
+	assert.Equal(t, "s2", msg.ID)
+	assert.Equal(t, codeRateLimited, errorCode(t, msg))
+}
+
+func TestServeSSE_ConnectionLimit(t *testing.T) {
+	hub := NewHub()
+	hub.limits = newConnLimits(Config{MaxConnectionsPerTenant: 1})
+	hub.limits.acquire(&Client{}, connOwner{tenant: "t1"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	cfg := Config{EnableAuth: true}
+	reqCtx := context.WithValue(context.Background(), identity.ContextKeyTenant, "t1")
+	req := httptest.NewRequest("GET", "/realtime/sse?collection=users", nil).WithContext(reqCtx)
+	rr := httptest.NewRecorder()
+	ServeSSE(hub, &MockQueryService{}, nil, cfg, rr, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	var p ErrorPayload
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &p))
+	assert.Equal(t, codeConnectionLimit, p.Code)
+}
@@ -0,0 +1,36 @@
+package realtime
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// Fan-out
+	messagesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_messages_dropped_total",
+		Help: "The total number of event messages dropped because a client queue was full",
+	}, []string{"policy"})
+
+	messagesCoalesced = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "realtime_messages_coalesced_total",
+		Help: "The total number of queued event messages replaced by a newer one",
+	})
+
+	clientsDisconnected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_clients_disconnected_total",
+		Help: "The total number of clients disconnected by the server",
+	}, []string{"reason"})
+
+	// Limits
+	limitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_limit_rejections_total",
+		Help: "The total number of connections, subscriptions and messages rejected by a limit",
+	}, []string{"limit"})
+)
+
+func init() {
+	prometheus.MustRegister(messagesDropped)
+	prometheus.MustRegister(messagesCoalesced)
+	prometheus.MustRegister(clientsDisconnected)
+	prometheus.MustRegister(limitRejections)
+}
@@ -0,0 +1,200 @@
+package realtime
+
+import (
+	"errors"
+	"strings"
+)
+
+// Wildcards of path patterns.
+const (
+	segmentWildcard = "*"  // any one segment
+	prefixWildcard  = "**" // any path below, as the last segment only
+)
+
+// pathPattern selects the documents a path or pattern subscription receives:
+// one document by its path, the documents of every collection matching a
+// pattern such as users/*/posts, or every document below a prefix such as
+// users/u1/**.
+type pathPattern struct {
+	raw      string
+	segments []string // without a trailing prefixWildcard
+	prefix   bool
+	document bool
+}
+
+// parseDocumentPath parses the path of a single document.
+func parseDocumentPath(path string) (*pathPattern, error) {
+	p, err := parsePathPattern(path)
+	if err != nil {
+		return nil, err
+	}
+	if p.prefix || len(p.segments)%2 != 0 || strings.Contains(p.raw, segmentWildcard) {
+		return nil, errors.New("path must name a document")
+	}
+	p.document = true
+	return p, nil
+}
+
+// parseCollectionPattern parses a collection pattern or path prefix.
+func parseCollectionPattern(pattern string) (*pathPattern, error) {
+	p, err := parsePathPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !p.prefix && len(p.segments)%2 == 0 {
+		return nil, errors.New("pattern must name collections, use path for a document")
+	}
+	return p, nil
+}
+
+func parsePathPattern(s string) (*pathPattern, error) {
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return nil, errors.New("empty path")
+	}
+	segments := strings.Split(s, "/")
+	p := &pathPattern{raw: s}
+	for i, seg := range segments {
+		switch {
+		case seg == "":
+			return nil, errors.New("empty path segment")
+		case seg == prefixWildcard:
+			if i != len(segments)-1 || i == 0 {
+				return nil, errors.New("** must follow a path prefix at the end")
+			}
+			p.prefix = true
+			segments = segments[:i]
+		case seg != segmentWildcard && strings.Contains(seg, segmentWildcard):
+			return nil, errors.New("* must be a whole segment")
+		}
+	}
+	p.segments = segments
+	return p, nil
+}
+
+// subscriptionPattern returns the path or pattern of a subscribe request, or
+// nil if it subscribes to a collection query.
+func subscriptionPattern(payload SubscribePayload) (*pathPattern, error) {
+	switch {
+	case payload.Path == "" && payload.Pattern == "":
+		return nil, nil
+	case payload.Path != "" && payload.Pattern != "":
+		return nil, errors.New("path and pattern are exclusive")
+	case payload.Query.Collection != "":
+		return nil, errors.New("path and pattern replace query.collection")
+	case payload.View:
+		return nil, errors.New("views need a collection query")
+	case payload.Path != "":
+		return parseDocumentPath(payload.Path)
+	case payload.SendSnapshot:
+		return nil, errors.New("pattern subscriptions have no snapshot")
+	}
+	return parseCollectionPattern(payload.Pattern)
+}
+
+// matches reports whether the document at fullpath is selected.
+func (p *pathPattern) matches(fullpath string) bool {
+	if fullpath == "" {
+		return false
+	}
+	segs := strings.Split(strings.Trim(fullpath, "/"), "/")
+	switch {
+	case p.document:
+		return p.raw == strings.Trim(fullpath, "/")
+	case p.prefix:
+		if len(segs) <= len(p.segments) {
+			return false
+		}
+	default:
+		if len(segs) != len(p.segments)+1 {
+			return false
+		}
+	}
+	for i, seg := range p.segments {
+		if seg != segmentWildcard && seg != segs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// listCollection returns the collection whose list permission authorizes a
+// collection pattern. Wildcards stay in place as listProbeID, so the rule
+// blocks matching any value of them decide.
+func (p *pathPattern) listCollection() string {
+	if len(p.segments)%2 == 0 {
+		// A document prefix: its subcollections.
+		return p.raw[:len(p.raw)-len(prefixWildcard)] + listProbeID
+	}
+	return strings.Join(p.segments, "/")
+}
+
+// trieSegments returns the segments under which p is stored in a pathTrie.
+func (p *pathPattern) trieSegments() []string {
+	if p.prefix {
+		return append(append([]string{}, p.segments...), prefixWildcard)
+	}
+	return p.segments
+}
+
+// pathNode is a node of a trie of path patterns. pattern is set on the node
+// where a pattern ends.
+type pathNode struct {
+	children map[string]*pathNode
+	pattern  string
+}
+
+func (n *pathNode) insert(p *pathPattern) {
+	for _, seg := range p.trieSegments() {
+		child, ok := n.children[seg]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[string]*pathNode)
+			}
+			child = &pathNode{}
+			n.children[seg] = child
+		}
+		n = child
+	}
+	n.pattern = p.raw
+}
+
+// remove drops the pattern stored under segs and prunes empty nodes. It
+// reports whether n is empty afterwards.
+func (n *pathNode) remove(segs []string) bool {
+	if len(segs) == 0 {
+		n.pattern = ""
+	} else if child, ok := n.children[segs[0]]; ok && child.remove(segs[1:]) {
+		delete(n.children, segs[0])
+	}
+	return n.pattern == "" && len(n.children) == 0
+}
+
+// match calls fn with every pattern that selects the document at fullpath.
+func (n *pathNode) match(fullpath string, fn func(pattern string)) {
+	segs := strings.Split(strings.Trim(fullpath, "/"), "/")
+	var walk func(n *pathNode, i int)
+	walk = func(n *pathNode, i int) {
+		// Documents end at the last segment, their collection one before.
+		if n.pattern != "" && i >= len(segs)-1 {
+			fn(n.pattern)
+		}
+		if i == len(segs) {
+			return
+		}
+		if child, ok := n.children[prefixWildcard]; ok {
+			fn(child.pattern)
+		}
+		if segs[i] != prefixWildcard {
+			if child, ok := n.children[segs[i]]; ok {
+				walk(child, i+1)
+			}
+		}
+		if segs[i] != segmentWildcard {
+			if child, ok := n.children[segmentWildcard]; ok {
+				walk(child, i+1)
+			}
+		}
+	}
+	walk(n, 0)
+}
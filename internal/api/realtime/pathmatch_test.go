@@ -0,0 +1,211 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePathPattern(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		parse    func(string) (*pathPattern, error)
+		in       string
+		wantErr  bool
+		segments []string
+		prefix   bool
+	}{
+		{"document", parseDocumentPath, "/users/u1/", false, []string{"users", "u1"}, false},
+		{"document in subcollection", parseDocumentPath, "users/u1/posts/p1", false, []string{"users", "u1", "posts", "p1"}, false},
+		{"document needs an id", parseDocumentPath, "users", true, nil, false},
+		{"document without wildcards", parseDocumentPath, "users/*", true, nil, false},
+		{"collection", parseCollectionPattern, "users/*/posts", false, []string{"users", "*", "posts"}, false},
+		{"prefix", parseCollectionPattern, "users/u1/**", false, []string{"users", "u1"}, true},
+		{"pattern of a document", parseCollectionPattern, "users/u1", true, nil, false},
+		{"partial wildcard", parseCollectionPattern, "users/u*/posts", true, nil, false},
+		{"prefix in the middle", parseCollectionPattern, "users/**/posts", true, nil, false},
+		{"bare prefix", parseCollectionPattern, "**", true, nil, false},
+		{"empty segment", parseCollectionPattern, "users//posts", true, nil, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := tc.parse(tc.in)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.segments, p.segments)
+			assert.Equal(t, tc.prefix, p.prefix)
+		})
+	}
+}
+
+func TestPathPattern_Matches(t *testing.T) {
+	doc, _ := parseDocumentPath("users/u1")
+	coll, _ := parseCollectionPattern("users/*/posts")
+	prefix, _ := parseCollectionPattern("users/u1/**")
+
+	assert.True(t, doc.matches("users/u1"))
+	assert.False(t, doc.matches("users/u2"))
+	assert.False(t, doc.matches("users/u1/posts/p1"))
+
+	assert.True(t, coll.matches("users/u1/posts/p1"))
+	assert.True(t, coll.matches("users/u2/posts/p9"))
+	assert.False(t, coll.matches("users/u1/likes/p1"))
+	assert.False(t, coll.matches("users/u1/posts/p1/comments/c1"))
+
+	assert.True(t, prefix.matches("users/u1/posts/p1"))
+	assert.True(t, prefix.matches("users/u1/posts/p1/comments/c1"))
+	assert.False(t, prefix.matches("users/u1"), "the prefix document itself")
+	assert.False(t, prefix.matches("users/u2/posts/p1"))
+	assert.False(t, prefix.matches(""))
+
+	assert.Equal(t, "users/*/posts", coll.listCollection())
+	assert.Equal(t, "users/u1/*", prefix.listCollection())
+	rooms, _ := parseCollectionPattern("rooms/**")
+	assert.Equal(t, "rooms", rooms.listCollection())
+}
+
+func TestPathNode_Match(t *testing.T) {
+	root := &pathNode{}
+	patterns := []string{"users/u1", "users/*/posts", "users/u1/posts", "users/u1/**", "users/**", "rooms/*/messages"}
+	for _, raw := range patterns {
+		p, err := parsePathPattern(raw)
+		require.NoError(t, err)
+		root.insert(p)
+	}
+
+	matches := func(path string) []string {
+		var got []string
+		root.match(path, func(pattern string) { got = append(got, pattern) })
+		return got
+	}
+	assert.ElementsMatch(t, []string{"users/**", "users/u1"}, matches("users/u1"))
+	assert.ElementsMatch(t, []string{"users/*/posts", "users/**", "users/u1/**", "users/u1/posts"}, matches("users/u1/posts/p1"))
+	assert.ElementsMatch(t, []string{"users/*/posts", "users/**"}, matches("users/u2/posts/p1"))
+	assert.ElementsMatch(t, []string{"users/**", "users/u1/**"}, matches("users/u1/posts/p1/comments/c1"))
+	assert.Empty(t, matches("rooms/r1"))
+
+	// Removing patterns prunes the trie.
+	for _, raw := range patterns {
+		p, _ := parsePathPattern(raw)
+		root.remove(p.trieSegments())
+	}
+	assert.Empty(t, root.children)
+}
+
+func TestSubscriptionIndex_LookupPatterns(t *testing.T) {
+	x := newSubscriptionIndex()
+	doc, _ := parseDocumentPath("users/u1")
+	posts, _ := parseCollectionPattern("users/*/posts")
+
+	docClient := indexedClient("t1", false)
+	docClient.subscriptions["d"] = Subscription{pattern: doc}
+	postsClient := indexedClient("t1", false)
+	postsClient.subscriptions["p"] = Subscription{pattern: posts}
+	users := indexedClient("t1", false, "users")
+	for _, c := range []*Client{docClient, postsClient, users} {
+		x.addClient(c)
+	}
+
+	assert.ElementsMatch(t, []*Client{docClient, users}, x.lookup("t1", "users", "users/u1"))
+	assert.ElementsMatch(t, []*Client{postsClient}, x.lookup("t1", "users/u1/posts", "users/u1/posts/p1"))
+	assert.Empty(t, x.lookup("t2", "users", "users/u1"))
+
+	x.remove(docClient, docClient.subscriptions["d"])
+	x.remove(postsClient, postsClient.subscriptions["p"])
+	assert.ElementsMatch(t, []*Client{users}, x.lookup("t1", "users", "users/u1"))
+	assert.Empty(t, x.patterns)
+}
+
+func TestHub_Broadcast_PathAndPattern(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	doc, _ := parseDocumentPath("users/u1")
+	posts, _ := parseCollectionPattern("users/*/posts")
+	c := &Client{
+		hub:             hub,
+		send:            make(chan BaseMessage, 10),
+		allowAllTenants: true,
+		subscriptions: map[string]Subscription{
+			"doc":   {IncludeData: true, pattern: doc},
+			"posts": {IncludeData: true, pattern: posts},
+		},
+	}
+	require.True(t, hub.Register(c))
+
+	broadcast := func(path, collection string) {
+		hub.Broadcast(storage.Event{
+			Id:       "default:" + path,
+			Type:     storage.EventUpdate,
+			Document: &storage.Document{Collection: collection, Fullpath: path, Data: map[string]interface{}{"n": 1}},
+		})
+	}
+	subID := func() string {
+		var payload EventPayload
+		require.NoError(t, json.Unmarshal(nextMessage(t, c, TypeEvent).Payload, &payload))
+		return payload.SubID
+	}
+
+	broadcast("users/u2", "users")
+	broadcast("users/u1/likes/l1", "users/u1/likes")
+	broadcast("users/u1", "users")
+	assert.Equal(t, "doc", subID())
+	broadcast("users/u7/posts/p1", "users/u7/posts")
+	assert.Equal(t, "posts", subID())
+	assertNoMessage(t, c)
+}
+
+func TestClient_Subscribe_PathAndPattern(t *testing.T) {
+	hub := NewHub()
+	hub.authorizer = newAuthorizer(&ownerAuthz{}, Config{})
+	qs := new(MockQueryService)
+	qs.On("GetDocument", mock.Anything, "default", "rooms/mine").
+		Return(model.Document{"id": "mine", "collection": "rooms", "owner": "u1", "version": int64(3)}, nil)
+	qs.On("GetDocument", mock.Anything, "default", "rooms/theirs").
+		Return(model.Document{"id": "theirs", "collection": "rooms", "owner": "u2", "version": int64(1)}, nil)
+	c := newPresenceClient(hub, "default")
+	c.queryService = qs
+	c.principal = newPrincipal("default", nil, "u1", "", nil)
+
+	subscribe := func(id string, payload SubscribePayload) {
+		c.handleMessage(BaseMessage{ID: id, Type: TypeSubscribe, Payload: mustMarshal(payload)})
+	}
+
+	for _, invalid := range []SubscribePayload{
+		{Path: "rooms/mine", Pattern: "rooms/**"},
+		{Path: "rooms/mine", Query: model.Query{Collection: "rooms"}},
+		{Path: "rooms/mine", View: true},
+		{Pattern: "rooms/**", SendSnapshot: true},
+		{Path: "rooms"},
+	} {
+		subscribe("bad", invalid)
+		assert.Equal(t, "invalid_subscription", errorCode(t, nextMessage(t, c, TypeError)))
+	}
+
+	subscribe("s1", SubscribePayload{Path: "rooms/theirs"})
+	assert.Equal(t, "permission_denied", errorCode(t, nextMessage(t, c, TypeError)))
+	subscribe("s2", SubscribePayload{Pattern: "secrets/*/items"})
+	assert.Equal(t, "permission_denied", errorCode(t, nextMessage(t, c, TypeError)))
+
+	subscribe("s3", SubscribePayload{Pattern: "rooms/**"})
+	nextMessage(t, c, TypeSubscribeAck)
+
+	subscribe("s4", SubscribePayload{Path: "rooms/mine", SendSnapshot: true})
+	nextMessage(t, c, TypeSubscribeAck)
+	var snapshot SnapshotPayload
+	require.NoError(t, json.Unmarshal(nextMessage(t, c, TypeSnapshot).Payload, &snapshot))
+	assert.True(t, snapshot.Done)
+	require.Len(t, snapshot.Documents, 1)
+	assert.Equal(t, "mine", snapshot.Documents[0]["id"])
+	assert.Equal(t, "rooms/mine", c.subscriptions["s4"].pattern.raw)
+}
@@ -0,0 +1,524 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultPresenceTTL is how long a member stays in a channel without a
+// heartbeat from its client, or without a refresh from its gateway.
+const defaultPresenceTTL = 60 * time.Second
+
+// Presence event types.
+const (
+	PresenceJoin   = "join"
+	PresenceLeave  = "leave"
+	PresenceUpdate = "update"
+)
+
+// presenceRefresh is the bus operation a gateway uses to keep its members
+// alive on other nodes. Nodes that missed the join learn the member from it.
+const presenceRefresh = "refresh"
+
+// presenceWire is a presence change as published on the bus.
+type presenceWire struct {
+	Node    string         `json:"node"`
+	Op      string         `json:"op"`
+	Tenant  string         `json:"tenant"`
+	Channel string         `json:"channel"`
+	Member  PresenceMember `json:"member"`
+}
+
+// presenceTracker keeps the membership of presence channels. Members of local
+// clients expire when the client stops sending messages; members of other
+// nodes expire when their node stops refreshing them.
+type presenceTracker struct {
+	mu       sync.Mutex
+	node     string
+	seq      uint64
+	ttl      time.Duration
+	now      func() time.Time
+	overflow OverflowPolicy
+	bus      Bus
+
+	// channels is keyed by tenant and channel name.
+	channels map[string]*presenceChannel
+	clients  map[*Client]*clientPresence
+}
+
+type presenceChannel struct {
+	tenant   string
+	name     string
+	members  map[string]*presenceEntry
+	watchers map[*Client]struct{}
+}
+
+type presenceEntry struct {
+	member   PresenceMember
+	node     string
+	client   *Client // nil for members of other nodes
+	lastSeen time.Time
+}
+
+// clientPresence records what a local client joined and watches.
+type clientPresence struct {
+	joined  map[string]string // channel key -> member ID
+	watched map[string]struct{}
+}
+
+func newPresenceTracker(node string) *presenceTracker {
+	return &presenceTracker{
+		node:     node,
+		ttl:      defaultPresenceTTL,
+		now:      time.Now,
+		overflow: OverflowDrop,
+		channels: make(map[string]*presenceChannel),
+		clients:  make(map[*Client]*clientPresence),
+	}
+}
+
+func presenceKey(tenant, channel string) string {
+	return tenant + "\x00" + channel
+}
+
+// channel returns the channel for key, creating it if needed.
+func (t *presenceTracker) channel(tenant, name string) *presenceChannel {
+	key := presenceKey(tenant, name)
+	ch, ok := t.channels[key]
+	if !ok {
+		ch = &presenceChannel{
+			tenant:   tenant,
+			name:     name,
+			members:  make(map[string]*presenceEntry),
+			watchers: make(map[*Client]struct{}),
+		}
+		t.channels[key] = ch
+	}
+	return ch
+}
+
+// gc drops a channel nobody is in or watching.
+func (t *presenceTracker) gc(ch *presenceChannel) {
+	if len(ch.members) == 0 && len(ch.watchers) == 0 {
+		delete(t.channels, presenceKey(ch.tenant, ch.name))
+	}
+}
+
+func (t *presenceTracker) clientState(c *Client) *clientPresence {
+	cp, ok := t.clients[c]
+	if !ok {
+		cp = &clientPresence{joined: make(map[string]string), watched: make(map[string]struct{})}
+		t.clients[c] = cp
+	}
+	return cp
+}
+
+// join adds c to a channel, or updates its metadata if it already joined,
+// and starts watching the channel. The ack and a membership snapshot are
+// queued before any later event.
+func (t *presenceTracker) join(c *Client, reqID, tenant, userID, name string, meta map[string]interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ch := t.channel(tenant, name)
+	cp := t.clientState(c)
+	key := presenceKey(tenant, name)
+
+	if id, ok := cp.joined[key]; ok {
+		e := ch.members[id]
+		e.member.Meta = meta
+		e.lastSeen = t.now()
+		c.enqueue(presenceAck(reqID, name, id), "", t.overflow)
+		t.notify(ch, PresenceUpdate, e.member, nil)
+		t.publish(PresenceUpdate, ch, e.member)
+		return
+	}
+
+	t.seq++
+	member := PresenceMember{
+		ID:       t.node + "-" + strconv.FormatUint(t.seq, 10),
+		UserID:   userID,
+		Meta:     meta,
+		JoinedAt: t.now().UnixMilli(),
+	}
+	ch.members[member.ID] = &presenceEntry{member: member, node: t.node, client: c, lastSeen: t.now()}
+	cp.joined[key] = member.ID
+
+	t.notify(ch, PresenceJoin, member, c)
+	t.publish(PresenceJoin, ch, member)
+
+	ch.watchers[c] = struct{}{}
+	cp.watched[key] = struct{}{}
+	c.enqueue(presenceAck(reqID, name, member.ID), "", t.overflow)
+	c.enqueue(t.snapshot(ch), "", t.overflow)
+}
+
+// update replaces the metadata of c's membership. It reports false if c has
+// not joined the channel.
+func (t *presenceTracker) update(c *Client, reqID, tenant, name string, meta map[string]interface{}) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := presenceKey(tenant, name)
+	cp, ok := t.clients[c]
+	if !ok {
+		return false
+	}
+	id, ok := cp.joined[key]
+	if !ok {
+		return false
+	}
+	ch := t.channels[key]
+	e := ch.members[id]
+	e.member.Meta = meta
+	e.lastSeen = t.now()
+	c.enqueue(presenceAck(reqID, name, id), "", t.overflow)
+	t.notify(ch, PresenceUpdate, e.member, nil)
+	t.publish(PresenceUpdate, ch, e.member)
+	return true
+}
+
+// leave removes c's membership and stops watching the channel.
+func (t *presenceTracker) leave(c *Client, reqID, tenant, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := presenceKey(tenant, name)
+	if cp, ok := t.clients[c]; ok {
+		id, joined := cp.joined[key]
+		delete(cp.joined, key)
+		t.unwatchLocked(c, cp, key)
+		if joined {
+			t.removeMember(t.channels[key], id, true)
+		}
+	}
+	c.enqueue(presenceAck(reqID, name, ""), "", t.overflow)
+}
+
+// subscribe makes c watch a channel without joining it.
+func (t *presenceTracker) subscribe(c *Client, reqID, tenant, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ch := t.channel(tenant, name)
+	ch.watchers[c] = struct{}{}
+	t.clientState(c).watched[presenceKey(tenant, name)] = struct{}{}
+	c.enqueue(presenceAck(reqID, name, ""), "", t.overflow)
+	c.enqueue(t.snapshot(ch), "", t.overflow)
+}
+
+// unsubscribe stops c watching a channel. A joined client stays a member.
+func (t *presenceTracker) unsubscribe(c *Client, reqID, tenant, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cp, ok := t.clients[c]; ok {
+		t.unwatchLocked(c, cp, presenceKey(tenant, name))
+	}
+	c.enqueue(presenceAck(reqID, name, ""), "", t.overflow)
+}
+
+func (t *presenceTracker) unwatchLocked(c *Client, cp *clientPresence, key string) {
+	delete(cp.watched, key)
+	if ch, ok := t.channels[key]; ok {
+		delete(ch.watchers, c)
+		t.gc(ch)
+	}
+	if len(cp.joined) == 0 && len(cp.watched) == 0 {
+		delete(t.clients, c)
+	}
+}
+
+// touch records a sign of life from c for all its memberships.
+func (t *presenceTracker) touch(c *Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cp, ok := t.clients[c]
+	if !ok {
+		return
+	}
+	now := t.now()
+	for key, id := range cp.joined {
+		if e, ok := t.channels[key].members[id]; ok {
+			e.lastSeen = now
+		}
+	}
+}
+
+// removeClient drops everything c joined or watched. It must be called
+// before c's send channel is closed.
+func (t *presenceTracker) removeClient(c *Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cp, ok := t.clients[c]
+	if !ok {
+		return
+	}
+	delete(t.clients, c)
+	for key, id := range cp.joined {
+		t.removeMember(t.channels[key], id, true)
+	}
+	for key := range cp.watched {
+		if ch, ok := t.channels[key]; ok {
+			delete(ch.watchers, c)
+			t.gc(ch)
+		}
+	}
+}
+
+// removeMember deletes a member and tells the watchers. Local removals are
+// published when publish is set.
+func (t *presenceTracker) removeMember(ch *presenceChannel, id string, publish bool) {
+	e, ok := ch.members[id]
+	if !ok {
+		return
+	}
+	delete(ch.members, id)
+	t.notify(ch, PresenceLeave, e.member, nil)
+	if publish && e.node == t.node {
+		t.publish(PresenceLeave, ch, e.member)
+	}
+	t.gc(ch)
+}
+
+// sweep expires members not heard of within the TTL.
+func (t *presenceTracker) sweep() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := t.now().Add(-t.ttl)
+	for _, ch := range t.channels {
+		for id, e := range ch.members {
+			if !e.lastSeen.Before(cutoff) {
+				continue
+			}
+			if e.client != nil {
+				log.Printf("[Info][Presence] Member expired channel=%s member=%s", ch.name, id)
+				if cp, ok := t.clients[e.client]; ok {
+					delete(cp.joined, presenceKey(ch.tenant, ch.name))
+				}
+			}
+			t.removeMember(ch, id, true)
+		}
+	}
+}
+
+// refresh republishes the local members so that other nodes keep them.
+func (t *presenceTracker) refresh() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.bus == nil {
+		return
+	}
+	for _, ch := range t.channels {
+		for _, e := range ch.members {
+			if e.node == t.node {
+				t.publish(presenceRefresh, ch, e.member)
+			}
+		}
+	}
+}
+
+// handleRemote applies a change published by another node.
+func (t *presenceTracker) handleRemote(data []byte) {
+	var w presenceWire
+	if err := json.Unmarshal(data, &w); err != nil {
+		log.Printf("[Warning][Presence] Invalid bus message: %v", err)
+		return
+	}
+	if w.Node == t.node || w.Channel == "" || w.Member.ID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if w.Op == PresenceLeave {
+		if ch, ok := t.channels[presenceKey(w.Tenant, w.Channel)]; ok {
+			t.removeMember(ch, w.Member.ID, false)
+		}
+		return
+	}
+
+	ch := t.channel(w.Tenant, w.Channel)
+	e, ok := ch.members[w.Member.ID]
+	if !ok {
+		ch.members[w.Member.ID] = &presenceEntry{member: w.Member, node: w.Node, lastSeen: t.now()}
+		t.notify(ch, PresenceJoin, w.Member, nil)
+		return
+	}
+	e.lastSeen = t.now()
+	if !reflect.DeepEqual(e.member.Meta, w.Member.Meta) {
+		e.member = w.Member
+		t.notify(ch, PresenceUpdate, w.Member, nil)
+	}
+}
+
+// notify queues a presence event for every watcher of ch except skip.
+func (t *presenceTracker) notify(ch *presenceChannel, typ string, member PresenceMember, skip *Client) {
+	if len(ch.watchers) == 0 {
+		return
+	}
+	msg := BaseMessage{Type: TypePresenceEvent, Payload: mustMarshal(PresenceEventPayload{
+		Channel: ch.name,
+		Type:    typ,
+		Member:  member,
+	})}
+	for c := range ch.watchers {
+		if c != skip {
+			c.enqueue(msg, "", t.overflow)
+		}
+	}
+}
+
+func (t *presenceTracker) publish(op string, ch *presenceChannel, member PresenceMember) {
+	if t.bus == nil {
+		return
+	}
+	data := mustMarshal(presenceWire{Node: t.node, Op: op, Tenant: ch.tenant, Channel: ch.name, Member: member})
+	if err := t.bus.Publish(data); err != nil {
+		log.Printf("[Warning][Presence] Failed to publish %s channel=%s: %v", op, ch.name, err)
+	}
+}
+
+// snapshot builds the membership snapshot of ch, oldest member first.
+func (t *presenceTracker) snapshot(ch *presenceChannel) BaseMessage {
+	members := make([]PresenceMember, 0, len(ch.members))
+	for _, e := range ch.members {
+		members = append(members, e.member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].JoinedAt != members[j].JoinedAt {
+			return members[i].JoinedAt < members[j].JoinedAt
+		}
+		return members[i].ID < members[j].ID
+	})
+	return BaseMessage{Type: TypePresenceSnapshot, Payload: mustMarshal(PresenceSnapshotPayload{
+		Channel: ch.name,
+		Members: members,
+	})}
+}
+
+// run expires members and refreshes the local ones on the bus until ctx is
+// cancelled.
+func (t *presenceTracker) run(ctx context.Context) {
+	if t.bus != nil {
+		unsubscribe, err := t.bus.Subscribe(t.handleRemote)
+		if err != nil {
+			log.Printf("[Warning][Presence] Failed to subscribe to presence bus, membership stays local: %v", err)
+		} else {
+			defer unsubscribe()
+		}
+	}
+
+	ticker := time.NewTicker(t.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.sweep()
+			t.refresh()
+		}
+	}
+}
+
+func presenceAck(reqID, channel, memberID string) BaseMessage {
+	return BaseMessage{ID: reqID, Type: TypePresenceAck, Payload: mustMarshal(PresenceAckPayload{
+		Channel:  channel,
+		MemberID: memberID,
+	})}
+}
+
+// handlePresence serves the presence messages of a client.
+func (c *Client) handlePresence(msg BaseMessage) {
+	if !c.authenticated {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "unauthorized", Message: "auth required"})}
+		return
+	}
+	var payload PresencePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil || !validTopic(payload.Channel) {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "invalid_presence", Message: "invalid channel name"})}
+		return
+	}
+	if !c.authorizePresence(msg.Type, payload) {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "permission_denied", Message: "presence not allowed on channel"})}
+		return
+	}
+
+	c.mu.Lock()
+	tenant := tenantOrDefault(c.tenant)
+	userID, _ := c.principal.auth.UID.(string)
+	c.mu.Unlock()
+
+	t := c.hub.presence
+	switch msg.Type {
+	case TypePresenceJoin:
+		t.join(c, msg.ID, tenant, userID, payload.Channel, payload.Meta)
+		log.Printf("[Info][WS] Joined presence channel=%s", payload.Channel)
+	case TypePresenceUpdate:
+		if !t.update(c, msg.ID, tenant, payload.Channel, payload.Meta) {
+			c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "not_joined", Message: "not a member of channel"})}
+		}
+	case TypePresenceLeave:
+		t.leave(c, msg.ID, tenant, payload.Channel)
+		log.Printf("[Info][WS] Left presence channel=%s", payload.Channel)
+	case TypePresenceSubscribe:
+		t.subscribe(c, msg.ID, tenant, payload.Channel)
+	case TypePresenceUnsubscribe:
+		t.unsubscribe(c, msg.ID, tenant, payload.Channel)
+	}
+}
+
+// authorizePresence checks a presence request against the rules path of its
+// channel: watching needs get, announcing oneself needs update, and joining
+// does both. Leaving and unsubscribing only drop the client's own state, so
+// they are always allowed. Clients that bypass tenant isolation are not
+// subject to rules.
+func (c *Client) authorizePresence(typ string, payload PresencePayload) bool {
+	if c.hub == nil || c.hub.authorizer == nil {
+		return true
+	}
+	var actions []string
+	switch typ {
+	case TypePresenceJoin:
+		actions = []string{"get", "update"}
+	case TypePresenceUpdate:
+		actions = []string{"update"}
+	case TypePresenceSubscribe:
+		actions = []string{"get"}
+	default:
+		return true
+	}
+
+	c.mu.Lock()
+	bypass, p := c.allowAllTenants, c.principal
+	c.mu.Unlock()
+	if bypass {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), authzEvalTimeout)
+	defer cancel()
+	for _, action := range actions {
+		allowed, err := c.hub.authorizer.canPresence(ctx, p, payload.Channel, action, payload.Meta)
+		if err != nil {
+			log.Printf("[Warning][WS] authz %s evaluation failed presence channel=%s: %v", action, payload.Channel, err)
+			return false
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
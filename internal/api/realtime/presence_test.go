@@ -0,0 +1,300 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBus delivers published messages synchronously to every
+// subscriber, like NATS delivers them to every node.
+type memoryBus struct {
+	mu       sync.Mutex
+	handlers []func([]byte)
+}
+
+func (b *memoryBus) Publish(data []byte) error {
+	b.mu.Lock()
+	handlers := append([]func([]byte){}, b.handlers...)
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h(data)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(handler func([]byte)) (func(), error) {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+	return func() {}, nil
+}
+
+func newPresenceClient(h *Hub, tenant string) *Client {
+	return &Client{
+		hub:           h,
+		send:          make(chan BaseMessage, 32),
+		subscriptions: make(map[string]Subscription),
+		tenant:        tenant,
+		authenticated: true,
+	}
+}
+
+func presenceRequest(c *Client, typ, id, channel string, meta map[string]interface{}) {
+	c.handleMessage(BaseMessage{ID: id, Type: typ, Payload: mustMarshal(PresencePayload{Channel: channel, Meta: meta})})
+}
+
+func nextMessage(t *testing.T, c *Client, typ string) BaseMessage {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		require.Equal(t, typ, msg.Type, "payload: %s", msg.Payload)
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("expected %s message", typ)
+		return BaseMessage{}
+	}
+}
+
+func assertNoMessage(t *testing.T, c *Client) {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		t.Fatalf("unexpected message %s: %s", msg.Type, msg.Payload)
+	default:
+	}
+}
+
+func presenceEvent(t *testing.T, c *Client) PresenceEventPayload {
+	t.Helper()
+	var p PresenceEventPayload
+	require.NoError(t, json.Unmarshal(nextMessage(t, c, TypePresenceEvent).Payload, &p))
+	return p
+}
+
+func presenceSnapshot(t *testing.T, c *Client) PresenceSnapshotPayload {
+	t.Helper()
+	var p PresenceSnapshotPayload
+	require.NoError(t, json.Unmarshal(nextMessage(t, c, TypePresenceSnapshot).Payload, &p))
+	return p
+}
+
+func TestPresence_JoinUpdateLeave(t *testing.T) {
+	h := NewHub()
+	alice := newPresenceClient(h, "t1")
+	bob := newPresenceClient(h, "t1")
+
+	presenceRequest(alice, TypePresenceJoin, "j1", "room", map[string]interface{}{"name": "alice"})
+	ack := nextMessage(t, alice, TypePresenceAck)
+	assert.Equal(t, "j1", ack.ID)
+	snap := presenceSnapshot(t, alice)
+	require.Len(t, snap.Members, 1)
+	assert.Equal(t, "alice", snap.Members[0].Meta["name"])
+
+	presenceRequest(bob, TypePresenceJoin, "j2", "room", map[string]interface{}{"name": "bob"})
+	nextMessage(t, bob, TypePresenceAck)
+	assert.Len(t, presenceSnapshot(t, bob).Members, 2)
+
+	evt := presenceEvent(t, alice)
+	assert.Equal(t, PresenceJoin, evt.Type)
+	assert.Equal(t, "room", evt.Channel)
+	assert.Equal(t, "bob", evt.Member.Meta["name"])
+	bobID := evt.Member.ID
+
+	presenceRequest(bob, TypePresenceUpdate, "u1", "room", map[string]interface{}{"name": "bob", "typing": true})
+	nextMessage(t, bob, TypePresenceAck)
+	evt = presenceEvent(t, alice)
+	assert.Equal(t, PresenceUpdate, evt.Type)
+	assert.Equal(t, true, evt.Member.Meta["typing"])
+	assert.Equal(t, PresenceUpdate, presenceEvent(t, bob).Type)
+
+	presenceRequest(bob, TypePresenceLeave, "l1", "room", nil)
+	evt = presenceEvent(t, alice)
+	assert.Equal(t, PresenceLeave, evt.Type)
+	assert.Equal(t, bobID, evt.Member.ID)
+}
+
+func TestPresence_UpdateWithoutJoin(t *testing.T) {
+	h := NewHub()
+	c := newPresenceClient(h, "t1")
+
+	presenceRequest(c, TypePresenceUpdate, "u1", "room", nil)
+	msg := nextMessage(t, c, TypeError)
+	assert.Contains(t, string(msg.Payload), "not_joined")
+
+	presenceRequest(c, TypePresenceJoin, "j1", "", nil)
+	msg = nextMessage(t, c, TypeError)
+	assert.Contains(t, string(msg.Payload), "invalid_presence")
+}
+
+// presenceAuthz allows everything on /presence/open and only get on
+// /presence/lobby.
+type presenceAuthz struct {
+	ownerAuthz
+	actions []string
+}
+
+func (a *presenceAuthz) Evaluate(ctx context.Context, path string, action string, req identity.AuthzRequest, res *identity.Resource) (bool, error) {
+	a.actions = append(a.actions, action+" "+path)
+	return path == "presence/open" || (path == "presence/lobby" && action == "get"), nil
+}
+
+func TestPresence_PermissionDenied(t *testing.T) {
+	h := NewHub()
+	authz := &presenceAuthz{}
+	h.authorizer = newAuthorizer(authz, Config{})
+	c := newPresenceClient(h, "t1")
+	c.principal = newPrincipal("t1", nil, "u1", "", nil)
+	watcher := newPresenceClient(h, "t1")
+	watcher.principal = newPrincipal("t1", nil, "u2", "", nil)
+
+	presenceRequest(c, TypePresenceJoin, "j1", "closed", nil)
+	msg := nextMessage(t, c, TypeError)
+	assert.Contains(t, string(msg.Payload), "permission_denied")
+	presenceRequest(c, TypePresenceSubscribe, "s1", "closed", nil)
+	nextMessage(t, c, TypeError)
+
+	// Watching a channel does not allow announcing oneself in it.
+	presenceRequest(watcher, TypePresenceSubscribe, "s2", "lobby", nil)
+	nextMessage(t, watcher, TypePresenceAck)
+	assert.Empty(t, presenceSnapshot(t, watcher).Members)
+	presenceRequest(c, TypePresenceJoin, "j2", "lobby", nil)
+	msg = nextMessage(t, c, TypeError)
+	assert.Contains(t, string(msg.Payload), "permission_denied")
+	assertNoMessage(t, watcher)
+
+	authz.actions = nil
+	presenceRequest(c, TypePresenceJoin, "j3", "open", map[string]interface{}{"name": "alice"})
+	nextMessage(t, c, TypePresenceAck)
+	presenceSnapshot(t, c)
+	assert.Equal(t, []string{"get presence/open", "update presence/open"}, authz.actions)
+
+	presenceRequest(c, TypePresenceJoin, "j4", "a/b", nil)
+	msg = nextMessage(t, c, TypeError)
+	assert.Contains(t, string(msg.Payload), "invalid_presence")
+}
+
+func TestPresence_SubscribeWithoutJoining(t *testing.T) {
+	h := NewHub()
+	member := newPresenceClient(h, "t1")
+	watcher := newPresenceClient(h, "t1")
+	other := newPresenceClient(h, "t2")
+
+	presenceRequest(member, TypePresenceJoin, "j1", "room", nil)
+	presenceRequest(watcher, TypePresenceSubscribe, "s1", "room", nil)
+	presenceRequest(other, TypePresenceSubscribe, "s2", "room", nil)
+
+	nextMessage(t, watcher, TypePresenceAck)
+	assert.Len(t, presenceSnapshot(t, watcher).Members, 1)
+
+	// Channels are tenant scoped.
+	nextMessage(t, other, TypePresenceAck)
+	assert.Empty(t, presenceSnapshot(t, other).Members)
+
+	presenceRequest(watcher, TypePresenceUnsubscribe, "s3", "room", nil)
+	nextMessage(t, watcher, TypePresenceAck)
+	h.presence.removeClient(member)
+	assertNoMessage(t, watcher)
+	assertNoMessage(t, other)
+}
+
+func TestPresence_DisconnectLeaves(t *testing.T) {
+	h := NewHub()
+	alice := newPresenceClient(h, "t1")
+	bob := newPresenceClient(h, "t1")
+
+	presenceRequest(alice, TypePresenceJoin, "j1", "room", nil)
+	presenceRequest(bob, TypePresenceJoin, "j2", "room", nil)
+	for len(alice.send) > 0 {
+		<-alice.send
+	}
+
+	h.presence.removeClient(bob)
+	assert.Equal(t, PresenceLeave, presenceEvent(t, alice).Type)
+
+	h.presence.removeClient(alice)
+	assert.Empty(t, h.presence.channels)
+	assert.Empty(t, h.presence.clients)
+}
+
+func TestPresence_ExpiresWithoutHeartbeat(t *testing.T) {
+	h := NewHub()
+	now := time.Unix(1000, 0)
+	h.presence.now = func() time.Time { return now }
+	h.presence.ttl = time.Minute
+
+	alice := newPresenceClient(h, "t1")
+	bob := newPresenceClient(h, "t1")
+	presenceRequest(alice, TypePresenceJoin, "j1", "room", nil)
+	presenceRequest(bob, TypePresenceJoin, "j2", "room", nil)
+	for len(alice.send) > 0 {
+		<-alice.send
+	}
+
+	// Alice keeps sending heartbeats, bob goes quiet.
+	now = now.Add(45 * time.Second)
+	h.presence.touch(alice)
+	now = now.Add(30 * time.Second)
+	h.presence.sweep()
+
+	evt := presenceEvent(t, alice)
+	assert.Equal(t, PresenceLeave, evt.Type)
+	assertNoMessage(t, alice)
+
+	// Bob can update no more, but can join again.
+	for len(bob.send) > 0 {
+		<-bob.send
+	}
+	presenceRequest(bob, TypePresenceUpdate, "u1", "room", nil)
+	nextMessage(t, bob, TypeError)
+}
+
+func TestPresence_SharedAcrossNodes(t *testing.T) {
+	bus := &memoryBus{}
+	h1, h2 := NewHub(), NewHub()
+	now := time.Unix(1000, 0)
+	for _, h := range []*Hub{h1, h2} {
+		h.presence.bus = bus
+		h.presence.ttl = time.Minute
+		h.presence.now = func() time.Time { return now }
+		_, _ = bus.Subscribe(h.presence.handleRemote)
+	}
+
+	watcher := newPresenceClient(h2, "t1")
+	presenceRequest(watcher, TypePresenceSubscribe, "s1", "room", nil)
+	nextMessage(t, watcher, TypePresenceAck)
+	presenceSnapshot(t, watcher)
+
+	alice := newPresenceClient(h1, "t1")
+	presenceRequest(alice, TypePresenceJoin, "j1", "room", map[string]interface{}{"name": "alice"})
+	evt := presenceEvent(t, watcher)
+	assert.Equal(t, PresenceJoin, evt.Type)
+	assert.Equal(t, "alice", evt.Member.Meta["name"])
+
+	// Refreshes keep the remote member alive without events.
+	now = now.Add(45 * time.Second)
+	h1.presence.touch(alice)
+	h1.presence.refresh()
+	now = now.Add(30 * time.Second)
+	h2.presence.sweep()
+	assertNoMessage(t, watcher)
+
+	presenceRequest(alice, TypePresenceUpdate, "u1", "room", map[string]interface{}{"name": "alice", "away": true})
+	assert.Equal(t, PresenceUpdate, presenceEvent(t, watcher).Type)
+
+	presenceRequest(alice, TypePresenceLeave, "l1", "room", nil)
+	assert.Equal(t, PresenceLeave, presenceEvent(t, watcher).Type)
+
+	// A node that stops refreshing loses its members.
+	presenceRequest(alice, TypePresenceJoin, "j2", "room", nil)
+	assert.Equal(t, PresenceJoin, presenceEvent(t, watcher).Type)
+	now = now.Add(2 * time.Minute)
+	h2.presence.sweep()
+	assert.Equal(t, PresenceLeave, presenceEvent(t, watcher).Type)
+}
@@ -17,8 +17,25 @@ const (
 	TypeUnsubscribeAck = "unsubscribe_ack"
 	TypeEvent          = "event"
 	TypeSnapshot       = "snapshot"
+	TypeReset          = "reset"
+	TypeChange         = "change"
 	TypeError          = "error"
 	TypeHeartbeat      = "heartbeat"
+
+	TypePresenceJoin        = "presence_join"
+	TypePresenceUpdate      = "presence_update"
+	TypePresenceLeave       = "presence_leave"
+	TypePresenceSubscribe   = "presence_subscribe"
+	TypePresenceUnsubscribe = "presence_unsubscribe"
+	TypePresenceAck         = "presence_ack"
+	TypePresenceSnapshot    = "presence_snapshot"
+	TypePresenceEvent       = "presence_event"
+
+	TypePublish    = "publish"
+	TypePublishAck = "publish_ack"
+	TypeMessage    = "message"
+
+	TypeReauthRequired = "reauth_required"
 )
 
 // BaseMessage is the envelope for all messages
@@ -38,6 +55,34 @@ type SubscribePayload struct {
 	Query        model.Query `json:"query"`
 	IncludeData  bool        `json:"includeData"`  // If true, events will include the full document
 	SendSnapshot bool        `json:"sendSnapshot"` // If true, sends current state immediately
+
+	// ResumeAfter is the resume token of the last event the client received.
+	// Missed events are replayed; if that is not possible the server sends a
+	// reset followed by a fresh snapshot.
+	ResumeAfter string `json:"resumeAfter,omitempty"`
+
+	// Patches asks for update events to carry the changed fields in
+	// PublicEvent.Patch, with Document reduced to the ID and system fields,
+	// when the server knows them. Other events carry the whole document.
+	// Requires IncludeData.
+	Patches bool `json:"patches,omitempty"`
+
+	// Path subscribes to the single document at this path and Pattern to the
+	// documents of every matching collection, instead of Query.Collection.
+	// In a pattern, * matches one path segment and a trailing ** everything
+	// below the prefix, e.g. users/*/posts or users/u1/**. Query filters still
+	// apply. Pattern subscriptions have no snapshot.
+	Path    string `json:"path,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+
+	// Topic subscribes to an ephemeral topic instead of a query. Messages
+	// published to it are delivered as message messages and never stored.
+	Topic string `json:"topic,omitempty"`
+
+	// View asks the server to maintain the query's result window (orderBy and
+	// limit aware) and report change messages instead of events. A view always
+	// starts with a snapshot; ResumeAfter is ignored.
+	View bool `json:"view,omitempty"`
 }
 
 // UnsubscribePayload
@@ -52,16 +97,111 @@ type EventPayload struct {
 }
 
 type PublicEvent struct {
-	Type      storage.EventType      `json:"type"`
-	Document  map[string]interface{} `json:"document,omitempty"`
-	ID        string                 `json:"id"`
-	Timestamp int64                  `json:"timestamp"`
+	Type        storage.EventType      `json:"type"`
+	Document    map[string]interface{} `json:"document,omitempty"`
+	ID          string                 `json:"id"`
+	Timestamp   int64                  `json:"timestamp"`
+	ResumeToken string                 `json:"resumeToken,omitempty"` // Opaque position to pass as resumeAfter
+	Patch       *storage.FieldPatch    `json:"patch,omitempty"`       // Changed fields, for subscriptions with Patches
 }
 
 // SnapshotPayload (Server -> Client)
+//
+// A snapshot is delivered as one or more pages. The last page has Done set;
+// Truncated reports that the result was capped by the server.
 type SnapshotPayload struct {
 	SubID     string                   `json:"subId"`
 	Documents []map[string]interface{} `json:"documents"`
+	Page      int                      `json:"page"`
+	Done      bool                     `json:"done"`
+	Truncated bool                     `json:"truncated,omitempty"`
+}
+
+// ChangePayload (Server -> Client) reports a document entering ("added"),
+// changing or moving within ("modified"), or leaving ("removed") the result
+// window of a view subscription. OldIndex is -1 for added documents and
+// NewIndex is -1 for removed ones.
+type ChangePayload struct {
+	SubID       string                 `json:"subId"`
+	Type        string                 `json:"type"`
+	ID          string                 `json:"id"`
+	Document    map[string]interface{} `json:"document,omitempty"`
+	OldIndex    int                    `json:"oldIndex"`
+	NewIndex    int                    `json:"newIndex"`
+	Timestamp   int64                  `json:"timestamp"`
+	ResumeToken string                 `json:"resumeToken,omitempty"`
+}
+
+// ResetPayload (Server -> Client) tells the client that a subscription could
+// not be resumed and its local state must be replaced by the snapshot that
+// follows.
+type ResetPayload struct {
+	SubID  string `json:"subId"`
+	Reason string `json:"reason"`
+}
+
+// PresencePayload (Client -> Server) names the presence channel of a join,
+// update, leave, subscribe or unsubscribe. Meta is set on join and update.
+//
+// Members expire when their client sends nothing for the presence TTL, so
+// joined clients send heartbeat messages more often than that.
+type PresencePayload struct {
+	Channel string                 `json:"channel"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+}
+
+// PresenceMember is one member of a presence channel.
+type PresenceMember struct {
+	ID       string                 `json:"id"`
+	UserID   string                 `json:"userId,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+	JoinedAt int64                  `json:"joinedAt"`
+}
+
+// PresenceAckPayload (Server -> Client) acknowledges a presence request.
+// MemberID is set on join and update.
+type PresenceAckPayload struct {
+	Channel  string `json:"channel"`
+	MemberID string `json:"memberId,omitempty"`
+}
+
+// PresenceSnapshotPayload (Server -> Client) lists the members of a channel
+// when the client starts watching it.
+type PresenceSnapshotPayload struct {
+	Channel string           `json:"channel"`
+	Members []PresenceMember `json:"members"`
+}
+
+// PresenceEventPayload (Server -> Client) reports a member joining, leaving
+// or updating its metadata.
+type PresenceEventPayload struct {
+	Channel string         `json:"channel"`
+	Type    string         `json:"type"`
+	Member  PresenceMember `json:"member"`
+}
+
+// PublishPayload (Client -> Server) sends Data to the subscribers of an
+// ephemeral topic. The message is not persisted.
+type PublishPayload struct {
+	Topic string          `json:"topic"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// TopicMessagePayload (Server -> Client) is a message published to a topic
+// subscription. UserID identifies the publisher when known.
+type TopicMessagePayload struct {
+	SubID     string          `json:"subId"`
+	Topic     string          `json:"topic"`
+	ID        string          `json:"id"`
+	UserID    string          `json:"userId,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// ReauthRequiredPayload asks the client to send an auth message with a fresh
+// token before the current one expires.
+type ReauthRequiredPayload struct {
+	ExpiresAt int64 `json:"expiresAt"` // Unix milliseconds
 }
 
 // ErrorPayload
@@ -0,0 +1,188 @@
+package realtime
+
+import (
+	"log"
+	"sync"
+)
+
+// OverflowPolicy decides what happens to an event message when a client's
+// send queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDrop discards the message.
+	OverflowDrop OverflowPolicy = "drop"
+
+	// OverflowCoalesce parks the message in a bounded side queue, where a newer
+	// event for the same subscription and document replaces an older one. The
+	// client is disconnected when the side queue is full as well.
+	OverflowCoalesce OverflowPolicy = "coalesce"
+
+	// OverflowDisconnect closes the connection so the client can reconnect and
+	// resume.
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+// defaultSendQueueSize is the capacity of a client's send channel.
+const defaultSendQueueSize = 256
+
+// sendQueueSize returns the configured capacity of a client's send channel.
+func (c Config) sendQueueSize() int {
+	if c.SendQueueSize > 0 {
+		return c.SendQueueSize
+	}
+	return defaultSendQueueSize
+}
+
+// coalesceQueue holds event messages that did not fit into a client's send
+// channel. Messages keep their arrival order; one with a key already queued
+// replaces the queued message in place.
+type coalesceQueue struct {
+	mu   sync.Mutex
+	msgs []BaseMessage
+	keys map[string]int
+	max  int
+
+	// wake is signalled when the queue becomes non-empty.
+	wake chan struct{}
+}
+
+func newCoalesceQueue(max int) *coalesceQueue {
+	return &coalesceQueue{
+		keys: make(map[string]int),
+		max:  max,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// push queues msg and reports false when the queue is full.
+func (q *coalesceQueue) push(key string, msg BaseMessage) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if key != "" {
+		if i, ok := q.keys[key]; ok {
+			q.msgs[i] = msg
+			messagesCoalesced.Inc()
+			return true
+		}
+	}
+	if len(q.msgs) >= q.max {
+		return false
+	}
+	if key != "" {
+		q.keys[key] = len(q.msgs)
+	}
+	q.msgs = append(q.msgs, msg)
+	if len(q.msgs) == 1 {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+func (q *coalesceQueue) empty() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.msgs) == 0
+}
+
+// drain removes and returns every queued message.
+func (q *coalesceQueue) drain() []BaseMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := q.msgs
+	q.msgs = nil
+	q.keys = make(map[string]int)
+	return msgs
+}
+
+// wakeC returns the channel signalled when messages are parked, or nil when
+// coalescing is not in use.
+func (c *Client) wakeC() <-chan struct{} {
+	if c.pending == nil {
+		return nil
+	}
+	return c.pending.wake
+}
+
+// takePending returns the messages parked under the coalesce policy, after
+// whatever is still in the send channel, so that the writer keeps the order
+// in which the hub produced them.
+func (c *Client) takePending() []BaseMessage {
+	var msgs []BaseMessage
+	for {
+		select {
+		case msg, ok := <-c.send:
+			if !ok {
+				return msgs
+			}
+			msgs = append(msgs, msg)
+		default:
+			return append(msgs, c.pending.drain()...)
+		}
+	}
+}
+
+// enqueue queues a hub message for the client according to policy. key
+// identifies what the message is about for coalescing; messages without a key
+// are never dropped or merged, so a full queue disconnects the client instead.
+// Called by the hub's shard worker of the client, when releasing a snapshot
+// buffer, or by the presence tracker with its lock held.
+func (c *Client) enqueue(msg BaseMessage, key string, policy OverflowPolicy) {
+	if c.evicted.Load() {
+		return
+	}
+
+	if policy == OverflowCoalesce && c.pending != nil && !c.pending.empty() {
+		// Older messages are parked; queue behind them to keep the order.
+		if !c.pending.push(key, msg) {
+			c.disconnect("queue_full")
+		}
+		return
+	}
+
+	select {
+	case c.send <- msg:
+		return
+	default:
+	}
+
+	switch {
+	case policy == OverflowCoalesce && c.pending != nil:
+		if !c.pending.push(key, msg) {
+			c.disconnect("queue_full")
+		}
+	case policy == OverflowDrop && key != "":
+		messagesDropped.WithLabelValues(string(policy)).Inc()
+	default:
+		c.disconnect("queue_full")
+	}
+}
+
+// overflowPolicy returns the overflow policy of the client's hub.
+func (c *Client) overflowPolicy() OverflowPolicy {
+	if c.hub == nil {
+		return OverflowDrop
+	}
+	return c.hub.overflow
+}
+
+// disconnect evicts a client that cannot keep up. The hub stops delivering to
+// it and the connection is closed, which unregisters the client through its
+// read loop.
+func (c *Client) disconnect(reason string) {
+	c.closeOnce.Do(func() {
+		c.evicted.Store(true)
+		clientsDisconnected.WithLabelValues(reason).Inc()
+		log.Printf("[Warning][WS] Disconnecting client tenant=%s reason=%s", c.tenant, reason)
+		if c.conn != nil {
+			c.conn.close()
+		}
+		if c.closed != nil {
+			close(c.closed)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Enqueue_Drop(t *testing.T) {
+	c := &Client{send: make(chan BaseMessage, 1)}
+
+	c.enqueue(BaseMessage{ID: "1"}, "s\x00a", OverflowDrop)
+	c.enqueue(BaseMessage{ID: "2"}, "s\x00b", OverflowDrop)
+
+	assert.Equal(t, "1", (<-c.send).ID)
+	assert.Empty(t, c.send)
+	assert.False(t, c.evicted.Load())
+
+	// Messages without a key cannot be dropped.
+	c.send <- BaseMessage{ID: "3"}
+	c.enqueue(BaseMessage{ID: "4"}, "", OverflowDrop)
+	assert.True(t, c.evicted.Load())
+}
+
+func TestClient_Enqueue_Coalesce(t *testing.T) {
+	c := &Client{send: make(chan BaseMessage, 1), pending: newCoalesceQueue(2)}
+
+	c.enqueue(BaseMessage{ID: "a1"}, "s\x00a", OverflowCoalesce)
+	c.enqueue(BaseMessage{ID: "b1"}, "s\x00b", OverflowCoalesce)
+	c.enqueue(BaseMessage{ID: "a2"}, "s\x00a", OverflowCoalesce)
+	c.enqueue(BaseMessage{ID: "b2"}, "s\x00b", OverflowCoalesce)
+
+	select {
+	case <-c.wakeC():
+	default:
+		t.Fatal("writer was not woken")
+	}
+
+	var ids []string
+	for _, msg := range c.takePending() {
+		ids = append(ids, msg.ID)
+	}
+	// The channel is drained first; a parked message is replaced by a newer
+	// event for the same document.
+	assert.Equal(t, []string{"a1", "b2", "a2"}, ids)
+	assert.False(t, c.evicted.Load())
+
+	// While messages are parked, new ones queue behind them even if the
+	// channel has room.
+	c.send <- BaseMessage{ID: "x"}
+	c.enqueue(BaseMessage{ID: "c1"}, "s\x00c", OverflowCoalesce)
+	<-c.send
+	c.enqueue(BaseMessage{ID: "d1"}, "s\x00d", OverflowCoalesce)
+	assert.Empty(t, c.send)
+
+	// A full side queue disconnects the client.
+	c.enqueue(BaseMessage{ID: "e1"}, "s\x00e", OverflowCoalesce)
+	assert.True(t, c.evicted.Load())
+}
+
+func TestClient_Enqueue_Disconnect(t *testing.T) {
+	c := &Client{send: make(chan BaseMessage, 1), closed: make(chan struct{})}
+
+	c.enqueue(BaseMessage{ID: "1"}, "s\x00a", OverflowDisconnect)
+	c.enqueue(BaseMessage{ID: "2"}, "s\x00b", OverflowDisconnect)
+
+	assert.True(t, c.evicted.Load())
+	select {
+	case <-c.closed:
+	default:
+		t.Fatal("closed was not signalled")
+	}
+
+	// Evicted clients receive nothing further and disconnect only once.
+	<-c.send
+	c.enqueue(BaseMessage{ID: "3"}, "s\x00c", OverflowDisconnect)
+	assert.Empty(t, c.send)
+	c.disconnect("queue_full")
+}
+
+func TestHub_ShardedDeliveryKeepsOrder(t *testing.T) {
+	hub := NewHub()
+	hub.configure(Config{BroadcastWorkers: 4})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	const clients, events = 16, 50
+	all := make([]*Client, clients)
+	for i := range all {
+		all[i] = &Client{
+			hub:           hub,
+			send:          make(chan BaseMessage, events),
+			tenant:        "t1",
+			subscriptions: map[string]Subscription{"s": {Query: model.Query{Collection: "users"}}},
+		}
+		require.True(t, hub.Register(all[i]))
+	}
+
+	for i := 0; i < events; i++ {
+		hub.Broadcast(storage.Event{
+			Id:        storage.CalculateTenantID("t1", "users/u"),
+			TenantID:  "t1",
+			Type:      storage.EventUpdate,
+			Document:  &storage.Document{TenantID: "t1", Collection: "users", Fullpath: "users/u", Version: int64(i + 1)},
+			Timestamp: int64(i + 1),
+		})
+	}
+
+	for _, c := range all {
+		require.Eventually(t, func() bool { return len(c.send) == events }, time.Second, 5*time.Millisecond)
+		for i := 0; i < events; i++ {
+			var payload EventPayload
+			require.NoError(t, json.Unmarshal((<-c.send).Payload, &payload))
+			assert.Equal(t, int64(i+1), payload.Delta.Timestamp)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/puller"
+	"github.com/codetrek/syntrix/internal/storage"
+)
+
+const (
+	// defaultResumeMaxEvents caps the number of events replayed for a single
+	// resumed subscription before the client is told to reset instead.
+	defaultResumeMaxEvents = 10000
+
+	resumeReplayTimeout = 30 * time.Second
+)
+
+var (
+	errResumeUnavailable  = errors.New("resume is not available on this gateway")
+	errInvalidResumeToken = errors.New("invalid resume token")
+	errResumeTooFarBehind = errors.New("too many events to replay")
+)
+
+// ReplaySource replays buffered change events after a position. It is
+// satisfied by puller.LocalService.
+type ReplaySource interface {
+	Replay(ctx context.Context, after map[string]string, coalesce bool) (puller.Iterator, error)
+}
+
+// resumer issues resume tokens for delivered events and replays the events a
+// client missed after one of them.
+//
+// Tokens are puller progress markers positioned on a single backend, so they
+// can be handed straight to the puller buffer. Live events from the storage
+// watch carry no puller event ID; it is derived from the cluster time and the
+// Mongo document key exactly as the puller normalizer does.
+type resumer struct {
+	source     ReplaySource
+	backend    string
+	collection string
+	maxEvents  int
+}
+
+func newResumer(dataCollection string, cfg Config) *resumer {
+	if cfg.PullerBackend == "" || dataCollection == "" {
+		return nil
+	}
+	maxEvents := cfg.ResumeMaxEvents
+	if maxEvents <= 0 {
+		maxEvents = defaultResumeMaxEvents
+	}
+	return &resumer{
+		backend:    cfg.PullerBackend,
+		collection: dataCollection,
+		maxEvents:  maxEvents,
+	}
+}
+
+// tokenFor returns the resume token of a live event, or "" when the event has
+// no cluster time.
+func (r *resumer) tokenFor(evt storage.Event) string {
+	if r == nil || evt.ClusterTime.IsZero() || evt.Id == "" {
+		return ""
+	}
+	ct := puller.ClusterTime{T: evt.ClusterTime.T, I: evt.ClusterTime.I}
+	return r.encode(puller.FormatEventID(ct, r.collection, evt.Id))
+}
+
+func (r *resumer) encode(eventID string) string {
+	pm := puller.NewProgressMarker()
+	pm.SetPosition(r.backend, eventID)
+	return pm.Encode()
+}
+
+// replay opens an iterator over the events recorded after token. It returns
+// puller.ErrPositionExpired when the buffer no longer covers the token.
+func (r *resumer) replay(ctx context.Context, token string) (puller.Iterator, error) {
+	if r == nil || r.source == nil {
+		return nil, errResumeUnavailable
+	}
+	pm, err := puller.DecodeProgressMarker(token)
+	if err != nil {
+		return nil, errInvalidResumeToken
+	}
+	pos := pm.Positions[r.backend]
+	if pos == "" {
+		return nil, errInvalidResumeToken
+	}
+	return r.source.Replay(ctx, map[string]string{r.backend: pos}, false)
+}
+
+// changeToEvent converts a buffered puller event into the storage event shape
+// the hub delivers. Events from other backends or collections are skipped.
+func (r *resumer) changeToEvent(ce *puller.ChangeEvent) (storage.Event, bool) {
+	return changeToEvent(ce, r.backend, r.collection)
+}
+
+// changeToEvent converts a puller change event of the data collection on
+// backend into a storage event.
+func changeToEvent(ce *puller.ChangeEvent, backend, collection string) (storage.Event, bool) {
+	if ce == nil || ce.Backend != backend || ce.MgoColl != collection {
+		return storage.Event{}, false
+	}
+
+	tenant := ce.TenantID
+	if tenant == "" {
+		if idx := strings.Index(ce.MgoDocID, ":"); idx != -1 {
+			tenant = ce.MgoDocID[:idx]
+		}
+	}
+
+	evt := storage.Event{
+		Id:          ce.MgoDocID,
+		TenantID:    tenant,
+		Timestamp:   ce.Timestamp * int64(time.Millisecond),
+		ClusterTime: storage.ClusterTime{T: ce.ClusterTime.T, I: ce.ClusterTime.I},
+	}
+
+	switch ce.OpType {
+	case puller.OperationInsert:
+		evt.Type = storage.EventCreate
+		evt.Document = ce.FullDocument
+	case puller.OperationUpdate, puller.OperationReplace:
+		switch {
+		case ce.FullDocument != nil && ce.FullDocument.Deleted:
+			// Soft delete: keep the last state for routing and authorization.
+			evt.Type = storage.EventDelete
+			evt.Before = ce.FullDocument
+		case ce.OpType == puller.OperationReplace:
+			evt.Type = storage.EventCreate
+			evt.Document = ce.FullDocument
+		default:
+			evt.Type = storage.EventUpdate
+			evt.Document = ce.FullDocument
+			evt.Patch = patchFromUpdate(ce.UpdateDesc)
+		}
+	case puller.OperationDelete:
+		evt.Type = storage.EventDelete
+	default:
+		return storage.Event{}, false
+	}
+	return evt, true
+}
+
+// patchFromUpdate converts the update description of a change into a patch
+// of the document data. Updates that replace the data wholesale, touch the
+// deleted flag or truncate arrays yield nil, so clients get the whole
+// document instead.
+func patchFromUpdate(desc *puller.UpdateDescription) *storage.FieldPatch {
+	if desc == nil || len(desc.TruncatedArrays) > 0 {
+		return nil
+	}
+	patch := &storage.FieldPatch{}
+	for field, value := range desc.UpdatedFields {
+		if field == "data" || field == "deleted" {
+			return nil
+		}
+		if path, ok := strings.CutPrefix(field, "data."); ok {
+			if patch.Set == nil {
+				patch.Set = make(map[string]interface{})
+			}
+			patch.Set[path] = value
+		}
+	}
+	for _, field := range desc.RemovedFields {
+		if field == "data" || field == "deleted" {
+			return nil
+		}
+		if path, ok := strings.CutPrefix(field, "data."); ok {
+			patch.Unset = append(patch.Unset, path)
+		}
+	}
+	return patch
+}
+
+// resumeSubscription replays the events subID missed after token and then
+// releases live events held back meanwhile. If the token cannot be served the
+// client receives a reset followed by a fresh snapshot.
+func (c *Client) resumeSubscription(subID, token string) {
+	var resume *resumer
+	if c.hub != nil {
+		resume = c.hub.resume
+	}
+
+	versions, err := c.replayEvents(resume, subID, token)
+	if err != nil {
+		log.Printf("[Info][WS] Resume not possible id=%s: %v", subID, err)
+		c.send <- BaseMessage{ID: subID, Type: TypeReset, Payload: mustMarshal(ResetPayload{SubID: subID, Reason: resetReason(err)})}
+
+		c.mu.Lock()
+		sub, ok := c.subscriptions[subID]
+		c.mu.Unlock()
+		if !ok {
+			return
+		}
+		c.snapshotSubscription(subID, sub)
+		return
+	}
+	c.finishSnapshot(subID, versions)
+}
+
+// replayEvents delivers matching buffered events after token to subID and
+// returns the latest version sent per document path. Repeated versions and
+// deletes are dropped as when releasing live events.
+func (c *Client) replayEvents(resume *resumer, subID, token string) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resumeReplayTimeout)
+	defer cancel()
+
+	iter, err := resume.replay(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	c.mu.Lock()
+	sub, ok := c.subscriptions[subID]
+	tenant, bypass := c.tenant, c.allowAllTenants
+	c.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	// Collect first so a replay that turns out too long sends nothing.
+	var msgs []BaseMessage
+	versions := make(map[string]int64)
+	count := 0
+	for iter.Next() {
+		ce := iter.Event()
+		evt, ok := resume.changeToEvent(ce)
+		if !ok {
+			continue
+		}
+		if count++; count > resume.maxEvents {
+			return nil, errResumeTooFarBehind
+		}
+		if !bypass && (tenant == "" || evt.TenantID != tenant) {
+			continue
+		}
+		if !sub.matches(evt) || !c.canReadEvent(evt) {
+			continue
+		}
+		path, deleted := eventPath(evt), evt.Type == storage.EventDelete
+		if deliveredBefore(versions, path, eventVersion(evt), deleted) {
+			continue
+		}
+		msgs = append(msgs, sub.eventMessage(subID, evt, resume.encode(ce.EventID)))
+		markDelivered(versions, path, eventVersion(evt), deleted)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, msg := range msgs {
+		c.send <- msg
+	}
+	return versions, nil
+}
+
+func resetReason(err error) string {
+	switch {
+	case errors.Is(err, puller.ErrPositionExpired):
+		return "expired"
+	case errors.Is(err, errInvalidResumeToken):
+		return "invalid_token"
+	case errors.Is(err, errResumeTooFarBehind):
+		return "too_far_behind"
+	case errors.Is(err, errResumeUnavailable):
+		return "unavailable"
+	}
+	return "replay_failed"
+}
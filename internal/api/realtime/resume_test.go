@@ -0,0 +1,328 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/puller"
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type sliceIterator struct {
+	events []*puller.ChangeEvent
+	pos    int
+}
+
+func (i *sliceIterator) Next() bool {
+	if i.pos >= len(i.events) {
+		return false
+	}
+	i.pos++
+	return true
+}
+
+func (i *sliceIterator) Event() *puller.ChangeEvent { return i.events[i.pos-1] }
+func (i *sliceIterator) Err() error                 { return nil }
+func (i *sliceIterator) Close() error               { return nil }
+
+type fakeReplaySource struct {
+	events []*puller.ChangeEvent
+	err    error
+	after  map[string]string
+}
+
+func (f *fakeReplaySource) Replay(ctx context.Context, after map[string]string, coalesce bool) (puller.Iterator, error) {
+	f.after = after
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sliceIterator{events: f.events}, nil
+}
+
+func changeEvent(eventID string, op puller.OperationType, doc *storage.Document) *puller.ChangeEvent {
+	return &puller.ChangeEvent{
+		EventID:      eventID,
+		TenantID:     "default",
+		MgoColl:      "documents",
+		MgoDocID:     "default:" + eventID,
+		OpType:       op,
+		FullDocument: doc,
+		ClusterTime:  puller.ClusterTime{T: 1, I: 1},
+		Backend:      "main",
+	}
+}
+
+func newResumeClient(src ReplaySource) *Client {
+	hub := NewHub()
+	hub.resume = newResumer("documents", Config{PullerBackend: "main"})
+	if src != nil {
+		hub.resume.source = src
+	}
+	return &Client{
+		hub:           hub,
+		send:          make(chan BaseMessage, 20),
+		tenant:        "default",
+		authenticated: true,
+		subscriptions: make(map[string]Subscription),
+	}
+}
+
+func TestResumer_TokenMatchesPullerEventID(t *testing.T) {
+	r := newResumer("documents", Config{PullerBackend: "main"})
+
+	evt := storage.Event{Id: "default:abc", ClusterTime: storage.ClusterTime{T: 100, I: 3}}
+	token := r.tokenFor(evt)
+	require.NotEmpty(t, token)
+
+	pm, err := puller.DecodeProgressMarker(token)
+	require.NoError(t, err)
+	assert.Equal(t, puller.FormatEventID(puller.ClusterTime{T: 100, I: 3}, "documents", "default:abc"), pm.Positions["main"])
+
+	assert.Empty(t, r.tokenFor(storage.Event{Id: "default:abc"}), "no cluster time, no token")
+	assert.Nil(t, newResumer("documents", Config{}))
+	assert.Empty(t, (*resumer)(nil).tokenFor(evt))
+}
+
+func TestResumer_ChangeToEvent(t *testing.T) {
+	r := newResumer("documents", Config{PullerBackend: "main"})
+	doc := &storage.Document{Fullpath: "rooms/r1", Collection: "rooms", Version: 2}
+
+	evt, ok := r.changeToEvent(changeEvent("e1", puller.OperationUpdate, doc))
+	require.True(t, ok)
+	assert.Equal(t, storage.EventUpdate, evt.Type)
+	assert.Equal(t, "default", evt.TenantID)
+	assert.Same(t, doc, evt.Document)
+
+	deleted := &storage.Document{Fullpath: "rooms/r1", Collection: "rooms", Deleted: true}
+	evt, ok = r.changeToEvent(changeEvent("e2", puller.OperationUpdate, deleted))
+	require.True(t, ok)
+	assert.Equal(t, storage.EventDelete, evt.Type)
+	assert.Same(t, deleted, evt.Before)
+
+	other := changeEvent("e3", puller.OperationInsert, doc)
+	other.MgoColl = "sys"
+	_, ok = r.changeToEvent(other)
+	assert.False(t, ok)
+}
+
+func TestClient_Resume_ReplaysMissedEvents(t *testing.T) {
+	src := &fakeReplaySource{events: []*puller.ChangeEvent{
+		changeEvent("e1", puller.OperationUpdate, &storage.Document{TenantID: "default", Fullpath: "rooms/r1", Collection: "rooms", Version: 3}),
+		changeEvent("e2", puller.OperationInsert, &storage.Document{TenantID: "default", Fullpath: "users/u1", Collection: "users", Version: 1}),
+	}}
+	c := newResumeClient(src)
+	token := c.hub.resume.encode("e0")
+
+	// A live event that raced with the replay and is already covered by it.
+	c.subscriptions["s1"] = Subscription{Query: model.Query{Collection: "rooms"}, snapshot: newSnapshotBuffer(0)}
+	c.subscriptions["s1"].snapshot.add(bufferedEvent{msg: BaseMessage{Type: TypeEvent, ID: "dup"}, path: "rooms/r1", version: 3})
+	c.subscriptions["s1"].snapshot.add(bufferedEvent{msg: BaseMessage{Type: TypeEvent, ID: "new"}, path: "rooms/r1", version: 4})
+
+	c.resumeSubscription("s1", token)
+
+	assert.Equal(t, map[string]string{"main": "e0"}, src.after)
+
+	msg := <-c.send
+	require.Equal(t, TypeEvent, msg.Type)
+	var payload EventPayload
+	require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+	assert.Equal(t, "s1", payload.SubID)
+	assert.Equal(t, "default:e1", payload.Delta.ID)
+	assert.Equal(t, c.hub.resume.encode("e1"), payload.Delta.ResumeToken)
+
+	assert.Equal(t, "new", (<-c.send).ID)
+	assert.Empty(t, c.send)
+	assert.Nil(t, c.subscriptions["s1"].snapshot)
+}
+
+func TestClient_Resume_DropsRepeatedEvents(t *testing.T) {
+	doc := func(version int64, deleted bool) *storage.Document {
+		return &storage.Document{TenantID: "default", Fullpath: "rooms/r1", Collection: "rooms", Version: version, Deleted: deleted}
+	}
+	src := &fakeReplaySource{events: []*puller.ChangeEvent{
+		changeEvent("e1", puller.OperationUpdate, doc(3, false)),
+		changeEvent("e2", puller.OperationUpdate, doc(3, false)),
+		changeEvent("e3", puller.OperationUpdate, doc(4, true)),
+		changeEvent("e4", puller.OperationUpdate, doc(4, true)),
+	}}
+	c := newResumeClient(src)
+
+	// The delete also raced with the replay as a live event.
+	c.subscriptions["s1"] = Subscription{Query: model.Query{Collection: "rooms"}, snapshot: newSnapshotBuffer(0)}
+	c.subscriptions["s1"].snapshot.add(bufferedEvent{msg: BaseMessage{Type: TypeEvent, ID: "dup"}, path: "rooms/r1", delete: true})
+
+	c.resumeSubscription("s1", c.hub.resume.encode("e0"))
+
+	var ids []string
+	for len(c.send) > 0 {
+		var payload EventPayload
+		require.NoError(t, json.Unmarshal((<-c.send).Payload, &payload))
+		ids = append(ids, payload.Delta.ID)
+	}
+	assert.Equal(t, []string{"default:e1", "default:e3"}, ids)
+}
+
+func TestClient_Resume_ResetsWithSnapshot(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    ReplaySource
+		token  string
+		reason string
+	}{
+		{name: "Expired", src: &fakeReplaySource{err: puller.ErrPositionExpired}, reason: "expired"},
+		{name: "NoSource", reason: "unavailable"},
+		{name: "BadToken", src: &fakeReplaySource{}, token: "!!", reason: "invalid_token"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newResumeClient(tc.src)
+			qs := new(MockQueryService)
+			qs.On("ExecuteQuery", mock.Anything, "default", mock.Anything).Return(snapshotDocs(1), nil)
+			c.queryService = qs
+
+			token := tc.token
+			if token == "" {
+				token = c.hub.resume.encode("e0")
+			}
+			payload, _ := json.Marshal(SubscribePayload{Query: model.Query{Collection: "users"}, ResumeAfter: token})
+			c.handleMessage(BaseMessage{ID: "s1", Type: TypeSubscribe, Payload: payload})
+
+			assert.Equal(t, TypeSubscribeAck, (<-c.send).Type)
+
+			msg := <-c.send
+			require.Equal(t, TypeReset, msg.Type)
+			var reset ResetPayload
+			require.NoError(t, json.Unmarshal(msg.Payload, &reset))
+			assert.Equal(t, "s1", reset.SubID)
+			assert.Equal(t, tc.reason, reset.Reason)
+
+			pages := readSnapshotPages(t, c)
+			require.Len(t, pages, 1)
+			assert.Len(t, pages[0].Documents, 1)
+			assert.Nil(t, c.subscriptions["s1"].snapshot)
+		})
+	}
+}
+
+func TestClient_Resume_TooFarBehind(t *testing.T) {
+	src := &fakeReplaySource{events: []*puller.ChangeEvent{
+		changeEvent("e1", puller.OperationInsert, &storage.Document{Collection: "users", Fullpath: "users/a"}),
+		changeEvent("e2", puller.OperationInsert, &storage.Document{Collection: "users", Fullpath: "users/b"}),
+	}}
+	c := newResumeClient(src)
+	c.hub.resume.maxEvents = 1
+	c.subscriptions["s1"] = Subscription{Query: model.Query{Collection: "users"}, snapshot: newSnapshotBuffer(0)}
+
+	_, err := c.replayEvents(c.hub.resume, "s1", c.hub.resume.encode("e0"))
+	assert.ErrorIs(t, err, errResumeTooFarBehind)
+	assert.Empty(t, c.send, "nothing is sent for an aborted replay")
+}
+
+func TestHub_Broadcast_AttachesResumeToken(t *testing.T) {
+	hub := NewHub()
+	hub.resume = newResumer("documents", Config{PullerBackend: "main"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	c := &Client{
+		hub:             hub,
+		send:            make(chan BaseMessage, 10),
+		allowAllTenants: true,
+		subscriptions:   map[string]Subscription{"s1": {Query: model.Query{Collection: "users"}}},
+	}
+	require.True(t, hub.Register(c))
+
+	evt := storage.Event{
+		Id:          "default:abc",
+		Type:        storage.EventCreate,
+		Document:    &storage.Document{Collection: "users", Fullpath: "users/abc"},
+		ClusterTime: storage.ClusterTime{T: 7, I: 1},
+	}
+	hub.Broadcast(evt)
+
+	msg := <-c.send
+	var payload EventPayload
+	require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+	assert.Equal(t, hub.resume.tokenFor(evt), payload.Delta.ResumeToken)
+	assert.NotEmpty(t, payload.Delta.ResumeToken)
+}
+
+func TestPatchFromUpdate(t *testing.T) {
+	assert.Nil(t, patchFromUpdate(nil))
+
+	patch := patchFromUpdate(&puller.UpdateDescription{
+		UpdatedFields: map[string]interface{}{"data.title": "new", "data.tags.1": "b", "version": int64(3), "updatedAt": int64(9)},
+		RemovedFields: []string{"data.draft"},
+	})
+	require.NotNil(t, patch)
+	assert.Equal(t, map[string]interface{}{"title": "new", "tags.1": "b"}, patch.Set)
+	assert.Equal(t, []string{"draft"}, patch.Unset)
+
+	for _, desc := range []*puller.UpdateDescription{
+		{UpdatedFields: map[string]interface{}{"data": map[string]interface{}{}}},
+		{UpdatedFields: map[string]interface{}{"deleted": false}},
+		{RemovedFields: []string{"data"}},
+		{TruncatedArrays: []puller.TruncatedArray{{Field: "data.tags", NewSize: 1}}},
+	} {
+		assert.Nil(t, patchFromUpdate(desc), "%+v", desc)
+	}
+}
+
+func TestHub_Broadcast_Patches(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	query := model.Query{Collection: "users"}
+	c := &Client{
+		hub:             hub,
+		send:            make(chan BaseMessage, 10),
+		allowAllTenants: true,
+		subscriptions: map[string]Subscription{
+			"full":  {Query: query, IncludeData: true},
+			"patch": {Query: query, IncludeData: true, Patches: true},
+		},
+	}
+	require.True(t, hub.Register(c))
+
+	doc := &storage.Document{Collection: "users", Fullpath: "users/abc", Version: 4, Data: map[string]interface{}{"name": "Bob", "age": 30}}
+	hub.Broadcast(storage.Event{
+		Id:       "default:abc",
+		Type:     storage.EventUpdate,
+		Document: doc,
+		Patch:    &storage.FieldPatch{Set: map[string]interface{}{"name": "Bob"}},
+	})
+
+	deltas := map[string]PublicEvent{}
+	for i := 0; i < 2; i++ {
+		var payload EventPayload
+		require.NoError(t, json.Unmarshal(nextMessage(t, c, TypeEvent).Payload, &payload))
+		deltas[payload.SubID] = payload.Delta
+	}
+	assert.Nil(t, deltas["full"].Patch)
+	assert.Equal(t, "Bob", deltas["full"].Document["name"])
+
+	patched := deltas["patch"]
+	require.NotNil(t, patched.Patch)
+	assert.Equal(t, map[string]interface{}{"name": "Bob"}, patched.Patch.Set)
+	assert.Equal(t, "abc", patched.Document["id"])
+	assert.EqualValues(t, 4, patched.Document["version"])
+	assert.NotContains(t, patched.Document, "age")
+
+	// Events without a patch carry the whole document either way.
+	hub.Broadcast(storage.Event{Id: "default:abc", Type: storage.EventUpdate, Document: doc})
+	for i := 0; i < 2; i++ {
+		var payload EventPayload
+		require.NoError(t, json.Unmarshal(nextMessage(t, c, TypeEvent).Payload, &payload))
+		assert.Nil(t, payload.Delta.Patch)
+		assert.EqualValues(t, 30, payload.Delta.Document["age"])
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/codetrek/syntrix/internal/engine"
 	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/codetrek/syntrix/internal/storage"
 )
 
 type Server struct {
@@ -15,6 +17,9 @@ type Server struct {
 	dataCollection string
 	auth           identity.AuthN
 	cfg            Config
+
+	// feed replaces the per-gateway storage watch when set.
+	feed *pullerFeed
 }
 
 // Config controls realtime auth and CORS behavior.
@@ -22,10 +27,63 @@ type Config struct {
 	AllowedOrigins []string
 	AllowDevOrigin bool
 	EnableAuth     bool
+
+	// AuthzCacheTTL and AuthzCacheSize tune memoization of per-document read
+	// decisions. Zero values use the package defaults.
+	AuthzCacheTTL  time.Duration
+	AuthzCacheSize int
+
+	// SnapshotPageSize, SnapshotMaxDocs and SnapshotBufferSize bound initial
+	// snapshot delivery. Zero values use the package defaults.
+	SnapshotPageSize   int
+	SnapshotMaxDocs    int
+	SnapshotBufferSize int
+
+	// PullerBackend names the puller backend whose buffer holds the data
+	// collection. Empty disables resume tokens. ResumeMaxEvents caps replay
+	// per subscription; zero uses the package default.
+	PullerBackend   string
+	ResumeMaxEvents int
+
+	// SendQueueSize is the per-client queue capacity and OverflowPolicy what
+	// happens when it is full. BroadcastWorkers is the number of fan-out
+	// shards. Zero values use the package defaults.
+	SendQueueSize    int
+	OverflowPolicy   OverflowPolicy
+	BroadcastWorkers int
+
+	// PresenceTTL is how long a presence member survives without a message
+	// from its client. Zero uses the package default.
+	PresenceTTL time.Duration
+
+	// EnableCompression negotiates permessage-deflate with WebSocket clients
+	// that offer it.
+	EnableCompression bool
+
+	// MaxConnectionsPerTenant and MaxConnectionsPerUser cap open WebSocket
+	// and SSE connections, MaxSubscriptionsPerConnection the query and topic
+	// subscriptions of one connection. MessageRate limits inbound WebSocket
+	// messages per second with bursts of MessageBurst. Zero disables a
+	// limit.
+	MaxConnectionsPerTenant       int
+	MaxConnectionsPerUser         int
+	MaxSubscriptionsPerConnection int
+	MessageRate                   float64
+	MessageBurst                  int
+
+	// ReauthWindow is how long before its token expires a connection gets
+	// reauth_required. Zero uses the package default.
+	ReauthWindow time.Duration
 }
 
-func NewServer(qs engine.Service, dataCollection string, auth identity.AuthN, cfg Config) *Server {
+// NewServer creates a realtime server. When authz is non-nil, subscriptions
+// require list permission on their collection and delivered documents are
+// filtered by read permission.
+func NewServer(qs engine.Service, dataCollection string, auth identity.AuthN, authz identity.AuthZ, cfg Config) *Server {
 	h := NewHub()
+	h.configure(cfg)
+	h.authorizer = newAuthorizer(authz, cfg)
+	h.resume = newResumer(dataCollection, cfg)
 	s := &Server{
 		hub:            h,
 		dataCollection: dataCollection,
@@ -36,6 +94,52 @@ func NewServer(qs engine.Service, dataCollection string, auth identity.AuthN, cf
 	return s
 }
 
+// SetReplaySource enables replay of missed events for resumed subscriptions.
+// It must be called before StartBackgroundTasks.
+func (s *Server) SetReplaySource(src ReplaySource) {
+	if s.hub.resume != nil {
+		s.hub.resume.source = src
+	}
+}
+
+// SetEventSource feeds the hub from a puller subscription instead of a
+// storage watch. consumerID must be unique per gateway; progress, if non-nil,
+// persists the subscription position across restarts. It must be called
+// before StartBackgroundTasks.
+func (s *Server) SetEventSource(src EventSource, consumerID string, progress ProgressStore) {
+	s.feed = &pullerFeed{
+		source:     src,
+		progress:   progress,
+		consumerID: consumerID,
+		backend:    s.cfg.PullerBackend,
+		collection: s.dataCollection,
+	}
+}
+
+// SetPresenceBus shares presence channel membership with other gateways
+// through bus. It must be called before StartBackgroundTasks.
+func (s *Server) SetPresenceBus(bus Bus) {
+	s.hub.presence.bus = bus
+}
+
+// SetTopicBus delivers ephemeral topic messages across gateways through bus.
+// It must be called before StartBackgroundTasks.
+func (s *Server) SetTopicBus(bus Bus) {
+	s.hub.topics.bus = bus
+}
+
+// SetRevocationBus shares session revocations with other gateways through
+// bus. It must be called before StartBackgroundTasks.
+func (s *Server) SetRevocationBus(bus Bus) {
+	s.hub.sessions.bus = bus
+}
+
+// RevokeSession closes the connections authenticated with tokens of session
+// on every gateway and refuses those tokens until expiresAt.
+func (s *Server) RevokeSession(tenant, session string, expiresAt time.Time) {
+	s.hub.sessions.revoke(tenant, session, expiresAt)
+}
+
 func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
 	s.wrapWS(w, r)
 }
@@ -44,6 +148,10 @@ func (s *Server) HandleSSE(w http.ResponseWriter, r *http.Request) {
 	s.wrapSSE(w, r)
 }
 
+func (s *Server) HandleStream(w http.ResponseWriter, r *http.Request) {
+	s.wrapStream(w, r)
+}
+
 func (s *Server) wrapWS(w http.ResponseWriter, r *http.Request) {
 	if tokenFromQueryParam(r) != "" {
 		http.Error(w, "Query token not allowed", http.StatusUnauthorized)
@@ -60,6 +168,24 @@ func (s *Server) wrapWS(w http.ResponseWriter, r *http.Request) {
 	ServeWs(s.hub, s.queryService, s.auth, s.cfg, w, r)
 }
 
+// wrapStream authenticates streams like WebSockets: a header token is
+// optional and the auth message can authenticate later.
+func (s *Server) wrapStream(w http.ResponseWriter, r *http.Request) {
+	if tokenFromQueryParam(r) != "" {
+		http.Error(w, "Query token not allowed", http.StatusUnauthorized)
+		return
+	}
+
+	if s.cfg.EnableAuth && s.auth != nil {
+		s.auth.MiddlewareOptional(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ServeStream(s.hub, s.queryService, s.auth, s.cfg, w, r)
+		})).ServeHTTP(w, r)
+		return
+	}
+
+	ServeStream(s.hub, s.queryService, s.auth, s.cfg, w, r)
+}
+
 func (s *Server) wrapSSE(w http.ResponseWriter, r *http.Request) {
 	if s.cfg.EnableAuth && s.auth != nil {
 		s.auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -85,11 +211,20 @@ func tokenFromQueryParam(r *http.Request) string {
 	return ""
 }
 
-// StartBackgroundTasks starts the hub and the change stream watcher.
+// StartBackgroundTasks starts the hub and the change stream watcher, or the
+// puller feed if one was set.
 // It returns an error if watching fails to start.
 // The background tasks run until ctx is cancelled.
 func (s *Server) StartBackgroundTasks(ctx context.Context) error {
 	go s.hub.Run(ctx)
+	go s.hub.presence.run(ctx)
+	go s.hub.topics.run(ctx)
+	go s.hub.sessions.run(ctx)
+
+	if s.feed != nil {
+		go s.feed.run(ctx, s.hub)
+		return nil
+	}
 
 	// Watch all collections
 	stream, err := s.queryService.WatchCollection(ctx, "", "")
@@ -109,6 +244,12 @@ func (s *Server) StartBackgroundTasks(ctx context.Context) error {
 					log.Println("[Realtime] Change stream closed")
 					return
 				}
+				if evt.Type == storage.EventReset {
+					// The change stream skipped ahead; clients catch up on
+					// their own when they resume.
+					log.Println("[Warning][Realtime] Change stream was reset, events may have been missed")
+					continue
+				}
 				// Broadcast all events, let Hub filter by subscription
 				log.Printf("[Realtime] Broadcasting event type=%s id=%s", evt.Type, evt.Id)
 				s.hub.Broadcast(evt)
@@ -74,7 +74,7 @@ func (m *mockQueryWatchStream) Push(ctx context.Context, tenant string, req stor
 }
 
 func TestServer_StartBackgroundTasks_WatchError(t *testing.T) {
-	srv := NewServer(&mockQueryWatchError{}, "", nil, Config{EnableAuth: false})
+	srv := NewServer(&mockQueryWatchError{}, "", nil, nil, Config{EnableAuth: false})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -86,7 +86,7 @@ func TestServer_StartBackgroundTasks_WatchError(t *testing.T) {
 func TestServer_StartBackgroundTasks_Broadcast(t *testing.T) {
 	stream := make(chan storage.Event, 1)
 	qs := &mockQueryWatchStream{stream: stream}
-	srv := NewServer(qs, "", nil, Config{EnableAuth: false})
+	srv := NewServer(qs, "", nil, nil, Config{EnableAuth: false})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -40,7 +40,7 @@ func TestTokenFromQueryParam_TableDriven(t *testing.T) {
 func TestServer_HandleWS_TableDriven(t *testing.T) {
 	t.Parallel()
 	mockQS := new(MockQueryService)
-	server := NewServer(mockQS, "docs", &mockAuthService{}, Config{EnableAuth: true})
+	server := NewServer(mockQS, "docs", &mockAuthService{}, nil, Config{EnableAuth: true})
 
 	// Start Hub
 	ctxHub, cancelHub := context.WithCancel(context.Background())
@@ -86,7 +86,7 @@ func TestServer_HandleWS_TableDriven(t *testing.T) {
 func TestServer_HandleSSE_TableDriven(t *testing.T) {
 	t.Parallel()
 	mockQS := new(MockQueryService)
-	server := NewServer(mockQS, "docs", &mockAuthService{}, Config{EnableAuth: true, AllowedOrigins: []string{"http://example.com"}})
+	server := NewServer(mockQS, "docs", &mockAuthService{}, nil, Config{EnableAuth: true, AllowedOrigins: []string{"http://example.com"}})
 
 	// Start Hub
 	ctxHub, cancelHub := context.WithCancel(context.Background())
@@ -0,0 +1,241 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/identity"
+)
+
+// Error codes of connections that lose their authentication.
+const (
+	codeTokenExpired = "token_expired"
+	codeTokenRevoked = "token_revoked"
+)
+
+// defaultReauthWindow is how long before its token expires a connection is
+// asked to authenticate again.
+const defaultReauthWindow = time.Minute
+
+// sessionCheckInterval is how often token expiry is checked.
+var sessionCheckInterval = time.Second
+
+// typeClose is queued after the last message of a connection the server
+// closes. It is never sent.
+const typeClose = "\x00close"
+
+// revocationWire is a session revocation as published on the bus.
+type revocationWire struct {
+	Node    string `json:"node"`
+	Tenant  string `json:"tenant"`
+	Session string `json:"session"`
+	Until   int64  `json:"until"`
+}
+
+// clientSession is the token a connection authenticated with.
+type clientSession struct {
+	key       string
+	expiresAt time.Time
+	warned    bool
+}
+
+// sessionTracker asks connections to re-authenticate before their token
+// expires, and closes them when it expires or its session is revoked.
+type sessionTracker struct {
+	mu       sync.Mutex
+	node     string
+	window   time.Duration
+	now      func() time.Time
+	bus      Bus
+	clients  map[*Client]*clientSession
+	sessions map[string]map[*Client]struct{}
+
+	// revoked remembers revoked sessions until their tokens expire, so a
+	// revoked token cannot authenticate a connection again.
+	revoked map[string]time.Time
+}
+
+func newSessionTracker(node string) *sessionTracker {
+	return &sessionTracker{
+		node:     node,
+		window:   defaultReauthWindow,
+		now:      time.Now,
+		clients:  make(map[*Client]*clientSession),
+		sessions: make(map[string]map[*Client]struct{}),
+		revoked:  make(map[string]time.Time),
+	}
+}
+
+func sessionKey(tenant, session string) string {
+	return tenant + "\x00" + session
+}
+
+// track records the token c authenticated with, replacing the previous one.
+// It returns false without changes if the token's session was revoked.
+func (t *sessionTracker) track(c *Client, claims *identity.Claims) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var s clientSession
+	if claims != nil {
+		if claims.SessionID != "" {
+			s.key = sessionKey(claims.TenantID, claims.SessionID)
+			if _, ok := t.revoked[s.key]; ok {
+				return false
+			}
+		}
+		if claims.ExpiresAt != nil {
+			s.expiresAt = claims.ExpiresAt.Time
+		}
+	}
+
+	t.removeLocked(c)
+	if s.key == "" && s.expiresAt.IsZero() {
+		return true
+	}
+	t.clients[c] = &s
+	if s.key != "" {
+		clients, ok := t.sessions[s.key]
+		if !ok {
+			clients = make(map[*Client]struct{})
+			t.sessions[s.key] = clients
+		}
+		clients[c] = struct{}{}
+	}
+	return true
+}
+
+// removeClient stops tracking c. It must be called before c's send channel
+// is closed.
+func (t *sessionTracker) removeClient(c *Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removeLocked(c)
+}
+
+func (t *sessionTracker) removeLocked(c *Client) {
+	s, ok := t.clients[c]
+	if !ok {
+		return
+	}
+	delete(t.clients, c)
+	if clients, ok := t.sessions[s.key]; ok {
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(t.sessions, s.key)
+		}
+	}
+}
+
+// check warns connections whose token is about to expire and closes those
+// whose token has expired.
+func (t *sessionTracker) check() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for c, s := range t.clients {
+		if s.expiresAt.IsZero() {
+			continue
+		}
+		if !now.Before(s.expiresAt) {
+			t.removeLocked(c)
+			c.closeWith(codeTokenExpired, "token expired", "token_expired")
+			continue
+		}
+		if !s.warned && !now.Before(s.expiresAt.Add(-t.window)) {
+			s.warned = true
+			c.enqueue(BaseMessage{Type: TypeReauthRequired, Payload: mustMarshal(ReauthRequiredPayload{
+				ExpiresAt: s.expiresAt.UnixMilli(),
+			})}, "", OverflowDisconnect)
+		}
+	}
+	for key, until := range t.revoked {
+		if now.After(until) {
+			delete(t.revoked, key)
+		}
+	}
+}
+
+// revoke closes the connections of a session on this gateway and on the
+// others. until is when the session's tokens expire.
+func (t *sessionTracker) revoke(tenant, session string, until time.Time) {
+	t.revokeLocal(tenant, session, until)
+	if t.bus == nil {
+		return
+	}
+	w := revocationWire{Node: t.node, Tenant: tenant, Session: session, Until: until.UnixMilli()}
+	if err := t.bus.Publish(mustMarshal(w)); err != nil {
+		log.Printf("[Warning][Realtime] Failed to publish revocation tenant=%s: %v", tenant, err)
+	}
+}
+
+func (t *sessionTracker) revokeLocal(tenant, session string, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := sessionKey(tenant, session)
+	t.revoked[key] = until
+	for c := range t.sessions[key] {
+		t.removeLocked(c)
+		c.closeWith(codeTokenRevoked, "token revoked", "token_revoked")
+	}
+}
+
+// handleRemote applies a revocation published by another gateway.
+func (t *sessionTracker) handleRemote(data []byte) {
+	var w revocationWire
+	if err := json.Unmarshal(data, &w); err != nil {
+		log.Printf("[Warning][Realtime] Invalid revocation message: %v", err)
+		return
+	}
+	if w.Node == t.node {
+		return
+	}
+	t.revokeLocal(w.Tenant, w.Session, time.UnixMilli(w.Until))
+}
+
+// run checks token expiry and receives revocations of other gateways until
+// ctx is cancelled.
+func (t *sessionTracker) run(ctx context.Context) {
+	if t.bus != nil {
+		unsubscribe, err := t.bus.Subscribe(t.handleRemote)
+		if err != nil {
+			log.Printf("[Warning][Realtime] Failed to subscribe to revocation bus, revocations stay local: %v", err)
+		} else {
+			defer unsubscribe()
+		}
+	}
+
+	ticker := time.NewTicker(sessionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.check()
+		}
+	}
+}
+
+// closeWith sends an error to the client and closes its connection once
+// everything queued before has been written.
+func (c *Client) closeWith(code, message, reason string) {
+	c.enqueue(BaseMessage{Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: code, Message: message})}, "", OverflowDisconnect)
+	c.enqueue(BaseMessage{Type: typeClose, Payload: mustMarshal(code)}, "", OverflowDisconnect)
+	if c.evicted.CompareAndSwap(false, true) {
+		clientsDisconnected.WithLabelValues(reason).Inc()
+		log.Printf("[Info][WS] Closing client tenant=%s reason=%s", c.tenant, reason)
+	}
+}
+
+// writeClose ends a connection closed by the server.
+func (c *Client) writeClose(msg BaseMessage) {
+	var code string
+	_ = json.Unmarshal(msg.Payload, &code)
+	c.conn.writeClose(code)
+}
@@ -0,0 +1,155 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sessionClaims(user, session string, expiresAt time.Time) *identity.Claims {
+	return &identity.Claims{
+		TenantID:         "t1",
+		UserID:           user,
+		SessionID:        session,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)},
+	}
+}
+
+// nextClose expects the error and close marker of a connection closed by the
+// server and returns the error code.
+func nextClose(t *testing.T, c *Client) string {
+	t.Helper()
+	code := errorCode(t, nextMessage(t, c, TypeError))
+	nextMessage(t, c, typeClose)
+	return code
+}
+
+func TestSessions_ReauthBeforeExpiry(t *testing.T) {
+	h := NewHub()
+	now := time.Unix(1000, 0)
+	h.sessions.now = func() time.Time { return now }
+	h.sessions.window = time.Minute
+
+	auth := new(MockAuthService)
+	auth.On("ValidateToken", "old").Return(sessionClaims("alice", "s1", now.Add(5*time.Minute)), nil)
+	auth.On("ValidateToken", "new").Return(sessionClaims("alice", "s2", now.Add(20*time.Minute)), nil)
+	auth.On("ValidateToken", "bob").Return(sessionClaims("bob", "s3", now.Add(20*time.Minute)), nil)
+	c := newPresenceClient(h, "")
+	c.auth = auth
+	c.authenticated = false
+
+	c.handleMessage(BaseMessage{ID: "a1", Type: TypeAuth, Payload: mustMarshal(AuthPayload{Token: "old"})})
+	nextMessage(t, c, TypeAuthAck)
+
+	h.sessions.check()
+	assertNoMessage(t, c)
+
+	now = now.Add(4*time.Minute + time.Second)
+	h.sessions.check()
+	var p ReauthRequiredPayload
+	require.NoError(t, json.Unmarshal(nextMessage(t, c, TypeReauthRequired).Payload, &p))
+	assert.Equal(t, now.Add(-4*time.Minute-time.Second).Add(5*time.Minute).UnixMilli(), p.ExpiresAt)
+	h.sessions.check()
+	assertNoMessage(t, c)
+
+	// A token of another user is refused, a fresh one of the same user
+	// extends the connection.
+	c.handleMessage(BaseMessage{ID: "a2", Type: TypeAuth, Payload: mustMarshal(AuthPayload{Token: "bob"})})
+	assert.Equal(t, "unauthorized", errorCode(t, nextMessage(t, c, TypeError)))
+	c.handleMessage(BaseMessage{ID: "a3", Type: TypeAuth, Payload: mustMarshal(AuthPayload{Token: "new"})})
+	nextMessage(t, c, TypeAuthAck)
+
+	now = now.Add(10 * time.Minute)
+	h.sessions.check()
+	assertNoMessage(t, c)
+
+	now = now.Add(10 * time.Minute)
+	h.sessions.check()
+	assert.Equal(t, codeTokenExpired, nextClose(t, c))
+	assert.True(t, c.evicted.Load())
+	assert.Empty(t, h.sessions.clients)
+}
+
+func TestSessions_Revoke(t *testing.T) {
+	bus := &memoryBus{}
+	h1, h2 := NewHub(), NewHub()
+	for _, h := range []*Hub{h1, h2} {
+		h.sessions.bus = bus
+		_, _ = bus.Subscribe(h.sessions.handleRemote)
+	}
+	exp := time.Now().Add(time.Hour)
+
+	local, remote, other := newPresenceClient(h1, "t1"), newPresenceClient(h2, "t1"), newPresenceClient(h2, "t1")
+	require.True(t, h1.sessions.track(local, sessionClaims("alice", "s1", exp)))
+	require.True(t, h2.sessions.track(remote, sessionClaims("alice", "s1", exp)))
+	require.True(t, h2.sessions.track(other, sessionClaims("alice", "s2", exp)))
+
+	h1.sessions.revoke("t1", "s1", exp)
+	assert.Equal(t, codeTokenRevoked, nextClose(t, local))
+	assert.Equal(t, codeTokenRevoked, nextClose(t, remote))
+	assertNoMessage(t, other)
+
+	// Revoked tokens cannot authenticate again until they expire.
+	auth := new(MockAuthService)
+	auth.On("ValidateToken", "tok").Return(sessionClaims("alice", "s1", exp), nil)
+	again := newPresenceClient(h2, "")
+	again.auth = auth
+	again.authenticated = false
+	again.handleMessage(BaseMessage{ID: "a1", Type: TypeAuth, Payload: mustMarshal(AuthPayload{Token: "tok"})})
+	assert.Equal(t, codeTokenRevoked, errorCode(t, nextMessage(t, again, TypeError)))
+	assert.False(t, again.authenticated)
+
+	h2.sessions.now = func() time.Time { return exp.Add(time.Second) }
+	h2.sessions.check()
+	assert.Empty(t, h2.sessions.revoked)
+}
+
+func TestServeWs_RevokedSessionCloses(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	claims := sessionClaims("alice", "s1", time.Now().Add(time.Hour))
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), identity.ContextKeyClaims, claims))
+		ServeWs(hub, &MockQueryService{}, nil, Config{}, w, r)
+	}))
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool {
+		hub.sessions.mu.Lock()
+		defer hub.sessions.mu.Unlock()
+		return len(hub.sessions.clients) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	hub.sessions.revoke("t1", "s1", claims.ExpiresAt.Time)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	var msg BaseMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, codeTokenRevoked, errorCode(t, msg))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "%v", err)
+
+	// New connections with the revoked token are refused.
+	again, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer again.Close()
+	require.NoError(t, again.SetReadDeadline(time.Now().Add(time.Second)))
+	require.NoError(t, again.ReadJSON(&msg))
+	assert.Equal(t, codeTokenRevoked, errorCode(t, msg))
+}
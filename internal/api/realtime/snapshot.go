@@ -0,0 +1,342 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+)
+
+const (
+	// defaultSnapshotPageSize is the number of documents per snapshot message.
+	defaultSnapshotPageSize = 500
+
+	// defaultSnapshotMaxDocs caps snapshots of queries without an explicit limit.
+	defaultSnapshotMaxDocs = 10000
+
+	// defaultSnapshotBufferSize caps live events held back while a snapshot is
+	// being read and delivered.
+	defaultSnapshotBufferSize = 10000
+
+	snapshotQueryTimeout = 30 * time.Second
+)
+
+// snapshotBuffer holds live events for a subscription whose initial snapshot
+// or resume replay is still in flight. It is guarded by the owning Client's mu.
+type snapshotBuffer struct {
+	events   []bufferedEvent
+	max      int
+	overflow bool
+}
+
+type bufferedEvent struct {
+	msg     BaseMessage
+	key     string // coalescing key of msg
+	path    string
+	version int64
+	delete  bool
+
+	// For query views the raw event is kept and applied to the window once
+	// the snapshot is in place.
+	evt      storage.Event
+	readable bool
+	token    string
+}
+
+func newSnapshotBuffer(max int) *snapshotBuffer {
+	if max <= 0 {
+		max = defaultSnapshotBufferSize
+	}
+	return &snapshotBuffer{max: max}
+}
+
+func (b *snapshotBuffer) add(evt bufferedEvent) {
+	if b.overflow {
+		return
+	}
+	if len(b.events) >= b.max {
+		b.overflow = true
+		b.events = nil
+		return
+	}
+	b.events = append(b.events, evt)
+}
+
+// sendSnapshot reads the subscription's query and delivers the result in
+// pages. The subscription must already be registered with a snapshotBuffer so
+// that events racing with the read are held back and reconciled afterwards.
+func (c *Client) sendSnapshot(subID string, query model.Query) {
+	pageSize := c.cfg.SnapshotPageSize
+	if pageSize <= 0 {
+		pageSize = defaultSnapshotPageSize
+	}
+
+	flatDocs, versions, truncated, err := c.readSnapshot(query)
+	if err != nil {
+		c.send <- BaseMessage{ID: subID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "snapshot_failed", Message: "failed to load snapshot"})}
+		c.finishSnapshot(subID, nil)
+		return
+	}
+
+	c.mu.Lock()
+	if sub, ok := c.subscriptions[subID]; ok && sub.view != nil {
+		if truncated && sub.view.limit <= 0 {
+			// The window is capped like an explicitly limited query.
+			sub.view.limit = len(flatDocs)
+		}
+		sub.view.reset(c.viewEntries(flatDocs))
+	}
+	c.mu.Unlock()
+
+	page := 0
+	for start := 0; ; start += pageSize {
+		end := start + pageSize
+		if end > len(flatDocs) {
+			end = len(flatDocs)
+		}
+		done := end == len(flatDocs)
+		c.send <- BaseMessage{
+			ID:   subID,
+			Type: TypeSnapshot,
+			Payload: mustMarshal(SnapshotPayload{
+				SubID:     subID,
+				Documents: toMaps(flatDocs[start:end]),
+				Page:      page,
+				Done:      done,
+				Truncated: done && truncated,
+			}),
+		}
+		if done {
+			break
+		}
+		page++
+	}
+
+	c.finishSnapshot(subID, versions)
+}
+
+// snapshotSubscription delivers the initial state of sub: the result of its
+// query, or the document of a path subscription. Pattern subscriptions have
+// none, so only the events held back are released.
+func (c *Client) snapshotSubscription(subID string, sub Subscription) {
+	switch {
+	case sub.pattern == nil:
+		c.sendSnapshot(subID, sub.Query)
+	case sub.pattern.document:
+		c.sendDocumentSnapshot(subID, sub.pattern.raw)
+	default:
+		c.finishSnapshot(subID, nil)
+	}
+}
+
+// sendDocumentSnapshot delivers the document at path as a single page, which
+// is empty if the document does not exist or may not be read.
+func (c *Client) sendDocumentSnapshot(subID, path string) {
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotQueryTimeout)
+	defer cancel()
+
+	docs := []model.Document{}
+	versions := make(map[string]int64, 1)
+	doc, err := c.queryService.GetDocument(ctx, c.tenant, path)
+	switch {
+	case err == nil:
+		version := documentVersion(doc)
+		versions[path] = version
+		if c.canReadDocument(path, version, doc) {
+			docs = append(docs, doc)
+		}
+	case !errors.Is(err, model.ErrNotFound):
+		log.Printf("[Error][WS] Snapshot read failed path=%s: %v", path, err)
+		c.send <- BaseMessage{ID: subID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "snapshot_failed", Message: "failed to load snapshot"})}
+		c.finishSnapshot(subID, nil)
+		return
+	}
+
+	c.send <- BaseMessage{
+		ID:      subID,
+		Type:    TypeSnapshot,
+		Payload: mustMarshal(SnapshotPayload{SubID: subID, Documents: toMaps(docs), Done: true}),
+	}
+	c.finishSnapshot(subID, versions)
+}
+
+// readSnapshot runs query and returns the readable documents in result order
+// along with the version of every document the query returned.
+func (c *Client) readSnapshot(query model.Query) ([]model.Document, map[string]int64, bool, error) {
+	maxDocs := c.cfg.SnapshotMaxDocs
+	if maxDocs <= 0 {
+		maxDocs = defaultSnapshotMaxDocs
+	}
+
+	q := query
+	q.ShowDeleted = false
+	if q.Limit <= 0 || q.Limit > maxDocs {
+		// Read one extra document to detect truncation.
+		q.Limit = maxDocs + 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotQueryTimeout)
+	defer cancel()
+
+	docs, err := c.queryService.ExecuteQuery(ctx, c.tenant, q)
+	if err != nil {
+		log.Printf("[Error][WS] Snapshot query failed: %v", err)
+		return nil, nil, false, err
+	}
+
+	truncated := false
+	if len(docs) > maxDocs {
+		docs = docs[:maxDocs]
+		truncated = true
+	}
+
+	versions := make(map[string]int64, len(docs))
+	flatDocs := make([]model.Document, 0, len(docs))
+	for _, doc := range docs {
+		path := doc.GetCollection() + "/" + doc.GetID()
+		version := documentVersion(doc)
+		versions[path] = version
+		if !c.canReadDocument(path, version, doc) {
+			continue
+		}
+		flatDocs = append(flatDocs, doc)
+	}
+	return flatDocs, versions, truncated, nil
+}
+
+// finishSnapshot releases events buffered during the snapshot or replay.
+// Events whose document version has already been delivered are dropped. For
+// query views the remaining events are applied to the window instead.
+//
+// The buffer is drained in rounds: the messages of a round are worked out
+// under c.mu and queued after it is released, while the hub keeps buffering
+// behind them. The buffer is only removed once a round finds it empty, so
+// live events cannot overtake buffered ones.
+func (c *Client) finishSnapshot(subID string, versions map[string]int64) {
+	policy := c.overflowPolicy()
+	for {
+		msgs, done := c.drainSnapshot(subID, versions)
+		for _, msg := range msgs {
+			c.enqueue(msg.msg, msg.key, policy)
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// queuedMessage is a message released from a snapshot buffer together with
+// its coalescing key.
+type queuedMessage struct {
+	msg BaseMessage
+	key string
+}
+
+// drainSnapshot takes the events buffered so far for subID and returns the
+// messages they yield. done reports that the buffer is gone, either because
+// it was empty, overflowed or was handed to a view refill.
+func (c *Client) drainSnapshot(subID string, versions map[string]int64) ([]queuedMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sub, ok := c.subscriptions[subID]
+	if !ok || sub.snapshot == nil {
+		return nil, true
+	}
+	buf := sub.snapshot
+
+	if buf.overflow {
+		delete(c.subscriptions, subID)
+		c.subIndex().remove(c, sub)
+		return []queuedMessage{{msg: BaseMessage{ID: subID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "snapshot_overflow", Message: "too many changes during snapshot, resubscribe"})}}}, true
+	}
+	if len(buf.events) == 0 {
+		sub.snapshot = nil
+		c.subscriptions[subID] = sub
+		return nil, true
+	}
+	events := buf.events
+	buf.events = nil
+
+	var msgs []queuedMessage
+	for i, evt := range events {
+		if deliveredBefore(versions, evt.path, evt.version, evt.delete) {
+			continue
+		}
+		if sub.view == nil {
+			msgs = append(msgs, queuedMessage{msg: evt.msg, key: evt.key})
+			continue
+		}
+		// Buffered view events were authorized when they were buffered. The
+		// buffer is detached while applying them so that a window that needs
+		// re-reading can start its refill.
+		sub.snapshot = nil
+		readable := evt.readable
+		for _, msg := range c.applyViewEvent(subID, sub, evt.evt, func() bool { return readable }, evt.token) {
+			msgs = append(msgs, queuedMessage{msg: msg})
+		}
+		if sub = c.subscriptions[subID]; sub.snapshot != buf {
+			// A refill started; it picks up the remaining events.
+			for _, rest := range events[i+1:] {
+				sub.snapshot.add(rest)
+			}
+			return msgs, true
+		}
+	}
+	return msgs, false
+}
+
+// versionDeleted marks a path in a versions map whose last delivered event
+// was a delete.
+const versionDeleted int64 = math.MinInt64
+
+// deliveredBefore reports whether an event for path at version, or a delete
+// of path, repeats what versions records as already sent.
+func deliveredBefore(versions map[string]int64, path string, version int64, deleted bool) bool {
+	v, ok := versions[path]
+	switch {
+	case !ok || path == "":
+		return false
+	case deleted:
+		return v == versionDeleted
+	default:
+		return version <= v
+	}
+}
+
+// markDelivered records in versions that an event for path was sent.
+func markDelivered(versions map[string]int64, path string, version int64, deleted bool) {
+	switch {
+	case path == "":
+	case deleted:
+		versions[path] = versionDeleted
+	default:
+		versions[path] = version
+	}
+}
+
+func toMaps(docs []model.Document) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(docs))
+	for i, doc := range docs {
+		out[i] = doc
+	}
+	return out
+}
+
+// documentVersion reads the version field of a flattened document, which is
+// an int64 from the local engine and a float64 after a JSON round trip.
+func documentVersion(doc model.Document) int64 {
+	switch v := doc["version"].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	return -1
+}
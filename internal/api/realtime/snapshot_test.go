@@ -0,0 +1,299 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotDocs(n int) []model.Document {
+	docs := make([]model.Document, n)
+	for i := range docs {
+		docs[i] = model.Document{"id": string(rune('a' + i)), "collection": "users", "version": int64(1)}
+	}
+	return docs
+}
+
+func readSnapshotPages(t *testing.T, c *Client) []SnapshotPayload {
+	t.Helper()
+	var pages []SnapshotPayload
+	for {
+		require.NotEmpty(t, c.send, "expected snapshot page")
+		msg := <-c.send
+		require.Equal(t, TypeSnapshot, msg.Type)
+		var p SnapshotPayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		pages = append(pages, p)
+		if p.Done {
+			return pages
+		}
+	}
+}
+
+func TestClient_SendSnapshot_UsesQueryAndPages(t *testing.T) {
+	qs := new(MockQueryService)
+	query := model.Query{
+		Collection: "users",
+		Filters:    model.Filters{{Field: "age", Op: ">", Value: 20}},
+		OrderBy:    []model.Order{{Field: "age", Direction: "desc"}},
+		Limit:      5,
+	}
+	qs.On("ExecuteQuery", mock.Anything, "t1", mock.MatchedBy(func(q model.Query) bool {
+		return q.Collection == "users" && len(q.Filters) == 1 && len(q.OrderBy) == 1 && q.Limit == 5 && !q.ShowDeleted
+	})).Return(snapshotDocs(5), nil)
+
+	c := &Client{
+		queryService:  qs,
+		cfg:           Config{SnapshotPageSize: 2},
+		send:          make(chan BaseMessage, 10),
+		tenant:        "t1",
+		subscriptions: map[string]Subscription{"s1": {Query: query, snapshot: newSnapshotBuffer(0)}},
+	}
+
+	c.sendSnapshot("s1", query)
+
+	pages := readSnapshotPages(t, c)
+	require.Len(t, pages, 3)
+	assert.Len(t, pages[0].Documents, 2)
+	assert.Len(t, pages[2].Documents, 1)
+	assert.Equal(t, 2, pages[2].Page)
+	assert.False(t, pages[2].Truncated)
+	assert.Nil(t, c.subscriptions["s1"].snapshot)
+	qs.AssertExpectations(t)
+}
+
+func TestClient_SendSnapshot_Truncated(t *testing.T) {
+	qs := new(MockQueryService)
+	qs.On("ExecuteQuery", mock.Anything, mock.Anything, mock.MatchedBy(func(q model.Query) bool {
+		return q.Limit == 4
+	})).Return(snapshotDocs(4), nil)
+
+	c := &Client{
+		queryService:  qs,
+		cfg:           Config{SnapshotMaxDocs: 3},
+		send:          make(chan BaseMessage, 10),
+		subscriptions: map[string]Subscription{"s1": {snapshot: newSnapshotBuffer(0)}},
+	}
+
+	c.sendSnapshot("s1", model.Query{Collection: "users"})
+
+	pages := readSnapshotPages(t, c)
+	require.Len(t, pages, 1)
+	assert.Len(t, pages[0].Documents, 3)
+	assert.True(t, pages[0].Truncated)
+}
+
+func TestClient_SendSnapshot_EmptyResult(t *testing.T) {
+	qs := new(MockQueryService)
+	qs.On("ExecuteQuery", mock.Anything, mock.Anything, mock.Anything).Return([]model.Document{}, nil)
+
+	c := &Client{
+		queryService:  qs,
+		send:          make(chan BaseMessage, 10),
+		subscriptions: map[string]Subscription{"s1": {snapshot: newSnapshotBuffer(0)}},
+	}
+
+	c.sendSnapshot("s1", model.Query{Collection: "users"})
+
+	pages := readSnapshotPages(t, c)
+	require.Len(t, pages, 1)
+	assert.Empty(t, pages[0].Documents)
+}
+
+func TestClient_FinishSnapshot_ReconcilesBufferedEvents(t *testing.T) {
+	buf := newSnapshotBuffer(0)
+	stale := BaseMessage{Type: TypeEvent, ID: "stale"}
+	fresh := BaseMessage{Type: TypeEvent, ID: "fresh"}
+	created := BaseMessage{Type: TypeEvent, ID: "created"}
+	deleted := BaseMessage{Type: TypeEvent, ID: "deleted"}
+	buf.add(bufferedEvent{msg: stale, path: "users/a", version: 2})
+	buf.add(bufferedEvent{msg: fresh, path: "users/a", version: 3})
+	buf.add(bufferedEvent{msg: created, path: "users/b", version: 1})
+	buf.add(bufferedEvent{msg: deleted, path: "users/a", delete: true})
+
+	c := &Client{
+		send:          make(chan BaseMessage, 10),
+		subscriptions: map[string]Subscription{"s1": {snapshot: buf}},
+	}
+
+	c.finishSnapshot("s1", map[string]int64{"users/a": 2})
+
+	var ids []string
+	for len(c.send) > 0 {
+		ids = append(ids, (<-c.send).ID)
+	}
+	assert.Equal(t, []string{"fresh", "created", "deleted"}, ids)
+	assert.Nil(t, c.subscriptions["s1"].snapshot)
+}
+
+func TestClient_FinishSnapshot_Overflow(t *testing.T) {
+	buf := newSnapshotBuffer(1)
+	buf.add(bufferedEvent{path: "users/a", version: 1})
+	buf.add(bufferedEvent{path: "users/b", version: 1})
+	assert.True(t, buf.overflow)
+
+	c := &Client{
+		send:          make(chan BaseMessage, 10),
+		subscriptions: map[string]Subscription{"s1": {snapshot: buf}},
+	}
+	c.finishSnapshot("s1", nil)
+
+	msg := <-c.send
+	assert.Equal(t, TypeError, msg.Type)
+	assert.Contains(t, string(msg.Payload), "snapshot_overflow")
+	assert.NotContains(t, c.subscriptions, "s1")
+}
+
+func TestClient_FinishSnapshot_FullQueue(t *testing.T) {
+	buf := newSnapshotBuffer(0)
+	buf.add(bufferedEvent{msg: BaseMessage{Type: TypeEvent, ID: "a"}, key: "s1\x00a", path: "users/a", version: 1})
+	buf.add(bufferedEvent{msg: BaseMessage{Type: TypeEvent, ID: "b"}, key: "s1\x00b", path: "users/b", version: 1})
+
+	c := &Client{
+		send:          make(chan BaseMessage, 1),
+		subscriptions: map[string]Subscription{"s1": {snapshot: buf}},
+	}
+	c.send <- BaseMessage{Type: TypeEvent, ID: "queued"}
+
+	done := make(chan struct{})
+	go func() {
+		c.finishSnapshot("s1", nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("finishSnapshot blocked on a full send queue")
+	}
+
+	// The overflow policy dropped the keyed events instead of blocking.
+	assert.Equal(t, "queued", (<-c.send).ID)
+	assert.Nil(t, c.subscriptions["s1"].snapshot)
+	assert.False(t, c.evicted.Load())
+}
+
+func TestHub_BuffersEventsDuringSnapshot(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	c := &Client{
+		hub:             hub,
+		send:            make(chan BaseMessage, 10),
+		allowAllTenants: true,
+		subscriptions: map[string]Subscription{
+			"s1": {Query: model.Query{Collection: "users"}, snapshot: newSnapshotBuffer(0)},
+		},
+	}
+	require.True(t, hub.Register(c))
+
+	hub.Broadcast(storage.Event{
+		Type:     storage.EventUpdate,
+		Document: &storage.Document{Fullpath: "users/a", Collection: "users", Version: 4},
+	})
+	// The hub handles one message at a time, so a second send completes only
+	// after the first event has been fanned out.
+	hub.Broadcast(storage.Event{Type: storage.EventUpdate, Document: &storage.Document{Collection: "other"}})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Empty(t, c.send)
+	buf := c.subscriptions["s1"].snapshot
+	require.Len(t, buf.events, 1)
+	assert.Equal(t, "users/a", buf.events[0].path)
+	assert.Equal(t, int64(4), buf.events[0].version)
+}
+
+func TestHub_StalledClientDoesNotBlockShard(t *testing.T) {
+	tests := []struct {
+		name    string
+		sub     Subscription
+		release func(c *Client)
+	}{
+		{
+			name:    "Snapshot",
+			sub:     Subscription{Query: model.Query{Collection: "users"}},
+			release: func(c *Client) { c.finishSnapshot("s1", nil) },
+		},
+		{
+			name:    "Refill",
+			sub:     Subscription{Query: model.Query{Collection: "users"}, view: newQueryView(model.Query{Collection: "users"})},
+			release: func(c *Client) { c.refillView("s1") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub()
+			hub.configure(Config{BroadcastWorkers: 1})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go hub.Run(ctx)
+
+			qs := new(MockQueryService)
+			qs.On("ExecuteQuery", mock.Anything, "default", mock.Anything).Return(snapshotDocs(3), nil)
+
+			// The stalled client's queue is full while its buffer is released.
+			sub := tt.sub
+			sub.snapshot = newSnapshotBuffer(0)
+			stalled := &Client{
+				hub:           hub,
+				queryService:  qs,
+				send:          make(chan BaseMessage, 1),
+				tenant:        "default",
+				subscriptions: map[string]Subscription{"s1": sub},
+			}
+			stalled.send <- BaseMessage{Type: TypeEvent}
+			other := &Client{
+				hub:           hub,
+				send:          make(chan BaseMessage, 10),
+				tenant:        "default",
+				subscriptions: map[string]Subscription{"s1": {Query: model.Query{Collection: "users"}}},
+			}
+			require.True(t, hub.Register(stalled))
+			require.True(t, hub.Register(other))
+
+			event := func(id string) storage.Event {
+				return storage.Event{
+					Id:       id,
+					Type:     storage.EventUpdate,
+					TenantID: "default",
+					Document: &storage.Document{TenantID: "default", Fullpath: "users/" + id, Collection: "users", Version: 1},
+				}
+			}
+			hub.Broadcast(event("a"))
+
+			released := make(chan struct{})
+			go func() {
+				tt.release(stalled)
+				close(released)
+			}()
+			hub.Broadcast(event("b"))
+			hub.Broadcast(event("c"))
+
+			for _, id := range []string{"a", "b", "c"} {
+				select {
+				case msg := <-other.send:
+					var payload EventPayload
+					require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+					assert.Equal(t, id, payload.Delta.ID)
+				case <-time.After(time.Second):
+					t.Fatalf("event %s not delivered to the other client", id)
+				}
+			}
+			select {
+			case <-released:
+			case <-time.After(time.Second):
+				t.Fatal("releasing the stalled client's buffer blocked")
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package realtime
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/engine"
+	"github.com/codetrek/syntrix/internal/identity"
+)
+
+// StreamMethod is a second path of the stream, shaped like a gRPC method path
+// so that proxies which route HTTP/2 traffic by gRPC paths pass it on. The
+// stream is not a gRPC service: there is no proto and messages are not
+// protobuf.
+const StreamMethod = "/syntrix.realtime.v1.Realtime/Connect"
+
+// Content types of streams. They borrow the gRPC names for the framing only; a
+// codec suffix picks the encoding of messages, e.g. application/grpc-web+json.
+const (
+	streamContentTypeGRPC    = "application/grpc"
+	streamContentTypeGRPCWeb = "application/grpc-web"
+)
+
+// Flags of the frame header.
+const (
+	frameCompressed = 0x01
+	frameTrailer    = 0x80
+)
+
+// Status codes a stream ends with, numbered as in gRPC.
+const (
+	grpcOK                = 0
+	grpcPermissionDenied  = 7
+	grpcResourceExhausted = 8
+	grpcUnavailable       = 14
+	grpcUnauthenticated   = 16
+)
+
+// streamCodec returns the codec of a stream content type and whether the
+// stream carries its trailers in the body, as gRPC-web does.
+func streamCodec(contentType string) (codec, bool, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false, false
+	}
+	base, sub, _ := strings.Cut(mediaType, "+")
+	var web bool
+	switch base {
+	case streamContentTypeGRPC:
+	case streamContentTypeGRPCWeb:
+		web = true
+	default:
+		return nil, false, false
+	}
+	switch sub {
+	case "json":
+		return jsonCodec{}, web, true
+	case "msgpack":
+		return msgpackCodec{}, web, true
+	case "cbor":
+		return cborCodec{}, web, true
+	}
+	return nil, false, false
+}
+
+// grpcStatusFor maps the error code a connection is closed with to the
+// status of the stream.
+func grpcStatusFor(code string) int {
+	switch code {
+	case "":
+		return grpcOK
+	case codeTokenExpired, codeTokenRevoked:
+		return grpcUnauthenticated
+	case codeConnectionLimit:
+		return grpcResourceExhausted
+	}
+	return grpcUnavailable
+}
+
+// streamTransport is a long-lived HTTP/2 request carrying length-prefixed
+// frames in both directions: a flag byte, the big-endian length and the
+// message in the codec of the stream. The frame layout is that of gRPC, but
+// the messages are not protobuf. Over HTTP/2 both directions are open at
+// once.
+type streamTransport struct {
+	ctx   context.Context
+	w     http.ResponseWriter
+	rc    *http.ResponseController
+	body  io.Reader
+	codec codec
+	web   bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newStreamTransport(ctx context.Context, w http.ResponseWriter, body io.Reader, c codec, web bool) *streamTransport {
+	return &streamTransport{
+		ctx:   ctx,
+		w:     w,
+		rc:    http.NewResponseController(w),
+		body:  body,
+		codec: c,
+		web:   web,
+		done:  make(chan struct{}),
+	}
+}
+
+// read returns the next message. When the client half-closes its side,
+// delivery goes on and read blocks until the stream ends.
+func (t *streamTransport) read() (BaseMessage, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(t.body, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			select {
+			case <-t.ctx.Done():
+			case <-t.done:
+			}
+		}
+		return BaseMessage{}, err
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > maxMessageSize {
+		return BaseMessage{}, fmt.Errorf("message of %d bytes exceeds the limit", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(t.body, data); err != nil {
+		return BaseMessage{}, err
+	}
+	if header[0]&(frameCompressed|frameTrailer) != 0 {
+		return BaseMessage{}, fmt.Errorf("%w: unsupported frame flags %#x", errMalformedMessage, header[0])
+	}
+	msg, err := t.codec.decode(data)
+	if err != nil {
+		return BaseMessage{}, fmt.Errorf("%w: %v", errMalformedMessage, err)
+	}
+	return msg, nil
+}
+
+func (t *streamTransport) write(msg BaseMessage) error {
+	data, err := t.codec.encode(msg)
+	if err != nil {
+		return fmt.Errorf("encoding message type=%s: %w", msg.Type, err)
+	}
+	return t.writeFrame(0, data)
+}
+
+func (t *streamTransport) writeFrame(flags byte, data []byte) error {
+	frame := make([]byte, 5+len(data))
+	frame[0] = flags
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	copy(frame[5:], data)
+
+	_ = t.rc.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := t.w.Write(frame); err != nil {
+		return err
+	}
+	return t.rc.Flush()
+}
+
+// ping does nothing: HTTP/2 keeps the connection alive with its own PING
+// frames, and heartbeat messages keep proxies from timing out the stream.
+func (t *streamTransport) ping() error { return nil }
+
+// writeClose ends the stream with its status: in a trailer frame for the
+// grpc-web content type, as HTTP trailers otherwise.
+func (t *streamTransport) writeClose(code string) {
+	status := strconv.Itoa(grpcStatusFor(code))
+	if t.web {
+		_ = t.writeFrame(frameTrailer, []byte("grpc-status: "+status+"\r\ngrpc-message: "+code+"\r\n"))
+		return
+	}
+	t.w.Header().Set("Grpc-Status", status)
+	t.w.Header().Set("Grpc-Message", code)
+	_ = t.rc.Flush()
+}
+
+// close ends the stream. A read in progress is cut off, so that the handler
+// can wait for its reader before returning.
+func (t *streamTransport) close() {
+	t.closeOnce.Do(func() {
+		close(t.done)
+		_ = t.rc.SetReadDeadline(time.Now())
+	})
+}
+
+// writeStreamStatus rejects a stream before it starts with a response that
+// only carries the status.
+func writeStreamStatus(w http.ResponseWriter, contentType string, status int, message string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Grpc-Status", strconv.Itoa(status))
+	w.Header().Set("Grpc-Message", message)
+	w.WriteHeader(http.StatusOK)
+}
+
+// ServeStream handles bidirectional realtime streams over HTTP/2: a
+// length-prefixed stream speaking the WebSocket protocol, one message per
+// frame, for clients whose network breaks WebSockets.
+func ServeStream(hub *Hub, qs engine.Service, auth identity.AuthN, cfg Config, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	contentType := r.Header.Get("Content-Type")
+	c, web, ok := streamCodec(contentType)
+	if !ok {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Expose-Headers", "Grpc-Status, Grpc-Message")
+		w.Header().Add("Vary", "Origin")
+	}
+	if err := checkAllowedOrigin(origin, r.Host, cfg, hasCredentials(r)); err != nil {
+		writeStreamStatus(w, contentType, grpcPermissionDenied, err.Error())
+		return
+	}
+
+	// HTTP/1.1 clients can only stream both ways if the server reads the
+	// request while responding. HTTP/2 streams always can.
+	_ = http.NewResponseController(w).EnableFullDuplex()
+
+	tenant, allowAll := tenantFromContext(ctx)
+	client := &Client{
+		hub:             hub,
+		queryService:    qs,
+		auth:            auth,
+		cfg:             cfg,
+		conn:            newStreamTransport(ctx, w, r.Body, c, web),
+		send:            make(chan BaseMessage, cfg.sendQueueSize()),
+		subscriptions:   make(map[string]Subscription),
+		tenant:          tenant,
+		authenticated:   !cfg.EnableAuth || tenant != "",
+		allowAllTenants: allowAll || !cfg.EnableAuth,
+		principal:       principalFromContext(ctx, tenant),
+		rate:            newRateLimiter(cfg.MessageRate, cfg.MessageBurst),
+		closed:          make(chan struct{}),
+	}
+	if hub.overflow == OverflowCoalesce {
+		client.pending = newCoalesceQueue(cfg.sendQueueSize())
+	}
+
+	if !hub.limits.acquire(client, newConnOwner(client.tenant, client.principal)) {
+		writeStreamStatus(w, contentType, grpcResourceExhausted, codeConnectionLimit)
+		return
+	}
+	if !hub.sessions.track(client, claimsFromContext(ctx)) {
+		hub.limits.release(client)
+		writeStreamStatus(w, contentType, grpcUnauthenticated, codeTokenRevoked)
+		return
+	}
+	if !client.hub.Register(client) {
+		hub.limits.release(client)
+		hub.sessions.removeClient(client)
+		writeStreamStatus(w, contentType, grpcUnavailable, "shutting down")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if !web {
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = http.NewResponseController(w).Flush()
+	log.Printf("[Info][Stream] stream established proto=%s content-type=%s", r.Proto, contentType)
+
+	// The response must be written before the handler returns, so the
+	// handler delivers the send queue and a goroutine reads. The request body
+	// must not be read after the handler returns, so it waits for the reader,
+	// which closing the transport cuts off.
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		client.readPump()
+	}()
+	client.writePump()
+	<-readDone
+	log.Println("[Info][Stream] stream closed")
+}
@@ -0,0 +1,217 @@
+package realtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFrame(t *testing.T, w io.Writer, msg BaseMessage) {
+	t.Helper()
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	_, err = w.Write(append(header, data...))
+	require.NoError(t, err)
+}
+
+func readFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	t.Helper()
+	header := make([]byte, 5)
+	_, err := io.ReadFull(r, header)
+	require.NoError(t, err)
+	data := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	_, err = io.ReadFull(r, data)
+	require.NoError(t, err)
+	return header[0], data
+}
+
+// readStreamMessage skips heartbeats and returns the next message.
+func readStreamMessage(t *testing.T, r io.Reader, typ string) BaseMessage {
+	t.Helper()
+	for {
+		_, data := readFrame(t, r)
+		var msg BaseMessage
+		require.NoError(t, json.Unmarshal(data, &msg))
+		if msg.Type == TypeHeartbeat {
+			continue
+		}
+		require.Equal(t, typ, msg.Type, "payload: %s", msg.Payload)
+		return msg
+	}
+}
+
+func TestStreamCodec(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		codec       codec
+		web         bool
+		ok          bool
+	}{
+		{"application/grpc+json", jsonCodec{}, false, true},
+		{"application/grpc-web+msgpack", msgpackCodec{}, true, true},
+		{"application/grpc-web+cbor; charset=binary", cborCodec{}, true, true},
+		{"application/grpc", nil, false, false},
+		{"application/grpc-web+proto", nil, false, false},
+		{"application/json", nil, false, false},
+	} {
+		c, web, ok := streamCodec(tc.contentType)
+		assert.Equal(t, tc.ok, ok, tc.contentType)
+		assert.Equal(t, tc.web, web, tc.contentType)
+		assert.Equal(t, tc.codec, c, tc.contentType)
+	}
+}
+
+func TestServeStream_Bidirectional(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeStream(hub, &MockQueryService{}, nil, Config{}, w, r)
+	}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+
+	body, requests := io.Pipe()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL+StreamMethod, body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc+json")
+
+	// Responses start before the request ends.
+	go writeFrame(t, requests, BaseMessage{ID: "s1", Type: TypeSubscribe, Payload: mustMarshal(SubscribePayload{Query: model.Query{Collection: "users"}, IncludeData: true})})
+	resp, err := s.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "application/grpc+json", resp.Header.Get("Content-Type"))
+	responses := bufio.NewReader(resp.Body)
+	readStreamMessage(t, responses, TypeSubscribeAck)
+
+	broadcast := func(id string) {
+		hub.Broadcast(storage.Event{
+			Id:       "default:" + id,
+			Type:     storage.EventCreate,
+			Document: &storage.Document{Collection: "users", Fullpath: "users/" + id, Data: map[string]interface{}{"name": id}},
+		})
+	}
+	broadcast("a")
+	var payload EventPayload
+	require.NoError(t, json.Unmarshal(readStreamMessage(t, responses, TypeEvent).Payload, &payload))
+	assert.Equal(t, "s1", payload.SubID)
+	assert.Equal(t, "a", payload.Delta.Document["name"])
+
+	// Events keep flowing after the client half-closes.
+	require.NoError(t, requests.Close())
+	broadcast("b")
+	require.NoError(t, json.Unmarshal(readStreamMessage(t, responses, TypeEvent).Payload, &payload))
+	assert.Equal(t, "b", payload.Delta.Document["name"])
+}
+
+// lateBody records reads that end after the handler has returned.
+type lateBody struct {
+	io.ReadCloser
+	returned atomic.Bool
+	late     atomic.Bool
+}
+
+func (b *lateBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.returned.Load() {
+		b.late.Store(true)
+	}
+	return n, err
+}
+
+func TestServeStream_WaitsForReader(t *testing.T) {
+	hub := NewHub()
+	hubCtx, stopHub := context.WithCancel(context.Background())
+	defer stopHub()
+	go hub.Run(hubCtx)
+
+	var body *lateBody
+	handled := make(chan struct{})
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = &lateBody{ReadCloser: r.Body}
+		r.Body = body
+		ServeStream(hub, &MockQueryService{}, nil, Config{}, w, r)
+		body.returned.Store(true)
+		close(handled)
+	}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reqBody, requests := io.Pipe()
+	defer requests.Close()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL+"/realtime/stream", reqBody)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc+json")
+
+	go writeFrame(t, requests, BaseMessage{ID: "s1", Type: TypeSubscribe, Payload: mustMarshal(SubscribePayload{Query: model.Query{Collection: "users"}})})
+	resp, err := s.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	readStreamMessage(t, bufio.NewReader(resp.Body), TypeSubscribeAck)
+
+	// The server ends the stream while the client keeps its side open.
+	stopHub()
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not return")
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, body.late.Load(), "request body read after the handler returned")
+}
+
+func TestServeStream_Rejects(t *testing.T) {
+	hub := NewHub()
+	hub.limits = newConnLimits(Config{MaxConnectionsPerTenant: 1})
+	hub.limits.acquire(&Client{}, connOwner{tenant: "default"})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/realtime/stream", nil)
+	r.Header.Set("Content-Type", "application/grpc+proto")
+	ServeStream(hub, nil, nil, Config{}, w, r)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	w = httptest.NewRecorder()
+	r.Header.Set("Content-Type", "application/grpc-web+json")
+	ServeStream(hub, nil, nil, Config{}, w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "8", w.Header().Get("Grpc-Status"))
+	assert.Equal(t, codeConnectionLimit, w.Header().Get("Grpc-Message"))
+}
+
+func TestStreamTransport_WriteClose(t *testing.T) {
+	w := httptest.NewRecorder()
+	web := newStreamTransport(context.Background(), w, nil, jsonCodec{}, true)
+	web.writeClose(codeTokenRevoked)
+	flags, data := readFrame(t, w.Body)
+	assert.Equal(t, byte(frameTrailer), flags)
+	assert.Equal(t, "grpc-status: 16\r\ngrpc-message: token_revoked\r\n", string(data))
+
+	w = httptest.NewRecorder()
+	native := newStreamTransport(context.Background(), w, nil, jsonCodec{}, false)
+	native.writeClose("")
+	assert.Equal(t, "0", w.Header().Get("Grpc-Status"))
+	assert.Zero(t, w.Body.Len())
+}
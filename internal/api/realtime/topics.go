@@ -0,0 +1,315 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codetrek/syntrix/pkg/model"
+)
+
+// maxTopicLength bounds topic names.
+const maxTopicLength = 256
+
+// topicWire is a topic message as published on the bus.
+type topicWire struct {
+	Node      string          `json:"node"`
+	Tenant    string          `json:"tenant"`
+	Topic     string          `json:"topic"`
+	ID        string          `json:"id"`
+	UserID    string          `json:"userId,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// topicRouter delivers ephemeral topic messages to subscribed clients.
+// Messages are never stored: a client only receives what is published while
+// it is subscribed.
+type topicRouter struct {
+	mu       sync.Mutex
+	node     string
+	seq      uint64
+	overflow OverflowPolicy
+	bus      Bus
+
+	// subs maps tenant and topic to the subscription IDs of each client;
+	// clients maps each client's subscription IDs back to the topic key.
+	subs    map[string]map[*Client]map[string]struct{}
+	clients map[*Client]map[string]string
+}
+
+func newTopicRouter(node string) *topicRouter {
+	return &topicRouter{
+		node:     node,
+		overflow: OverflowDrop,
+		subs:     make(map[string]map[*Client]map[string]struct{}),
+		clients:  make(map[*Client]map[string]string),
+	}
+}
+
+func topicKey(tenant, topic string) string {
+	return tenant + "\x00" + topic
+}
+
+// validTopic reports whether topic can be used as a single rules path
+// segment.
+func validTopic(topic string) bool {
+	return topic != "" && len(topic) <= maxTopicLength && !strings.Contains(topic, "/")
+}
+
+// subscribe delivers messages of topic to c under subID, replacing whatever
+// subID was subscribed to before.
+func (r *topicRouter) subscribe(c *Client, subID, tenant, topic string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.unsubscribeLocked(c, subID)
+	key := topicKey(tenant, topic)
+	clients, ok := r.subs[key]
+	if !ok {
+		clients = make(map[*Client]map[string]struct{})
+		r.subs[key] = clients
+	}
+	ids, ok := clients[c]
+	if !ok {
+		ids = make(map[string]struct{})
+		clients[c] = ids
+	}
+	ids[subID] = struct{}{}
+
+	byID, ok := r.clients[c]
+	if !ok {
+		byID = make(map[string]string)
+		r.clients[c] = byID
+	}
+	byID[subID] = key
+}
+
+// unsubscribe removes the topic subscription subID of c and reports whether
+// there was one.
+func (r *topicRouter) unsubscribe(c *Client, subID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.unsubscribeLocked(c, subID)
+}
+
+func (r *topicRouter) unsubscribeLocked(c *Client, subID string) bool {
+	byID, ok := r.clients[c]
+	if !ok {
+		return false
+	}
+	key, ok := byID[subID]
+	if !ok {
+		return false
+	}
+	delete(byID, subID)
+	if len(byID) == 0 {
+		delete(r.clients, c)
+	}
+	if clients, ok := r.subs[key]; ok {
+		delete(clients[c], subID)
+		if len(clients[c]) == 0 {
+			delete(clients, c)
+		}
+		if len(clients) == 0 {
+			delete(r.subs, key)
+		}
+	}
+	return true
+}
+
+// count returns the number of topic subscriptions of c.
+func (r *topicRouter) count(c *Client) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clients[c])
+}
+
+// has reports whether c has a topic subscription subID.
+func (r *topicRouter) has(c *Client, subID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.clients[c][subID]
+	return ok
+}
+
+// removeClient drops every topic subscription of c. It must be called before
+// c's send channel is closed.
+func (r *topicRouter) removeClient(c *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for subID := range r.clients[c] {
+		r.unsubscribeLocked(c, subID)
+	}
+}
+
+// publish delivers data to the local subscribers of topic, except the
+// publishing client, and to the other gateways.
+func (r *topicRouter) publish(from *Client, tenant, topic, userID string, data json.RawMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	w := topicWire{
+		Node:      r.node,
+		Tenant:    tenant,
+		Topic:     topic,
+		ID:        r.node + "-" + strconv.FormatUint(r.seq, 10),
+		UserID:    userID,
+		Data:      data,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	r.deliverLocked(w, from)
+
+	if r.bus != nil {
+		if err := r.bus.Publish(mustMarshal(w)); err != nil {
+			log.Printf("[Warning][Topics] Failed to publish topic=%s: %v", topic, err)
+		}
+	}
+}
+
+// handleRemote delivers a message published on another gateway.
+func (r *topicRouter) handleRemote(data []byte) {
+	var w topicWire
+	if err := json.Unmarshal(data, &w); err != nil {
+		log.Printf("[Warning][Topics] Invalid bus message: %v", err)
+		return
+	}
+	if w.Node == r.node {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliverLocked(w, nil)
+}
+
+func (r *topicRouter) deliverLocked(w topicWire, skip *Client) {
+	for c, ids := range r.subs[topicKey(w.Tenant, w.Topic)] {
+		if c == skip {
+			continue
+		}
+		for subID := range ids {
+			msg := BaseMessage{Type: TypeMessage, Payload: mustMarshal(TopicMessagePayload{
+				SubID:     subID,
+				Topic:     w.Topic,
+				ID:        w.ID,
+				UserID:    w.UserID,
+				Data:      w.Data,
+				Timestamp: w.Timestamp,
+			})}
+			// Ephemeral messages are dropped rather than queued behind a
+			// slow client, so every message has its own key.
+			c.enqueue(msg, subID+"\x00"+w.ID, r.overflow)
+		}
+	}
+}
+
+// run receives messages of other gateways until ctx is cancelled.
+func (r *topicRouter) run(ctx context.Context) {
+	if r.bus == nil {
+		return
+	}
+	unsubscribe, err := r.bus.Subscribe(r.handleRemote)
+	if err != nil {
+		log.Printf("[Warning][Topics] Failed to subscribe to topic bus, topics stay local: %v", err)
+		return
+	}
+	<-ctx.Done()
+	unsubscribe()
+}
+
+// handleTopicSubscribe serves a subscribe message for an ephemeral topic.
+func (c *Client) handleTopicSubscribe(msg BaseMessage, topic string) {
+	if !validTopic(topic) {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "invalid_topic", Message: "invalid topic name"})}
+		return
+	}
+	if !c.authorizeTopic(topic, "get", nil) {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "permission_denied", Message: "subscribe not allowed on topic"})}
+		return
+	}
+	if !c.allowSubscription(msg.ID) {
+		return
+	}
+
+	c.mu.Lock()
+	if old, ok := c.subscriptions[msg.ID]; ok {
+		delete(c.subscriptions, msg.ID)
+		c.subIndex().remove(c, old)
+	}
+	c.mu.Unlock()
+
+	c.hub.topics.subscribe(c, msg.ID, c.topicTenant(), topic)
+	log.Printf("[Info][WS] Subscribed to topic=%s id=%s", topic, msg.ID)
+	c.send <- BaseMessage{ID: msg.ID, Type: TypeSubscribeAck}
+}
+
+// handlePublish serves a publish message.
+func (c *Client) handlePublish(msg BaseMessage) {
+	if !c.authenticated {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "unauthorized", Message: "auth required"})}
+		return
+	}
+	var payload PublishPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil || !validTopic(payload.Topic) {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "invalid_topic", Message: "invalid topic name"})}
+		return
+	}
+	if !c.authorizeTopic(payload.Topic, "create", payload.Data) {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "permission_denied", Message: "publish not allowed on topic"})}
+		return
+	}
+
+	c.mu.Lock()
+	userID, _ := c.principal.auth.UID.(string)
+	c.mu.Unlock()
+
+	c.hub.topics.publish(c, c.topicTenant(), payload.Topic, userID, payload.Data)
+	if msg.ID != "" {
+		c.send <- BaseMessage{ID: msg.ID, Type: TypePublishAck}
+	}
+}
+
+// topicTenant returns the tenant that scopes the client's topics.
+func (c *Client) topicTenant() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return tenantOrDefault(c.tenant)
+}
+
+// tenantOrDefault scopes presence channels and topics of clients without a
+// tenant (auth disabled) to the default tenant.
+func tenantOrDefault(tenant string) string {
+	if tenant == "" {
+		return model.DefaultTenantID
+	}
+	return tenant
+}
+
+// authorizeTopic checks action on the rules path of topic. Clients that
+// bypass tenant isolation are not subject to rules.
+func (c *Client) authorizeTopic(topic, action string, data json.RawMessage) bool {
+	if c.hub == nil || c.hub.authorizer == nil {
+		return true
+	}
+	c.mu.Lock()
+	bypass, p := c.allowAllTenants, c.principal
+	c.mu.Unlock()
+	if bypass {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), authzEvalTimeout)
+	defer cancel()
+	allowed, err := c.hub.authorizer.canTopic(ctx, p, topic, action, data)
+	if err != nil {
+		log.Printf("[Warning][WS] authz %s evaluation failed topic=%s: %v", action, topic, err)
+		return false
+	}
+	return allowed
+}
@@ -0,0 +1,186 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// topicAuthz allows reading /topics/lobby and publishing to it only with a
+// "text" field.
+type topicAuthz struct{}
+
+func (topicAuthz) Evaluate(ctx context.Context, path string, action string, req identity.AuthzRequest, res *identity.Resource) (bool, error) {
+	if path != "topics/lobby" {
+		return false, nil
+	}
+	switch action {
+	case "get":
+		return true, nil
+	case "create":
+		return res != nil && res.Data["text"] != nil, nil
+	}
+	return false, nil
+}
+
+func (topicAuthz) GetRules() *identity.RuleSet      { return nil }
+func (topicAuthz) UpdateRules(content []byte) error { return nil }
+func (topicAuthz) LoadRules(path string) error      { return nil }
+
+func topicSubscribe(c *Client, id, topic string) {
+	c.handleMessage(BaseMessage{ID: id, Type: TypeSubscribe, Payload: mustMarshal(SubscribePayload{Topic: topic})})
+}
+
+func topicPublish(c *Client, id, topic string, data string) {
+	c.handleMessage(BaseMessage{ID: id, Type: TypePublish, Payload: mustMarshal(PublishPayload{Topic: topic, Data: json.RawMessage(data)})})
+}
+
+func topicMessage(t *testing.T, c *Client) TopicMessagePayload {
+	t.Helper()
+	var p TopicMessagePayload
+	require.NoError(t, json.Unmarshal(nextMessage(t, c, TypeMessage).Payload, &p))
+	return p
+}
+
+func TestTopics_PublishSubscribe(t *testing.T) {
+	h := NewHub()
+	alice := newPresenceClient(h, "t1")
+	alice.principal = newPrincipal("t1", nil, "alice", "", nil)
+	bob := newPresenceClient(h, "t1")
+	other := newPresenceClient(h, "t2")
+
+	for _, c := range []*Client{alice, bob, other} {
+		topicSubscribe(c, "typing", "room-1")
+		nextMessage(t, c, TypeSubscribeAck)
+	}
+
+	topicPublish(alice, "p1", "room-1", `{"typing":true}`)
+	assert.Equal(t, "p1", nextMessage(t, alice, TypePublishAck).ID)
+
+	msg := topicMessage(t, bob)
+	assert.Equal(t, "typing", msg.SubID)
+	assert.Equal(t, "room-1", msg.Topic)
+	assert.Equal(t, "alice", msg.UserID)
+	assert.JSONEq(t, `{"typing":true}`, string(msg.Data))
+
+	// No echo to the publisher and no delivery across tenants.
+	assertNoMessage(t, alice)
+	assertNoMessage(t, other)
+
+	// Publishing without a request ID is not acknowledged.
+	topicPublish(bob, "", "room-1", `1`)
+	assertNoMessage(t, bob)
+	assert.JSONEq(t, `1`, string(topicMessage(t, alice).Data))
+
+	bob.handleMessage(BaseMessage{ID: "u1", Type: TypeUnsubscribe, Payload: mustMarshal(UnsubscribePayload{ID: "typing"})})
+	nextMessage(t, bob, TypeUnsubscribeAck)
+	topicPublish(alice, "", "room-1", `2`)
+	assertNoMessage(t, bob)
+}
+
+func TestTopics_QuerySubscriptionReplacesTopic(t *testing.T) {
+	h := NewHub()
+	c := newPresenceClient(h, "t1")
+	pub := newPresenceClient(h, "t1")
+
+	topicSubscribe(c, "s1", "room-1")
+	nextMessage(t, c, TypeSubscribeAck)
+
+	c.handleMessage(BaseMessage{ID: "s1", Type: TypeSubscribe, Payload: mustMarshal(SubscribePayload{Query: model.Query{Collection: "users"}})})
+	nextMessage(t, c, TypeSubscribeAck)
+
+	topicPublish(pub, "", "room-1", `{}`)
+	assertNoMessage(t, c)
+	assert.Contains(t, c.subscriptions, "s1")
+}
+
+func TestTopics_InvalidTopic(t *testing.T) {
+	h := NewHub()
+	c := newPresenceClient(h, "t1")
+
+	topicSubscribe(c, "s1", "a/b")
+	assert.Contains(t, string(nextMessage(t, c, TypeError).Payload), "invalid_topic")
+
+	topicPublish(c, "p1", "", `{}`)
+	assert.Contains(t, string(nextMessage(t, c, TypeError).Payload), "invalid_topic")
+
+	c.authenticated = false
+	topicPublish(c, "p2", "room", `{}`)
+	assert.Contains(t, string(nextMessage(t, c, TypeError).Payload), "unauthorized")
+}
+
+func TestTopics_Authorization(t *testing.T) {
+	h := NewHub()
+	h.authorizer = newAuthorizer(topicAuthz{}, Config{})
+	c := newPresenceClient(h, "t1")
+	c.principal = newPrincipal("t1", nil, "u1", "", nil)
+
+	topicSubscribe(c, "s1", "secret")
+	assert.Contains(t, string(nextMessage(t, c, TypeError).Payload), "permission_denied")
+
+	topicSubscribe(c, "s2", "lobby")
+	nextMessage(t, c, TypeSubscribeAck)
+
+	topicPublish(c, "p1", "lobby", `{"image":"x"}`)
+	assert.Contains(t, string(nextMessage(t, c, TypeError).Payload), "permission_denied")
+
+	topicPublish(c, "p2", "lobby", `{"text":"hi"}`)
+	nextMessage(t, c, TypePublishAck)
+
+	// System connections bypass rules.
+	c.allowAllTenants = true
+	topicPublish(c, "p3", "secret", `{}`)
+	nextMessage(t, c, TypePublishAck)
+}
+
+func TestTopics_SharedAcrossNodes(t *testing.T) {
+	bus := &memoryBus{}
+	h1, h2 := NewHub(), NewHub()
+	for _, h := range []*Hub{h1, h2} {
+		h.topics.bus = bus
+		_, _ = bus.Subscribe(h.topics.handleRemote)
+	}
+
+	pub := newPresenceClient(h1, "t1")
+	local := newPresenceClient(h1, "t1")
+	remote := newPresenceClient(h2, "t1")
+	for _, c := range []*Client{local, remote} {
+		topicSubscribe(c, "s1", "cursors")
+		nextMessage(t, c, TypeSubscribeAck)
+	}
+
+	topicPublish(pub, "", "cursors", `{"x":1}`)
+	first := topicMessage(t, local)
+	second := topicMessage(t, remote)
+	assert.Equal(t, first.ID, second.ID)
+	assertNoMessage(t, local)
+
+	h2.topics.removeClient(remote)
+	topicPublish(pub, "", "cursors", `{"x":2}`)
+	assertNoMessage(t, remote)
+	assert.Empty(t, h2.topics.subs)
+}
+
+func TestServeSSE_Topic(t *testing.T) {
+	hub := NewHub()
+	hub.authorizer = newAuthorizer(topicAuthz{}, Config{})
+	cfg := Config{EnableAuth: true}
+	ctx := context.WithValue(context.Background(), identity.ContextKeyTenant, "default")
+
+	req := httptest.NewRequest("GET", "/realtime/sse?topic=a/b", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	ServeSSE(hub, &MockQueryService{}, nil, cfg, rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	req = httptest.NewRequest("GET", "/realtime/sse?topic=secret", nil).WithContext(ctx)
+	rr = httptest.NewRecorder()
+	ServeSSE(hub, &MockQueryService{}, nil, cfg, rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,132 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// errMalformedMessage wraps inbound messages that cannot be decoded. The
+// connection stays open and the message is skipped.
+var errMalformedMessage = errors.New("malformed message")
+
+// transport carries the messages of one realtime connection: a WebSocket, an
+// SSE response or an HTTP/2 stream. Like a WebSocket it supports one reader
+// and one writer at a time; read is called from readPump, write, ping and
+// writeClose from the goroutine delivering the send queue.
+type transport interface {
+	// read returns the next inbound message. Transports without an inbound
+	// direction block until the connection ends.
+	read() (BaseMessage, error)
+
+	// write sends msg to the peer.
+	write(msg BaseMessage) error
+
+	// ping keeps an idle connection open below the message protocol.
+	ping() error
+
+	// writeClose tells the peer that the connection ends. code is the error
+	// code the server closes with, empty when the send queue was closed.
+	writeClose(code string)
+
+	// close releases the connection. It may be called from any goroutine and
+	// more than once.
+	close()
+}
+
+// wsTransport is a WebSocket in the encoding negotiated for it.
+type wsTransport struct {
+	conn  *websocket.Conn
+	codec codec
+}
+
+func newWSTransport(conn *websocket.Conn, c codec) *wsTransport {
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error { conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
+	return &wsTransport{conn: conn, codec: c}
+}
+
+func (t *wsTransport) read() (BaseMessage, error) {
+	_, data, err := t.conn.ReadMessage()
+	if err != nil {
+		return BaseMessage{}, err
+	}
+	msg, err := t.codec.decode(data)
+	if err != nil {
+		return BaseMessage{}, fmt.Errorf("%w: %v", errMalformedMessage, err)
+	}
+	return msg, nil
+}
+
+// write writes msg as one frame in the connection's encoding.
+func (t *wsTransport) write(msg BaseMessage) error {
+	data, err := t.codec.encode(msg)
+	if err != nil {
+		return fmt.Errorf("encoding message type=%s: %w", msg.Type, err)
+	}
+	t.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return t.conn.WriteMessage(t.codec.frameType(), data)
+}
+
+// ping sends a WebSocket ping frame; browsers answer it with a pong.
+func (t *wsTransport) ping() error {
+	t.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return t.conn.WriteMessage(websocket.PingMessage, nil)
+}
+
+func (t *wsTransport) writeClose(code string) {
+	t.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if code == "" {
+		_ = t.conn.WriteMessage(websocket.CloseMessage, []byte{})
+		return
+	}
+	_ = t.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, code))
+}
+
+func (t *wsTransport) close() {
+	t.conn.Close()
+}
+
+// sseTransport is a Server-Sent Events response. It has no inbound direction;
+// clients subscribe through query parameters.
+type sseTransport struct {
+	ctx     context.Context
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (t *sseTransport) read() (BaseMessage, error) {
+	<-t.ctx.Done()
+	return BaseMessage{}, t.ctx.Err()
+}
+
+func (t *sseTransport) write(msg BaseMessage) error {
+	if err := writeSSE(t.w, msg); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+// ping writes an SSE comment, which EventSource ignores.
+func (t *sseTransport) ping() error {
+	if _, err := fmt.Fprintf(t.w, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+// writeClose flushes what has been written; the response ends when the
+// handler returns.
+func (t *sseTransport) writeClose(code string) {
+	t.flusher.Flush()
+}
+
+func (t *sseTransport) close() {}
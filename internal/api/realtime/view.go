@@ -0,0 +1,352 @@
+package realtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+)
+
+// Change types reported for query views.
+const (
+	ChangeAdded    = "added"
+	ChangeModified = "modified"
+	ChangeRemoved  = "removed"
+)
+
+// queryView tracks the result window of an ordered, optionally limited query
+// so that documents entering, moving within and leaving the window can be
+// reported with their positions. It is guarded by the owning Client's mu.
+type queryView struct {
+	orderBy []model.Order
+	limit   int // 0 means unbounded
+	docs    []viewEntry
+}
+
+type viewEntry struct {
+	key     string // storage document ID (tenant:hash)
+	path    string
+	version int64
+	doc     model.Document
+}
+
+type viewChange struct {
+	kind     string
+	entry    viewEntry
+	oldIndex int
+	newIndex int
+}
+
+func newQueryView(q model.Query) *queryView {
+	return &queryView{orderBy: q.OrderBy, limit: q.Limit}
+}
+
+func (v *queryView) indexOf(key string) int {
+	for i := range v.docs {
+		if v.docs[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertPos returns the index a document takes in the window: after every
+// document that does not sort after it, so ties keep arrival order.
+func (v *queryView) insertPos(doc model.Document) int {
+	return sort.Search(len(v.docs), func(i int) bool {
+		return v.compare(doc, v.docs[i].doc) < 0
+	})
+}
+
+func (v *queryView) insertAt(i int, e viewEntry) {
+	v.docs = append(v.docs, viewEntry{})
+	copy(v.docs[i+1:], v.docs[i:])
+	v.docs[i] = e
+}
+
+func (v *queryView) removeAt(i int) viewEntry {
+	e := v.docs[i]
+	v.docs = append(v.docs[:i], v.docs[i+1:]...)
+	return e
+}
+
+func (v *queryView) full() bool {
+	return v.limit > 0 && len(v.docs) >= v.limit
+}
+
+// apply updates the window for one document. include reports whether the
+// document currently matches the query and may be read by the client. The
+// returned refill flag is set when documents outside the window may now
+// belong in it, which only a new query can tell.
+func (v *queryView) apply(e viewEntry, include bool) (changes []viewChange, refill bool) {
+	old := v.indexOf(e.key)
+	wasFull := v.full()
+
+	if !include {
+		if old == -1 {
+			return nil, false
+		}
+		removed := v.removeAt(old)
+		return []viewChange{{kind: ChangeRemoved, entry: removed, oldIndex: old, newIndex: -1}}, wasFull
+	}
+
+	if old == -1 {
+		pos := v.insertPos(e.doc)
+		if v.limit > 0 && pos >= v.limit {
+			return nil, false
+		}
+		v.insertAt(pos, e)
+		changes = append(changes, viewChange{kind: ChangeAdded, entry: e, oldIndex: -1, newIndex: pos})
+		if v.limit > 0 && len(v.docs) > v.limit {
+			last := len(v.docs) - 1
+			evicted := v.removeAt(last)
+			changes = append(changes, viewChange{kind: ChangeRemoved, entry: evicted, oldIndex: last, newIndex: -1})
+		}
+		return changes, false
+	}
+
+	prev := v.docs[old]
+	if e.version >= 0 && prev.version >= 0 && e.version <= prev.version {
+		// Stale or duplicate delivery.
+		return nil, false
+	}
+	v.removeAt(old)
+	pos := v.insertPos(e.doc)
+	v.insertAt(pos, e)
+	refill = wasFull && pos == len(v.docs)-1 && v.compare(e.doc, prev.doc) > 0
+	return []viewChange{{kind: ChangeModified, entry: e, oldIndex: old, newIndex: pos}}, refill
+}
+
+// reset replaces the window with a freshly queried one and returns the
+// changes that turn the old window into the new one: removals first, then
+// additions and moves in result order.
+func (v *queryView) reset(entries []viewEntry) []viewChange {
+	next := make(map[string]viewEntry, len(entries))
+	for _, e := range entries {
+		next[e.key] = e
+	}
+
+	var changes []viewChange
+	for i := len(v.docs) - 1; i >= 0; i-- {
+		if _, ok := next[v.docs[i].key]; !ok {
+			removed := v.removeAt(i)
+			changes = append(changes, viewChange{kind: ChangeRemoved, entry: removed, oldIndex: i, newIndex: -1})
+		}
+	}
+	for i, e := range entries {
+		old := v.indexOf(e.key)
+		switch {
+		case old == -1:
+			v.insertAt(i, e)
+			changes = append(changes, viewChange{kind: ChangeAdded, entry: e, oldIndex: -1, newIndex: i})
+		case old != i || v.docs[old].version != e.version:
+			v.removeAt(old)
+			v.insertAt(i, e)
+			changes = append(changes, viewChange{kind: ChangeModified, entry: e, oldIndex: old, newIndex: i})
+		}
+	}
+	return changes
+}
+
+// compare orders two flattened documents by the query's orderBy fields.
+func (v *queryView) compare(a, b model.Document) int {
+	for _, o := range v.orderBy {
+		c := compareValues(fieldValue(a, o.Field), fieldValue(b, o.Field))
+		if o.Direction == "desc" {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func fieldValue(doc model.Document, field string) interface{} {
+	var cur interface{} = map[string]interface{}(doc)
+	for _, part := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// compareValues orders values as null < bool < number < string < time, with
+// anything else compared by its string form.
+func compareValues(a, b interface{}) int {
+	ra, rb := valueRank(a), valueRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+	switch ra {
+	case 0:
+		return 0
+	case 1:
+		ab, bb := a.(bool), b.(bool)
+		switch {
+		case ab == bb:
+			return 0
+		case !ab:
+			return -1
+		}
+		return 1
+	case 2:
+		af, bf := toFloat(a), toFloat(b)
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	case 3:
+		return strings.Compare(a.(string), b.(string))
+	case 4:
+		return a.(time.Time).Compare(b.(time.Time))
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func valueRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int, int32, int64, float32, float64:
+		return 2
+	case string:
+		return 3
+	case time.Time:
+		return 4
+	}
+	return 5
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// viewEntryFromEvent builds the window entry for the document an event
+// refers to.
+func viewEntryFromEvent(evt storage.Event) viewEntry {
+	e := viewEntry{key: evt.Id, path: eventPath(evt), version: eventVersion(evt)}
+	if evt.Document != nil {
+		e.doc = flattenDocument(evt.Document)
+	}
+	return e
+}
+
+// viewMessage builds the change message for a view subscription.
+func viewMessage(subID string, sub Subscription, ch viewChange, timestamp int64, token string) BaseMessage {
+	payload := ChangePayload{
+		SubID:       subID,
+		Type:        ch.kind,
+		ID:          ch.entry.doc.GetID(),
+		OldIndex:    ch.oldIndex,
+		NewIndex:    ch.newIndex,
+		Timestamp:   timestamp,
+		ResumeToken: token,
+	}
+	if payload.ID == "" {
+		payload.ID = extractIDFromFullpath(ch.entry.path)
+	}
+	if sub.IncludeData && ch.kind != ChangeRemoved {
+		payload.Document = ch.entry.doc
+	}
+	return BaseMessage{ID: subID, Type: TypeChange, Payload: mustMarshal(payload)}
+}
+
+// applyViewEvent feeds one event into a view subscription and returns the
+// resulting messages. readable is consulted only for documents that match.
+// Callers must hold c.mu.
+func (c *Client) applyViewEvent(subID string, sub Subscription, evt storage.Event, readable func() bool, token string) []BaseMessage {
+	entry := viewEntryFromEvent(evt)
+	if eventCollection(evt) != sub.Query.Collection && sub.view.indexOf(entry.key) == -1 {
+		return nil
+	}
+
+	include := evt.Type != storage.EventDelete && sub.matches(evt) && readable()
+	changes, refill := sub.view.apply(entry, include)
+
+	msgs := make([]BaseMessage, 0, len(changes))
+	for _, ch := range changes {
+		msgs = append(msgs, viewMessage(subID, sub, ch, evt.Timestamp, token))
+	}
+	if refill && sub.snapshot == nil {
+		// Hold back further events until the window has been re-read.
+		sub.snapshot = newSnapshotBuffer(c.cfg.SnapshotBufferSize)
+		c.subscriptions[subID] = sub
+		go c.refillView(subID)
+	}
+	return msgs
+}
+
+// refillView re-reads a view's query after documents left a full window and
+// reports the difference to the client.
+func (c *Client) refillView(subID string) {
+	c.mu.Lock()
+	sub, ok := c.subscriptions[subID]
+	c.mu.Unlock()
+	if !ok || sub.view == nil {
+		return
+	}
+
+	policy := c.overflowPolicy()
+	docs, versions, _, err := c.readSnapshot(sub.Query)
+	if err != nil {
+		c.enqueue(BaseMessage{ID: subID, Type: TypeError, Payload: mustMarshal(ErrorPayload{Code: "view_refresh_failed", Message: "failed to refresh query view"})}, "", policy)
+		c.finishSnapshot(subID, nil)
+		return
+	}
+
+	// Live events stay buffered until finishSnapshot, so the changes can be
+	// queued after the lock is released without being overtaken.
+	c.mu.Lock()
+	changes := sub.view.reset(c.viewEntries(docs))
+	c.mu.Unlock()
+
+	now := time.Now().UnixNano()
+	for _, ch := range changes {
+		c.enqueue(viewMessage(subID, sub, ch, now, ""), "", policy)
+	}
+
+	c.finishSnapshot(subID, versions)
+}
+
+// viewEntries converts snapshot documents into window entries. Callers must
+// hold c.mu.
+func (c *Client) viewEntries(docs []model.Document) []viewEntry {
+	entries := make([]viewEntry, 0, len(docs))
+	for _, doc := range docs {
+		path := doc.GetCollection() + "/" + doc.GetID()
+		entries = append(entries, viewEntry{
+			key:     storage.CalculateTenantID(c.tenant, path),
+			path:    path,
+			version: documentVersion(doc),
+			doc:     doc,
+		})
+	}
+	return entries
+}
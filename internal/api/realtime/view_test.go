@@ -0,0 +1,216 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func scoreEntry(id string, score int, version int64) viewEntry {
+	return viewEntry{
+		key:     "t1:" + id,
+		path:    "players/" + id,
+		version: version,
+		doc:     model.Document{"id": id, "collection": "players", "score": score, "version": version},
+	}
+}
+
+func changeKinds(changes []viewChange) []string {
+	var out []string
+	for _, ch := range changes {
+		out = append(out, ch.kind+":"+ch.entry.doc.GetID())
+	}
+	return out
+}
+
+func viewKeys(v *queryView) []string {
+	var out []string
+	for _, e := range v.docs {
+		out = append(out, e.doc.GetID())
+	}
+	return out
+}
+
+func TestQueryView_ApplyLimitedWindow(t *testing.T) {
+	v := newQueryView(model.Query{OrderBy: []model.Order{{Field: "score", Direction: "desc"}}, Limit: 2})
+
+	changes, refill := v.apply(scoreEntry("a", 10, 1), true)
+	assert.Equal(t, []string{"added:a"}, changeKinds(changes))
+	assert.Equal(t, 0, changes[0].newIndex)
+	assert.False(t, refill)
+
+	v.apply(scoreEntry("b", 5, 1), true)
+	assert.Equal(t, []string{"a", "b"}, viewKeys(v))
+
+	// A better score enters at the top and evicts the last entry.
+	changes, _ = v.apply(scoreEntry("c", 20, 1), true)
+	assert.Equal(t, []string{"added:c", "removed:b"}, changeKinds(changes))
+	// Indexes apply in order: after "c" was added, "b" sat at index 2.
+	assert.Equal(t, 2, changes[1].oldIndex)
+	assert.Equal(t, -1, changes[1].newIndex)
+
+	// A worse score than the window does not enter.
+	changes, _ = v.apply(scoreEntry("d", 1, 1), true)
+	assert.Empty(t, changes)
+
+	// Moving within the window reports both positions.
+	changes, refill = v.apply(scoreEntry("a", 30, 2), true)
+	require.Len(t, changes, 1)
+	assert.Equal(t, ChangeModified, changes[0].kind)
+	assert.Equal(t, 1, changes[0].oldIndex)
+	assert.Equal(t, 0, changes[0].newIndex)
+	assert.False(t, refill)
+
+	// Stale versions are ignored.
+	changes, _ = v.apply(scoreEntry("a", 0, 1), true)
+	assert.Empty(t, changes)
+
+	// Dropping to the bottom of a full window may let outside documents in.
+	changes, refill = v.apply(scoreEntry("a", 2, 3), true)
+	assert.Equal(t, []string{"modified:a"}, changeKinds(changes))
+	assert.True(t, refill)
+
+	// Leaving a full window also needs a refill.
+	changes, refill = v.apply(scoreEntry("c", 20, 2), false)
+	assert.Equal(t, []string{"removed:c"}, changeKinds(changes))
+	assert.True(t, refill)
+	assert.Equal(t, []string{"a"}, viewKeys(v))
+}
+
+func TestQueryView_Reset(t *testing.T) {
+	v := newQueryView(model.Query{OrderBy: []model.Order{{Field: "score", Direction: "desc"}}, Limit: 3})
+	v.reset([]viewEntry{scoreEntry("a", 30, 1), scoreEntry("b", 20, 1), scoreEntry("c", 10, 1)})
+
+	changes := v.reset([]viewEntry{scoreEntry("c", 40, 2), scoreEntry("a", 30, 1), scoreEntry("d", 5, 1)})
+	assert.Equal(t, []string{"removed:b", "modified:c", "added:d"}, changeKinds(changes))
+	assert.Equal(t, 1, changes[1].oldIndex)
+	assert.Equal(t, 0, changes[1].newIndex)
+	assert.Equal(t, []string{"c", "a", "d"}, viewKeys(v))
+}
+
+func TestCompareValues(t *testing.T) {
+	now := time.Now()
+	ordered := []interface{}{nil, false, true, -1, 2.5, int64(3), "a", "b", now, now.Add(time.Second)}
+	for i := 0; i < len(ordered)-1; i++ {
+		assert.Equal(t, -1, compareValues(ordered[i], ordered[i+1]), "%v < %v", ordered[i], ordered[i+1])
+		assert.Equal(t, 1, compareValues(ordered[i+1], ordered[i]))
+	}
+	assert.Equal(t, 0, compareValues(3, 3.0))
+	assert.Equal(t, "x", fieldValue(model.Document{"a": map[string]interface{}{"b": "x"}}, "a.b"))
+	assert.Nil(t, fieldValue(model.Document{"a": 1}, "a.b"))
+}
+
+func readChange(t *testing.T, c *Client) ChangePayload {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		require.Equal(t, TypeChange, msg.Type)
+		var p ChangePayload
+		require.NoError(t, json.Unmarshal(msg.Payload, &p))
+		return p
+	case <-time.After(time.Second):
+		t.Fatal("expected change message")
+	}
+	return ChangePayload{}
+}
+
+func TestClient_ViewSubscription_RemovedAndRefilled(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	query := model.Query{
+		Collection: "players",
+		Filters:    model.Filters{{Field: "active", Op: "==", Value: true}},
+		OrderBy:    []model.Order{{Field: "score", Direction: "desc"}},
+		Limit:      2,
+	}
+	doc := func(id string, score int, version int64) model.Document {
+		return model.Document{"id": id, "collection": "players", "score": score, "active": true, "version": version}
+	}
+
+	qs := new(MockQueryService)
+	qs.On("ExecuteQuery", mock.Anything, "t1", mock.Anything).
+		Return([]model.Document{doc("a", 30, 1), doc("b", 20, 1)}, nil).Once()
+	qs.On("ExecuteQuery", mock.Anything, "t1", mock.Anything).
+		Return([]model.Document{doc("b", 20, 1), doc("c", 10, 1)}, nil).Once()
+
+	c := &Client{
+		hub:           hub,
+		queryService:  qs,
+		send:          make(chan BaseMessage, 20),
+		subscriptions: make(map[string]Subscription),
+		tenant:        "t1",
+		authenticated: true,
+	}
+	require.True(t, hub.Register(c))
+
+	payload, _ := json.Marshal(SubscribePayload{Query: query, IncludeData: true, View: true})
+	c.handleMessage(BaseMessage{ID: "v1", Type: TypeSubscribe, Payload: payload})
+
+	assert.Equal(t, TypeSubscribeAck, (<-c.send).Type)
+	pages := readSnapshotPages(t, c)
+	require.Len(t, pages[0].Documents, 2)
+
+	// "a" stops matching the filter: it is removed and the window refilled.
+	hub.Broadcast(storage.Event{
+		Id:       storage.CalculateTenantID("t1", "players/a"),
+		TenantID: "t1",
+		Type:     storage.EventUpdate,
+		Document: &storage.Document{TenantID: "t1", Fullpath: "players/a", Collection: "players", Version: 2, Data: map[string]interface{}{"score": 30, "active": false}},
+	})
+
+	removed := readChange(t, c)
+	assert.Equal(t, ChangeRemoved, removed.Type)
+	assert.Equal(t, "a", removed.ID)
+	assert.Equal(t, 0, removed.OldIndex)
+	assert.Equal(t, -1, removed.NewIndex)
+
+	added := readChange(t, c)
+	assert.Equal(t, ChangeAdded, added.Type)
+	assert.Equal(t, "c", added.ID)
+	assert.Equal(t, 1, added.NewIndex)
+	assert.Equal(t, float64(10), added.Document["score"])
+
+	qs.AssertExpectations(t)
+}
+
+func TestClient_RefillView_FullQueue(t *testing.T) {
+	qs := new(MockQueryService)
+	qs.On("ExecuteQuery", mock.Anything, "t1", mock.Anything).
+		Return([]model.Document{{"id": "a", "collection": "players", "version": int64(1)}}, nil)
+
+	query := model.Query{Collection: "players", Limit: 2}
+	c := &Client{
+		queryService: qs,
+		send:         make(chan BaseMessage, 1),
+		tenant:       "t1",
+		subscriptions: map[string]Subscription{
+			"v1": {Query: query, view: newQueryView(query), snapshot: newSnapshotBuffer(0)},
+		},
+	}
+	c.send <- BaseMessage{Type: TypeEvent}
+
+	done := make(chan struct{})
+	go func() {
+		c.refillView("v1")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("refillView blocked on a full send queue")
+	}
+
+	// A view change cannot be dropped, so the client is disconnected.
+	assert.True(t, c.evicted.Load())
+	assert.Nil(t, c.subscriptions["v1"].snapshot)
+}
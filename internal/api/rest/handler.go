@@ -16,6 +16,8 @@ import (
 
 	"github.com/codetrek/syntrix/internal/engine"
 	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/codetrek/syntrix/internal/server"
+	"github.com/codetrek/syntrix/internal/trigger"
 	"github.com/codetrek/syntrix/pkg/model"
 )
 
@@ -37,9 +39,12 @@ func getParsedBody(ctx context.Context) map[string]interface{} {
 }
 
 type Handler struct {
-	engine engine.Service
-	auth   identity.AuthN
-	authz  identity.AuthZ
+	engine      engine.Service
+	auth        identity.AuthN
+	authz       identity.AuthZ
+	triggers    trigger.Store
+	deadLetters trigger.DeadLetterService
+	deliveries  trigger.DeliveryLogService
 }
 
 func NewHandler(engine engine.Service, auth identity.AuthN, authz identity.AuthZ) *Handler {
@@ -82,6 +87,7 @@ const (
 	ErrCodePreconditionFailed = "PRECONDITION_FAILED"
 	ErrCodeRequestTooLarge    = "REQUEST_TOO_LARGE"
 	ErrCodeInternalError      = "INTERNAL_ERROR"
+	ErrCodeServiceUnavailable = "SERVICE_UNAVAILABLE"
 )
 
 // writeError writes a structured JSON error response
@@ -125,6 +131,8 @@ func withRequestID(next http.HandlerFunc) http.HandlerFunc {
 		}
 		w.Header().Set("X-Request-ID", requestID)
 		ctx := context.WithValue(r.Context(), contextKeyRequestID, requestID)
+		// Also expose it to internal service clients, which forward it.
+		ctx = server.WithRequestID(ctx, requestID)
 		next(w, r.WithContext(ctx))
 	}
 }
@@ -242,6 +250,27 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 		mux.HandleFunc("GET /admin/rules", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleAdminGetRules), DefaultRequestTimeout))))
 		mux.HandleFunc("POST /admin/rules/push", withRequestID(withRecover(withTimeout(maxBodySize(h.adminOnly(h.handleAdminPushRules), LargeMaxBodySize), LongRequestTimeout))))
 		mux.HandleFunc("GET /admin/health", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleAdminHealth), DefaultRequestTimeout))))
+
+		// Trigger Management
+		mux.HandleFunc("GET /admin/triggers", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleAdminListTriggers), DefaultRequestTimeout))))
+		mux.HandleFunc("POST /admin/triggers", withRequestID(withRecover(withTimeout(maxBodySize(h.adminOnly(h.handleAdminCreateTrigger), DefaultMaxBodySize), DefaultRequestTimeout))))
+		mux.HandleFunc("GET /admin/triggers/{id}", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleAdminGetTrigger), DefaultRequestTimeout))))
+		mux.HandleFunc("PUT /admin/triggers/{id}", withRequestID(withRecover(withTimeout(maxBodySize(h.adminOnly(h.handleAdminUpdateTrigger), DefaultMaxBodySize), DefaultRequestTimeout))))
+		mux.HandleFunc("POST /admin/triggers/{id}/enable", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleAdminEnableTrigger), DefaultRequestTimeout))))
+		mux.HandleFunc("POST /admin/triggers/{id}/disable", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleAdminDisableTrigger), DefaultRequestTimeout))))
+		mux.HandleFunc("DELETE /admin/triggers/{id}", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleAdminDeleteTrigger), DefaultRequestTimeout))))
+
+		// Trigger Dead Letters
+		mux.HandleFunc("GET /admin/triggers/{id}/dead-letters", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleListDeadLetters), DefaultRequestTimeout))))
+		mux.HandleFunc("GET /admin/triggers/{id}/dead-letters/{seq}", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleGetDeadLetter), DefaultRequestTimeout))))
+		mux.HandleFunc("POST /admin/triggers/{id}/dead-letters/{seq}/replay", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleReplayDeadLetter), DefaultRequestTimeout))))
+		mux.HandleFunc("POST /admin/triggers/{id}/dead-letters/replay", withRequestID(withRecover(withTimeout(maxBodySize(h.adminOnly(h.handleReplayDeadLetters), DefaultMaxBodySize), LongRequestTimeout))))
+		mux.HandleFunc("DELETE /admin/triggers/{id}/dead-letters/{seq}", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleDeleteDeadLetter), DefaultRequestTimeout))))
+		mux.HandleFunc("DELETE /admin/triggers/{id}/dead-letters", withRequestID(withRecover(withTimeout(h.adminOnly(h.handlePurgeDeadLetters), DefaultRequestTimeout))))
+
+		// Trigger Delivery Log (the live tail runs until the client leaves)
+		mux.HandleFunc("GET /admin/triggers/{id}/deliveries", withRequestID(withRecover(withTimeout(h.adminOnly(h.handleListDeliveries), DefaultRequestTimeout))))
+		mux.HandleFunc("GET /admin/triggers/{id}/deliveries/stream", withRequestID(withRecover(h.adminOnly(h.handleTailDeliveries))))
 	}
 
 	// Health Check (no auth, minimal timeout)
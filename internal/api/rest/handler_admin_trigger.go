@@ -0,0 +1,175 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codetrek/syntrix/internal/trigger"
+	"github.com/codetrek/syntrix/pkg/model"
+)
+
+// SetTriggerStore enables the trigger management endpoints. Without it they
+// answer 503.
+func (h *Handler) SetTriggerStore(s trigger.Store) {
+	h.triggers = s
+}
+
+func (h *Handler) triggerStoreOrError(w http.ResponseWriter) bool {
+	if h.triggers == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeServiceUnavailable, "Trigger management is not available")
+		return false
+	}
+	return true
+}
+
+func writeTriggerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, trigger.ErrInvalidTrigger):
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, trigger.ErrTriggerNotFound):
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Trigger not found")
+	case errors.Is(err, trigger.ErrTriggerExists):
+		writeError(w, http.StatusConflict, ErrCodeConflict, "Trigger already exists")
+	case errors.Is(err, model.ErrPreconditionFailed):
+		writeError(w, http.StatusPreconditionFailed, ErrCodePreconditionFailed, "Trigger changed concurrently")
+	default:
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "Failed to access triggers")
+	}
+}
+
+// decodeTrigger reads a trigger definition from the request body. The tenant
+// is the caller's; a different tenant in the body is rejected.
+func decodeTrigger(w http.ResponseWriter, r *http.Request, tenant string) (*trigger.Trigger, bool) {
+	var t trigger.Trigger
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body")
+		return nil, false
+	}
+	if t.Tenant != "" && t.Tenant != tenant {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Trigger tenant does not match the caller's tenant")
+		return nil, false
+	}
+	t.Tenant = tenant
+	return &t, true
+}
+
+func (h *Handler) handleAdminListTriggers(w http.ResponseWriter, r *http.Request) {
+	if !h.triggerStoreOrError(w) {
+		return
+	}
+	tenant, ok := h.tenantOrError(w, r)
+	if !ok {
+		return
+	}
+
+	triggers, err := h.triggers.List(r.Context(), tenant)
+	if err != nil {
+		writeTriggerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, triggers)
+}
+
+func (h *Handler) handleAdminGetTrigger(w http.ResponseWriter, r *http.Request) {
+	if !h.triggerStoreOrError(w) {
+		return
+	}
+	tenant, ok := h.tenantOrError(w, r)
+	if !ok {
+		return
+	}
+
+	t, err := h.triggers.Get(r.Context(), tenant, r.PathValue("id"))
+	if err != nil {
+		writeTriggerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Handler) handleAdminCreateTrigger(w http.ResponseWriter, r *http.Request) {
+	if !h.triggerStoreOrError(w) {
+		return
+	}
+	tenant, ok := h.tenantOrError(w, r)
+	if !ok {
+		return
+	}
+	t, ok := decodeTrigger(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	if err := h.triggers.Create(r.Context(), t); err != nil {
+		writeTriggerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *Handler) handleAdminUpdateTrigger(w http.ResponseWriter, r *http.Request) {
+	if !h.triggerStoreOrError(w) {
+		return
+	}
+	tenant, ok := h.tenantOrError(w, r)
+	if !ok {
+		return
+	}
+	t, ok := decodeTrigger(w, r, tenant)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	if t.ID != "" && t.ID != id {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Trigger ID does not match the path")
+		return
+	}
+	t.ID = id
+
+	if err := h.triggers.Update(r.Context(), t); err != nil {
+		writeTriggerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Handler) handleAdminEnableTrigger(w http.ResponseWriter, r *http.Request) {
+	h.setTriggerDisabled(w, r, false)
+}
+
+func (h *Handler) handleAdminDisableTrigger(w http.ResponseWriter, r *http.Request) {
+	h.setTriggerDisabled(w, r, true)
+}
+
+func (h *Handler) setTriggerDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	if !h.triggerStoreOrError(w) {
+		return
+	}
+	tenant, ok := h.tenantOrError(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.triggers.SetDisabled(r.Context(), tenant, r.PathValue("id"), disabled); err != nil {
+		writeTriggerError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleAdminDeleteTrigger(w http.ResponseWriter, r *http.Request) {
+	if !h.triggerStoreOrError(w) {
+		return
+	}
+	tenant, ok := h.tenantOrError(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.triggers.Delete(r.Context(), tenant, r.PathValue("id")); err != nil {
+		writeTriggerError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/internal/trigger"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTriggerStore struct {
+	mock.Mock
+}
+
+func (m *MockTriggerStore) List(ctx context.Context, tenant string) ([]*trigger.Trigger, error) {
+	args := m.Called(ctx, tenant)
+	triggers, _ := args.Get(0).([]*trigger.Trigger)
+	return triggers, args.Error(1)
+}
+
+func (m *MockTriggerStore) Get(ctx context.Context, tenant, id string) (*trigger.Trigger, error) {
+	args := m.Called(ctx, tenant, id)
+	t, _ := args.Get(0).(*trigger.Trigger)
+	return t, args.Error(1)
+}
+
+func (m *MockTriggerStore) Create(ctx context.Context, t *trigger.Trigger) error {
+	return m.Called(ctx, t).Error(0)
+}
+
+func (m *MockTriggerStore) Update(ctx context.Context, t *trigger.Trigger) error {
+	return m.Called(ctx, t).Error(0)
+}
+
+func (m *MockTriggerStore) SetDisabled(ctx context.Context, tenant, id string, disabled bool) error {
+	return m.Called(ctx, tenant, id, disabled).Error(0)
+}
+
+func (m *MockTriggerStore) Delete(ctx context.Context, tenant, id string) error {
+	return m.Called(ctx, tenant, id).Error(0)
+}
+
+func (m *MockTriggerStore) Watch(ctx context.Context, tenant string) (<-chan storage.Event, error) {
+	args := m.Called(ctx, tenant)
+	ch, _ := args.Get(0).(<-chan storage.Event)
+	return ch, args.Error(1)
+}
+
+func newTriggerServer() (*TestServer, *MockTriggerStore) {
+	server := createTestServer(nil, nil, nil)
+	store := new(MockTriggerStore)
+	server.Handler.SetTriggerStore(store)
+	return server, store
+}
+
+const triggerBody = `{"triggerId":"t1","collection":"orders","events":["create"],"url":"https://example.com/hook"}`
+
+func TestAdminTriggers_Unavailable(t *testing.T) {
+	server := createTestServer(nil, nil, nil)
+	w := serveAdmin(server, "GET", "/admin/triggers", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestAdminTriggers_Create(t *testing.T) {
+	server, store := newTriggerServer()
+	store.On("Create", mock.Anything, mock.MatchedBy(func(t *trigger.Trigger) bool { return t.ID == "t1" })).
+		Return(nil).Once()
+	store.On("Create", mock.Anything, mock.Anything).Return(trigger.ErrTriggerExists).Once()
+	store.On("Create", mock.Anything, mock.Anything).Return(errors.New("store down")).Once()
+
+	w := serveAdmin(server, "POST", "/admin/triggers", triggerBody)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created trigger.Trigger
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "default", created.Tenant, "the caller's tenant")
+
+	w = serveAdmin(server, "POST", "/admin/triggers", triggerBody)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serveAdmin(server, "POST", "/admin/triggers", triggerBody)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = serveAdmin(server, "POST", "/admin/triggers", `{"triggerId":"t1","tenant":"other"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveAdmin(server, "POST", "/admin/triggers", `{`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAdminTriggers_Invalid(t *testing.T) {
+	server, store := newTriggerServer()
+	store.On("Create", mock.Anything, mock.Anything).Return(trigger.ValidateDefinition(&trigger.Trigger{ID: "t1", Tenant: "default"}))
+
+	w := serveAdmin(server, "POST", "/admin/triggers", `{"triggerId":"t1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "collection is required")
+}
+
+func TestAdminTriggers_ListGetUpdate(t *testing.T) {
+	server, store := newTriggerServer()
+	store.On("List", mock.Anything, "default").Return([]*trigger.Trigger{{ID: "t1"}, {ID: "t2"}}, nil)
+	store.On("Get", mock.Anything, "default", "t1").Return(&trigger.Trigger{ID: "t1"}, nil)
+	store.On("Get", mock.Anything, "default", "t9").Return(nil, trigger.ErrTriggerNotFound)
+	store.On("Update", mock.Anything, mock.MatchedBy(func(t *trigger.Trigger) bool {
+		return t.ID == "t1" && t.Tenant == "default" && t.URL == "https://example.com/hook"
+	})).Return(nil)
+
+	w := serveAdmin(server, "GET", "/admin/triggers", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []trigger.Trigger
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Len(t, list, 2)
+
+	w = serveAdmin(server, "GET", "/admin/triggers/t1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serveAdmin(server, "GET", "/admin/triggers/t9", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveAdmin(server, "PUT", "/admin/triggers/t1", triggerBody)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serveAdmin(server, "PUT", "/admin/triggers/t2", triggerBody)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "ID mismatch")
+	store.AssertExpectations(t)
+}
+
+func TestAdminTriggers_EnableDisableDelete(t *testing.T) {
+	server, store := newTriggerServer()
+	store.On("SetDisabled", mock.Anything, "default", "t1", true).Return(nil)
+	store.On("SetDisabled", mock.Anything, "default", "t1", false).Return(model.ErrPreconditionFailed)
+	store.On("Delete", mock.Anything, "default", "t1").Return(nil)
+	store.On("Delete", mock.Anything, "default", "t9").Return(trigger.ErrTriggerNotFound)
+
+	w := serveAdmin(server, "POST", "/admin/triggers/t1/disable", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = serveAdmin(server, "POST", "/admin/triggers/t1/enable", "")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = serveAdmin(server, "DELETE", "/admin/triggers/t1", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = serveAdmin(server, "DELETE", "/admin/triggers/t9", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	store.AssertExpectations(t)
+}
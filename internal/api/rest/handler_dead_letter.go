@@ -0,0 +1,211 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/codetrek/syntrix/internal/trigger"
+)
+
+// DeadLetterListResponse is a page of the dead letters of a trigger. Next is
+// the value of the after parameter for the following page, zero on the last.
+type DeadLetterListResponse struct {
+	DeadLetters []*trigger.DeadLetter `json:"deadLetters"`
+	Next        uint64                `json:"next,omitempty"`
+}
+
+// DeadLetterReplayResponse reports how many dead letters were replayed.
+type DeadLetterReplayResponse struct {
+	Replayed int `json:"replayed"`
+}
+
+// SetDeadLetterService enables the dead-letter admin endpoints. Without it
+// they answer 503, e.g. on gateways that run without NATS.
+func (h *Handler) SetDeadLetterService(s trigger.DeadLetterService) {
+	h.deadLetters = s
+}
+
+// deadLetterTarget returns the tenant and trigger a dead-letter request is
+// about, writing an error response if it cannot be served.
+func (h *Handler) deadLetterTarget(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	if h.deadLetters == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeServiceUnavailable, "Trigger dead letters are not available")
+		return "", "", false
+	}
+	tenant, ok := h.tenantOrError(w, r)
+	if !ok {
+		return "", "", false
+	}
+	triggerID := r.PathValue("id")
+	if triggerID == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing trigger ID")
+		return "", "", false
+	}
+	return tenant, triggerID, true
+}
+
+func deadLetterID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(r.PathValue("seq"), 10, 64)
+	if err != nil || id == 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid dead letter ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeDeadLetterError(w http.ResponseWriter, err error) {
+	if errors.Is(err, trigger.ErrDeadLetterNotFound) {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "Dead letter not found")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "Failed to access dead letters")
+}
+
+// parseDeadLetterFilter reads a filter from the query parameters event,
+// status, since, until (Unix milliseconds), after and limit.
+func parseDeadLetterFilter(r *http.Request) (trigger.DeadLetterFilter, error) {
+	q := r.URL.Query()
+	filter := trigger.DeadLetterFilter{Event: q.Get("event")}
+	var err error
+	parseInt := func(name string, dst *int64) {
+		if v := q.Get(name); v != "" && err == nil {
+			*dst, err = strconv.ParseInt(v, 10, 64)
+		}
+	}
+	var status, limit int64
+	parseInt("status", &status)
+	parseInt("since", &filter.Since)
+	parseInt("until", &filter.Until)
+	parseInt("limit", &limit)
+	if v := q.Get("after"); v != "" && err == nil {
+		filter.After, err = strconv.ParseUint(v, 10, 64)
+	}
+	filter.Status = int(status)
+	filter.Limit = int(limit)
+	return filter, err
+}
+
+func (h *Handler) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	tenant, triggerID, ok := h.deadLetterTarget(w, r)
+	if !ok {
+		return
+	}
+	filter, err := parseDeadLetterFilter(r)
+	if err != nil || filter.Limit < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid filter")
+		return
+	}
+
+	letters, err := h.deadLetters.ListDeadLetters(r.Context(), tenant, triggerID, filter)
+	if err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	resp := DeadLetterListResponse{DeadLetters: letters}
+	if resp.DeadLetters == nil {
+		resp.DeadLetters = []*trigger.DeadLetter{}
+	}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = trigger.DefaultDeadLetterPageSize
+	}
+	if len(letters) > 0 && len(letters) >= limit {
+		resp.Next = letters[len(letters)-1].ID
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	tenant, triggerID, ok := h.deadLetterTarget(w, r)
+	if !ok {
+		return
+	}
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	letter, err := h.deadLetters.GetDeadLetter(r.Context(), tenant, triggerID, id)
+	if err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, letter)
+}
+
+func (h *Handler) handleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	tenant, triggerID, ok := h.deadLetterTarget(w, r)
+	if !ok {
+		return
+	}
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.deadLetters.ReplayDeadLetter(r.Context(), tenant, triggerID, id); err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, DeadLetterReplayResponse{Replayed: 1})
+}
+
+// handleReplayDeadLetters replays the dead letters selected by the filter in
+// the request body; an empty body replays all of them.
+func (h *Handler) handleReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	tenant, triggerID, ok := h.deadLetterTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var filter trigger.DeadLetterFilter
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid request body")
+		return
+	}
+	if filter.Limit < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid filter")
+		return
+	}
+
+	n, err := h.deadLetters.ReplayDeadLetters(r.Context(), tenant, triggerID, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "Replayed "+strconv.Itoa(n)+" dead letters before failing")
+		return
+	}
+	writeJSON(w, http.StatusOK, DeadLetterReplayResponse{Replayed: n})
+}
+
+func (h *Handler) handleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	tenant, triggerID, ok := h.deadLetterTarget(w, r)
+	if !ok {
+		return
+	}
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.deadLetters.DeleteDeadLetter(r.Context(), tenant, triggerID, id); err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handlePurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	tenant, triggerID, ok := h.deadLetterTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.deadLetters.PurgeDeadLetters(r.Context(), tenant, triggerID); err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
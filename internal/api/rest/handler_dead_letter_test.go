@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/trigger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDeadLetterService struct {
+	mock.Mock
+}
+
+func (m *MockDeadLetterService) ListDeadLetters(ctx context.Context, tenant, triggerID string, filter trigger.DeadLetterFilter) ([]*trigger.DeadLetter, error) {
+	args := m.Called(ctx, tenant, triggerID, filter)
+	letters, _ := args.Get(0).([]*trigger.DeadLetter)
+	return letters, args.Error(1)
+}
+
+func (m *MockDeadLetterService) GetDeadLetter(ctx context.Context, tenant, triggerID string, id uint64) (*trigger.DeadLetter, error) {
+	args := m.Called(ctx, tenant, triggerID, id)
+	letter, _ := args.Get(0).(*trigger.DeadLetter)
+	return letter, args.Error(1)
+}
+
+func (m *MockDeadLetterService) ReplayDeadLetter(ctx context.Context, tenant, triggerID string, id uint64) error {
+	return m.Called(ctx, tenant, triggerID, id).Error(0)
+}
+
+func (m *MockDeadLetterService) ReplayDeadLetters(ctx context.Context, tenant, triggerID string, filter trigger.DeadLetterFilter) (int, error) {
+	args := m.Called(ctx, tenant, triggerID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDeadLetterService) DeleteDeadLetter(ctx context.Context, tenant, triggerID string, id uint64) error {
+	return m.Called(ctx, tenant, triggerID, id).Error(0)
+}
+
+func (m *MockDeadLetterService) PurgeDeadLetters(ctx context.Context, tenant, triggerID string) error {
+	return m.Called(ctx, tenant, triggerID).Error(0)
+}
+
+func newDeadLetterServer() (*TestServer, *MockDeadLetterService) {
+	server := createTestServer(nil, nil, nil)
+	dl := new(MockDeadLetterService)
+	server.Handler.SetDeadLetterService(dl)
+	return server, dl
+}
+
+func serveAdmin(server *TestServer, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+func TestDeadLetters_Unavailable(t *testing.T) {
+	server := createTestServer(nil, nil, nil)
+	w := serveAdmin(server, "GET", "/admin/triggers/t1/dead-letters", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), ErrCodeServiceUnavailable)
+}
+
+func TestDeadLetters_List(t *testing.T) {
+	server, dl := newDeadLetterServer()
+	letters := []*trigger.DeadLetter{{ID: 3, Status: 500}, {ID: 9, Status: 500}}
+	dl.On("ListDeadLetters", mock.Anything, "default", "t1", trigger.DeadLetterFilter{Event: "create", Status: 500, Since: 10, After: 2, Limit: 2}).
+		Return(letters, nil)
+	dl.On("ListDeadLetters", mock.Anything, "default", "t2", trigger.DeadLetterFilter{}).Return(nil, nil)
+
+	w := serveAdmin(server, "GET", "/admin/triggers/t1/dead-letters?event=create&status=500&since=10&after=2&limit=2", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp DeadLetterListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.DeadLetters, 2)
+	assert.Equal(t, uint64(9), resp.Next)
+
+	w = serveAdmin(server, "GET", "/admin/triggers/t2/dead-letters", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"deadLetters":[]}`, w.Body.String())
+
+	w = serveAdmin(server, "GET", "/admin/triggers/t1/dead-letters?status=abc", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeadLetters_GetDeleteReplay(t *testing.T) {
+	server, dl := newDeadLetterServer()
+	dl.On("GetDeadLetter", mock.Anything, "default", "t1", uint64(5)).Return(&trigger.DeadLetter{ID: 5, Error: "boom"}, nil)
+	dl.On("GetDeadLetter", mock.Anything, "default", "t1", uint64(6)).Return(nil, trigger.ErrDeadLetterNotFound)
+	dl.On("ReplayDeadLetter", mock.Anything, "default", "t1", uint64(5)).Return(nil)
+	dl.On("DeleteDeadLetter", mock.Anything, "default", "t1", uint64(5)).Return(nil)
+	dl.On("DeleteDeadLetter", mock.Anything, "default", "t1", uint64(6)).Return(errors.New("nats down"))
+
+	w := serveAdmin(server, "GET", "/admin/triggers/t1/dead-letters/5", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"boom"`)
+
+	w = serveAdmin(server, "GET", "/admin/triggers/t1/dead-letters/6", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveAdmin(server, "GET", "/admin/triggers/t1/dead-letters/x", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAdmin(server, "POST", "/admin/triggers/t1/dead-letters/5/replay", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"replayed":1}`, w.Body.String())
+
+	w = serveAdmin(server, "DELETE", "/admin/triggers/t1/dead-letters/5", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serveAdmin(server, "DELETE", "/admin/triggers/t1/dead-letters/6", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	dl.AssertExpectations(t)
+}
+
+func TestDeadLetters_ReplayByFilterAndPurge(t *testing.T) {
+	server, dl := newDeadLetterServer()
+	dl.On("ReplayDeadLetters", mock.Anything, "default", "t1", trigger.DeadLetterFilter{Status: 503}).Return(4, nil)
+	dl.On("ReplayDeadLetters", mock.Anything, "default", "t1", trigger.DeadLetterFilter{}).Return(2, errors.New("nats down"))
+	dl.On("PurgeDeadLetters", mock.Anything, "default", "t1").Return(nil)
+
+	w := serveAdmin(server, "POST", "/admin/triggers/t1/dead-letters/replay", `{"status":503}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"replayed":4}`, w.Body.String())
+
+	// An empty body replays everything.
+	w = serveAdmin(server, "POST", "/admin/triggers/t1/dead-letters/replay", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Replayed 2 dead letters")
+
+	w = serveAdmin(server, "POST", "/admin/triggers/t1/dead-letters/replay", `{"limit":-1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveAdmin(server, "POST", "/admin/triggers/t1/dead-letters/replay", `{`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAdmin(server, "DELETE", "/admin/triggers/t1/dead-letters", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	dl.AssertExpectations(t)
+}
+
+func TestDeadLetters_AdminOnly(t *testing.T) {
+	mockAuth := &AdminTestAuthService{MockAuthService: new(MockAuthService)}
+	server := createTestServer(nil, mockAuth, new(MockAuthzService))
+	server.Handler.SetDeadLetterService(new(MockDeadLetterService))
+
+	req := httptest.NewRequest("DELETE", "/admin/triggers/t1/dead-letters", nil)
+	req.Header.Set("X-Role", "user")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
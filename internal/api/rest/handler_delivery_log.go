@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger"
+)
+
+// deliveryTailHeartbeat is how often an idle delivery tail sends a comment to
+// keep proxies from closing it.
+var deliveryTailHeartbeat = 15 * time.Second
+
+// deliveryTailWriteWait bounds one write to a delivery tail. Each write
+// extends the deadline, so the tail outlives the server's write timeout.
+const deliveryTailWriteWait = 10 * time.Second
+
+// DeliveryListResponse is a page of the delivery log of a trigger. Next is
+// the value of the after parameter for the following page, zero on the last.
+type DeliveryListResponse struct {
+	Deliveries []*trigger.DeliveryRecord `json:"deliveries"`
+	Next       uint64                    `json:"next,omitempty"`
+}
+
+// SetDeliveryLogService enables the delivery log admin endpoints. Without it
+// they answer 503, e.g. on gateways that run without NATS.
+func (h *Handler) SetDeliveryLogService(s trigger.DeliveryLogService) {
+	h.deliveries = s
+}
+
+// deliveryTarget returns the tenant and trigger a delivery log request is
+// about, writing an error response if it cannot be served.
+func (h *Handler) deliveryTarget(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	if h.deliveries == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeServiceUnavailable, "Trigger delivery log is not available")
+		return "", "", false
+	}
+	tenant, ok := h.tenantOrError(w, r)
+	if !ok {
+		return "", "", false
+	}
+	triggerID := r.PathValue("id")
+	if triggerID == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Missing trigger ID")
+		return "", "", false
+	}
+	return tenant, triggerID, true
+}
+
+// parseDeliveryLogFilter reads a filter from the query parameters outcome,
+// status, after and limit.
+func parseDeliveryLogFilter(r *http.Request) (trigger.DeliveryLogFilter, error) {
+	q := r.URL.Query()
+	filter := trigger.DeliveryLogFilter{Outcome: q.Get("outcome")}
+	var err error
+	if v := q.Get("status"); v != "" {
+		filter.Status, err = strconv.Atoi(v)
+	}
+	if v := q.Get("limit"); v != "" && err == nil {
+		filter.Limit, err = strconv.Atoi(v)
+	}
+	if v := q.Get("after"); v != "" && err == nil {
+		filter.After, err = strconv.ParseUint(v, 10, 64)
+	}
+	return filter, err
+}
+
+func (h *Handler) handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	tenant, triggerID, ok := h.deliveryTarget(w, r)
+	if !ok {
+		return
+	}
+	filter, err := parseDeliveryLogFilter(r)
+	if err != nil || filter.Limit < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "Invalid filter")
+		return
+	}
+
+	records, err := h.deliveries.ListDeliveries(r.Context(), tenant, triggerID, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "Failed to read delivery log")
+		return
+	}
+
+	resp := DeliveryListResponse{Deliveries: records}
+	if resp.Deliveries == nil {
+		resp.Deliveries = []*trigger.DeliveryRecord{}
+	}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = trigger.DefaultDeliveryLogPageSize
+	}
+	if len(records) > 0 && len(records) >= limit {
+		resp.Next = records[len(records)-1].ID
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleTailDeliveries streams the delivery records of a trigger as
+// Server-Sent Events named "delivery" until the client goes away. The event
+// ID is the record ID.
+func (h *Handler) handleTailDeliveries(w http.ResponseWriter, r *http.Request) {
+	tenant, triggerID, ok := h.deliveryTarget(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	records, err := h.deliveries.TailDeliveries(ctx, tenant, triggerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "Failed to tail delivery log")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Printf("[Warning] Delivery tail of trigger %s cannot stream: %v", triggerID, err)
+		return
+	}
+
+	ticker := time.NewTicker(deliveryTailHeartbeat)
+	defer ticker.Stop()
+
+	write := func(format string, args ...interface{}) error {
+		_ = rc.SetWriteDeadline(time.Now().Add(deliveryTailWriteWait))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err = write(": heartbeat\n\n")
+		case rec, ok := <-records:
+			if !ok {
+				return
+			}
+			data, jsonErr := json.Marshal(rec)
+			if jsonErr != nil {
+				continue
+			}
+			err = write("id: %d\nevent: delivery\ndata: %s\n\n", rec.ID, data)
+		}
+		if err != nil {
+			log.Printf("[Info] Delivery tail of trigger %s closed: %v", triggerID, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDeliveryLogService struct {
+	mock.Mock
+}
+
+func (m *MockDeliveryLogService) ListDeliveries(ctx context.Context, tenant, triggerID string, filter trigger.DeliveryLogFilter) ([]*trigger.DeliveryRecord, error) {
+	args := m.Called(ctx, tenant, triggerID, filter)
+	records, _ := args.Get(0).([]*trigger.DeliveryRecord)
+	return records, args.Error(1)
+}
+
+func (m *MockDeliveryLogService) TailDeliveries(ctx context.Context, tenant, triggerID string) (<-chan *trigger.DeliveryRecord, error) {
+	args := m.Called(ctx, tenant, triggerID)
+	records, _ := args.Get(0).(<-chan *trigger.DeliveryRecord)
+	return records, args.Error(1)
+}
+
+func newDeliveryLogServer() (*TestServer, *MockDeliveryLogService) {
+	server := createTestServer(nil, nil, nil)
+	dl := new(MockDeliveryLogService)
+	server.Handler.SetDeliveryLogService(dl)
+	return server, dl
+}
+
+func TestDeliveries_Unavailable(t *testing.T) {
+	server := createTestServer(nil, nil, nil)
+	w := serveAdmin(server, "GET", "/admin/triggers/t1/deliveries", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = serveAdmin(server, "GET", "/admin/triggers/t1/deliveries/stream", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestDeliveries_List(t *testing.T) {
+	server, dl := newDeliveryLogServer()
+	records := []*trigger.DeliveryRecord{{ID: 3, Status: 503}, {ID: 8, Status: 503}}
+	dl.On("ListDeliveries", mock.Anything, "default", "t1", trigger.DeliveryLogFilter{Outcome: "retrying", Status: 503, After: 2, Limit: 2}).
+		Return(records, nil)
+	dl.On("ListDeliveries", mock.Anything, "default", "t2", trigger.DeliveryLogFilter{}).Return(nil, nil)
+	dl.On("ListDeliveries", mock.Anything, "default", "t3", trigger.DeliveryLogFilter{}).Return(nil, errors.New("nats down"))
+
+	w := serveAdmin(server, "GET", "/admin/triggers/t1/deliveries?outcome=retrying&status=503&after=2&limit=2", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp DeliveryListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Deliveries, 2)
+	assert.Equal(t, uint64(8), resp.Next)
+
+	w = serveAdmin(server, "GET", "/admin/triggers/t2/deliveries", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"deliveries":[]}`, w.Body.String())
+
+	w = serveAdmin(server, "GET", "/admin/triggers/t3/deliveries", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = serveAdmin(server, "GET", "/admin/triggers/t1/deliveries?limit=-1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveAdmin(server, "GET", "/admin/triggers/t1/deliveries?after=x", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeliveries_Tail(t *testing.T) {
+	server, dl := newDeliveryLogServer()
+	records := make(chan *trigger.DeliveryRecord, 2)
+	records <- &trigger.DeliveryRecord{ID: 4, TaskID: "task-1", Status: 200}
+	records <- &trigger.DeliveryRecord{ID: 5, TaskID: "task-2", Status: 503}
+	close(records)
+	dl.On("TailDeliveries", mock.Anything, "default", "t1").Return((<-chan *trigger.DeliveryRecord)(records), nil)
+
+	w := serveAdmin(server, "GET", "/admin/triggers/t1/deliveries/stream", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "id: 4\nevent: delivery\ndata: {")
+	assert.Contains(t, body, `"taskId":"task-2"`)
+	assert.Equal(t, 2, strings.Count(body, "event: delivery"))
+}
+
+func TestDeliveries_Tail_Heartbeat(t *testing.T) {
+	original := deliveryTailHeartbeat
+	deliveryTailHeartbeat = 5 * time.Millisecond
+	defer func() { deliveryTailHeartbeat = original }()
+
+	server, dl := newDeliveryLogServer()
+	dl.On("TailDeliveries", mock.Anything, "default", "t1").Return((<-chan *trigger.DeliveryRecord)(make(chan *trigger.DeliveryRecord)), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/admin/triggers/t1/deliveries/stream", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), ": heartbeat\n\n")
+}
+
+func TestDeliveries_Tail_Error(t *testing.T) {
+	server, dl := newDeliveryLogServer()
+	dl.On("TailDeliveries", mock.Anything, "default", "t1").Return(nil, errors.New("nats down"))
+
+	w := serveAdmin(server, "GET", "/admin/triggers/t1/deliveries/stream", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestDeliveries_AdminOnly(t *testing.T) {
+	mockAuth := &AdminTestAuthService{MockAuthService: new(MockAuthService)}
+	server := createTestServer(nil, mockAuth, new(MockAuthzService))
+	server.Handler.SetDeliveryLogService(new(MockDeliveryLogService))
+
+	req := httptest.NewRequest("GET", "/admin/triggers/t1/deliveries/stream", nil)
+	req.Header.Set("X-Role", "user")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/codetrek/syntrix/internal/api/rest"
 	"github.com/codetrek/syntrix/internal/engine"
 	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/codetrek/syntrix/internal/trigger"
 )
 
 type Server struct {
@@ -25,11 +26,26 @@ func NewServer(engine engine.Service, auth identity.AuthN, authz identity.AuthZ,
 	return s
 }
 
+// SetTriggerStore enables the trigger management admin endpoints.
+func (s *Server) SetTriggerStore(store trigger.Store) {
+	s.rest.SetTriggerStore(store)
+}
+
+// SetDeadLetterService enables the trigger dead-letter admin endpoints.
+func (s *Server) SetDeadLetterService(dl trigger.DeadLetterService) {
+	s.rest.SetDeadLetterService(dl)
+}
+
+// SetDeliveryLogService enables the trigger delivery log admin endpoints.
+func (s *Server) SetDeliveryLogService(dl trigger.DeliveryLogService) {
+	s.rest.SetDeliveryLogService(dl)
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Grpc-Web, X-User-Agent, Grpc-Timeout")
 
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusOK)
@@ -47,6 +63,8 @@ func (s *Server) routes() {
 	if s.realtime != nil {
 		s.mux.HandleFunc("GET /realtime/ws", s.realtime.HandleWS)
 		s.mux.HandleFunc("GET /realtime/sse", s.realtime.HandleSSE)
+		s.mux.HandleFunc("POST /realtime/stream", s.realtime.HandleStream)
+		s.mux.HandleFunc("POST "+realtime.StreamMethod, s.realtime.HandleStream)
 	}
 
 	// Console
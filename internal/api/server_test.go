@@ -71,7 +71,7 @@ func TestNewServer_WithRealtime(t *testing.T) {
 	mockAuth := new(MockAuthService)
 	mockAuthz := new(MockAuthzEngine)
 
-	rt := realtime.NewServer(mockQuery, "docs", mockAuth, realtime.Config{EnableAuth: true})
+	rt := realtime.NewServer(mockQuery, "docs", mockAuth, nil, realtime.Config{EnableAuth: true})
 
 	server := NewServer(mockQuery, mockAuth, mockAuthz, rt)
 	assert.NotNil(t, server)
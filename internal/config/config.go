@@ -39,16 +39,62 @@ type StandaloneConfig struct {
 }
 
 type GatewayConfig struct {
-	Port            int               `yaml:"port"`
-	QueryServiceURL string            `yaml:"query_service_url"`
-	Auth            GatewayAuthConfig `yaml:"auth"`
-	Realtime        RealtimeConfig    `yaml:"realtime"`
+	Port            int    `yaml:"port"`
+	QueryServiceURL string `yaml:"query_service_url"`
+	// QueryGRPCAddress, when set, makes the gateway reach the query service
+	// over gRPC instead of HTTP.
+	QueryGRPCAddress string            `yaml:"query_grpc_address"`
+	Auth             GatewayAuthConfig `yaml:"auth"`
+	Realtime         RealtimeConfig    `yaml:"realtime"`
 }
 
 type RealtimeConfig struct {
-	AllowedOrigins []string `yaml:"allowed_origins"`
-	AllowDevOrigin bool     `yaml:"allow_dev_origin"`
-	EnableAuth     bool     `yaml:"enable_auth"`
+	AllowedOrigins []string      `yaml:"allowed_origins"`
+	AllowDevOrigin bool          `yaml:"allow_dev_origin"`
+	EnableAuth     bool          `yaml:"enable_auth"`
+	AuthzCacheTTL  time.Duration `yaml:"authz_cache_ttl"`
+	AuthzCacheSize int           `yaml:"authz_cache_size"`
+
+	SnapshotPageSize   int `yaml:"snapshot_page_size"`
+	SnapshotMaxDocs    int `yaml:"snapshot_max_docs"`
+	SnapshotBufferSize int `yaml:"snapshot_buffer_size"`
+
+	ResumeMaxEvents int `yaml:"resume_max_events"`
+
+	// Fan-out: per-client queue size, overflow policy (drop, coalesce or
+	// disconnect) and number of broadcast workers (0 = GOMAXPROCS).
+	SendQueueSize    int    `yaml:"send_queue_size"`
+	OverflowPolicy   string `yaml:"overflow_policy"`
+	BroadcastWorkers int    `yaml:"broadcast_workers"`
+
+	// Event source: "storage" watches the change stream per gateway,
+	// "puller" subscribes to the puller, in-process or at PullerAddress.
+	// ConsumerID identifies the gateway to the puller (default: hostname).
+	Source        string `yaml:"source"`
+	PullerAddress string `yaml:"puller_address"`
+	ConsumerID    string `yaml:"consumer_id"`
+
+	// Bus: "local" keeps presence and ephemeral topics per gateway, "nats"
+	// shares them through the trigger NATS connection. PresenceTTL expires
+	// presence members whose client has been silent that long.
+	Bus         string        `yaml:"bus"`
+	PresenceTTL time.Duration `yaml:"presence_ttl"`
+
+	// Compression offers permessage-deflate to WebSocket clients.
+	Compression bool `yaml:"compression"`
+
+	// Limits (0 = unlimited): open connections per tenant and per user,
+	// subscriptions per connection, and inbound WebSocket messages per
+	// second with bursts of MessageBurst (0 = MessageRate).
+	MaxConnectionsPerTenant       int     `yaml:"max_connections_per_tenant"`
+	MaxConnectionsPerUser         int     `yaml:"max_connections_per_user"`
+	MaxSubscriptionsPerConnection int     `yaml:"max_subscriptions_per_connection"`
+	MessageRate                   float64 `yaml:"message_rate"`
+	MessageBurst                  int     `yaml:"message_burst"`
+
+	// ReauthWindow is how long before its token expires a connection is
+	// asked to send a fresh one.
+	ReauthWindow time.Duration `yaml:"reauth_window"`
 }
 
 type GatewayAuthConfig struct {
@@ -68,10 +114,30 @@ type CSPConfig struct {
 }
 
 type TriggerConfig struct {
-	NatsURL     string `yaml:"nats_url"`
-	RulesFile   string `yaml:"rules_file"`
-	WorkerCount int    `yaml:"worker_count"`
-	StreamName  string `yaml:"stream_name"`
+	NatsURL     string              `yaml:"nats_url"`
+	RulesFile   string              `yaml:"rules_file"`
+	WorkerCount int                 `yaml:"worker_count"`
+	StreamName  string              `yaml:"stream_name"`
+	Stream      TriggerStreamConfig `yaml:"stream"`
+	// NATSSinkPrefix is the subject prefix nats:// targets publish under; a
+	// trigger of tenant t may only publish to <prefix>.<t>.>. Defaults to
+	// "sinks".
+	NATSSinkPrefix string `yaml:"nats_sink_prefix"`
+}
+
+// TriggerStreamConfig configures the JetStream stream of delivery tasks.
+// Storage is "file" or "memory"; when empty it is "file" if
+// deployment.standalone.nats_data_dir is set. Limits of 0 are unlimited;
+// Discard ("old" or "new") picks what happens at a limit. DuplicateWindow is
+// how long republished tasks are dropped by ID (JetStream default 2m if 0).
+type TriggerStreamConfig struct {
+	Storage         string        `yaml:"storage"`
+	MaxAge          time.Duration `yaml:"max_age"`
+	MaxBytes        int64         `yaml:"max_bytes"`
+	MaxMsgs         int64         `yaml:"max_msgs"`
+	Discard         string        `yaml:"discard"`
+	Replicas        int           `yaml:"replicas"`
+	DuplicateWindow time.Duration `yaml:"duplicate_window"`
 }
 
 type StorageConfig struct {
@@ -199,6 +265,24 @@ func LoadConfig() *Config {
 				AllowedOrigins: []string{"http://localhost:8080", "http://localhost:3000", "http://localhost:5173"},
 				AllowDevOrigin: true,
 				EnableAuth:     true,
+				AuthzCacheTTL:  30 * time.Second,
+				AuthzCacheSize: 100000,
+
+				SnapshotPageSize:   500,
+				SnapshotMaxDocs:    10000,
+				SnapshotBufferSize: 10000,
+
+				ResumeMaxEvents: 10000,
+
+				SendQueueSize:  256,
+				OverflowPolicy: "drop",
+
+				Source: "storage",
+
+				Bus:         "local",
+				PresenceTTL: 60 * time.Second,
+
+				ReauthWindow: 60 * time.Second,
 			},
 		},
 		Query: QueryConfig{
@@ -238,6 +322,9 @@ func LoadConfig() *Config {
 	if val := os.Getenv("GATEWAY_QUERY_SERVICE_URL"); val != "" {
 		cfg.Gateway.QueryServiceURL = val
 	}
+	if val := os.Getenv("GATEWAY_QUERY_GRPC_ADDRESS"); val != "" {
+		cfg.Gateway.QueryGRPCAddress = val
+	}
 
 	if val := os.Getenv("QUERY_PORT"); val != "" {
 		if port, err := strconv.Atoi(val); err == nil {
@@ -313,6 +400,36 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("deployment.mode must be 'standalone' or 'distributed', got '%s'", mode)
 	}
 
+	// Validate Realtime Overflow Policy
+	switch policy := c.Gateway.Realtime.OverflowPolicy; policy {
+	case "", "drop", "coalesce", "disconnect":
+	default:
+		return fmt.Errorf("gateway.realtime.overflow_policy must be 'drop', 'coalesce' or 'disconnect', got '%s'", policy)
+	}
+	if source := c.Gateway.Realtime.Source; source != "" && source != "storage" && source != "puller" {
+		return fmt.Errorf("gateway.realtime.source must be 'storage' or 'puller', got '%s'", source)
+	}
+	if bus := c.Gateway.Realtime.Bus; bus != "" && bus != "local" && bus != "nats" {
+		return fmt.Errorf("gateway.realtime.bus must be 'local' or 'nats', got '%s'", bus)
+	}
+	if rt := c.Gateway.Realtime; rt.MaxConnectionsPerTenant < 0 || rt.MaxConnectionsPerUser < 0 ||
+		rt.MaxSubscriptionsPerConnection < 0 || rt.MessageRate < 0 || rt.MessageBurst < 0 {
+		return fmt.Errorf("gateway.realtime limits must not be negative")
+	}
+
+	// Validate Trigger Stream
+	stream := c.Trigger.Stream
+	if stream.Storage != "" && stream.Storage != "file" && stream.Storage != "memory" {
+		return fmt.Errorf("trigger.stream.storage must be 'file' or 'memory', got '%s'", stream.Storage)
+	}
+	if stream.Discard != "" && stream.Discard != "old" && stream.Discard != "new" {
+		return fmt.Errorf("trigger.stream.discard must be 'old' or 'new', got '%s'", stream.Discard)
+	}
+	if stream.MaxAge < 0 || stream.MaxBytes < 0 || stream.MaxMsgs < 0 || stream.Replicas < 0 ||
+		stream.DuplicateWindow < 0 {
+		return fmt.Errorf("trigger.stream limits must not be negative")
+	}
+
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -27,6 +28,7 @@ func TestLoadConfig_EnvVars(t *testing.T) {
 	os.Setenv("DB_NAME", "testdb")
 	os.Setenv("GATEWAY_PORT", "9090")
 	os.Setenv("GATEWAY_QUERY_SERVICE_URL", "http://api-env")
+	os.Setenv("GATEWAY_QUERY_GRPC_ADDRESS", "query-env:9000")
 	os.Setenv("QUERY_PORT", "9092")
 	os.Setenv("QUERY_CSP_SERVICE_URL", "http://csp-env")
 	os.Setenv("CSP_PORT", "9093")
@@ -37,6 +39,7 @@ func TestLoadConfig_EnvVars(t *testing.T) {
 		os.Unsetenv("DB_NAME")
 		os.Unsetenv("GATEWAY_PORT")
 		os.Unsetenv("GATEWAY_QUERY_SERVICE_URL")
+		os.Unsetenv("GATEWAY_QUERY_GRPC_ADDRESS")
 		os.Unsetenv("QUERY_PORT")
 		os.Unsetenv("QUERY_CSP_SERVICE_URL")
 		os.Unsetenv("CSP_PORT")
@@ -50,6 +53,7 @@ func TestLoadConfig_EnvVars(t *testing.T) {
 	assert.Equal(t, "testdb", cfg.Storage.Backends["default_mongo"].Mongo.DatabaseName)
 	assert.Equal(t, 9090, cfg.Gateway.Port)
 	assert.Equal(t, "http://api-env", cfg.Gateway.QueryServiceURL)
+	assert.Equal(t, "query-env:9000", cfg.Gateway.QueryGRPCAddress)
 	assert.Equal(t, 9092, cfg.Query.Port)
 	assert.Equal(t, "http://csp-env", cfg.Query.CSPServiceURL)
 	assert.Equal(t, 9093, cfg.CSP.Port)
@@ -201,6 +205,59 @@ func TestValidate(t *testing.T) {
 	}
 	err = cfg.Validate()
 	assert.NoError(t, err)
+
+	// Case 7: Invalid realtime overflow policy
+	cfg.Gateway.Realtime.OverflowPolicy = "block"
+	err = cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "overflow_policy must be")
+
+	// Case 8: Invalid realtime event source
+	cfg.Gateway.Realtime.OverflowPolicy = ""
+	cfg.Gateway.Realtime.Source = "csp"
+	err = cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "source must be")
+
+	// Case 9: Invalid realtime bus
+	cfg.Gateway.Realtime.Source = ""
+	cfg.Gateway.Realtime.Bus = "redis"
+	err = cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "bus must be")
+
+	// Case 10: Negative realtime limit
+	cfg.Gateway.Realtime.Bus = ""
+	cfg.Gateway.Realtime.MaxSubscriptionsPerConnection = -1
+	err = cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "limits must not be negative")
+
+	// Case 11: Invalid trigger stream settings
+	cfg.Gateway.Realtime.MaxSubscriptionsPerConnection = 0
+	cfg.Trigger.Stream.Storage = "disk"
+	err = cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "trigger.stream.storage must be")
+
+	cfg.Trigger.Stream.Storage = "file"
+	cfg.Trigger.Stream.Discard = "oldest"
+	err = cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "trigger.stream.discard must be")
+
+	cfg.Trigger.Stream.Discard = "new"
+	cfg.Trigger.Stream.MaxBytes = -1
+	err = cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "trigger.stream limits must not be negative")
+
+	cfg.Trigger.Stream.MaxBytes = 1 << 30
+	cfg.Trigger.Stream.DuplicateWindow = -time.Second
+	assert.Error(t, cfg.Validate())
+
+	cfg.Trigger.Stream.DuplicateWindow = 10 * time.Minute
+	assert.NoError(t, cfg.Validate())
 }
 
 func TestLoadConfig_DeploymentDefaults(t *testing.T) {
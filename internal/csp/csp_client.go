@@ -4,25 +4,38 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/codetrek/syntrix/internal/storage"
 )
 
+const (
+	watchRetryMin = 100 * time.Millisecond
+	watchRetryMax = 10 * time.Second
+)
+
 // CSPClient provides HTTP-based access to a remote CSP service.
 // Use this in distributed mode where CSP runs as a separate service.
 type CSPClient struct {
 	baseURL string
 	client  *http.Client
+
+	retryMin time.Duration
+	retryMax time.Duration
 }
 
 // NewClient creates a new CSPClient with the given CSP service URL.
 func NewClient(baseURL string) *CSPClient {
 	return &CSPClient{
-		baseURL: baseURL,
-		client:  &http.Client{},
+		baseURL:  baseURL,
+		client:   &http.Client{},
+		retryMin: watchRetryMin,
+		retryMax: watchRetryMax,
 	}
 }
 
@@ -32,13 +45,84 @@ func (c *CSPClient) SetHTTPClient(client *http.Client) {
 }
 
 // Watch returns a channel of events by connecting to the remote CSP service.
-// Note: resumeToken and opts are not currently supported over HTTP protocol;
-// the server manages state internally. These parameters are accepted for
-// interface compatibility but are ignored.
+// Events carry the resume token the service streamed with them.
+//
+// When the connection breaks, the client reconnects with backoff and resumes
+// after the last token it received. If the service no longer retains that
+// token, an event of type storage.EventReset is emitted and the watch
+// continues from now. The channel is closed when ctx is cancelled.
+// opts are not supported over HTTP and are ignored.
 func (c *CSPClient) Watch(ctx context.Context, tenant, collection string, resumeToken interface{}, opts storage.WatchOptions) (<-chan storage.Event, error) {
-	reqBody, err := json.Marshal(map[string]string{
-		"collection": collection,
-		"tenant":     tenant,
+	token := ""
+	if resumeToken != nil {
+		t, err := encodeResumeToken(resumeToken)
+		if err != nil {
+			return nil, err
+		}
+		token = t
+	}
+
+	body, err := c.openWatch(ctx, tenant, collection, token)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan storage.Event)
+
+	go func() {
+		defer close(out)
+
+		backoff := c.retryMin
+		for {
+			if body != nil {
+				var ok bool
+				token, ok = c.readWatch(ctx, body, out, token)
+				body.Close()
+				body = nil
+				if !ok {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			body, err = c.openWatch(ctx, tenant, collection, token)
+			switch {
+			case err == nil:
+				log.Printf("[Info][CSP CSPClient] Watch reconnected collection=%s resumed=%t", collection, token != "")
+				backoff = c.retryMin
+			case errors.Is(err, storage.ErrResumeTokenExpired):
+				log.Printf("[Warning][CSP CSPClient] Watch resume token expired, resetting stream collection=%s", collection)
+				token = ""
+				reset := storage.Event{Type: storage.EventReset, TenantID: tenant, Timestamp: time.Now().UnixNano()}
+				select {
+				case out <- reset:
+				case <-ctx.Done():
+					return
+				}
+				backoff = c.retryMin
+			default:
+				log.Printf("[Warning][CSP CSPClient] Watch reconnect failed: %v", err)
+				if backoff *= 2; backoff > c.retryMax {
+					backoff = c.retryMax
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// openWatch starts a watch request resuming after token, if set.
+func (c *CSPClient) openWatch(ctx context.Context, tenant, collection, token string) (io.ReadCloser, error) {
+	reqBody, err := json.Marshal(watchRequest{
+		TenantID:    tenant,
+		Collection:  collection,
+		ResumeAfter: token,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -57,31 +141,47 @@ func (c *CSPClient) Watch(ctx context.Context, tenant, collection string, resume
 
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
+		if resp.StatusCode == http.StatusGone {
+			return nil, fmt.Errorf("csp watch failed: %w", storage.ErrResumeTokenExpired)
+		}
 		return nil, fmt.Errorf("csp watch failed with status: %d", resp.StatusCode)
 	}
+	return resp.Body, nil
+}
 
-	out := make(chan storage.Event)
-
-	go func() {
-		defer close(out)
-		defer resp.Body.Close()
-
-		decoder := json.NewDecoder(resp.Body)
-		for {
-			var evt storage.Event
-			if err := decoder.Decode(&evt); err != nil {
+// readWatch forwards events from body until the stream ends and returns the
+// last resume token. ok is false when ctx is done.
+func (c *CSPClient) readWatch(ctx context.Context, body io.Reader, out chan<- storage.Event, token string) (string, bool) {
+	decoder := json.NewDecoder(body)
+	for {
+		var frame watchEvent
+		if err := decoder.Decode(&frame); err != nil {
+			if ctx.Err() != nil {
+				return token, false
+			}
+			if err != io.EOF {
 				log.Printf("[Error][CSP CSPClient] Watch decode event failed: %v\n", err)
-				return
 			}
-			select {
-			case out <- evt:
-			case <-ctx.Done():
-				return
+			return token, true
+		}
+
+		evt := frame.Event
+		if frame.ResumeToken != "" {
+			raw, err := decodeResumeToken(frame.ResumeToken)
+			if err != nil {
+				log.Printf("[Warning][CSP CSPClient] Watch received invalid resume token: %v", err)
+			} else {
+				evt.ResumeToken = raw
+				token = frame.ResumeToken
 			}
 		}
-	}()
 
-	return out, nil
+		select {
+		case out <- evt:
+		case <-ctx.Done():
+			return token, false
+		}
+	}
 }
 
 // Ensure CSPClient implements Service interface at compile time.
@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/codetrek/syntrix/internal/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestNewCSPClient(t *testing.T) {
@@ -64,7 +68,8 @@ func TestCSPClient_Watch_Success(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL)
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	ch, err := client.Watch(ctx, "test-tenant", "users", nil, storage.WatchOptions{})
 
@@ -75,6 +80,9 @@ func TestCSPClient_Watch_Success(t *testing.T) {
 	receivedEvents := make([]storage.Event, 0, 2)
 	for evt := range ch {
 		receivedEvents = append(receivedEvents, evt)
+		if len(receivedEvents) == len(events) {
+			cancel()
+		}
 	}
 
 	assert.Len(t, receivedEvents, 2)
@@ -164,26 +172,43 @@ func TestCSPClient_Watch_ContextCancel(t *testing.T) {
 }
 
 func TestCSPClient_Watch_InvalidJSON(t *testing.T) {
+	var calls atomic.Int32
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		// Send invalid JSON
-		w.Write([]byte("invalid json\n"))
+		if calls.Add(1) == 1 {
+			// Send invalid JSON
+			w.Write([]byte("invalid json\n"))
+			return
+		}
+		json.NewEncoder(w).Encode(storage.Event{Id: "users/1", Type: storage.EventCreate})
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
 	}))
 	defer server.Close()
 
 	client := NewClient(server.URL)
-	ctx := context.Background()
+	client.retryMin = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	ch, err := client.Watch(ctx, "test-tenant", "users", nil, storage.WatchOptions{})
 
 	assert.NoError(t, err) // Connection succeeds
 	assert.NotNil(t, ch)
 
-	// Channel should close due to decode error
-	evt, ok := <-ch
-	assert.False(t, ok, "channel should be closed after decode error")
-	assert.Equal(t, storage.Event{}, evt)
+	// The broken stream is dropped and the client reconnects
+	select {
+	case evt := <-ch:
+		assert.Equal(t, "users/1", evt.Id)
+	case <-time.After(time.Second):
+		t.Fatal("expected event after reconnect")
+	}
+	assert.Equal(t, int32(2), calls.Load())
+
+	cancel()
+	for range ch {
+	}
 }
 
 func TestCSPClient_Watch_EmptyTenantAndCollection(t *testing.T) {
@@ -204,7 +229,7 @@ func TestCSPClient_Watch_EmptyTenantAndCollection(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL)
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
 
 	ch, err := client.Watch(ctx, "", "", nil, storage.WatchOptions{})
 
@@ -212,9 +237,150 @@ func TestCSPClient_Watch_EmptyTenantAndCollection(t *testing.T) {
 	assert.NotNil(t, ch)
 
 	// Wait for channel to close
+	cancel()
 	<-ch
 }
 
+func TestCSPClient_Watch_ResumesAfterLastToken(t *testing.T) {
+	token := bson.M{"_data": "82A1"}
+	encoded, err := encodeResumeToken(token)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var resumeAfter []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req watchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		resumeAfter = append(resumeAfter, req.ResumeAfter)
+		n := len(resumeAfter)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+		if n == 1 {
+			// Stream one event, then drop the connection.
+			json.NewEncoder(w).Encode(watchEvent{Event: storage.Event{Id: "users/1"}, ResumeToken: encoded})
+			return
+		}
+		json.NewEncoder(w).Encode(watchEvent{Event: storage.Event{Id: "users/2"}})
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.retryMin = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := client.Watch(ctx, "t1", "users", nil, storage.WatchOptions{})
+	require.NoError(t, err)
+
+	first := <-ch
+	assert.Equal(t, "users/1", first.Id)
+	raw, ok := first.ResumeToken.(bson.Raw)
+	require.True(t, ok)
+	assert.Equal(t, "82A1", raw.Lookup("_data").StringValue())
+
+	second := <-ch
+	assert.Equal(t, "users/2", second.Id)
+
+	mu.Lock()
+	assert.Equal(t, []string{"", encoded}, resumeAfter)
+	mu.Unlock()
+}
+
+// clusterTimeStorage streams one event carrying a cluster time.
+type clusterTimeStorage struct {
+	fakeStorage
+}
+
+func (f *clusterTimeStorage) Watch(ctx context.Context, tenant string, collection string, resumeToken interface{}, opts storage.WatchOptions) (<-chan storage.Event, error) {
+	ch := make(chan storage.Event, 1)
+	ch <- storage.Event{Id: "users/1", Type: storage.EventUpdate, ClusterTime: storage.ClusterTime{T: 1700000000, I: 7}}
+	close(ch)
+	return ch, nil
+}
+
+func TestCSPClient_Watch_KeepsClusterTime(t *testing.T) {
+	server := httptest.NewServer(NewServer(&clusterTimeStorage{}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := NewClient(server.URL).Watch(ctx, "t1", "users", nil, storage.WatchOptions{})
+	require.NoError(t, err)
+
+	select {
+	case evt := <-ch:
+		assert.Equal(t, "users/1", evt.Id)
+		assert.Equal(t, storage.ClusterTime{T: 1700000000, I: 7}, evt.ClusterTime)
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+}
+
+func TestCSPClient_Watch_ExpiredTokenResetsStream(t *testing.T) {
+	encoded, err := encodeResumeToken(bson.M{"_data": "82A1"})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var resumeAfter []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req watchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		resumeAfter = append(resumeAfter, req.ResumeAfter)
+		n := len(resumeAfter)
+		mu.Unlock()
+
+		switch {
+		case n == 1:
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(watchEvent{Event: storage.Event{Id: "users/1"}, ResumeToken: encoded})
+		case req.ResumeAfter != "":
+			http.Error(w, "Resume token expired", http.StatusGone)
+		default:
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(watchEvent{Event: storage.Event{Id: "users/9"}})
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.retryMin = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := client.Watch(ctx, "t1", "users", nil, storage.WatchOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "users/1", (<-ch).Id)
+	reset := <-ch
+	assert.Equal(t, storage.EventReset, reset.Type)
+	assert.Equal(t, "t1", reset.TenantID)
+	assert.Equal(t, "users/9", (<-ch).Id)
+
+	mu.Lock()
+	assert.Equal(t, []string{"", encoded, ""}, resumeAfter)
+	mu.Unlock()
+}
+
+func TestCSPClient_Watch_ExpiredInitialToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Resume token expired", http.StatusGone)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ch, err := client.Watch(context.Background(), "t1", "users", bson.M{"_data": "82A1"}, storage.WatchOptions{})
+
+	assert.ErrorIs(t, err, storage.ErrResumeTokenExpired)
+	assert.Nil(t, ch)
+}
+
 func TestCSPClient_ImplementsService(t *testing.T) {
 	var svc Service = NewClient("http://localhost:8083")
 	assert.NotNil(t, svc)
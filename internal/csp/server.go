@@ -2,6 +2,7 @@ package csp
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
@@ -36,17 +37,39 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("CSP Service OK"))
 }
 
+// watchRequest is the body of a watch request. ResumeAfter is the token of
+// the last event the caller received.
+type watchRequest struct {
+	TenantID    string `json:"tenant"`
+	Collection  string `json:"collection"`
+	ResumeAfter string `json:"resumeAfter,omitempty"`
+}
+
+// watchEvent is a streamed event together with the token to resume after it.
+type watchEvent struct {
+	storage.Event
+	ResumeToken string `json:"resumeToken,omitempty"`
+}
+
 func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		TenantID   string `json:"tenant"`
-		Collection string `json:"collection"`
-	}
+	var req watchRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Println("[Error][Watch] invalid request body:", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	var resumeToken interface{}
+	if req.ResumeAfter != "" {
+		token, err := decodeResumeToken(req.ResumeAfter)
+		if err != nil {
+			log.Println("[Error][Watch] invalid resume token:", err)
+			http.Error(w, "Invalid resume token", http.StatusBadRequest)
+			return
+		}
+		resumeToken = token
+	}
+
 	// Ensure the writer supports flushing
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -55,6 +78,21 @@ func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Start watching via Storage Backend (Mongo) before sending headers, so a
+	// failure can still be reported with a status code.
+	// TODO: Extract tenant from request or context
+	stream, err := s.storage.Watch(r.Context(), req.TenantID, req.Collection, resumeToken, storage.WatchOptions{})
+	if err != nil {
+		if errors.Is(err, storage.ErrResumeTokenExpired) {
+			log.Println("[Warning][Watch] resume token expired:", err)
+			http.Error(w, "Resume token expired", http.StatusGone)
+			return
+		}
+		log.Println("[Error][Watch] failed to start watch:", err)
+		http.Error(w, "Failed to start watch", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -62,16 +100,7 @@ func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
 	// Flush headers immediately
 	flusher.Flush()
 
-	// Start watching via Storage Backend (Mongo)
-	// TODO: Extract tenant from request or context
-	stream, err := s.storage.Watch(r.Context(), req.TenantID, req.Collection, nil, storage.WatchOptions{})
-	if err != nil {
-		// Headers already sent, can't send error status now.
-		log.Println("[Error][Watch] failed to start watch:", err)
-		return
-	}
-
-	log.Printf("[Info][Watch] starting watch on collection: %s", req.Collection)
+	log.Printf("[Info][Watch] starting watch on collection: %s resumed=%t", req.Collection, resumeToken != nil)
 
 	encoder := json.NewEncoder(w)
 
@@ -85,7 +114,15 @@ func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
 				log.Println("[Info][Watch] watch stream closed")
 				return
 			}
-			if err := encoder.Encode(evt); err != nil {
+			out := watchEvent{Event: evt}
+			if evt.ResumeToken != nil {
+				token, err := encodeResumeToken(evt.ResumeToken)
+				if err != nil {
+					log.Println("[Warning][Watch] failed to encode resume token:", err)
+				}
+				out.ResumeToken = token
+			}
+			if err := encoder.Encode(out); err != nil {
 				log.Println("[Error][Watch] failed to encode event:", err)
 				return
 			}
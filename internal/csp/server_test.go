@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,6 +13,8 @@ import (
 	"github.com/codetrek/syntrix/pkg/model"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type fakeStorage struct{}
@@ -112,10 +115,73 @@ func TestServerHandleWatch_WatchError(t *testing.T) {
 
 	srv.ServeHTTP(w, req)
 
-	// Headers are flushed before Watch, so status is 200.
-	// But body should be empty as Watch failed.
+	// Watch starts before headers are sent, so the failure has a status.
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+type resumeStorage struct {
+	fakeStorage
+	token interface{}
+	err   error
+}
+
+func (f *resumeStorage) Watch(ctx context.Context, tenant string, collection string, resumeToken interface{}, opts storage.WatchOptions) (<-chan storage.Event, error) {
+	f.token = resumeToken
+	if f.err != nil {
+		return nil, f.err
+	}
+	ch := make(chan storage.Event, 1)
+	ch <- storage.Event{Id: "users/2", Type: storage.EventCreate, ResumeToken: bson.M{"_data": "82B2"}}
+	close(ch)
+	return ch, nil
+}
+
+func TestServerHandleWatch_ResumeAfter(t *testing.T) {
+	st := &resumeStorage{}
+	srv := NewServer(st)
+	after, err := encodeResumeToken(bson.M{"_data": "82A1"})
+	require.NoError(t, err)
+	b, _ := json.Marshal(watchRequest{Collection: "users", ResumeAfter: after})
+	req := httptest.NewRequest(http.MethodPost, "/internal/v1/watch", bytes.NewReader(b))
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, req)
+
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Empty(t, w.Body.String())
+	raw, ok := st.token.(bson.Raw)
+	require.True(t, ok)
+	assert.Equal(t, "82A1", raw.Lookup("_data").StringValue())
+
+	// Each event carries the token to resume after it
+	var evt watchEvent
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&evt))
+	assert.Equal(t, "users/2", evt.Id)
+	token, err := decodeResumeToken(evt.ResumeToken)
+	require.NoError(t, err)
+	assert.Equal(t, "82B2", token.Lookup("_data").StringValue())
+}
+
+func TestServerHandleWatch_ResumeTokenExpired(t *testing.T) {
+	srv := NewServer(&resumeStorage{err: fmt.Errorf("%w: history lost", storage.ErrResumeTokenExpired)})
+	after, _ := encodeResumeToken(bson.M{"_data": "82A1"})
+	b, _ := json.Marshal(watchRequest{Collection: "users", ResumeAfter: after})
+	req := httptest.NewRequest(http.MethodPost, "/internal/v1/watch", bytes.NewReader(b))
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+}
+
+func TestServerHandleWatch_InvalidResumeToken(t *testing.T) {
+	srv := NewServer(&fakeStorage{})
+	b, _ := json.Marshal(watchRequest{Collection: "users", ResumeAfter: "!!"})
+	req := httptest.NewRequest(http.MethodPost, "/internal/v1/watch", bytes.NewReader(b))
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 type blockingStorage struct {
@@ -0,0 +1,31 @@
+package csp
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// encodeResumeToken turns a backend resume token into the opaque string sent
+// over the wire. Tokens are BSON documents (e.g. MongoDB's {_data: ...}).
+func encodeResumeToken(token interface{}) (string, error) {
+	raw, err := bson.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("invalid resume token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeResumeToken reverses encodeResumeToken.
+func decodeResumeToken(s string) (bson.Raw, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resume token: %w", err)
+	}
+	raw := bson.Raw(b)
+	if err := raw.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid resume token: %w", err)
+	}
+	return raw, nil
+}
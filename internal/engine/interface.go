@@ -4,10 +4,13 @@ import (
 	"context"
 	"net/http"
 
+	enginev1 "github.com/codetrek/syntrix/api/engine/v1"
 	"github.com/codetrek/syntrix/internal/csp"
 	"github.com/codetrek/syntrix/internal/engine/internal/client"
 	"github.com/codetrek/syntrix/internal/engine/internal/core"
+	enginegrpc "github.com/codetrek/syntrix/internal/engine/internal/grpc"
 	"github.com/codetrek/syntrix/internal/engine/internal/httphandler"
+	"github.com/codetrek/syntrix/internal/server"
 	"github.com/codetrek/syntrix/internal/storage"
 	"github.com/codetrek/syntrix/pkg/model"
 )
@@ -44,6 +47,20 @@ func NewClient(baseURL string) Service {
 	return client.New(baseURL)
 }
 
+// GRPCClient is a remote Query Service client speaking gRPC.
+type GRPCClient = enginegrpc.Client
+
+// NewGRPCClient creates a remote Query Service client (gRPC client).
+// Close it to release the connection.
+func NewGRPCClient(address string) (*GRPCClient, error) {
+	return enginegrpc.NewClient(address)
+}
+
+// RegisterGRPC exposes the Query Service on the shared gRPC server.
+func RegisterGRPC(s Service) {
+	server.RegisterGRPC(&enginev1.QueryService_ServiceDesc, enginegrpc.NewServer(s))
+}
+
 // NewHTTPHandler creates an HTTP handler for the Query Service.
 // The handler exposes the Service interface over HTTP.
 func NewHTTPHandler(s Service) http.Handler {
@@ -0,0 +1,220 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	enginev1 "github.com/codetrek/syntrix/api/engine/v1"
+	"github.com/codetrek/syntrix/internal/server"
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Client is a gRPC client for the query service.
+//
+// Deadlines of the calling context are propagated to the server, as is the
+// request ID set with server.WithRequestID. Errors are mapped back to the
+// engine's errors (model.ErrNotFound, model.ErrPreconditionFailed, ...), so
+// callers can keep using errors.Is.
+type Client struct {
+	conn   *grpc.ClientConn
+	client enginev1.QueryServiceClient
+}
+
+// NewClient creates a client for the query service at address.
+func NewClient(address string) (*Client, error) {
+	conn, err := grpc.NewClient(address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(server.RequestIDUnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(server.RequestIDStreamClientInterceptor),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to query service: %w", err)
+	}
+	return newClient(conn), nil
+}
+
+func newClient(conn *grpc.ClientConn) *Client {
+	return &Client{
+		conn:   conn,
+		client: enginev1.NewQueryServiceClient(conn),
+	}
+}
+
+// Close closes the client connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func decodeDocument(b []byte) (model.Document, error) {
+	var doc model.Document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	return doc, nil
+}
+
+func (c *Client) GetDocument(ctx context.Context, tenant string, path string) (model.Document, error) {
+	resp, err := c.client.GetDocument(ctx, &enginev1.GetDocumentRequest{Tenant: tenant, Path: path})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return decodeDocument(resp.GetDocument())
+}
+
+func (c *Client) CreateDocument(ctx context.Context, tenant string, doc model.Document) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.CreateDocument(ctx, &enginev1.CreateDocumentRequest{Tenant: tenant, Document: b})
+	return fromStatus(err)
+}
+
+func (c *Client) ReplaceDocument(ctx context.Context, tenant string, data model.Document, pred model.Filters) (model.Document, error) {
+	req, err := writeRequest(tenant, data, pred)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.ReplaceDocument(ctx, req)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return decodeDocument(resp.GetDocument())
+}
+
+func (c *Client) PatchDocument(ctx context.Context, tenant string, data model.Document, pred model.Filters) (model.Document, error) {
+	req, err := writeRequest(tenant, data, pred)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.PatchDocument(ctx, req)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return decodeDocument(resp.GetDocument())
+}
+
+func writeRequest(tenant string, data model.Document, pred model.Filters) (*enginev1.WriteDocumentRequest, error) {
+	doc, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := json.Marshal(pred)
+	if err != nil {
+		return nil, err
+	}
+	return &enginev1.WriteDocumentRequest{Tenant: tenant, Document: doc, Filters: filters}, nil
+}
+
+func (c *Client) DeleteDocument(ctx context.Context, tenant string, path string, pred model.Filters) error {
+	filters, err := json.Marshal(pred)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.DeleteDocument(ctx, &enginev1.DeleteDocumentRequest{Tenant: tenant, Path: path, Filters: filters})
+	return fromStatus(err)
+}
+
+func (c *Client) ExecuteQuery(ctx context.Context, tenant string, q model.Query) ([]model.Document, error) {
+	query, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.ExecuteQuery(ctx, &enginev1.ExecuteQueryRequest{Tenant: tenant, Query: query})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	docs := make([]model.Document, 0, len(resp.GetDocuments()))
+	for _, b := range resp.GetDocuments() {
+		doc, err := decodeDocument(b)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// WatchCollection returns a channel of change events. An error starting the
+// watch on the server is returned here; the channel is closed when the
+// stream ends or ctx is cancelled.
+func (c *Client) WatchCollection(ctx context.Context, tenant string, collection string) (<-chan storage.Event, error) {
+	stream, err := c.client.WatchCollection(ctx, &enginev1.WatchCollectionRequest{Tenant: tenant, Collection: collection})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+
+	// The server sends headers once the watch is established.
+	md, err := stream.Header()
+	if err == nil && md == nil {
+		// Terminated without headers; the status comes from Recv.
+		_, err = stream.Recv()
+	}
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+
+	out := make(chan storage.Event)
+	go func() {
+		defer close(out)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					log.Printf("[Warning][Engine gRPC] Watch stream ended: %v", err)
+				}
+				return
+			}
+			var evt storage.Event
+			if err := json.Unmarshal(msg.GetEvent(), &evt); err != nil {
+				log.Printf("[Error][Engine gRPC] Watch decode event failed: %v", err)
+				continue
+			}
+			select {
+			case out <- evt:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (c *Client) Pull(ctx context.Context, tenant string, req storage.ReplicationPullRequest) (*storage.ReplicationPullResponse, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Pull(ctx, &enginev1.PullRequest{Tenant: tenant, Request: b})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	var result storage.ReplicationPullResponse
+	if err := json.Unmarshal(resp.GetResponse(), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode pull response: %w", err)
+	}
+	return &result, nil
+}
+
+func (c *Client) Push(ctx context.Context, tenant string, req storage.ReplicationPushRequest) (*storage.ReplicationPushResponse, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Push(ctx, &enginev1.PushRequest{Tenant: tenant, Request: b})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	var result storage.ReplicationPushResponse
+	if err := json.Unmarshal(resp.GetResponse(), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode push response: %w", err)
+	}
+	return &result, nil
+}
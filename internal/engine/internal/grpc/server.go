@@ -0,0 +1,223 @@
+// Package grpc exposes the query engine over gRPC and implements a
+// gRPC-backed client for it.
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	enginev1 "github.com/codetrek/syntrix/api/engine/v1"
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Service defines the interface required by the gRPC server.
+type Service interface {
+	GetDocument(ctx context.Context, tenant string, path string) (model.Document, error)
+	CreateDocument(ctx context.Context, tenant string, doc model.Document) error
+	ReplaceDocument(ctx context.Context, tenant string, data model.Document, pred model.Filters) (model.Document, error)
+	PatchDocument(ctx context.Context, tenant string, data model.Document, pred model.Filters) (model.Document, error)
+	DeleteDocument(ctx context.Context, tenant string, path string, pred model.Filters) error
+	ExecuteQuery(ctx context.Context, tenant string, q model.Query) ([]model.Document, error)
+	WatchCollection(ctx context.Context, tenant string, collection string) (<-chan storage.Event, error)
+	Pull(ctx context.Context, tenant string, req storage.ReplicationPullRequest) (*storage.ReplicationPullResponse, error)
+	Push(ctx context.Context, tenant string, req storage.ReplicationPushRequest) (*storage.ReplicationPushResponse, error)
+}
+
+// Server implements the QueryService gRPC interface on top of a Service.
+type Server struct {
+	enginev1.UnimplementedQueryServiceServer
+
+	service Service
+}
+
+// NewServer creates a gRPC server for the given service.
+func NewServer(service Service) *Server {
+	return &Server{service: service}
+}
+
+func tenantOrDefault(t string) string {
+	if t == "" {
+		return model.DefaultTenantID
+	}
+	return t
+}
+
+// decode unmarshals an optional JSON field of a request.
+func decode(data []byte, v interface{}, field string) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid %s: %v", field, err)
+	}
+	return nil
+}
+
+func encode(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	return b, nil
+}
+
+func documentResponse(doc model.Document) (*enginev1.DocumentResponse, error) {
+	b, err := encode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &enginev1.DocumentResponse{Document: b}, nil
+}
+
+func (s *Server) GetDocument(ctx context.Context, req *enginev1.GetDocumentRequest) (*enginev1.DocumentResponse, error) {
+	doc, err := s.service.GetDocument(ctx, tenantOrDefault(req.GetTenant()), req.GetPath())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return documentResponse(doc)
+}
+
+func (s *Server) CreateDocument(ctx context.Context, req *enginev1.CreateDocumentRequest) (*enginev1.Empty, error) {
+	var doc model.Document
+	if err := decode(req.GetDocument(), &doc, "document"); err != nil {
+		return nil, err
+	}
+	if err := s.service.CreateDocument(ctx, tenantOrDefault(req.GetTenant()), doc); err != nil {
+		return nil, toStatus(err)
+	}
+	return &enginev1.Empty{}, nil
+}
+
+func (s *Server) ReplaceDocument(ctx context.Context, req *enginev1.WriteDocumentRequest) (*enginev1.DocumentResponse, error) {
+	var doc model.Document
+	var pred model.Filters
+	if err := decode(req.GetDocument(), &doc, "document"); err != nil {
+		return nil, err
+	}
+	if err := decode(req.GetFilters(), &pred, "filters"); err != nil {
+		return nil, err
+	}
+	out, err := s.service.ReplaceDocument(ctx, tenantOrDefault(req.GetTenant()), doc, pred)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return documentResponse(out)
+}
+
+func (s *Server) PatchDocument(ctx context.Context, req *enginev1.WriteDocumentRequest) (*enginev1.DocumentResponse, error) {
+	var doc model.Document
+	var pred model.Filters
+	if err := decode(req.GetDocument(), &doc, "document"); err != nil {
+		return nil, err
+	}
+	if err := decode(req.GetFilters(), &pred, "filters"); err != nil {
+		return nil, err
+	}
+	out, err := s.service.PatchDocument(ctx, tenantOrDefault(req.GetTenant()), doc, pred)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return documentResponse(out)
+}
+
+func (s *Server) DeleteDocument(ctx context.Context, req *enginev1.DeleteDocumentRequest) (*enginev1.Empty, error) {
+	var pred model.Filters
+	if err := decode(req.GetFilters(), &pred, "filters"); err != nil {
+		return nil, err
+	}
+	if err := s.service.DeleteDocument(ctx, tenantOrDefault(req.GetTenant()), req.GetPath(), pred); err != nil {
+		return nil, toStatus(err)
+	}
+	return &enginev1.Empty{}, nil
+}
+
+func (s *Server) ExecuteQuery(ctx context.Context, req *enginev1.ExecuteQueryRequest) (*enginev1.ExecuteQueryResponse, error) {
+	var q model.Query
+	if err := decode(req.GetQuery(), &q, "query"); err != nil {
+		return nil, err
+	}
+	docs, err := s.service.ExecuteQuery(ctx, tenantOrDefault(req.GetTenant()), q)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &enginev1.ExecuteQueryResponse{Documents: make([][]byte, 0, len(docs))}
+	for _, doc := range docs {
+		b, err := encode(doc)
+		if err != nil {
+			return nil, err
+		}
+		resp.Documents = append(resp.Documents, b)
+	}
+	return resp, nil
+}
+
+// WatchCollection streams change events. Empty tenant and collection watch
+// everything, as with the local service. Headers are sent once the watch is
+// established, so clients can tell a failed start from a quiet stream.
+func (s *Server) WatchCollection(req *enginev1.WatchCollectionRequest, stream grpc.ServerStreamingServer[enginev1.WatchEvent]) error {
+	ctx := stream.Context()
+	events, err := s.service.WatchCollection(ctx, req.GetTenant(), req.GetCollection())
+	if err != nil {
+		return toStatus(err)
+	}
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case evt, ok := <-events:
+			if !ok {
+				return nil
+			}
+			b, err := encode(evt)
+			if err != nil {
+				log.Printf("[Error][Engine gRPC] Failed to encode event: %v", err)
+				continue
+			}
+			if err := stream.Send(&enginev1.WatchEvent{Event: b}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) Pull(ctx context.Context, req *enginev1.PullRequest) (*enginev1.PullResponse, error) {
+	var pullReq storage.ReplicationPullRequest
+	if err := decode(req.GetRequest(), &pullReq, "request"); err != nil {
+		return nil, err
+	}
+	resp, err := s.service.Pull(ctx, tenantOrDefault(req.GetTenant()), pullReq)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	b, err := encode(resp)
+	if err != nil {
+		return nil, err
+	}
+	return &enginev1.PullResponse{Response: b}, nil
+}
+
+func (s *Server) Push(ctx context.Context, req *enginev1.PushRequest) (*enginev1.PushResponse, error) {
+	var pushReq storage.ReplicationPushRequest
+	if err := decode(req.GetRequest(), &pushReq, "request"); err != nil {
+		return nil, err
+	}
+	resp, err := s.service.Push(ctx, tenantOrDefault(req.GetTenant()), pushReq)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	b, err := encode(resp)
+	if err != nil {
+		return nil, err
+	}
+	return &enginev1.PushResponse{Response: b}, nil
+}
@@ -0,0 +1,282 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	enginev1 "github.com/codetrek/syntrix/api/engine/v1"
+	"github.com/codetrek/syntrix/internal/server"
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type mockService struct {
+	mock.Mock
+}
+
+func (m *mockService) GetDocument(ctx context.Context, tenant string, path string) (model.Document, error) {
+	args := m.Called(ctx, tenant, path)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(model.Document), args.Error(1)
+}
+
+func (m *mockService) CreateDocument(ctx context.Context, tenant string, doc model.Document) error {
+	return m.Called(ctx, tenant, doc).Error(0)
+}
+
+func (m *mockService) ReplaceDocument(ctx context.Context, tenant string, data model.Document, pred model.Filters) (model.Document, error) {
+	args := m.Called(ctx, tenant, data, pred)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(model.Document), args.Error(1)
+}
+
+func (m *mockService) PatchDocument(ctx context.Context, tenant string, data model.Document, pred model.Filters) (model.Document, error) {
+	args := m.Called(ctx, tenant, data, pred)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(model.Document), args.Error(1)
+}
+
+func (m *mockService) DeleteDocument(ctx context.Context, tenant string, path string, pred model.Filters) error {
+	return m.Called(ctx, tenant, path, pred).Error(0)
+}
+
+func (m *mockService) ExecuteQuery(ctx context.Context, tenant string, q model.Query) ([]model.Document, error) {
+	args := m.Called(ctx, tenant, q)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Document), args.Error(1)
+}
+
+func (m *mockService) WatchCollection(ctx context.Context, tenant string, collection string) (<-chan storage.Event, error) {
+	args := m.Called(ctx, tenant, collection)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(<-chan storage.Event), args.Error(1)
+}
+
+func (m *mockService) Pull(ctx context.Context, tenant string, req storage.ReplicationPullRequest) (*storage.ReplicationPullResponse, error) {
+	args := m.Called(ctx, tenant, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.ReplicationPullResponse), args.Error(1)
+}
+
+func (m *mockService) Push(ctx context.Context, tenant string, req storage.ReplicationPushRequest) (*storage.ReplicationPushResponse, error) {
+	args := m.Called(ctx, tenant, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.ReplicationPushResponse), args.Error(1)
+}
+
+// startTestServer serves svc over an in-memory listener and returns a client
+// for it. Incoming metadata of each unary call is sent to mdCh if not nil.
+func startTestServer(t *testing.T, svc Service, mdCh chan<- metadata.MD) *Client {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if mdCh != nil {
+			md, _ := metadata.FromIncomingContext(ctx)
+			mdCh <- md
+		}
+		return handler(ctx, req)
+	}))
+	enginev1.RegisterQueryServiceServer(s, NewServer(svc))
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(server.RequestIDUnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(server.RequestIDStreamClientInterceptor),
+	)
+	require.NoError(t, err)
+	c := newClient(conn)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestClient_Documents(t *testing.T) {
+	svc := new(mockService)
+	c := startTestServer(t, svc, nil)
+	ctx := context.Background()
+
+	doc := model.Document{"id": "1", "collection": "users", "name": "Alice"}
+	pred := model.Filters{{Field: "version", Op: "==", Value: float64(1)}}
+
+	svc.On("GetDocument", mock.Anything, "default", "users/1").Return(doc, nil).Once()
+	got, err := c.GetDocument(ctx, "", "users/1")
+	require.NoError(t, err)
+	assert.Equal(t, doc, got)
+
+	svc.On("CreateDocument", mock.Anything, "t1", doc).Return(nil).Once()
+	require.NoError(t, c.CreateDocument(ctx, "t1", doc))
+
+	svc.On("ReplaceDocument", mock.Anything, "t1", doc, pred).Return(doc, nil).Once()
+	got, err = c.ReplaceDocument(ctx, "t1", doc, pred)
+	require.NoError(t, err)
+	assert.Equal(t, doc, got)
+
+	svc.On("PatchDocument", mock.Anything, "t1", doc, model.Filters(nil)).Return(doc, nil).Once()
+	got, err = c.PatchDocument(ctx, "t1", doc, nil)
+	require.NoError(t, err)
+	assert.Equal(t, doc, got)
+
+	svc.On("DeleteDocument", mock.Anything, "t1", "users/1", pred).Return(nil).Once()
+	require.NoError(t, c.DeleteDocument(ctx, "t1", "users/1", pred))
+
+	q := model.Query{Collection: "users", Limit: 10}
+	svc.On("ExecuteQuery", mock.Anything, "t1", q).Return([]model.Document{doc}, nil).Once()
+	docs, err := c.ExecuteQuery(ctx, "t1", q)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Document{doc}, docs)
+
+	svc.AssertExpectations(t)
+}
+
+func TestClient_Replication(t *testing.T) {
+	svc := new(mockService)
+	c := startTestServer(t, svc, nil)
+	ctx := context.Background()
+
+	pullReq := storage.ReplicationPullRequest{Collection: "users", Checkpoint: 5, Limit: 10}
+	pullResp := &storage.ReplicationPullResponse{Checkpoint: 7}
+	svc.On("Pull", mock.Anything, "t1", pullReq).Return(pullResp, nil).Once()
+	gotPull, err := c.Pull(ctx, "t1", pullReq)
+	require.NoError(t, err)
+	assert.Equal(t, pullResp, gotPull)
+
+	pushReq := storage.ReplicationPushRequest{Collection: "users"}
+	pushResp := &storage.ReplicationPushResponse{}
+	svc.On("Push", mock.Anything, "t1", pushReq).Return(pushResp, nil).Once()
+	gotPush, err := c.Push(ctx, "t1", pushReq)
+	require.NoError(t, err)
+	assert.Equal(t, pushResp, gotPush)
+
+	svc.AssertExpectations(t)
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	svc := new(mockService)
+	c := startTestServer(t, svc, nil)
+	ctx := context.Background()
+
+	svc.On("GetDocument", mock.Anything, "default", "users/missing").Return(nil, model.ErrNotFound)
+	_, err := c.GetDocument(ctx, "", "users/missing")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	svc.On("CreateDocument", mock.Anything, "default", mock.Anything).Return(model.ErrExists)
+	assert.ErrorIs(t, c.CreateDocument(ctx, "", model.Document{"id": "1"}), model.ErrExists)
+
+	svc.On("DeleteDocument", mock.Anything, "default", "users/1", mock.Anything).Return(model.ErrPreconditionFailed)
+	assert.ErrorIs(t, c.DeleteDocument(ctx, "", "users/1", nil), model.ErrPreconditionFailed)
+
+	svc.On("ExecuteQuery", mock.Anything, "default", mock.Anything).Return(nil, assert.AnError)
+	_, err = c.ExecuteQuery(ctx, "", model.Query{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), assert.AnError.Error())
+}
+
+func TestClient_DeadlineAndRequestID(t *testing.T) {
+	svc := new(mockService)
+	mdCh := make(chan metadata.MD, 1)
+	c := startTestServer(t, svc, mdCh)
+
+	var serverDeadline time.Time
+	svc.On("GetDocument", mock.Anything, "default", "users/1").
+		Run(func(args mock.Arguments) {
+			serverDeadline, _ = args.Get(0).(context.Context).Deadline()
+		}).
+		Return(model.Document{"id": "1"}, nil)
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	ctx = server.WithRequestID(ctx, "req-123")
+
+	_, err := c.GetDocument(ctx, "", "users/1")
+	require.NoError(t, err)
+
+	assert.WithinDuration(t, deadline, serverDeadline, time.Second)
+	md := <-mdCh
+	assert.Equal(t, []string{"req-123"}, md.Get(server.RequestIDMetadataKey))
+}
+
+func TestClient_DeadlineExceeded(t *testing.T) {
+	svc := new(mockService)
+	c := startTestServer(t, svc, nil)
+
+	svc.On("GetDocument", mock.Anything, "default", "users/slow").
+		Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(nil, context.DeadlineExceeded)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.GetDocument(ctx, "", "users/slow")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_WatchCollection(t *testing.T) {
+	svc := new(mockService)
+	c := startTestServer(t, svc, nil)
+
+	events := make(chan storage.Event, 1)
+	events <- storage.Event{Id: "users/1", Type: storage.EventCreate}
+	svc.On("WatchCollection", mock.Anything, "t1", "users").Return((<-chan storage.Event)(events), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.WatchCollection(ctx, "t1", "users")
+	require.NoError(t, err)
+
+	select {
+	case evt := <-stream:
+		assert.Equal(t, "users/1", evt.Id)
+		assert.Equal(t, storage.EventCreate, evt.Type)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	close(events)
+	select {
+	case _, ok := <-stream:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("stream not closed")
+	}
+}
+
+func TestClient_WatchCollection_StartError(t *testing.T) {
+	svc := new(mockService)
+	c := startTestServer(t, svc, nil)
+
+	svc.On("WatchCollection", mock.Anything, "t1", "users").Return(nil, model.ErrPermissionDenied)
+
+	stream, err := c.WatchCollection(context.Background(), "t1", "users")
+	assert.Nil(t, stream)
+	assert.ErrorIs(t, err, model.ErrPermissionDenied)
+}
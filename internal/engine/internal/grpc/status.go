@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/codetrek/syntrix/pkg/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusCodes maps engine errors to gRPC codes and back.
+var statusCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{model.ErrNotFound, codes.NotFound},
+	{model.ErrExists, codes.AlreadyExists},
+	{model.ErrPreconditionFailed, codes.FailedPrecondition},
+	{model.ErrInvalidQuery, codes.InvalidArgument},
+	{model.ErrPermissionDenied, codes.PermissionDenied},
+	{model.ErrIndexNotReady, codes.Unavailable},
+}
+
+// toStatus converts an engine error into a gRPC status error.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	for _, sc := range statusCodes {
+		if errors.Is(err, sc.err) {
+			return status.Error(sc.code, err.Error())
+		}
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// fromStatus converts a gRPC status error into the matching engine error, so
+// callers can keep using errors.Is.
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.Canceled:
+		return fmt.Errorf("%w: %s", context.Canceled, st.Message())
+	case codes.DeadlineExceeded:
+		return fmt.Errorf("%w: %s", context.DeadlineExceeded, st.Message())
+	}
+	for _, sc := range statusCodes {
+		if st.Code() == sc.code {
+			return fmt.Errorf("%w: %s", sc.err, st.Message())
+		}
+	}
+	return err
+}
@@ -222,15 +222,16 @@ func (h *Handler) handleWatchCollection(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	flusher.Flush()
 	tenant := tenantOrDefault(req.Tenant)
 	stream, err := h.service.WatchCollection(r.Context(), tenant, req.Collection)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
 	encoder := json.NewEncoder(w)
 	for {
 		select {
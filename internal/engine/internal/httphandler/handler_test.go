@@ -525,8 +525,9 @@ func TestHandler_WatchCollection_ServiceError(t *testing.T) {
 
 	handler.ServeHTTP(w, req)
 
-	// Should return 200 OK with headers set before error, then exit
-	assert.Equal(t, http.StatusOK, w.Code)
+	// The watch starts before headers are sent, so the error is reported
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), assert.AnError.Error())
 	mockService.AssertExpectations(t)
 }
 
@@ -1,6 +1,8 @@
 package identity
 
 import (
+	"time"
+
 	"github.com/codetrek/syntrix/internal/config"
 	"github.com/codetrek/syntrix/internal/engine"
 	"github.com/codetrek/syntrix/internal/identity/internal/authn"
@@ -30,6 +32,12 @@ func NewAuthN(cfg config.AuthNConfig, users storage.UserStore, revocations stora
 	return authn.NewAuthService(cfg, users, revocations)
 }
 
+// NotifyRevocations wraps a revocation store so that notify learns of every
+// token revoked immediately, such as on logout.
+func NotifyRevocations(store storage.TokenRevocationStore, notify func(tenant, jti string, expiresAt time.Time)) storage.TokenRevocationStore {
+	return authn.NotifyRevocations(store, notify)
+}
+
 // NewAuthZ creates a new authorization engine.
 func NewAuthZ(cfg config.AuthZConfig, qs engine.Service) (AuthZ, error) {
 	return authz.NewEngine(cfg, qs)
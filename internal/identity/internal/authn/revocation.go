@@ -0,0 +1,28 @@
+package authn
+
+import (
+	"context"
+	"time"
+)
+
+// notifyingRevocations reports immediate revocations of a store as they
+// succeed.
+type notifyingRevocations struct {
+	TokenRevocationStore
+	notify func(tenant, jti string, expiresAt time.Time)
+}
+
+// NotifyRevocations wraps store so that notify is called after every
+// successful immediate revocation. Revocations with a grace period, as done
+// when a refresh token is rotated, are not reported.
+func NotifyRevocations(store TokenRevocationStore, notify func(tenant, jti string, expiresAt time.Time)) TokenRevocationStore {
+	return &notifyingRevocations{TokenRevocationStore: store, notify: notify}
+}
+
+func (s *notifyingRevocations) RevokeTokenImmediate(ctx context.Context, tenant string, jti string, expiresAt time.Time) error {
+	if err := s.TokenRevocationStore.RevokeTokenImmediate(ctx, tenant, jti, expiresAt); err != nil {
+		return err
+	}
+	s.notify(tenant, jti, expiresAt)
+	return nil
+}
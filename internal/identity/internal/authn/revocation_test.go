@@ -0,0 +1,34 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNotifyRevocations(t *testing.T) {
+	store := new(MockStorage)
+	var notified []string
+	revocations := NotifyRevocations(store, func(tenant, jti string, expiresAt time.Time) {
+		notified = append(notified, tenant+"/"+jti)
+	})
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour)
+
+	store.On("RevokeTokenImmediate", mock.Anything, "t1", "j1", exp).Return(nil).Once()
+	assert.NoError(t, revocations.RevokeTokenImmediate(ctx, "t1", "j1", exp))
+
+	store.On("RevokeTokenImmediate", mock.Anything, "t1", "j2", exp).Return(errors.New("down")).Once()
+	assert.Error(t, revocations.RevokeTokenImmediate(ctx, "t1", "j2", exp))
+
+	// Rotation keeps a grace period and is not reported.
+	store.On("RevokeToken", mock.Anything, "t1", "j3", exp).Return(nil).Once()
+	assert.NoError(t, revocations.RevokeToken(ctx, "t1", "j3", exp))
+
+	assert.Equal(t, []string{"t1/j1"}, notified)
+	store.AssertExpectations(t)
+}
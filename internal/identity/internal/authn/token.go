@@ -115,11 +115,12 @@ func (s *TokenService) GenerateTokenPair(user *User) (*TokenPair, error) {
 
 	// Access Token
 	accessClaims := Claims{
-		Username: user.Username,
-		Roles:    user.Roles,
-		Disabled: user.Disabled,
-		TenantID: tenantID,
-		UserID:   user.ID,
+		Username:  user.Username,
+		Roles:     user.Roles,
+		Disabled:  user.Disabled,
+		TenantID:  tenantID,
+		UserID:    user.ID,
+		SessionID: jti,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   user.ID,
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
@@ -139,9 +140,10 @@ func (s *TokenService) GenerateTokenPair(user *User) (*TokenPair, error) {
 
 	// Refresh Token
 	refreshClaims := Claims{
-		Username: user.Username,
-		TenantID: tenantID,
-		UserID:   user.ID,
+		Username:  user.Username,
+		TenantID:  tenantID,
+		UserID:    user.ID,
+		SessionID: jti,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   user.ID,
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.refreshTTL)),
@@ -51,6 +51,11 @@ func TestTokenService_GenerateAndValidate(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, user.ID, refreshClaims.Subject)
 	assert.Equal(t, user.Username, refreshClaims.Username)
+
+	// Both tokens belong to the session of the refresh token.
+	assert.Equal(t, refreshClaims.ID, refreshClaims.SessionID)
+	assert.Equal(t, refreshClaims.ID, claims.SessionID)
+	assert.NotEqual(t, refreshClaims.ID, claims.ID)
 }
 
 func TestTokenService_ExpiredToken(t *testing.T) {
@@ -27,6 +27,10 @@ type Claims struct {
 	Disabled bool     `json:"disabled"`
 	TenantID string   `json:"tid"`
 	UserID   string   `json:"oid"`
+
+	// SessionID is the JTI of the refresh token issued with the token.
+	// Revoking it ends the session of both tokens.
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
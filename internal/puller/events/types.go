@@ -3,6 +3,8 @@
 package events
 
 import (
+	"errors"
+
 	"github.com/codetrek/syntrix/internal/storage"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -152,6 +154,10 @@ func uintToString(v uint32) string {
 	return string(buf[i:])
 }
 
+// ErrPositionExpired is returned by Replay when the requested position is
+// older than the oldest event still held in the buffer.
+var ErrPositionExpired = errors.New("replay position is outside buffer retention")
+
 // Iterator provides ordered iteration over events.
 type Iterator interface {
 	// Next advances to the next event. Returns false when done.
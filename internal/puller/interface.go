@@ -44,8 +44,10 @@ import (
 	"github.com/codetrek/syntrix/internal/puller/events"
 	"github.com/codetrek/syntrix/internal/puller/internal/client"
 	"github.com/codetrek/syntrix/internal/puller/internal/core"
+	"github.com/codetrek/syntrix/internal/puller/internal/cursor"
 	pullergrpc "github.com/codetrek/syntrix/internal/puller/internal/grpc"
 	"github.com/codetrek/syntrix/internal/puller/internal/health"
+	"github.com/codetrek/syntrix/internal/puller/internal/normalizer"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -153,6 +155,27 @@ type TruncatedArray = events.TruncatedArray
 type ClusterTime = events.ClusterTime
 type OperationType = events.OperationType
 type Iterator = events.Iterator
+type ProgressMarker = cursor.ProgressMarker
+
+// ErrPositionExpired is returned by Replay when a position has been cleaned
+// from the buffer and events after it may be missing.
+var ErrPositionExpired = events.ErrPositionExpired
+
+// NewProgressMarker creates an empty progress marker.
+func NewProgressMarker() *ProgressMarker {
+	return cursor.NewProgressMarker()
+}
+
+// DecodeProgressMarker decodes a progress marker from its string form.
+func DecodeProgressMarker(s string) (*ProgressMarker, error) {
+	return cursor.DecodeProgressMarker(s)
+}
+
+// FormatEventID returns the event ID the puller assigns to a change of docID
+// in the given Mongo collection at cluster time ct.
+func FormatEventID(ct ClusterTime, collection, docID string) string {
+	return normalizer.FormatEventID(ct, collection, docID)
+}
 
 const (
 	OperationInsert  = events.OperationInsert
@@ -12,7 +12,9 @@ import (
 	"github.com/codetrek/syntrix/internal/puller/events"
 	"github.com/codetrek/syntrix/internal/storage"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 // Client is a gRPC client for the puller service.
@@ -43,58 +45,44 @@ func New(address string, logger *slog.Logger) (*Client, error) {
 // Subscribe subscribes to events from the puller service.
 // The after parameter is the progress marker to resume from.
 // Returns a channel of events that will be closed when the subscription ends.
+// A marker the puller no longer buffers fails with events.ErrPositionExpired.
 func (c *Client) Subscribe(ctx context.Context, consumerID string, after string) (<-chan *events.PullerEvent, error) {
-	req := &pullerv1.SubscribeRequest{
+	return c.subscribe(ctx, &pullerv1.SubscribeRequest{
 		ConsumerId: consumerID,
 		After:      after,
-	}
-
-	stream, err := c.client.Subscribe(ctx, req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe: %w", err)
-	}
-
-	ch := make(chan *events.PullerEvent, 1000)
-
-	go func() {
-		defer close(ch)
-
-		for {
-			evt, err := stream.Recv()
-			if err != nil {
-				if err == io.EOF {
-					c.logger.Info("subscription stream closed")
-					return
-				}
-				c.logger.Error("subscription stream error", "error", err)
-				return
-			}
-
-			normalized := c.convertEvent(evt)
-			select {
-			case ch <- normalized:
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
-
-	return ch, nil
+	})
 }
 
 // SubscribeWithCoalesce subscribes with catch-up coalescing enabled.
 func (c *Client) SubscribeWithCoalesce(ctx context.Context, consumerID string, after string) (<-chan *events.PullerEvent, error) {
-	req := &pullerv1.SubscribeRequest{
+	return c.subscribe(ctx, &pullerv1.SubscribeRequest{
 		ConsumerId:        consumerID,
 		After:             after,
 		CoalesceOnCatchUp: true,
-	}
+	})
+}
 
+func (c *Client) subscribe(ctx context.Context, req *pullerv1.SubscribeRequest) (<-chan *events.PullerEvent, error) {
 	stream, err := c.client.Subscribe(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to subscribe: %w", err)
 	}
 
+	if req.GetAfter() != "" {
+		// The server sends headers once it has accepted the position.
+		md, err := stream.Header()
+		if err == nil && md == nil {
+			// Terminated without headers; the status comes from Recv.
+			_, err = stream.Recv()
+		}
+		if err != nil {
+			if status.Code(err) == codes.OutOfRange {
+				return nil, fmt.Errorf("failed to subscribe: %w", events.ErrPositionExpired)
+			}
+			return nil, fmt.Errorf("failed to subscribe: %w", err)
+		}
+	}
+
 	ch := make(chan *events.PullerEvent, 1000)
 
 	go func() {
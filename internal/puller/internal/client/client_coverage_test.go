@@ -2,13 +2,19 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"testing"
 	"time"
 
 	pullerv1 "github.com/codetrek/syntrix/api/puller/v1"
+	"github.com/codetrek/syntrix/internal/puller/events"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // mockErrorSubscribeClient implements pullerv1.PullerService_SubscribeClient that returns an error
@@ -123,3 +129,61 @@ func TestClient_SubscribeWithCoalesce_Error(t *testing.T) {
 		t.Error("Expected error from SubscribeWithCoalesce")
 	}
 }
+
+// mockHeaderSubscribeClient returns headers (or none) before its events.
+type mockHeaderSubscribeClient struct {
+	mockErrorSubscribeClient
+	header metadata.MD
+}
+
+func (m *mockHeaderSubscribeClient) Header() (metadata.MD, error) {
+	return m.header, nil
+}
+
+func TestClient_Subscribe_ResumeHandshake(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		stream  *mockHeaderSubscribeClient
+		wantErr error
+	}{
+		{
+			name:   "Accepted",
+			stream: &mockHeaderSubscribeClient{mockErrorSubscribeClient: mockErrorSubscribeClient{err: io.EOF}, header: metadata.Pairs("x-puller-mode", "catchup")},
+		},
+		{
+			name:    "Expired",
+			stream:  &mockHeaderSubscribeClient{mockErrorSubscribeClient: mockErrorSubscribeClient{err: status.Error(codes.OutOfRange, "expired")}},
+			wantErr: events.ErrPositionExpired,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			c := &Client{
+				client: &mockPullerServiceClient{
+					subscribeFunc: func(ctx context.Context, in *pullerv1.SubscribeRequest, opts ...grpc.CallOption) (pullerv1.PullerService_SubscribeClient, error) {
+						return tc.stream, nil
+					},
+				},
+				logger: slog.Default(),
+			}
+
+			ch, err := c.Subscribe(context.Background(), "c1", "marker")
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Subscribe failed: %v", err)
+			}
+			if _, ok := <-ch; ok {
+				t.Error("Channel should be closed without events")
+			}
+		})
+	}
+}
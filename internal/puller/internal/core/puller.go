@@ -429,6 +429,19 @@ func (p *Puller) Replay(ctx context.Context, after map[string]string, coalesce b
 					return nil, fmt.Errorf("invalid event ID %q for backend %q: %w", eventID, name, err)
 				}
 				startID = events.FormatBufferKey(ct, eventID)
+
+				// The cleaner trims the buffer from the front, so a start key
+				// older than the first retained key means events were dropped.
+				first, err := backend.buffer.First()
+				if err == nil && (first == "" || first > startID) {
+					err = events.ErrPositionExpired
+				}
+				if err != nil {
+					for _, it := range iters {
+						it.Close()
+					}
+					return nil, fmt.Errorf("backend %q: %w", name, err)
+				}
 			}
 		}
 
@@ -468,6 +481,11 @@ func (i *backendInjectingIterator) Event() *events.ChangeEvent {
 
 // Subscribe subscribes to events from the puller with the given progress marker.
 // Returns a channel of events.
+//
+// When after holds a position, the events buffered since then are replayed
+// before live delivery starts; a position no longer covered by the buffer
+// fails with events.ErrPositionExpired. A subscriber that falls behind while
+// live is caught up from the buffer the same way.
 func (p *Puller) Subscribe(ctx context.Context, consumerID string, after string) (<-chan *events.PullerEvent, error) {
 	pm := cursor.NewProgressMarker()
 
@@ -483,32 +501,93 @@ func (p *Puller) Subscribe(ctx context.Context, consumerID string, after string)
 	sub := NewSubscriber(consumerID, pm, false, 1000)
 	p.subs.Add(sub)
 
+	// Open the initial replay before returning so that an expired position
+	// is reported to the caller.
+	var iter events.Iterator
+	if len(pm.Positions) > 0 {
+		var err error
+		iter, err = p.Replay(ctx, pm.Positions, false)
+		if err != nil {
+			p.subs.Remove(consumerID)
+			return nil, err
+		}
+	}
+
 	outCh := make(chan *events.PullerEvent, 1000)
 
+	send := func(evt *events.ChangeEvent) bool {
+		if !sub.ShouldSend(evt.Backend, evt.ClusterTime) {
+			return true
+		}
+		// Update subscriber's progress
+		sub.UpdatePosition(evt.Backend, evt.EventID, evt.ClusterTime)
+
+		wrapper := &events.PullerEvent{
+			Change:   evt,
+			Progress: sub.CurrentProgress().Encode(),
+		}
+
+		select {
+		case outCh <- wrapper:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
 	go func() {
 		defer close(outCh)
 		defer p.subs.Remove(consumerID)
 
 		for {
+			if iter != nil {
+				ok := true
+				for ok && iter.Next() {
+					ok = send(iter.Event())
+				}
+				iter.Close()
+				if !ok {
+					return
+				}
+				if err := iter.Err(); err != nil {
+					p.logger.Error("replay error", "consumerId", consumerID, "error", err)
+					return
+				}
+				iter = nil
+
+				// Live events that overflowed during the replay are still in
+				// the buffer.
+				if sub.GetAndResetOverflow() {
+					drainChannel(sub.Events())
+					next, err := p.Replay(ctx, sub.CurrentProgress().Positions, false)
+					if err != nil {
+						p.logger.Error("failed to resume replay", "consumerId", consumerID, "error", err)
+						return
+					}
+					iter = next
+					continue
+				}
+			}
+
 			select {
 			case <-ctx.Done():
 				return
 			case <-sub.Done():
 				return
 			case evt := <-sub.Events():
-				// Update subscriber's progress
-				sub.UpdatePosition(evt.Backend, evt.EventID, evt.ClusterTime)
-
-				wrapper := &events.PullerEvent{
-					Change:   evt,
-					Progress: sub.CurrentProgress().Encode(),
-				}
-
-				select {
-				case outCh <- wrapper:
-				case <-ctx.Done():
+				overflow := sub.GetAndResetOverflow()
+				if evt != nil && !send(evt) {
 					return
 				}
+				if overflow {
+					drainChannel(sub.Events())
+					next, err := p.Replay(ctx, sub.CurrentProgress().Positions, false)
+					if err != nil {
+						p.logger.Error("failed to start catch-up replay", "consumerId", consumerID, "error", err)
+						return
+					}
+					iter = next
+				}
 			}
 		}
 	}()
@@ -516,6 +595,16 @@ func (p *Puller) Subscribe(ctx context.Context, consumerID string, after string)
 	return outCh, nil
 }
 
+func drainChannel(ch <-chan *events.ChangeEvent) {
+	for {
+		select {
+		case <-ch:
+		default:
+			return
+		}
+	}
+}
+
 func parseSize(s string) (int64, error) {
 	if s == "" {
 		return 0, nil
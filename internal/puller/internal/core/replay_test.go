@@ -11,6 +11,7 @@ import (
 	"github.com/codetrek/syntrix/internal/config"
 	"github.com/codetrek/syntrix/internal/puller/events"
 	"github.com/codetrek/syntrix/internal/puller/internal/buffer"
+	"github.com/codetrek/syntrix/internal/puller/internal/cursor"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -142,6 +143,15 @@ func TestPuller_Replay(t *testing.T) {
 		assert.Equal(t, "2-2-hash2", iter.Event().EventID)
 	})
 
+	t.Run("PositionExpired", func(t *testing.T) {
+		// A position older than the first retained event was cleaned away.
+		after := map[string]string{
+			backendName: "0-5-hash0",
+		}
+		_, err := p.Replay(context.Background(), after, false)
+		assert.ErrorIs(t, err, events.ErrPositionExpired)
+	})
+
 	t.Run("InvalidEventID", func(t *testing.T) {
 		after := map[string]string{
 			backendName: "invalid-id",
@@ -149,4 +159,45 @@ func TestPuller_Replay(t *testing.T) {
 		_, err := p.Replay(context.Background(), after, false)
 		assert.Error(t, err)
 	})
+	t.Run("SubscribeCatchesUp", func(t *testing.T) {
+		pm := cursor.NewProgressMarker()
+		pm.SetPosition(backendName, evt1.EventID)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch, err := p.Subscribe(ctx, "catchup", pm.Encode())
+		require.NoError(t, err)
+
+		select {
+		case evt := <-ch:
+			assert.Equal(t, "2-2-hash2", evt.Change.EventID)
+			decoded, err := cursor.DecodeProgressMarker(evt.Progress)
+			require.NoError(t, err)
+			assert.Equal(t, "2-2-hash2", decoded.Positions[backendName])
+		case <-time.After(time.Second):
+			t.Fatal("expected replayed event")
+		}
+
+		// Live events follow the replay; already replayed ones are skipped.
+		dup := *evt2
+		dup.Backend = backendName
+		p.subs.Broadcast(&dup)
+		evt3 := &events.ChangeEvent{EventID: "3-3-hash3", ClusterTime: events.ClusterTime{T: 3, I: 3}, Backend: backendName}
+		p.subs.Broadcast(evt3)
+		select {
+		case evt := <-ch:
+			assert.Equal(t, "3-3-hash3", evt.Change.EventID)
+		case <-time.After(time.Second):
+			t.Fatal("expected live event")
+		}
+	})
+
+	t.Run("SubscribeExpired", func(t *testing.T) {
+		pm := cursor.NewProgressMarker()
+		pm.SetPosition(backendName, "0-5-hash0")
+
+		_, err := p.Subscribe(context.Background(), "expired", pm.Encode())
+		assert.ErrorIs(t, err, events.ErrPositionExpired)
+		assert.Nil(t, p.subs.Get("expired"))
+	})
 }
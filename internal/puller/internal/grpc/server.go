@@ -3,6 +3,7 @@ package grpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -15,6 +16,7 @@ import (
 	"github.com/codetrek/syntrix/internal/puller/internal/cursor"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -211,6 +213,22 @@ func (s *Server) Subscribe(req *pullerv1.SubscribeRequest, stream pullerv1.Pulle
 		s.logger.Info("starting in live mode (no history requested)", "consumerId", sub.ID)
 	}
 
+	// Open the initial replay before sending headers, so that clients
+	// resuming from a position can tell an expired one from a healthy stream
+	// before any event arrives.
+	var iter events.Iterator
+	if mode == "catchup" {
+		iter, err = s.eventSource.Replay(ctx, sub.CurrentProgress().Positions, sub.CoalesceOnCatchUp)
+		if err != nil {
+			s.logger.Error("failed to start replay", "error", err)
+			return replayStatus(err)
+		}
+		if err := stream.SendHeader(metadata.Pairs(modeHeader, mode)); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -223,15 +241,17 @@ func (s *Server) Subscribe(req *pullerv1.SubscribeRequest, stream pullerv1.Pulle
 		}
 
 		if mode == "catchup" {
-			// Drain channel to make space for new events
-			drainChannel(sub.Events())
-			sub.GetAndResetOverflow() // Clear overflow flag
-
-			// Start replay
-			iter, err := s.eventSource.Replay(ctx, sub.CurrentProgress().Positions, sub.CoalesceOnCatchUp)
-			if err != nil {
-				s.logger.Error("failed to start replay", "error", err)
-				return status.Errorf(codes.Internal, "failed to start replay: %v", err)
+			if iter == nil {
+				// Drain channel to make space for new events
+				drainChannel(sub.Events())
+				sub.GetAndResetOverflow() // Clear overflow flag
+
+				// Start replay
+				iter, err = s.eventSource.Replay(ctx, sub.CurrentProgress().Positions, sub.CoalesceOnCatchUp)
+				if err != nil {
+					s.logger.Error("failed to start replay", "error", err)
+					return replayStatus(err)
+				}
 			}
 
 			// Replay loop
@@ -250,8 +270,10 @@ func (s *Server) Subscribe(req *pullerv1.SubscribeRequest, stream pullerv1.Pulle
 				}
 			}
 			iter.Close()
+			replayErr := iter.Err()
+			iter = nil
 
-			if err := iter.Err(); err != nil {
+			if err := replayErr; err != nil {
 				s.logger.Error("replay error", "error", err)
 				return status.Errorf(codes.Internal, "replay error: %v", err)
 			}
@@ -299,6 +321,19 @@ func (s *Server) Subscribe(req *pullerv1.SubscribeRequest, stream pullerv1.Pulle
 	}
 }
 
+// modeHeader is sent once a subscription with a starting position has been
+// accepted.
+const modeHeader = "x-puller-mode"
+
+// replayStatus maps a replay error to a gRPC status. A position that is no
+// longer buffered is reported as OutOfRange.
+func replayStatus(err error) error {
+	if errors.Is(err, events.ErrPositionExpired) {
+		return status.Errorf(codes.OutOfRange, "failed to start replay: %v", err)
+	}
+	return status.Errorf(codes.Internal, "failed to start replay: %v", err)
+}
+
 func drainChannel(ch <-chan *events.ChangeEvent) {
 	for {
 		select {
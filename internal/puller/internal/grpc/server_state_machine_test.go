@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -9,9 +10,13 @@ import (
 	pullerv1 "github.com/codetrek/syntrix/api/puller/v1"
 	"github.com/codetrek/syntrix/internal/config"
 	"github.com/codetrek/syntrix/internal/puller/events"
+	"github.com/codetrek/syntrix/internal/puller/internal/cursor"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // --- Mocks ---
@@ -421,3 +426,27 @@ func TestServer_Boundary_InvalidMarker(t *testing.T) {
 		t.Error("Expected error for invalid marker")
 	}
 }
+
+func TestServer_Boundary_ExpiredMarker(t *testing.T) {
+	// Scenario: Subscribe after a position that was cleaned from the buffer
+
+	cfg := config.PullerGRPCConfig{ChannelSize: 100}
+	source := &controllableEventSource{
+		replayFunc: func(ctx context.Context, after map[string]string, coalesce bool) (events.Iterator, error) {
+			return nil, fmt.Errorf("backend %q: %w", "db1", events.ErrPositionExpired)
+		},
+	}
+	server := NewServer(cfg, source, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	stream := &mockStream{ctx: ctx, t: t}
+
+	pm := cursor.NewProgressMarker()
+	pm.SetPosition("db1", "1-1-abc")
+	req := &pullerv1.SubscribeRequest{ConsumerId: "expired-marker", After: pm.Encode()}
+
+	err := server.Subscribe(req, stream)
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+	stream.AssertNotCalled(t, "Send", mock.Anything)
+}
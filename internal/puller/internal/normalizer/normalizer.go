@@ -144,6 +144,12 @@ func formatCompoundKey(docKey bson.M) string {
 
 // generateEventID generates a unique event ID.
 func generateEventID(ct primitive.Timestamp, collection, docID string) string {
+	return FormatEventID(events.ClusterTimeFromPrimitive(ct), collection, docID)
+}
+
+// FormatEventID returns the event ID the puller assigns to a change of docID
+// in collection at cluster time ct.
+func FormatEventID(ct events.ClusterTime, collection, docID string) string {
 	// Format: {clusterTime.T}-{clusterTime.I}-{hash(collection+docID)}
 	data := fmt.Sprintf("%s/%s", collection, docID)
 	hash := sha256.Sum256([]byte(data))
@@ -176,3 +176,55 @@ func TestStreamInterceptors_Context(t *testing.T) {
 	err := srv.loggingStreamInterceptor(nil, stream, info, handler)
 	assert.NoError(t, err)
 }
+
+type headerStream struct {
+	MockServerStream
+	header metadata.MD
+}
+
+func (m *headerStream) SetHeader(md metadata.MD) error {
+	m.header = metadata.Join(m.header, md)
+	return nil
+}
+
+func TestRequestIDInterceptors(t *testing.T) {
+	t.Run("Unary uses incoming ID", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "req-1"))
+		var got string
+		_, err := requestIDUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			got = GetRequestID(ctx)
+			return nil, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "req-1", got)
+	})
+
+	t.Run("Stream generates ID", func(t *testing.T) {
+		ss := &headerStream{MockServerStream: MockServerStream{ctx: context.Background()}}
+		var got string
+		err := requestIDStreamInterceptor(nil, ss, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+			got = GetRequestID(stream.Context())
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, got)
+		assert.Equal(t, []string{got}, ss.header.Get(RequestIDMetadataKey))
+	})
+
+	t.Run("Client forwards ID", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "req-2")
+		err := RequestIDUnaryClientInterceptor(ctx, "/test", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			assert.Equal(t, []string{"req-2"}, md.Get(RequestIDMetadataKey))
+			return nil
+		})
+		assert.NoError(t, err)
+
+		_, err = RequestIDStreamClientInterceptor(context.Background(), nil, nil, "/test", func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			_, ok := metadata.FromOutgoingContext(ctx)
+			assert.False(t, ok, "no ID, no metadata")
+			return nil, nil
+		})
+		assert.NoError(t, err)
+	})
+}
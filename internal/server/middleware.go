@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -44,6 +45,14 @@ func GetRequestID(ctx context.Context) string {
 	return ""
 }
 
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKeyRequestID, id)
+}
+
+// RequestIDMetadataKey is the gRPC metadata key carrying the request ID
+const RequestIDMetadataKey = "x-request-id"
+
 // --- HTTP Middleware ---
 
 // Middleware defines a function that wraps an http.Handler.
@@ -95,8 +104,7 @@ func (s *serverImpl) requestIDMiddleware(next http.Handler) http.Handler {
 			reqID = uuid.New().String()
 		}
 		w.Header().Set("X-Request-ID", reqID)
-		ctx := context.WithValue(r.Context(), contextKeyRequestID, reqID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), reqID)))
 	})
 }
 
@@ -168,6 +176,7 @@ func (rw *responseWriter) WriteHeader(code int) {
 func (s *serverImpl) unaryInterceptors() grpc.ServerOption {
 	interceptors := []grpc.UnaryServerInterceptor{
 		s.recoveryUnaryInterceptor,
+		requestIDUnaryInterceptor,
 		s.loggingUnaryInterceptor,
 	}
 	return grpc.ChainUnaryInterceptor(interceptors...)
@@ -176,11 +185,63 @@ func (s *serverImpl) unaryInterceptors() grpc.ServerOption {
 func (s *serverImpl) streamInterceptors() grpc.ServerOption {
 	interceptors := []grpc.StreamServerInterceptor{
 		s.recoveryStreamInterceptor,
+		requestIDStreamInterceptor,
 		s.loggingStreamInterceptor,
 	}
 	return grpc.ChainStreamInterceptor(interceptors...)
 }
 
+// incomingRequestID returns the request ID sent by the caller, or a new one.
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDMetadataKey); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+	return uuid.New().String()
+}
+
+func requestIDUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	reqID := incomingRequestID(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, reqID))
+	return handler(WithRequestID(ctx, reqID), req)
+}
+
+func requestIDStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	reqID := incomingRequestID(ss.Context())
+	_ = ss.SetHeader(metadata.Pairs(RequestIDMetadataKey, reqID))
+	return handler(srv, &requestIDStream{ServerStream: ss, ctx: WithRequestID(ss.Context(), reqID)})
+}
+
+// requestIDStream exposes a context carrying the request ID to stream handlers.
+type requestIDStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestIDStream) Context() context.Context {
+	return s.ctx
+}
+
+// RequestIDUnaryClientInterceptor forwards the request ID of the calling
+// context to the server.
+func RequestIDUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+}
+
+// RequestIDStreamClientInterceptor forwards the request ID of the calling
+// context to the server.
+func RequestIDStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+}
+
+func outgoingRequestID(ctx context.Context) context.Context {
+	if id := GetRequestID(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, id)
+	}
+	return ctx
+}
+
 func (s *serverImpl) recoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -222,6 +283,7 @@ func (s *serverImpl) loggingUnaryInterceptor(ctx context.Context, req interface{
 
 	s.logger.Log(ctx, level, "gRPC Request",
 		"method", info.FullMethod,
+		"request_id", GetRequestID(ctx),
 		"code", code,
 		"duration", duration,
 		"error", err,
@@ -243,6 +305,7 @@ func (s *serverImpl) loggingStreamInterceptor(srv interface{}, ss grpc.ServerStr
 
 	s.logger.Log(ss.Context(), level, "gRPC Stream",
 		"method", info.FullMethod,
+		"request_id", GetRequestID(ss.Context()),
 		"code", code,
 		"duration", duration,
 		"error", err,
@@ -2,15 +2,19 @@ package services
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"sync"
 
+	"github.com/codetrek/syntrix/internal/api"
 	"github.com/codetrek/syntrix/internal/api/realtime"
 	"github.com/codetrek/syntrix/internal/config"
 	"github.com/codetrek/syntrix/internal/identity"
 	"github.com/codetrek/syntrix/internal/puller"
 	"github.com/codetrek/syntrix/internal/storage"
 	"github.com/codetrek/syntrix/internal/trigger"
+
+	"github.com/nats-io/nats.go"
 )
 
 // DeploymentMode represents the deployment mode of the service.
@@ -58,11 +62,16 @@ type Manager struct {
 	revocationStore storage.TokenRevocationStore
 	authService     identity.AuthN
 	rtServer        *realtime.Server
+	apiServer       *api.Server
 	triggerConsumer triggerConsumer
 	triggerService  triggerService
+	triggerFactory  io.Closer // owns the connections of the delivery sinks
 	natsProvider    trigger.NATSProvider
+	natsConn        *nats.Conn
 	pullerService   puller.LocalService
 	pullerGRPC      *puller.GRPCServer
+	realtimePuller  io.Closer
+	queryClient     io.Closer
 	wg              sync.WaitGroup
 }
 
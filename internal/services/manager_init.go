@@ -3,10 +3,13 @@ package services
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"strconv"
+	"time"
 
 	"github.com/codetrek/syntrix/internal/api"
 	"github.com/codetrek/syntrix/internal/api/realtime"
@@ -19,6 +22,7 @@ import (
 	"github.com/codetrek/syntrix/internal/storage"
 	"github.com/codetrek/syntrix/internal/trigger"
 	triggerengine "github.com/codetrek/syntrix/internal/trigger/engine"
+	triggertypes "github.com/codetrek/syntrix/internal/trigger/types"
 
 	"github.com/nats-io/nats.go"
 )
@@ -90,12 +94,17 @@ func (m *Manager) initDistributed(ctx context.Context) error {
 		queryService = m.createQueryService(cspService)
 		if !m.opts.ForceQueryClient {
 			m.initQueryHTTPServer(queryService)
+			engine.RegisterGRPC(queryService)
 		}
 	}
 
 	if m.opts.RunAPI {
 		if queryService == nil {
-			queryService = engine.NewClient(m.cfg.Gateway.QueryServiceURL)
+			client, err := m.createQueryClient()
+			if err != nil {
+				return err
+			}
+			queryService = client
 		}
 		if err := m.initAPIServer(queryService); err != nil {
 			return err
@@ -139,8 +148,16 @@ func (m *Manager) initAuthService(ctx context.Context) error {
 		return nil
 	}
 
+	// Logouts close the realtime connections of the session, once the
+	// gateway has been set up.
+	revocations := identity.NotifyRevocations(m.revocationStore, func(tenant, jti string, expiresAt time.Time) {
+		if m.rtServer != nil {
+			m.rtServer.RevokeSession(tenant, jti, expiresAt)
+		}
+	})
+
 	var authErr error
-	m.authService, authErr = identity.NewAuthN(m.cfg.Identity.AuthN, m.userStore, m.revocationStore)
+	m.authService, authErr = identity.NewAuthN(m.cfg.Identity.AuthN, m.userStore, revocations)
 	if authErr != nil {
 		return fmt.Errorf("failed to create auth service: %w", authErr)
 	}
@@ -157,6 +174,21 @@ func (m *Manager) createQueryService(cspService csp.Service) engine.Service {
 	return service
 }
 
+// createQueryClient creates a client for a remote query service, over gRPC
+// when gateway.query_grpc_address is set and HTTP otherwise.
+func (m *Manager) createQueryClient() (engine.Service, error) {
+	if addr := m.cfg.Gateway.QueryGRPCAddress; addr != "" {
+		client, err := engine.NewGRPCClient(addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create query gRPC client: %w", err)
+		}
+		m.queryClient = client
+		log.Printf("Using Query Service over gRPC at %s", addr)
+		return client, nil
+	}
+	return engine.NewClient(m.cfg.Gateway.QueryServiceURL), nil
+}
+
 // initQueryHTTPServer creates an HTTP server for the query service.
 // In standalone mode, this is not called since query service runs in-process.
 func (m *Manager) initQueryHTTPServer(service engine.Service) {
@@ -185,13 +217,66 @@ func (m *Manager) initAPIServer(queryService engine.Service) error {
 		AllowedOrigins: m.cfg.Gateway.Realtime.AllowedOrigins,
 		AllowDevOrigin: m.cfg.Gateway.Realtime.AllowDevOrigin,
 		EnableAuth:     m.cfg.Gateway.Realtime.EnableAuth,
+		AuthzCacheTTL:  m.cfg.Gateway.Realtime.AuthzCacheTTL,
+		AuthzCacheSize: m.cfg.Gateway.Realtime.AuthzCacheSize,
+
+		SnapshotPageSize:   m.cfg.Gateway.Realtime.SnapshotPageSize,
+		SnapshotMaxDocs:    m.cfg.Gateway.Realtime.SnapshotMaxDocs,
+		SnapshotBufferSize: m.cfg.Gateway.Realtime.SnapshotBufferSize,
+
+		PullerBackend:   m.cfg.Storage.Topology.Document.Primary,
+		ResumeMaxEvents: m.cfg.Gateway.Realtime.ResumeMaxEvents,
+
+		SendQueueSize:    m.cfg.Gateway.Realtime.SendQueueSize,
+		OverflowPolicy:   realtime.OverflowPolicy(m.cfg.Gateway.Realtime.OverflowPolicy),
+		BroadcastWorkers: m.cfg.Gateway.Realtime.BroadcastWorkers,
+
+		PresenceTTL: m.cfg.Gateway.Realtime.PresenceTTL,
+
+		EnableCompression: m.cfg.Gateway.Realtime.Compression,
+
+		MaxConnectionsPerTenant:       m.cfg.Gateway.Realtime.MaxConnectionsPerTenant,
+		MaxConnectionsPerUser:         m.cfg.Gateway.Realtime.MaxConnectionsPerUser,
+		MaxSubscriptionsPerConnection: m.cfg.Gateway.Realtime.MaxSubscriptionsPerConnection,
+		MessageRate:                   m.cfg.Gateway.Realtime.MessageRate,
+		MessageBurst:                  m.cfg.Gateway.Realtime.MessageBurst,
+
+		ReauthWindow: m.cfg.Gateway.Realtime.ReauthWindow,
+	}
+	m.rtServer = realtime.NewServer(queryService, m.cfg.Storage.Topology.Document.DataCollection, m.authService, authzEngine, rtCfg)
+	if m.pullerService != nil {
+		// Resumed subscriptions replay from the in-process puller buffer.
+		m.rtServer.SetReplaySource(m.pullerService)
+	}
+	if m.cfg.Gateway.Realtime.Bus == "nats" {
+		nc, err := m.connectNATS()
+		if err != nil {
+			return fmt.Errorf("failed to connect realtime bus to NATS: %w", err)
+		}
+		m.rtServer.SetPresenceBus(realtime.NewNATSBus(nc, realtime.DefaultPresenceSubject))
+		m.rtServer.SetTopicBus(realtime.NewNATSBus(nc, realtime.DefaultTopicSubject))
+		m.rtServer.SetRevocationBus(realtime.NewNATSBus(nc, realtime.DefaultRevocationSubject))
 	}
-	m.rtServer = realtime.NewServer(queryService, m.cfg.Storage.Topology.Document.DataCollection, m.authService, rtCfg)
+	if m.cfg.Gateway.Realtime.Source == "puller" {
+		if err := m.initRealtimeFeed(); err != nil {
+			return err
+		}
+	}
+
+	// Realtime streams need HTTP/2, which clients reach without TLS by prior
+	// knowledge (h2c).
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
 
-	apiServer := api.NewServer(queryService, m.authService, authzEngine, m.rtServer)
+	m.apiServer = api.NewServer(queryService, m.authService, authzEngine, m.rtServer)
+	if m.docStore != nil {
+		m.apiServer.SetTriggerStore(trigger.NewStore(m.docStore))
+	}
 	m.servers = append(m.servers, &http.Server{
-		Addr:    listenAddr(m.opts.ListenHost, m.cfg.Gateway.Port),
-		Handler: apiServer,
+		Addr:      listenAddr(m.opts.ListenHost, m.cfg.Gateway.Port),
+		Handler:   m.apiServer,
+		Protocols: protocols,
 	})
 	m.serverNames = append(m.serverNames, "Unified Gateway")
 
@@ -221,7 +306,13 @@ func listenAddr(host string, port int) string {
 	return net.JoinHostPort(host, strconv.Itoa(port))
 }
 
-func (m *Manager) initTriggerServices() error {
+// connectNATS returns the process-wide NATS connection, connecting on first
+// use. Triggers and realtime presence share it.
+func (m *Manager) connectNATS() (*nats.Conn, error) {
+	if m.natsConn != nil {
+		return m.natsConn, nil
+	}
+
 	// Create NATS provider based on deployment mode
 	if m.opts.Mode == ModeStandalone && m.cfg.Deployment.Standalone.EmbeddedNATS {
 		m.natsProvider = trigger.NewEmbeddedNATSProvider(m.cfg.Deployment.Standalone.NATSDataDir)
@@ -230,14 +321,49 @@ func (m *Manager) initTriggerServices() error {
 	}
 
 	nc, err := m.natsProvider.Connect(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	m.natsConn = nc
+	return nc, nil
+}
+
+// triggerStreamOptions maps the trigger stream config. Tasks are kept on
+// disk by default when NATS has a data directory to keep them in.
+func (m *Manager) triggerStreamOptions() triggertypes.StreamOptions {
+	sc := m.cfg.Trigger.Stream
+	storage := sc.Storage
+	if storage == "" {
+		storage = triggertypes.StreamStorageMemory
+		if m.cfg.Deployment.Standalone.NATSDataDir != "" {
+			storage = triggertypes.StreamStorageFile
+		}
+	}
+	return triggertypes.StreamOptions{
+		Storage:         storage,
+		MaxAge:          sc.MaxAge,
+		MaxBytes:        sc.MaxBytes,
+		MaxMsgs:         sc.MaxMsgs,
+		Discard:         sc.Discard,
+		Replicas:        sc.Replicas,
+		DuplicateWindow: sc.DuplicateWindow,
+	}
+}
+
+func (m *Manager) initTriggerServices() error {
+	nc, err := m.connectNATS()
 	if err != nil {
 		return fmt.Errorf("failed to connect to NATS: %w", err)
 	}
 
-	factory, err := triggerFactoryFactory(m.docStore, nc, m.authService, triggerengine.WithStreamName(m.cfg.Trigger.StreamName))
+	factory, err := triggerFactoryFactory(m.docStore, nc, m.authService,
+		triggerengine.WithStreamName(m.cfg.Trigger.StreamName),
+		triggerengine.WithStreamOptions(m.triggerStreamOptions()),
+		triggerengine.WithNATSSinkPrefix(m.cfg.Trigger.NATSSinkPrefix))
 	if err != nil {
 		return fmt.Errorf("failed to create trigger factory: %w", err)
 	}
+	m.triggerFactory = factory
 
 	if m.opts.RunTriggerEvaluator {
 		engine, err := factory.Engine()
@@ -269,12 +395,63 @@ func (m *Manager) initTriggerServices() error {
 		}
 		m.triggerConsumer = cons
 
+		// The gateway of a worker process serves its dead letters.
+		if m.apiServer != nil {
+			deadLetters, err := factory.DeadLetters()
+			if err != nil {
+				return fmt.Errorf("failed to create trigger dead letter service: %w", err)
+			}
+			m.apiServer.SetDeadLetterService(deadLetters)
+
+			deliveries, err := factory.Deliveries()
+			if err != nil {
+				return fmt.Errorf("failed to create trigger delivery log service: %w", err)
+			}
+			m.apiServer.SetDeliveryLogService(deliveries)
+		}
+
 		log.Println("Initialized Trigger Worker Service")
 	}
 
 	return nil
 }
 
+// initRealtimeFeed feeds the realtime hub from the puller instead of a
+// per-gateway change stream.
+func (m *Manager) initRealtimeFeed() error {
+	rtCfg := m.cfg.Gateway.Realtime
+
+	var source realtime.EventSource
+	switch {
+	case rtCfg.PullerAddress != "":
+		client, err := puller.NewClient(rtCfg.PullerAddress, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create realtime puller client: %w", err)
+		}
+		source = client
+		if closer, ok := client.(io.Closer); ok {
+			m.realtimePuller = closer
+		}
+	case m.pullerService != nil:
+		source = m.pullerService
+	default:
+		return fmt.Errorf("gateway.realtime.source is 'puller' but no puller_address is set and no puller runs in-process")
+	}
+
+	consumerID := rtCfg.ConsumerID
+	if consumerID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to derive realtime consumer id: %w", err)
+		}
+		consumerID = "realtime-" + host
+	}
+
+	m.rtServer.SetEventSource(source, consumerID, realtime.NewProgressStore(m.docStore, consumerID))
+	log.Printf("Realtime gateway fed by puller (consumer: %s)", consumerID)
+	return nil
+}
+
 func (m *Manager) initPullerService(ctx context.Context) error {
 	log.Println("Initializing Change Stream Puller Service...")
 
@@ -504,6 +504,7 @@ func TestManager_Init_StandaloneMode(t *testing.T) {
 	}
 
 	cfg := config.LoadConfig()
+	cfg.Identity.AuthN.PrivateKeyFile = filepath.Join(t.TempDir(), "auth.pem")
 	cfg.Gateway.Port = 0
 	cfg.Identity.AuthZ.RulesFile = ""
 	mgr := NewManager(cfg, Options{
@@ -534,6 +535,7 @@ func TestManager_Init_StandaloneMode_NoHTTPForCSP(t *testing.T) {
 	}
 
 	cfg := config.LoadConfig()
+	cfg.Identity.AuthN.PrivateKeyFile = filepath.Join(t.TempDir(), "auth.pem")
 	cfg.Gateway.Port = 0
 	cfg.CSP.Port = 0
 	cfg.Query.Port = 0
@@ -608,6 +610,7 @@ func TestManager_initStandalone_APIServerError(t *testing.T) {
 	}
 
 	cfg := config.LoadConfig()
+	cfg.Identity.AuthN.PrivateKeyFile = filepath.Join(t.TempDir(), "auth.pem")
 	cfg.Gateway.Port = 0
 	cfg.Identity.AuthZ.RulesFile = "/nonexistent/rules/file.yaml"
 	mgr := NewManager(cfg, Options{
@@ -635,6 +638,7 @@ func TestManager_initDistributed_APIServerError(t *testing.T) {
 	}
 
 	cfg := config.LoadConfig()
+	cfg.Identity.AuthN.PrivateKeyFile = filepath.Join(t.TempDir(), "auth.pem")
 	cfg.Gateway.Port = 0
 	cfg.Identity.AuthZ.RulesFile = "/nonexistent/rules/file.yaml"
 	mgr := NewManager(cfg, Options{
@@ -47,12 +47,33 @@ func (m *Manager) Shutdown(ctx context.Context) {
 		log.Println("Timeout waiting for background tasks.")
 	}
 
+	// Close the connections of the trigger delivery sinks
+	if m.triggerFactory != nil {
+		if err := m.triggerFactory.Close(); err != nil {
+			log.Printf("Error closing trigger factory: %v", err)
+		}
+	}
+
 	// Close NATS provider (handles both connection and any embedded server)
 	if m.natsProvider != nil {
 		log.Println("Closing NATS provider...")
 		m.natsProvider.Close()
 	}
 
+	// Close the gateway's query service connection
+	if m.queryClient != nil {
+		if err := m.queryClient.Close(); err != nil {
+			log.Printf("Error closing query client: %v", err)
+		}
+	}
+
+	// Close the realtime gateway's puller connection
+	if m.realtimePuller != nil {
+		if err := m.realtimePuller.Close(); err != nil {
+			log.Printf("Error closing realtime puller client: %v", err)
+		}
+	}
+
 	// Stop Puller gRPC Server
 	if m.pullerGRPC != nil {
 		log.Println("Stopping Puller gRPC Server...")
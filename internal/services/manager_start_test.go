@@ -95,7 +95,7 @@ func TestManager_Start_RealtimeRetry(t *testing.T) {
 	mockQuery := new(MockQueryService)
 	mockQuery.On("WatchCollection", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("watch failed"))
 
-	mgr.rtServer = realtime.NewServer(mockQuery, "", nil, realtime.Config{})
+	mgr.rtServer = realtime.NewServer(mockQuery, "", nil, nil, realtime.Config{})
 
 	bgCtx, bgCancel := context.WithCancel(context.Background())
 
@@ -116,7 +116,7 @@ func TestManager_Start_RealtimeBackground_Failure(t *testing.T) {
 	cfg := config.LoadConfig()
 	mgr := NewManager(cfg, Options{RunAPI: true})
 	stub := &rtQueryStub{failAlways: true}
-	mgr.rtServer = realtime.NewServer(stub, cfg.Storage.Topology.Document.DataCollection, nil, realtime.Config{})
+	mgr.rtServer = realtime.NewServer(stub, cfg.Storage.Topology.Document.DataCollection, nil, nil, realtime.Config{})
 
 	bgCtx, bgCancel := context.WithCancel(context.Background())
 	defer bgCancel()
@@ -134,7 +134,7 @@ func TestManager_Start_RealtimeBackground_Success(t *testing.T) {
 	cfg := config.LoadConfig()
 	mgr := NewManager(cfg, Options{RunAPI: true})
 	stub := &rtQueryStub{}
-	mgr.rtServer = realtime.NewServer(stub, cfg.Storage.Topology.Document.DataCollection, nil, realtime.Config{})
+	mgr.rtServer = realtime.NewServer(stub, cfg.Storage.Topology.Document.DataCollection, nil, nil, realtime.Config{})
 
 	bgCtx, bgCancel := context.WithCancel(context.Background())
 	defer bgCancel()
@@ -150,7 +150,7 @@ func TestManager_Start_RealtimeBackground_RetryThenSuccess(t *testing.T) {
 	cfg := config.LoadConfig()
 	mgr := NewManager(cfg, Options{RunAPI: true})
 	stub := &rtQueryStub{failFirst: true}
-	mgr.rtServer = realtime.NewServer(stub, cfg.Storage.Topology.Document.DataCollection, nil, realtime.Config{})
+	mgr.rtServer = realtime.NewServer(stub, cfg.Storage.Topology.Document.DataCollection, nil, nil, realtime.Config{})
 
 	bgCtx, bgCancel := context.WithCancel(context.Background())
 	defer bgCancel()
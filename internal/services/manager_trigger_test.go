@@ -2,16 +2,20 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/codetrek/syntrix/internal/api"
 	"github.com/codetrek/syntrix/internal/config"
 	"github.com/codetrek/syntrix/internal/identity"
 	"github.com/codetrek/syntrix/internal/storage"
 	"github.com/codetrek/syntrix/internal/trigger"
 	"github.com/codetrek/syntrix/internal/trigger/engine"
+	"github.com/codetrek/syntrix/internal/trigger/types"
 
 	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
@@ -39,6 +43,22 @@ func (m *MockFactory) Consumer(numWorkers int) (engine.TaskConsumer, error) {
 	return args.Get(0).(engine.TaskConsumer), args.Error(1)
 }
 
+func (m *MockFactory) DeadLetters() (trigger.DeadLetterService, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(trigger.DeadLetterService), args.Error(1)
+}
+
+func (m *MockFactory) Deliveries() (trigger.DeliveryLogService, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(trigger.DeliveryLogService), args.Error(1)
+}
+
 func (m *MockFactory) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -103,6 +123,37 @@ func TestManager_InitTriggerServices_WorkerOnly(t *testing.T) {
 	mockFactory.AssertExpectations(t)
 }
 
+func TestManager_InitTriggerServices_WorkerServesDeadLetters(t *testing.T) {
+	cfg := config.LoadConfig()
+	mgr := NewManager(cfg, Options{RunTriggerWorker: true})
+	mgr.authService = &stubAuthN{}
+	mgr.apiServer = api.NewServer(&stubQueryService{}, mgr.authService, nil, nil)
+
+	origConnector := trigger.GetNatsConnectFunc()
+	origFactory := triggerFactoryFactory
+	defer func() {
+		trigger.SetNatsConnectFunc(origConnector)
+		triggerFactoryFactory = origFactory
+	}()
+
+	trigger.SetNatsConnectFunc(func(string, ...nats.Option) (*nats.Conn, error) { return &nats.Conn{}, nil })
+
+	mockFactory := new(MockFactory)
+	triggerFactoryFactory = func(storage.DocumentStore, *nats.Conn, identity.AuthN, ...engine.FactoryOption) (engine.TriggerFactory, error) {
+		return mockFactory, nil
+	}
+	mockFactory.On("Consumer", cfg.Trigger.WorkerCount).Return(&fakeConsumer{}, nil)
+	mockFactory.On("DeadLetters").Return(struct{ trigger.DeadLetterService }{}, nil).Times(2)
+	mockFactory.On("DeadLetters").Return(nil, errors.New("no stream")).Once()
+	mockFactory.On("Deliveries").Return(struct{ trigger.DeliveryLogService }{}, nil).Once()
+	mockFactory.On("Deliveries").Return(nil, errors.New("no stream")).Once()
+
+	assert.NoError(t, mgr.initTriggerServices())
+	assert.ErrorContains(t, mgr.initTriggerServices(), "delivery log")
+	assert.ErrorContains(t, mgr.initTriggerServices(), "dead letter")
+	mockFactory.AssertExpectations(t)
+}
+
 func TestManager_InitTriggerServices_EvaluatorOnly_WithRules(t *testing.T) {
 	cfg := config.LoadConfig()
 	tmpDir := t.TempDir()
@@ -251,3 +302,22 @@ func (f *fakePublisher) Publish(context.Context, *trigger.DeliveryTask) error {
 }
 
 func (f *fakePublisher) Close() {}
+
+func TestManager_TriggerStreamOptions(t *testing.T) {
+	cfg := config.LoadConfig()
+	cfg.Trigger.Stream = config.TriggerStreamConfig{MaxAge: time.Hour, Discard: "new", Replicas: 3, DuplicateWindow: time.Minute}
+	mgr := NewManager(cfg, Options{})
+
+	opts := mgr.triggerStreamOptions()
+	assert.Equal(t, types.StreamStorageFile, opts.Storage, "file when NATS has a data dir")
+	assert.Equal(t, time.Hour, opts.MaxAge)
+	assert.Equal(t, "new", opts.Discard)
+	assert.Equal(t, 3, opts.Replicas)
+	assert.Equal(t, time.Minute, opts.DuplicateWindow)
+
+	cfg.Deployment.Standalone.NATSDataDir = ""
+	assert.Equal(t, types.StreamStorageMemory, mgr.triggerStreamOptions().Storage)
+
+	cfg.Trigger.Stream.Storage = "file"
+	assert.Equal(t, types.StreamStorageFile, mgr.triggerStreamOptions().Storage)
+}
@@ -15,6 +15,10 @@ type AuthProvider = types.AuthProvider
 type OpKind = types.OpKind
 type EventType = types.EventType
 type Event = types.Event
+type FieldPatch = types.FieldPatch
+type ClusterTime = types.ClusterTime
+type Origin = types.Origin
+type Transactor = types.Transactor
 type ReplicationPullRequest = types.ReplicationPullRequest
 type ReplicationPullResponse = types.ReplicationPullResponse
 type ReplicationPushChange = types.ReplicationPushChange
@@ -36,9 +40,13 @@ const (
 	EventCreate = types.EventCreate
 	EventUpdate = types.EventUpdate
 	EventDelete = types.EventDelete
+	EventReset  = types.EventReset
 )
 
 var (
 	ErrUserNotFound = types.ErrUserNotFound
 	ErrUserExists   = types.ErrUserExists
+
+	ErrResumeTokenExpired      = types.ErrResumeTokenExpired
+	ErrTransactionsUnsupported = types.ErrTransactionsUnsupported
 )
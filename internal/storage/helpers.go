@@ -1,6 +1,10 @@
 package storage
 
-import "github.com/codetrek/syntrix/internal/storage/types"
+import (
+	"context"
+
+	"github.com/codetrek/syntrix/internal/storage/types"
+)
 
 // CalculateTenantID calculates the tenant-aware document ID
 func CalculateTenantID(tenant, fullpath string) string {
@@ -17,3 +21,13 @@ func CalculateID(fullpath string) string {
 func NewDocument(tenant string, fullpath string, collection string, data map[string]interface{}) *Document {
 	return types.NewDocument(tenant, fullpath, collection, data)
 }
+
+// WithOrigin returns a context whose writes are tagged with origin
+func WithOrigin(ctx context.Context, origin *Origin) context.Context {
+	return types.WithOrigin(ctx, origin)
+}
+
+// OriginFrom returns the origin carried by ctx, or nil
+func OriginFrom(ctx context.Context) *Origin {
+	return types.OriginFrom(ctx)
+}
@@ -388,3 +388,57 @@ func TestMongoBackend_Delete_WithFilter(t *testing.T) {
 	_, err = backend.Get(ctx, tenant, path)
 	assert.ErrorIs(t, err, model.ErrNotFound)
 }
+
+func TestMongoBackend_OriginTagging(t *testing.T) {
+	backend := setupTestBackend(t)
+	ctx := context.Background()
+	tenant := "default"
+	origin := &types.Origin{TriggerID: "t1", TaskID: "task-1", Depth: 1}
+	tagged := types.WithOrigin(ctx, origin)
+
+	doc := types.NewDocument(tenant, "orders/o1", "orders", map[string]interface{}{"n": 1})
+	require.NoError(t, backend.Create(tagged, tenant, doc))
+
+	got, err := backend.Get(ctx, tenant, "orders/o1")
+	require.NoError(t, err)
+	assert.Equal(t, origin, got.Origin)
+
+	// A write without an origin clears the tag.
+	require.NoError(t, backend.Patch(ctx, tenant, "orders/o1", map[string]interface{}{"n": 2}, nil))
+	got, err = backend.Get(ctx, tenant, "orders/o1")
+	require.NoError(t, err)
+	assert.Nil(t, got.Origin)
+
+	require.NoError(t, backend.Update(tagged, tenant, "orders/o1", map[string]interface{}{"n": 3}, nil))
+	got, err = backend.Get(ctx, tenant, "orders/o1")
+	require.NoError(t, err)
+	assert.Equal(t, origin, got.Origin)
+}
+
+func TestMongoBackend_RunTransaction(t *testing.T) {
+	backend := setupTestBackend(t)
+	ctx := context.Background()
+	tenant := "default"
+	tx := backend.(types.Transactor)
+
+	err := tx.RunTransaction(ctx, tenant, func(ctx context.Context) error {
+		if err := backend.Create(ctx, tenant, types.NewDocument(tenant, "orders/a", "orders", map[string]interface{}{})); err != nil {
+			return err
+		}
+		return backend.Create(ctx, tenant, types.NewDocument(tenant, "orders/b", "orders", map[string]interface{}{}))
+	})
+	require.NoError(t, err)
+	_, err = backend.Get(ctx, tenant, "orders/b")
+	assert.NoError(t, err)
+
+	// A failing write rolls back the earlier ones.
+	err = tx.RunTransaction(ctx, tenant, func(ctx context.Context) error {
+		if err := backend.Create(ctx, tenant, types.NewDocument(tenant, "orders/c", "orders", map[string]interface{}{})); err != nil {
+			return err
+		}
+		return backend.Update(ctx, tenant, "orders/missing", map[string]interface{}{}, nil)
+	})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	_, err = backend.Get(ctx, tenant, "orders/c")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
@@ -3,6 +3,7 @@ package mongo
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/codetrek/syntrix/pkg/model"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -75,6 +77,9 @@ func (m *documentStore) Create(ctx context.Context, tenant string, doc *types.Do
 
 	// Ensure soft-delete fields are reset
 	doc.Deleted = false
+	if origin := types.OriginFrom(ctx); origin != nil {
+		doc.Origin = origin
+	}
 
 	_, err := collection.InsertOne(ctx, doc)
 	if mongo.IsDuplicateKeyError(err) {
@@ -102,7 +107,7 @@ func (m *documentStore) Update(ctx context.Context, tenant string, path string,
 	filter["tenant_id"] = tenant
 	filter["deleted"] = bson.M{"$ne": true}
 
-	update := bson.M{
+	update := withOrigin(ctx, bson.M{
 		"$set": bson.M{
 			"data":       data,
 			"updated_at": time.Now().UnixMilli(),
@@ -110,7 +115,7 @@ func (m *documentStore) Update(ctx context.Context, tenant string, path string,
 		"$inc": bson.M{
 			"version": 1,
 		},
-	}
+	})
 
 	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -144,12 +149,12 @@ func (m *documentStore) Patch(ctx context.Context, tenant string, path string, d
 		updates["data."+k] = v
 	}
 
-	update := bson.M{
+	update := withOrigin(ctx, bson.M{
 		"$set": updates,
 		"$inc": bson.M{
 			"version": 1,
 		},
-	}
+	})
 
 	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -176,7 +181,7 @@ func (m *documentStore) Delete(ctx context.Context, tenant string, path string,
 	filter["tenant_id"] = tenant
 	filter["deleted"] = bson.M{"$ne": true}
 
-	update := bson.M{
+	update := withOrigin(ctx, bson.M{
 		"$set": bson.M{
 			"deleted":        true,
 			"data":           bson.M{},
@@ -186,7 +191,7 @@ func (m *documentStore) Delete(ctx context.Context, tenant string, path string,
 		"$inc": bson.M{
 			"version": 1,
 		},
-	}
+	})
 
 	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -212,6 +217,35 @@ func (m *documentStore) Delete(ctx context.Context, tenant string, path string,
 	return nil
 }
 
+// withOrigin tags an update with the origin carried by ctx, or clears the tag
+// left by an earlier trigger write.
+func withOrigin(ctx context.Context, update bson.M) bson.M {
+	if origin := types.OriginFrom(ctx); origin != nil {
+		update["$set"].(bson.M)["origin"] = origin
+	} else {
+		update["$unset"] = bson.M{"origin": ""}
+	}
+	return update
+}
+
+// RunTransaction runs fn inside a MongoDB transaction. The driver retries fn
+// on transient transaction errors, so fn must be safe to run again.
+func (m *documentStore) RunTransaction(ctx context.Context, tenant string, fn func(ctx context.Context) error) error {
+	if m.client == nil {
+		return types.ErrTransactionsUnsupported
+	}
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
 func (m *documentStore) Query(ctx context.Context, tenant string, q model.Query) ([]*types.Document, error) {
 	collection := m.getCollection(q.Collection)
 
@@ -303,6 +337,9 @@ func (m *documentStore) Watch(ctx context.Context, tenant string, collectionName
 	collection := m.getCollection(collectionName)
 	stream, err := m.openStream(ctx, collection, pipeline, changeStreamOpts)
 	if err != nil {
+		if resumeToken != nil && isHistoryLost(err) {
+			return nil, fmt.Errorf("%w: %v", types.ErrResumeTokenExpired, err)
+		}
 		return nil, err
 	}
 
@@ -334,6 +371,22 @@ func (m *documentStore) Watch(ctx context.Context, tenant string, collectionName
 	return out, nil
 }
 
+// Server error codes returned when a change stream cannot resume.
+const (
+	codeChangeStreamFatalError  = 280
+	codeChangeStreamHistoryLost = 286
+)
+
+// isHistoryLost reports whether err means the resume token is no longer in
+// the oplog.
+func isHistoryLost(err error) bool {
+	var se mongo.ServerError
+	if !errors.As(err, &se) {
+		return false
+	}
+	return se.HasErrorCode(codeChangeStreamHistoryLost) || se.HasErrorCode(codeChangeStreamFatalError)
+}
+
 type changeStreamEvent struct {
 	ID                       interface{}     `bson:"_id"`
 	OperationType            string          `bson:"operationType"`
@@ -342,7 +395,7 @@ type changeStreamEvent struct {
 	DocumentKey              struct {
 		ID string `bson:"_id"`
 	} `bson:"documentKey"`
-	ClusterTime interface{} `bson:"clusterTime"` // Timestamp
+	ClusterTime primitive.Timestamp `bson:"clusterTime"`
 }
 
 func (m *documentStore) convertChangeEvent(changeEvent changeStreamEvent, tenant string, collectionName string) (*types.Event, bool) {
@@ -382,8 +435,12 @@ func (m *documentStore) convertChangeEvent(changeEvent changeStreamEvent, tenant
 		TenantID:    eventTenant,
 		ResumeToken: changeEvent.ID,
 		Timestamp:   time.Now().UnixNano(),
+		ClusterTime: types.ClusterTime{T: changeEvent.ClusterTime.T, I: changeEvent.ClusterTime.I},
 		Before:      changeEvent.FullDocumentBeforeChange,
 	}
+	if changeEvent.FullDocument != nil {
+		evt.Origin = changeEvent.FullDocument.Origin
+	}
 
 	switch changeEvent.OperationType {
 	case "insert":
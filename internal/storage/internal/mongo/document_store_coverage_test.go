@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/codetrek/syntrix/pkg/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -255,10 +258,12 @@ func TestDocumentStore_Watch_Coverage(t *testing.T) {
 			DocumentKey: struct {
 				ID string `bson:"_id"`
 			}{ID: "t1:path"},
+			ClusterTime: primitive.Timestamp{T: 10, I: 2},
 		}
 		evt, ok := ds.convertChangeEvent(change, "", "")
 		assert.True(t, ok)
 		assert.Equal(t, types.EventCreate, evt.Type)
+		assert.Equal(t, types.ClusterTime{T: 10, I: 2}, evt.ClusterTime)
 	})
 
 	t.Run("Watch_CtxDoneDuringSend", func(t *testing.T) {
@@ -317,3 +322,44 @@ func (s *stubChangeStream) Decode(v any) error {
 func (s *stubChangeStream) Err() error { return nil }
 
 func (s *stubChangeStream) Close(context.Context) error { return nil }
+
+func TestIsHistoryLost(t *testing.T) {
+	assert.True(t, isHistoryLost(mongo.CommandError{Code: codeChangeStreamHistoryLost, Name: "ChangeStreamHistoryLost"}))
+	assert.True(t, isHistoryLost(fmt.Errorf("watch: %w", mongo.CommandError{Code: codeChangeStreamFatalError})))
+	assert.False(t, isHistoryLost(mongo.CommandError{Code: 11000}))
+	assert.False(t, isHistoryLost(assert.AnError))
+}
+
+func TestWithOrigin(t *testing.T) {
+	origin := &types.Origin{TriggerID: "t1", TaskID: "task-1", Depth: 1}
+
+	update := withOrigin(types.WithOrigin(context.Background(), origin), bson.M{"$set": bson.M{}})
+	assert.Equal(t, origin, update["$set"].(bson.M)["origin"])
+	assert.NotContains(t, update, "$unset")
+
+	update = withOrigin(context.Background(), bson.M{"$set": bson.M{}})
+	assert.Equal(t, bson.M{"origin": ""}, update["$unset"])
+	assert.NotContains(t, update["$set"], "origin")
+}
+
+func TestConvertChangeEvent_Origin(t *testing.T) {
+	ds := &documentStore{}
+	origin := &types.Origin{TriggerID: "t1", TaskID: "task-1", Depth: 1}
+	change := changeStreamEvent{
+		OperationType: "update",
+		FullDocument:  &types.Document{TenantID: "t1", Collection: "c1", Deleted: true, Origin: origin},
+		DocumentKey: struct {
+			ID string `bson:"_id"`
+		}{ID: "t1:path"},
+	}
+	evt, ok := ds.convertChangeEvent(change, "", "")
+	require.True(t, ok)
+	assert.Equal(t, types.EventDelete, evt.Type)
+	assert.Equal(t, origin, evt.Origin)
+}
+
+func TestRunTransaction_NoClient(t *testing.T) {
+	ds := &documentStore{}
+	err := ds.RunTransaction(context.Background(), "t1", func(context.Context) error { return nil })
+	assert.ErrorIs(t, err, types.ErrTransactionsUnsupported)
+}
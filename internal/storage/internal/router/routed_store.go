@@ -73,6 +73,19 @@ func (s *RoutedDocumentStore) Watch(ctx context.Context, tenant string, collecti
 	return store.Watch(ctx, tenant, collection, resumeToken, opts)
 }
 
+// RunTransaction runs fn in a transaction on the tenant's write store.
+func (s *RoutedDocumentStore) RunTransaction(ctx context.Context, tenant string, fn func(ctx context.Context) error) error {
+	store, err := s.router.Select(tenant, types.OpWrite)
+	if err != nil {
+		return err
+	}
+	tx, ok := store.(types.Transactor)
+	if !ok {
+		return types.ErrTransactionsUnsupported
+	}
+	return tx.RunTransaction(ctx, tenant, fn)
+}
+
 func (s *RoutedDocumentStore) Close(ctx context.Context) error {
 	// We don't close the underlying store here as it might be shared.
 	// The Provider manages lifecycle.
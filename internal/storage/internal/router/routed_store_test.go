@@ -510,3 +510,58 @@ func TestRoutedRevocationStore(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+type mockTxStore struct {
+	mockDocumentStore
+}
+
+func (m *mockTxStore) RunTransaction(ctx context.Context, tenant string, fn func(ctx context.Context) error) error {
+	args := m.Called(ctx, tenant)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
+func TestRoutedDocumentStore_RunTransaction(t *testing.T) {
+	ctx := context.Background()
+	tenant := "default"
+
+	t.Run("delegates to write store", func(t *testing.T) {
+		router := new(mockDocRouter)
+		store := new(mockTxStore)
+		router.On("Select", tenant, types.OpWrite).Return(store, nil)
+		store.On("RunTransaction", ctx, tenant).Return(nil)
+
+		rs := NewRoutedDocumentStore(router).(types.Transactor)
+		called := false
+		err := rs.RunTransaction(ctx, tenant, func(context.Context) error {
+			called = true
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, called)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("unsupported store", func(t *testing.T) {
+		router := new(mockDocRouter)
+		router.On("Select", tenant, types.OpWrite).Return(new(mockDocumentStore), nil)
+
+		rs := NewRoutedDocumentStore(router).(types.Transactor)
+		err := rs.RunTransaction(ctx, tenant, func(context.Context) error { return nil })
+
+		assert.ErrorIs(t, err, types.ErrTransactionsUnsupported)
+	})
+
+	t.Run("select error", func(t *testing.T) {
+		router := new(mockDocRouter)
+		router.On("Select", tenant, types.OpWrite).Return(nil, assert.AnError)
+
+		rs := NewRoutedDocumentStore(router).(types.Transactor)
+		err := rs.RunTransaction(ctx, tenant, func(context.Context) error { return nil })
+
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
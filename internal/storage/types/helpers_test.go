@@ -1,6 +1,7 @@
 package types
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -63,3 +64,12 @@ func TestNewDocument_NoSlashCollection(t *testing.T) {
 	assert.Equal(t, "root", doc.Collection)
 	assert.Empty(t, doc.Parent)
 }
+
+func TestOrigin_Context(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, OriginFrom(ctx))
+
+	origin := &Origin{TriggerID: "t1", TaskID: "task-1", Depth: 2}
+	assert.Same(t, origin, OriginFrom(WithOrigin(ctx, origin)))
+	assert.Nil(t, OriginFrom(WithOrigin(ctx, nil)))
+}
@@ -11,6 +11,14 @@ import (
 var (
 	ErrUserNotFound = errors.New("user not found")
 	ErrUserExists   = errors.New("user already exists")
+
+	// ErrResumeTokenExpired is returned by Watch when the resume token points
+	// before the oldest change the backend still retains.
+	ErrResumeTokenExpired = errors.New("resume token expired")
+
+	// ErrTransactionsUnsupported is returned by RunTransaction when the
+	// backend cannot apply writes atomically.
+	ErrTransactionsUnsupported = errors.New("transactions not supported")
 )
 
 // User represents a user in the system
@@ -72,6 +80,31 @@ type Document struct {
 
 	// Deleted indicates if the document is soft-deleted
 	Deleted bool `json:"deleted,omitempty" bson:"deleted,omitempty"`
+
+	// Origin identifies the trigger delivery that made the last write, if any
+	Origin *Origin `json:"origin,omitempty" bson:"origin,omitempty"`
+}
+
+// Origin tags a write made on behalf of a trigger delivery.
+type Origin struct {
+	TriggerID string `json:"triggerId" bson:"trigger_id"`
+	TaskID    string `json:"taskId" bson:"task_id"`
+	// Depth counts the trigger hops behind the write: 1 for a write-back to a
+	// user's change, 2 for a write-back to that write, and so on.
+	Depth int `json:"depth" bson:"depth"`
+}
+
+type originKey struct{}
+
+// WithOrigin returns a context whose writes are tagged with origin.
+func WithOrigin(ctx context.Context, origin *Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// OriginFrom returns the origin carried by ctx, or nil.
+func OriginFrom(ctx context.Context) *Origin {
+	origin, _ := ctx.Value(originKey{}).(*Origin)
+	return origin
 }
 
 // WatchOptions defines options for watching changes
@@ -109,6 +142,15 @@ type DocumentStore interface {
 	Close(ctx context.Context) error
 }
 
+// Transactor is implemented by document stores that can apply several writes
+// atomically.
+type Transactor interface {
+	// RunTransaction calls fn with a context bound to a transaction on the
+	// tenant's store. Writes fn makes through that context commit together
+	// when fn returns nil and are rolled back otherwise.
+	RunTransaction(ctx context.Context, tenant string, fn func(ctx context.Context) error) error
+}
+
 // UserStore defines the interface for user storage operations
 type UserStore interface {
 	CreateUser(ctx context.Context, tenant string, user *User) error
@@ -182,8 +224,24 @@ const (
 	EventCreate EventType = "create"
 	EventUpdate EventType = "update"
 	EventDelete EventType = "delete"
+
+	// EventReset is emitted by a reconnecting watch that could not resume from
+	// its last token. Changes in between are lost and consumers must resync.
+	EventReset EventType = "reset"
 )
 
+// ClusterTime is the commit timestamp a backend assigns to a change, when it
+// provides one (MongoDB cluster time).
+type ClusterTime struct {
+	T uint32 `json:"T"`
+	I uint32 `json:"I"`
+}
+
+// IsZero reports whether the cluster time is unset.
+func (c ClusterTime) IsZero() bool {
+	return c.T == 0 && c.I == 0
+}
+
 // Event represents a database change event
 type Event struct {
 	Id          string      `json:"id"`
@@ -192,7 +250,17 @@ type Event struct {
 	Document    *Document   `json:"document,omitempty"` // Nil for delete
 	Before      *Document   `json:"before,omitempty"`   // Previous state, if available
 	Timestamp   int64       `json:"timestamp"`
-	ResumeToken interface{} `json:"-"` // Opaque token for resuming watch
+	ClusterTime ClusterTime `json:"clusterTime,omitempty"` // Backend commit time, zero if unknown
+	ResumeToken interface{} `json:"-"`                     // Opaque token for resuming watch
+	Patch       *FieldPatch `json:"patch,omitempty"`       // Changed fields of an update, if known
+	Origin      *Origin     `json:"origin,omitempty"`      // Trigger delivery behind the change, if any
+}
+
+// FieldPatch describes an update by the fields it set and removed. Fields are
+// dotted paths into Document.Data; numeric components index arrays.
+type FieldPatch struct {
+	Set   map[string]interface{} `json:"set,omitempty"`
+	Unset []string               `json:"unset,omitempty"`
 }
 
 // ReplicationPullRequest represents a request to pull changes
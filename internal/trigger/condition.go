@@ -0,0 +1,49 @@
+package trigger
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ConditionEnvOptions declares the variables a trigger condition and its
+// filters see: the event with its type, timestamp, document and before image.
+func ConditionEnvOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Variable("event", cel.MapType(cel.StringType, cel.DynType)),
+	}
+}
+
+// CompileCondition checks that condition is a CEL expression returning a
+// boolean. An empty condition matches every event.
+func CompileCondition(condition string) error {
+	if condition == "" {
+		return nil
+	}
+	env, err := cel.NewEnv(ConditionEnvOptions()...)
+	if err != nil {
+		return err
+	}
+	ast, issues := env.Compile(condition)
+	if issues != nil && issues.Err() != nil {
+		return fmt.Errorf("invalid condition: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return fmt.Errorf("condition must return bool, got %s", ast.OutputType())
+	}
+	return nil
+}
+
+// CompileFilters checks that every filter is a CEL expression returning a
+// boolean. Filters narrow the events a trigger fires on, like Condition.
+func CompileFilters(filters []string) error {
+	for i, filter := range filters {
+		if filter == "" {
+			return fmt.Errorf("filter %d is empty", i)
+		}
+		if err := CompileCondition(filter); err != nil {
+			return fmt.Errorf("filter %d: %w", i, err)
+		}
+	}
+	return nil
+}
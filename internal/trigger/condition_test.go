@@ -0,0 +1,25 @@
+package trigger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileCondition(t *testing.T) {
+	assert.NoError(t, CompileCondition(""))
+	assert.NoError(t, CompileCondition(`event.document.status == "active"`))
+	assert.NoError(t, CompileCondition(`event.type == "update" && event.before.total < event.document.total`))
+
+	assert.ErrorContains(t, CompileCondition(`event.document.status ==`), "invalid condition")
+	assert.ErrorContains(t, CompileCondition(`doc.status == "active"`), "invalid condition")
+	assert.ErrorContains(t, CompileCondition(`"active"`), "must return bool")
+}
+
+func TestCompileFilters(t *testing.T) {
+	assert.NoError(t, CompileFilters(nil))
+	assert.NoError(t, CompileFilters([]string{`event.document.total > 100`, `event.type != "delete"`}))
+
+	assert.ErrorContains(t, CompileFilters([]string{`true`, ``}), "filter 1 is empty")
+	assert.ErrorContains(t, CompileFilters([]string{`event.document.total + 1`}), "filter 0")
+}
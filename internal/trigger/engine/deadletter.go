@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/codetrek/syntrix/internal/trigger/internal/pubsub"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+)
+
+// deadLetterService implements types.DeadLetterService. Replay publishes the
+// task to the task stream again before the dead letter is removed, so a
+// failure in between leaves a duplicate rather than a lost delivery.
+type deadLetterService struct {
+	queue     pubsub.DeadLetterQueue
+	publisher pubsub.TaskPublisher
+}
+
+func (s *deadLetterService) ListDeadLetters(ctx context.Context, tenant, triggerID string, filter types.DeadLetterFilter) ([]*types.DeadLetter, error) {
+	return s.queue.List(ctx, tenant, triggerID, filter)
+}
+
+func (s *deadLetterService) GetDeadLetter(ctx context.Context, tenant, triggerID string, id uint64) (*types.DeadLetter, error) {
+	return s.queue.Get(ctx, tenant, triggerID, id)
+}
+
+func (s *deadLetterService) ReplayDeadLetter(ctx context.Context, tenant, triggerID string, id uint64) error {
+	letter, err := s.queue.Get(ctx, tenant, triggerID, id)
+	if err != nil {
+		return err
+	}
+	return s.replay(ctx, letter)
+}
+
+func (s *deadLetterService) ReplayDeadLetters(ctx context.Context, tenant, triggerID string, filter types.DeadLetterFilter) (int, error) {
+	remaining := filter.Limit
+	replayed := 0
+	for {
+		page := filter
+		page.Limit = types.MaxDeadLetterPageSize
+		if remaining > 0 && remaining < page.Limit {
+			page.Limit = remaining
+		}
+		letters, err := s.queue.List(ctx, tenant, triggerID, page)
+		if err != nil {
+			return replayed, err
+		}
+		for _, letter := range letters {
+			if err := s.replay(ctx, letter); err != nil {
+				return replayed, err
+			}
+			replayed++
+		}
+		if len(letters) < page.Limit {
+			return replayed, nil
+		}
+		if remaining > 0 {
+			remaining -= len(letters)
+			if remaining == 0 {
+				return replayed, nil
+			}
+		}
+		filter.After = letters[len(letters)-1].ID
+	}
+}
+
+func (s *deadLetterService) replay(ctx context.Context, letter *types.DeadLetter) error {
+	task := letter.Task
+	// The publisher decides the subject again and the worker mints a fresh token.
+	task.SubjectHashed = false
+	task.PreIssuedToken = ""
+	task.Replay = letter.ID
+	if err := s.publisher.Publish(ctx, &task); err != nil {
+		return err
+	}
+	return s.queue.Delete(ctx, task.Tenant, task.TriggerID, letter.ID)
+}
+
+func (s *deadLetterService) DeleteDeadLetter(ctx context.Context, tenant, triggerID string, id uint64) error {
+	return s.queue.Delete(ctx, tenant, triggerID, id)
+}
+
+func (s *deadLetterService) PurgeDeadLetters(ctx context.Context, tenant, triggerID string) error {
+	return s.queue.Purge(ctx, tenant, triggerID)
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDeadLetterQueue struct {
+	mock.Mock
+}
+
+func (m *MockDeadLetterQueue) Add(ctx context.Context, letter *types.DeadLetter) error {
+	return m.Called(ctx, letter).Error(0)
+}
+
+func (m *MockDeadLetterQueue) List(ctx context.Context, tenant, triggerID string, filter types.DeadLetterFilter) ([]*types.DeadLetter, error) {
+	args := m.Called(ctx, tenant, triggerID, filter)
+	letters, _ := args.Get(0).([]*types.DeadLetter)
+	return letters, args.Error(1)
+}
+
+func (m *MockDeadLetterQueue) Get(ctx context.Context, tenant, triggerID string, id uint64) (*types.DeadLetter, error) {
+	args := m.Called(ctx, tenant, triggerID, id)
+	letter, _ := args.Get(0).(*types.DeadLetter)
+	return letter, args.Error(1)
+}
+
+func (m *MockDeadLetterQueue) Delete(ctx context.Context, tenant, triggerID string, id uint64) error {
+	return m.Called(ctx, tenant, triggerID, id).Error(0)
+}
+
+func (m *MockDeadLetterQueue) Purge(ctx context.Context, tenant, triggerID string) error {
+	return m.Called(ctx, tenant, triggerID).Error(0)
+}
+
+func deadLetter(id uint64) *types.DeadLetter {
+	return &types.DeadLetter{
+		ID: id,
+		Task: types.DeliveryTask{
+			TriggerID:      "t1",
+			Tenant:         "acme",
+			DocumentID:     "d1",
+			PreIssuedToken: "stale",
+			SubjectHashed:  true,
+		},
+	}
+}
+
+func TestDeadLetterService_ReplayDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	q := new(MockDeadLetterQueue)
+	pub := new(MockPublisher)
+	s := &deadLetterService{queue: q, publisher: pub}
+
+	q.On("Get", ctx, "acme", "t1", uint64(7)).Return(deadLetter(7), nil)
+	pub.On("Publish", ctx, mock.MatchedBy(func(task *types.DeliveryTask) bool {
+		return task.DocumentID == "d1" && task.PreIssuedToken == "" && !task.SubjectHashed && task.Replay == 7
+	})).Return(nil)
+	q.On("Delete", ctx, "acme", "t1", uint64(7)).Return(nil)
+
+	assert.NoError(t, s.ReplayDeadLetter(ctx, "acme", "t1", 7))
+	q.AssertExpectations(t)
+	pub.AssertExpectations(t)
+
+	// Not found dead letters are reported as such.
+	q.On("Get", ctx, "acme", "t1", uint64(8)).Return(nil, types.ErrDeadLetterNotFound)
+	assert.ErrorIs(t, s.ReplayDeadLetter(ctx, "acme", "t1", 8), types.ErrDeadLetterNotFound)
+}
+
+func TestDeadLetterService_ReplayDeadLetter_KeepsLetterOnPublishError(t *testing.T) {
+	ctx := context.Background()
+	q := new(MockDeadLetterQueue)
+	pub := new(MockPublisher)
+	s := &deadLetterService{queue: q, publisher: pub}
+
+	q.On("Get", ctx, "acme", "t1", uint64(7)).Return(deadLetter(7), nil)
+	pub.On("Publish", ctx, mock.Anything).Return(errors.New("nats down"))
+
+	assert.Error(t, s.ReplayDeadLetter(ctx, "acme", "t1", 7))
+	q.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeadLetterService_ReplayDeadLetters_Pages(t *testing.T) {
+	ctx := context.Background()
+	q := new(MockDeadLetterQueue)
+	pub := new(MockPublisher)
+	s := &deadLetterService{queue: q, publisher: pub}
+
+	full := make([]*types.DeadLetter, types.MaxDeadLetterPageSize)
+	for i := range full {
+		full[i] = deadLetter(uint64(i + 1))
+	}
+	filter := types.DeadLetterFilter{Status: 500}
+	first := filter
+	first.Limit = types.MaxDeadLetterPageSize
+	second := first
+	second.After = types.MaxDeadLetterPageSize
+
+	q.On("List", ctx, "acme", "t1", first).Return(full, nil)
+	q.On("List", ctx, "acme", "t1", second).Return([]*types.DeadLetter{deadLetter(5000)}, nil)
+	q.On("Delete", ctx, "acme", "t1", mock.Anything).Return(nil)
+	pub.On("Publish", ctx, mock.Anything).Return(nil)
+
+	n, err := s.ReplayDeadLetters(ctx, "acme", "t1", filter)
+	assert.NoError(t, err)
+	assert.Equal(t, types.MaxDeadLetterPageSize+1, n)
+	q.AssertExpectations(t)
+}
+
+func TestDeadLetterService_ReplayDeadLetters_Limit(t *testing.T) {
+	ctx := context.Background()
+	q := new(MockDeadLetterQueue)
+	pub := new(MockPublisher)
+	s := &deadLetterService{queue: q, publisher: pub}
+
+	filter := types.DeadLetterFilter{Limit: 2}
+	q.On("List", ctx, "acme", "t1", filter).Return([]*types.DeadLetter{deadLetter(1), deadLetter(2)}, nil)
+	q.On("Delete", ctx, "acme", "t1", mock.Anything).Return(nil)
+	pub.On("Publish", ctx, mock.Anything).Return(nil).Once()
+	pub.On("Publish", ctx, mock.Anything).Return(errors.New("nats down")).Once()
+
+	n, err := s.ReplayDeadLetters(ctx, "acme", "t1", filter)
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	q.AssertNumberOfCalls(t, "List", 1)
+}
+
+func TestDeadLetterService_Passthrough(t *testing.T) {
+	ctx := context.Background()
+	q := new(MockDeadLetterQueue)
+	s := &deadLetterService{queue: q}
+
+	filter := types.DeadLetterFilter{Event: "create"}
+	q.On("List", ctx, "acme", "t1", filter).Return([]*types.DeadLetter{deadLetter(1)}, nil)
+	q.On("Get", ctx, "acme", "t1", uint64(1)).Return(deadLetter(1), nil)
+	q.On("Delete", ctx, "acme", "t1", uint64(1)).Return(nil)
+	q.On("Purge", ctx, "acme", "t1").Return(nil)
+
+	letters, err := s.ListDeadLetters(ctx, "acme", "t1", filter)
+	assert.NoError(t, err)
+	assert.Len(t, letters, 1)
+	letter, err := s.GetDeadLetter(ctx, "acme", "t1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), letter.ID)
+	assert.NoError(t, s.DeleteDeadLetter(ctx, "acme", "t1", 1))
+	assert.NoError(t, s.PurgeDeadLetters(ctx, "acme", "t1"))
+	q.AssertExpectations(t)
+}
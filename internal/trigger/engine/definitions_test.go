@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/internal/trigger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDefinitions is a trigger.Store whose changes the test announces.
+type fakeDefinitions struct {
+	trigger.Store
+	mu       sync.Mutex
+	triggers []*trigger.Trigger
+	changes  chan storage.Event
+	watchErr error
+}
+
+func (f *fakeDefinitions) List(ctx context.Context, tenant string) ([]*trigger.Trigger, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*trigger.Trigger(nil), f.triggers...), nil
+}
+
+func (f *fakeDefinitions) Watch(ctx context.Context, tenant string) (<-chan storage.Event, error) {
+	return f.changes, f.watchErr
+}
+
+func (f *fakeDefinitions) set(triggers ...*trigger.Trigger) {
+	f.mu.Lock()
+	f.triggers = triggers
+	f.mu.Unlock()
+	f.changes <- storage.Event{Type: storage.EventUpdate}
+}
+
+func definition(id string, disabled bool) *trigger.Trigger {
+	return &trigger.Trigger{ID: id, Tenant: "default", Collection: "users", Events: []string{"create"}, URL: "http://example.com", Disabled: disabled}
+}
+
+func (e *defaultTriggerEngine) activeIDs() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var ids []string
+	for _, t := range e.triggers {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
+
+func TestEngine_ReloadsDefinitions(t *testing.T) {
+	defs := &fakeDefinitions{
+		triggers: []*trigger.Trigger{definition("t3", false), definition("t2", true)},
+		changes:  make(chan storage.Event),
+	}
+	mockWatcher := new(MockWatcher)
+	mockWatcher.On("Watch", mock.Anything).Return((<-chan storage.Event)(make(chan storage.Event)), nil)
+	e := &defaultTriggerEngine{watcher: mockWatcher, definitions: defs, tenant: "default"}
+	require.NoError(t, e.LoadTriggers([]*trigger.Trigger{definition("t1", false), definition("t2", false)}))
+	assert.Equal(t, []string{"t1", "t2"}, e.activeIDs())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() { errCh <- e.Start(ctx) }()
+
+	// A stored definition replaces the loaded trigger with its ID.
+	assert.Eventually(t, func() bool {
+		ids := e.activeIDs()
+		return len(ids) == 2 && ids[0] == "t3" && ids[1] == "t1"
+	}, time.Second, 10*time.Millisecond)
+
+	defs.set(definition("t2", false), definition("t4", false))
+	assert.Eventually(t, func() bool {
+		ids := e.activeIDs()
+		return len(ids) == 3 && ids[0] == "t2" && ids[1] == "t4" && ids[2] == "t1"
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
+
+func TestEngine_DefinitionsWatchError(t *testing.T) {
+	defs := &fakeDefinitions{watchErr: errors.New("no change streams")}
+	e := &defaultTriggerEngine{watcher: new(MockWatcher), definitions: defs, tenant: "default"}
+	assert.ErrorContains(t, e.Start(context.Background()), "watch trigger definitions")
+}
@@ -0,0 +1,22 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/codetrek/syntrix/internal/trigger/internal/pubsub"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+)
+
+// deliveryLogService implements types.DeliveryLogService over the delivery
+// log the consumer writes.
+type deliveryLogService struct {
+	log pubsub.DeliveryLog
+}
+
+func (s *deliveryLogService) ListDeliveries(ctx context.Context, tenant, triggerID string, filter types.DeliveryLogFilter) ([]*types.DeliveryRecord, error) {
+	return s.log.List(ctx, tenant, triggerID, filter)
+}
+
+func (s *deliveryLogService) TailDeliveries(ctx context.Context, tenant, triggerID string) (<-chan *types.DeliveryRecord, error) {
+	return s.log.Tail(ctx, tenant, triggerID)
+}
@@ -2,9 +2,13 @@ package engine
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/codetrek/syntrix/internal/storage"
 	"github.com/codetrek/syntrix/internal/trigger"
 	"github.com/codetrek/syntrix/internal/trigger/internal/evaluator"
 	"github.com/codetrek/syntrix/internal/trigger/internal/pubsub"
@@ -12,13 +16,33 @@ import (
 	"github.com/codetrek/syntrix/internal/trigger/types"
 )
 
+const (
+	// minDispatchBackoff and maxDispatchBackoff bound the wait between
+	// attempts to hand a task to NATS.
+	minDispatchBackoff = 100 * time.Millisecond
+	maxDispatchBackoff = 10 * time.Second
+)
+
 // defaultTriggerEngine implements TriggerEngine.
 type defaultTriggerEngine struct {
 	evaluator evaluator.Evaluator
 	watcher   watcher.DocumentWatcher
 	publisher pubsub.TaskPublisher
-	triggers  []*trigger.Trigger
-	mu        sync.RWMutex
+	scheduler *scheduler // fires scheduled triggers and delayed tasks, nil without NATS
+
+	// dispatchBackoff is the first wait before a failed publish is retried;
+	// zero means minDispatchBackoff.
+	dispatchBackoff time.Duration
+
+	// definitions holds the triggers managed through the admin API. The
+	// engine reloads them whenever they change.
+	definitions trigger.Store
+	tenant      string
+
+	static   []*trigger.Trigger // loaded with LoadTriggers
+	stored   []*trigger.Trigger // from definitions
+	triggers []*trigger.Trigger // enabled triggers of both, stored first
+	mu       sync.RWMutex
 }
 
 // LoadTriggers validates and loads the given triggers.
@@ -30,23 +54,120 @@ func (e *defaultTriggerEngine) LoadTriggers(triggers []*trigger.Trigger) error {
 		if err := trigger.ValidateTrigger(t); err != nil {
 			return err
 		}
+		if !e.canRun(t) {
+			return fmt.Errorf("trigger %s needs a scheduler, which requires NATS", t.ID)
+		}
 	}
 
-	e.triggers = triggers
+	e.static = triggers
+	e.mergeLocked()
 	return nil
 }
 
+// reloadDefinitions replaces the stored triggers with the current
+// definitions of the tenant.
+func (e *defaultTriggerEngine) reloadDefinitions(ctx context.Context) error {
+	stored, err := e.definitions.List(ctx, e.tenant)
+	if err != nil {
+		return fmt.Errorf("failed to load trigger definitions: %w", err)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].ID < stored[j].ID })
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stored = stored
+	e.mergeLocked()
+	log.Printf("[Info] Loaded %d trigger definitions for tenant %s", len(stored), e.tenant)
+	return nil
+}
+
+// mergeLocked computes the active triggers. A stored definition replaces a
+// loaded trigger with the same ID, and disabled triggers are left out, as are
+// stored ones the engine cannot run.
+func (e *defaultTriggerEngine) mergeLocked() {
+	ids := make(map[string]bool, len(e.stored))
+	triggers := make([]*trigger.Trigger, 0, len(e.stored)+len(e.static))
+	for _, t := range e.stored {
+		ids[t.ID] = true
+		if t.Disabled {
+			continue
+		}
+		if !e.canRun(t) {
+			log.Printf("[Warning] Skipping trigger %s: it needs a scheduler, which requires NATS", t.ID)
+			continue
+		}
+		triggers = append(triggers, t)
+	}
+	for _, t := range e.static {
+		if !ids[t.ID] && !t.Disabled {
+			triggers = append(triggers, t)
+		}
+	}
+	e.triggers = triggers
+}
+
+// canRun reports whether the engine can run t. Schedules and delays are
+// kept by the scheduler, which the engine has only with NATS.
+func (e *defaultTriggerEngine) canRun(t *trigger.Trigger) bool {
+	return e.scheduler != nil || (t.Schedule == "" && t.Delay == 0)
+}
+
+// watchDefinitions follows changes of the stored triggers. It returns a nil
+// channel, which never delivers, when the engine has no definitions store.
+func (e *defaultTriggerEngine) watchDefinitions(ctx context.Context) (<-chan storage.Event, error) {
+	if e.definitions == nil {
+		return nil, nil
+	}
+	changes, err := e.definitions.Watch(ctx, e.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch trigger definitions: %w", err)
+	}
+	// Load after the watch opens so no change falls in between.
+	if err := e.reloadDefinitions(ctx); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
 // Start begins the trigger processing loop.
 func (e *defaultTriggerEngine) Start(ctx context.Context) error {
+	changes, err := e.watchDefinitions(ctx)
+	if err != nil {
+		return err
+	}
+
 	stream, err := e.watcher.Watch(ctx)
 	if err != nil {
 		return err
 	}
 
+	if e.scheduler != nil {
+		schedCtx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.scheduler.run(schedCtx, e.activeTriggers)
+		}()
+		defer wg.Wait()
+		defer cancel()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
+		case _, ok := <-changes:
+			if !ok {
+				log.Printf("[Warning] Trigger definitions watch closed for tenant %s, reopening", e.tenant)
+				if changes, err = e.watchDefinitions(ctx); err != nil {
+					log.Printf("[Error] %v; definition changes are no longer applied", err)
+				}
+				continue
+			}
+			if err := e.reloadDefinitions(ctx); err != nil {
+				log.Printf("[Error] %v", err)
+			}
 		case evt, ok := <-stream:
 			if !ok {
 				return nil
@@ -58,6 +179,18 @@ func (e *defaultTriggerEngine) Start(ctx context.Context) error {
 				continue
 			}
 
+			// Changes written back at the depth limit end the chain, so
+			// triggers answering each other cannot loop forever.
+			depth := 0
+			if evt.Origin != nil {
+				depth = evt.Origin.Depth
+			}
+			if depth >= types.MaxTriggerDepth {
+				log.Printf("[Warning] Skipping change %s written by trigger %s at depth %d: trigger depth limit reached", evt.Id, evt.Origin.TriggerID, depth)
+				e.saveCheckpoint(ctx, &evt)
+				continue
+			}
+
 			e.mu.RLock()
 			currentTriggers := e.triggers
 			e.mu.RUnlock()
@@ -83,32 +216,106 @@ func (e *defaultTriggerEngine) Start(ctx context.Context) error {
 						payload = evt.Before.Data
 					}
 
-					task := &trigger.DeliveryTask{
-						TriggerID:   t.ID,
-						Tenant:      t.Tenant,
-						Event:       string(evt.Type),
-						Collection:  collection,
-						DocumentID:  documentID,
-						Payload:     payload,
-						URL:         t.URL,
-						Headers:     t.Headers,
-						RetryPolicy: t.RetryPolicy,
-						Timeout:     trigger.Duration(types.DefaultTaskTimeout),
-					}
-					if e.publisher != nil {
-						if err := e.publisher.Publish(ctx, task); err != nil {
-							log.Printf("[Error] Failed to publish task for trigger %s: %v", t.ID, err)
+					position := eventPosition(&evt)
+					task := newTask(t)
+					task.ID = taskID(t.ID, position, collection, documentID)
+					task.Event = string(evt.Type)
+					task.Collection = collection
+					task.DocumentID = documentID
+					task.LSN = position
+					task.Seq = eventSeq(&evt)
+					task.Depth = depth
+					task.Payload = payload
+					// With IncludeBefore the webhook sees both images of the
+					// change; Payload stays the current one for older receivers.
+					if t.IncludeBefore {
+						if evt.Before != nil {
+							task.Before = evt.Before.Data
+						}
+						if evt.Document != nil {
+							task.After = evt.Document.Data
 						}
 					}
+					if err := e.dispatch(ctx, t, task, eventTime(&evt)); err != nil {
+						// Only shutdown ends the retries. The checkpoint
+						// stays before this event, so it is read again.
+						return nil
+					}
 				}
 			}
 
-			if evt.ResumeToken != nil {
-				if err := e.watcher.SaveCheckpoint(ctx, evt.ResumeToken); err != nil {
-					log.Printf("[Error] Failed to save checkpoint: %v", err)
-				}
+			e.saveCheckpoint(ctx, &evt)
+		}
+	}
+}
+
+// dispatch hands task to NATS: it publishes it, or stores it until due when
+// t has a delay. Failures are retried with backoff until they succeed or ctx
+// is done, since moving the checkpoint past the event would lose the task.
+// Retrying is safe: the task ID dedups a publish that reached the stream,
+// and a delayed task is written once per ID.
+func (e *defaultTriggerEngine) dispatch(ctx context.Context, t *trigger.Trigger, task *trigger.DeliveryTask, eventAt time.Time) error {
+	backoff := e.dispatchBackoff
+	if backoff <= 0 {
+		backoff = minDispatchBackoff
+	}
+	for {
+		var err error
+		switch {
+		case t.Delay > 0:
+			if err = e.scheduler.store.AddDelayed(ctx, task, eventAt.Add(time.Duration(t.Delay))); err != nil {
+				log.Printf("[Error] Failed to delay task for trigger %s, retrying in %v: %v", t.ID, backoff, err)
+			}
+		case e.publisher != nil:
+			if err = e.publisher.Publish(ctx, task); err != nil {
+				log.Printf("[Error] Failed to publish task for trigger %s, retrying in %v: %v", t.ID, backoff, err)
 			}
 		}
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxDispatchBackoff)
+	}
+}
+
+// activeTriggers returns the enabled triggers.
+func (e *defaultTriggerEngine) activeTriggers() []*trigger.Trigger {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.triggers
+}
+
+// newTask returns a task of t carrying its delivery settings.
+func newTask(t *trigger.Trigger) *trigger.DeliveryTask {
+	task := &trigger.DeliveryTask{
+		TriggerID:   t.ID,
+		Tenant:      t.Tenant,
+		URL:         t.URL,
+		Headers:     t.Headers,
+		RetryPolicy: t.RetryPolicy,
+		Concurrency: t.Concurrency,
+		RateLimit:   t.RateLimit,
+		Timeout:     t.Timeout,
+	}
+	if task.Timeout <= 0 {
+		task.Timeout = trigger.Duration(types.DefaultTaskTimeout)
+	}
+	return task
+}
+
+// saveCheckpoint records evt as processed.
+func (e *defaultTriggerEngine) saveCheckpoint(ctx context.Context, evt *storage.Event) {
+	if evt.ResumeToken == nil {
+		return
+	}
+	if err := e.watcher.SaveCheckpoint(ctx, evt.ResumeToken); err != nil {
+		log.Printf("[Error] Failed to save checkpoint: %v", err)
 	}
 }
 
@@ -10,6 +10,7 @@ import (
 	"github.com/codetrek/syntrix/internal/trigger/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoadTriggers(t *testing.T) {
@@ -33,6 +34,28 @@ func TestLoadTriggers(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestLoadTriggers_DelayNeedsScheduler(t *testing.T) {
+	t.Parallel()
+	e := &defaultTriggerEngine{}
+
+	delayed := &trigger.Trigger{
+		ID:         "remind",
+		Tenant:     "tenant1",
+		Collection: "users",
+		Events:     []string{"create"},
+		URL:        "http://example.com",
+		Delay:      trigger.Duration(time.Minute),
+	}
+	err := e.LoadTriggers([]*trigger.Trigger{delayed})
+	assert.ErrorContains(t, err, "needs a scheduler")
+	assert.Empty(t, e.triggers)
+
+	// Stored definitions cannot be refused, so they are left out.
+	e.stored = []*trigger.Trigger{delayed}
+	e.mergeLocked()
+	assert.Empty(t, e.triggers)
+}
+
 func TestStart(t *testing.T) {
 	t.Parallel()
 	mockEvaluator := new(MockEvaluator)
@@ -47,11 +70,13 @@ func TestStart(t *testing.T) {
 
 	// Setup triggers
 	trig := &trigger.Trigger{
-		ID:         "t1",
-		Tenant:     "tenant1",
-		Collection: "users",
-		Events:     []string{"create"},
-		URL:        "http://example.com",
+		ID:          "t1",
+		Tenant:      "tenant1",
+		Collection:  "users",
+		Events:      []string{"create"},
+		URL:         "http://example.com",
+		Concurrency: 2,
+		RateLimit:   10,
 	}
 	e.LoadTriggers([]*trigger.Trigger{trig})
 
@@ -75,7 +100,8 @@ func TestStart(t *testing.T) {
 
 	// Expect publish
 	mockPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(task *types.DeliveryTask) bool {
-		return task.TriggerID == "t1" && task.DocumentID == "doc1"
+		return task.TriggerID == "t1" && task.DocumentID == "doc1" && task.Concurrency == 2 && task.RateLimit == 10 &&
+			task.LSN == "token1" && task.ID == taskID("t1", "token1", "users", "doc1")
 	})).Return(nil)
 
 	// Expect checkpoint save
@@ -106,6 +132,61 @@ func TestStart(t *testing.T) {
 	mockPublisher.AssertExpectations(t)
 }
 
+func TestStart_TaskFromTrigger(t *testing.T) {
+	t.Parallel()
+	mockEvaluator := new(MockEvaluator)
+	mockWatcher := new(MockWatcher)
+	mockPublisher := new(MockPublisher)
+
+	e := &defaultTriggerEngine{
+		evaluator: mockEvaluator,
+		watcher:   mockWatcher,
+		publisher: mockPublisher,
+	}
+
+	trig := &trigger.Trigger{
+		ID:            "t1",
+		Tenant:        "tenant1",
+		Collection:    "users",
+		Events:        []string{"update"},
+		URL:           "http://example.com",
+		IncludeBefore: true,
+		Timeout:       trigger.Duration(30 * time.Second),
+	}
+	require.NoError(t, e.LoadTriggers([]*trigger.Trigger{trig}))
+
+	eventCh := make(chan storage.Event)
+	mockWatcher.On("Watch", mock.Anything).Return((<-chan storage.Event)(eventCh), nil)
+
+	evt := storage.Event{
+		Type:     storage.EventUpdate,
+		Document: &storage.Document{Id: "doc1", Collection: "users", Data: map[string]interface{}{"n": 2}},
+		Before:   &storage.Document{Id: "doc1", Collection: "users", Data: map[string]interface{}{"n": 1}},
+	}
+	mockEvaluator.On("Evaluate", mock.Anything, trig, &evt).Return(true, nil)
+
+	published := make(chan *types.DeliveryTask, 1)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		published <- args.Get(1).(*types.DeliveryTask)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		errCh <- e.Start(ctx)
+	}()
+	eventCh <- evt
+
+	task := <-published
+	assert.Equal(t, trigger.Duration(30*time.Second), task.Timeout)
+	assert.Equal(t, map[string]interface{}{"n": 1}, task.Before)
+	assert.Equal(t, map[string]interface{}{"n": 2}, task.After)
+
+	cancel()
+	close(eventCh)
+	assert.NoError(t, <-errCh)
+}
+
 func TestClose(t *testing.T) {
 	t.Parallel()
 	e := &defaultTriggerEngine{}
@@ -241,9 +322,10 @@ func TestStart_PublishError(t *testing.T) {
 	mockPublisher := new(MockPublisher)
 
 	e := &defaultTriggerEngine{
-		evaluator: mockEvaluator,
-		watcher:   mockWatcher,
-		publisher: mockPublisher,
+		evaluator:       mockEvaluator,
+		watcher:         mockWatcher,
+		publisher:       mockPublisher,
+		dispatchBackoff: time.Millisecond,
 	}
 
 	trig := &trigger.Trigger{
@@ -265,10 +347,21 @@ func TestStart_PublishError(t *testing.T) {
 			Collection: "users",
 			Data:       map[string]interface{}{"foo": "bar"},
 		},
+		ResumeToken: "token1",
 	}
 
+	var taskIDs []string
 	mockEvaluator.On("Evaluate", mock.Anything, trig, &evt).Return(true, nil)
-	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(assert.AnError)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(assert.AnError).Twice().Run(func(args mock.Arguments) {
+		taskIDs = append(taskIDs, args.Get(1).(*types.DeliveryTask).ID)
+	})
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+		taskIDs = append(taskIDs, args.Get(1).(*types.DeliveryTask).ID)
+	})
+	saved := make(chan struct{})
+	mockWatcher.On("SaveCheckpoint", mock.Anything, "token1").Return(nil).Once().Run(func(args mock.Arguments) {
+		close(saved)
+	})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	errCh := make(chan error)
@@ -277,13 +370,70 @@ func TestStart_PublishError(t *testing.T) {
 	}()
 
 	eventCh <- evt
-	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-saved:
+	case <-time.After(time.Second):
+		t.Fatal("expected the checkpoint once the publish went through")
+	}
 	cancel()
 	close(eventCh)
 
-	err := <-errCh
-	assert.NoError(t, err) // Continues despite publish error
+	assert.NoError(t, <-errCh)
 	mockPublisher.AssertExpectations(t)
+	require.Len(t, taskIDs, 3)
+	assert.Equal(t, taskIDs[0], taskIDs[2], "retries reuse the task ID for dedup")
+}
+
+func TestStart_PublishError_KeepsCheckpoint(t *testing.T) {
+	t.Parallel()
+	mockEvaluator := new(MockEvaluator)
+	mockWatcher := new(MockWatcher)
+	mockPublisher := new(MockPublisher)
+
+	e := &defaultTriggerEngine{
+		evaluator:       mockEvaluator,
+		watcher:         mockWatcher,
+		publisher:       mockPublisher,
+		dispatchBackoff: time.Millisecond,
+	}
+
+	trig := &trigger.Trigger{
+		ID:         "t1",
+		Tenant:     "tenant1",
+		Collection: "users",
+		Events:     []string{"create"},
+		URL:        "http://example.com",
+	}
+	e.LoadTriggers([]*trigger.Trigger{trig})
+
+	eventCh := make(chan storage.Event)
+	mockWatcher.On("Watch", mock.Anything).Return((<-chan storage.Event)(eventCh), nil)
+
+	evt := storage.Event{
+		Type: storage.EventCreate,
+		Document: &storage.Document{
+			Id:         "doc1",
+			Collection: "users",
+			Data:       map[string]interface{}{"foo": "bar"},
+		},
+		ResumeToken: "token1",
+	}
+
+	mockEvaluator.On("Evaluate", mock.Anything, trig, &evt).Return(true, nil)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(assert.AnError)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		errCh <- e.Start(ctx)
+	}()
+
+	eventCh <- evt
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	assert.NoError(t, <-errCh)
+	mockWatcher.AssertNotCalled(t, "SaveCheckpoint", mock.Anything, mock.Anything)
 }
 
 func TestStart_SaveCheckpointError(t *testing.T) {
@@ -458,3 +608,67 @@ func TestClose_Success(t *testing.T) {
 	mockWatcher.AssertExpectations(t)
 	mockPublisher.AssertExpectations(t)
 }
+
+func TestStart_OriginDepth(t *testing.T) {
+	t.Parallel()
+	mockEvaluator := new(MockEvaluator)
+	mockWatcher := new(MockWatcher)
+	mockPublisher := new(MockPublisher)
+
+	e := &defaultTriggerEngine{
+		evaluator: mockEvaluator,
+		watcher:   mockWatcher,
+		publisher: mockPublisher,
+	}
+	trig := &trigger.Trigger{ID: "t1", Tenant: "tenant1", Collection: "users", Events: []string{"update"}, URL: "http://example.com"}
+	require.NoError(t, e.LoadTriggers([]*trigger.Trigger{trig}))
+
+	eventCh := make(chan storage.Event)
+	mockWatcher.On("Watch", mock.Anything).Return((<-chan storage.Event)(eventCh), nil)
+	mockWatcher.On("SaveCheckpoint", mock.Anything, mock.Anything).Return(nil)
+	mockEvaluator.On("Evaluate", mock.Anything, trig, mock.Anything).Return(true, nil)
+
+	published := make(chan *types.DeliveryTask, 2)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		published <- args.Get(1).(*types.DeliveryTask)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		errCh <- e.Start(ctx)
+	}()
+
+	// A write-back fires triggers one hop deeper.
+	eventCh <- storage.Event{
+		Type:        storage.EventUpdate,
+		Document:    &storage.Document{Id: "doc1", Collection: "users"},
+		Origin:      &storage.Origin{TriggerID: "t0", TaskID: "task-0", Depth: 3},
+		ResumeToken: "tok1",
+	}
+	task := <-published
+	assert.Equal(t, 3, task.Depth)
+
+	// At the depth limit the chain ends; the change is still checkpointed.
+	eventCh <- storage.Event{
+		Type:        storage.EventUpdate,
+		Document:    &storage.Document{Id: "doc1", Collection: "users"},
+		Origin:      &storage.Origin{TriggerID: "t1", TaskID: task.ID, Depth: types.MaxTriggerDepth},
+		ResumeToken: "tok2",
+	}
+	// Send one more event so the loop has handled the previous one.
+	eventCh <- storage.Event{
+		Type:     storage.EventUpdate,
+		Document: &storage.Document{Id: "doc2", Collection: "users"},
+	}
+	task = <-published
+	assert.Equal(t, "users", task.Collection)
+	assert.Equal(t, 0, task.Depth)
+	assert.Empty(t, published)
+
+	cancel()
+	close(eventCh)
+	assert.NoError(t, <-errCh)
+	mockWatcher.AssertCalled(t, "SaveCheckpoint", mock.Anything, "tok2")
+	mockPublisher.AssertNumberOfCalls(t, "Publish", 2)
+}
@@ -1,10 +1,16 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/codetrek/syntrix/internal/identity"
 	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/internal/trigger"
 	"github.com/codetrek/syntrix/internal/trigger/internal/evaluator"
 	"github.com/codetrek/syntrix/internal/trigger/internal/pubsub"
 	"github.com/codetrek/syntrix/internal/trigger/internal/watcher"
@@ -18,8 +24,16 @@ var (
 	newTaskConsumer  = func(nc *nats.Conn, w worker.DeliveryWorker, streamName string, numWorkers int, metrics types.Metrics, opts ...pubsub.ConsumerOption) (pubsub.TaskConsumer, error) {
 		return pubsub.NewTaskConsumer(nc, w, streamName, numWorkers, metrics, opts...)
 	}
+	newDeadLetterQueue = pubsub.NewDeadLetterQueue
+	newDeliveryLimiter = pubsub.NewDeliveryLimiter
+	newDeliveryLog     = pubsub.NewDeliveryLog
+	newScheduleStore   = pubsub.NewScheduleStore
+	newLeaderElector   = pubsub.NewLeaderElector
 )
 
+// validSinkPrefix matches a NATS subject without wildcards.
+var validSinkPrefix = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)*$`)
+
 // FactoryOption configures the factory.
 type FactoryOption func(*defaultTriggerFactory)
 
@@ -60,31 +74,60 @@ func WithStreamName(name string) FactoryOption {
 	}
 }
 
+// WithStreamOptions sets the storage, limits and replicas of the task stream.
+func WithStreamOptions(opts types.StreamOptions) FactoryOption {
+	return func(f *defaultTriggerFactory) {
+		f.streamOpts = opts
+	}
+}
+
+// WithNATSSinkPrefix sets the subject prefix nats:// targets publish under.
+func WithNATSSinkPrefix(prefix string) FactoryOption {
+	return func(f *defaultTriggerFactory) {
+		if prefix != "" {
+			f.natsSinkPrefix = prefix
+		}
+	}
+}
+
 // defaultTriggerFactory implements TriggerFactory.
 type defaultTriggerFactory struct {
-	store        storage.DocumentStore
-	nats         *nats.Conn
-	auth         identity.AuthN
-	tenant       string
-	startFromNow bool
-	metrics      types.Metrics
-	secrets      worker.SecretProvider
-	streamName   string
+	store          storage.DocumentStore
+	nats           *nats.Conn
+	auth           identity.AuthN
+	tenant         string
+	startFromNow   bool
+	metrics        types.Metrics
+	secrets        worker.SecretProvider
+	streamName     string
+	streamOpts     types.StreamOptions
+	natsSinkPrefix string
+
+	mu      sync.Mutex
+	closers []io.Closer // sinks of the consumers created
 }
 
 // NewFactory creates a new TriggerFactory.
 func NewFactory(store storage.DocumentStore, nats *nats.Conn, auth identity.AuthN, opts ...FactoryOption) (TriggerFactory, error) {
 	f := &defaultTriggerFactory{
-		store:      store,
-		nats:       nats,
-		auth:       auth,
-		tenant:     "default",
-		metrics:    &types.NoopMetrics{},
-		streamName: "TRIGGERS",
+		store:          store,
+		nats:           nats,
+		auth:           auth,
+		tenant:         "default",
+		metrics:        &types.NoopMetrics{},
+		streamName:     "TRIGGERS",
+		natsSinkPrefix: types.DefaultNATSSinkPrefix,
 	}
 	for _, opt := range opts {
 		opt(f)
 	}
+	// Sinks publish on the connection of the task streams, so their subjects
+	// must stay clear of those streams.
+	first, _, _ := strings.Cut(f.natsSinkPrefix, ".")
+	if !validSinkPrefix.MatchString(f.natsSinkPrefix) || types.IsReservedSubject(f.natsSinkPrefix) ||
+		first == f.streamName || strings.HasPrefix(first, f.streamName+"_") {
+		return nil, fmt.Errorf("invalid nats sink prefix: %s", f.natsSinkPrefix)
+	}
 	return f, nil
 }
 
@@ -100,18 +143,37 @@ func (f *defaultTriggerFactory) Engine() (TriggerEngine, error) {
 	})
 
 	var pub pubsub.TaskPublisher
+	var sched *scheduler
 	if f.nats != nil {
-		p, err := newTaskPublisher(f.nats, f.streamName, f.metrics)
+		p, err := newTaskPublisher(f.nats, f.streamName, f.metrics, pubsub.WithPublisherStreamOptions(f.streamOpts))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create publisher: %w", err)
 		}
 		pub = p
+
+		store, err := newScheduleStore(f.nats, f.streamName, f.streamOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create schedule store: %w", err)
+		}
+		elector, err := newLeaderElector(f.nats, f.streamName, f.tenant)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scheduler election: %w", err)
+		}
+		sched = newScheduler(f.tenant, store, elector, pub)
+	}
+
+	var definitions trigger.Store
+	if f.store != nil {
+		definitions = trigger.NewStore(f.store)
 	}
 
 	return &defaultTriggerEngine{
-		evaluator: eval,
-		watcher:   w,
-		publisher: pub,
+		evaluator:   eval,
+		watcher:     w,
+		publisher:   pub,
+		scheduler:   sched,
+		definitions: definitions,
+		tenant:      f.tenant,
 	}, nil
 }
 
@@ -121,17 +183,96 @@ func (f *defaultTriggerFactory) Consumer(numWorkers int) (TaskConsumer, error) {
 		return nil, fmt.Errorf("nats connection is required for consumer")
 	}
 
-	w := worker.NewDeliveryWorker(f.auth, f.secrets, worker.HTTPClientOptions{}, f.metrics)
+	w := f.deliveryRouter()
+
+	dlq, err := newDeadLetterQueue(f.nats, f.streamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead letter queue: %w", err)
+	}
+
+	limiter, err := newDeliveryLimiter(f.nats, f.streamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delivery limiter: %w", err)
+	}
+
+	deliveries, err := newDeliveryLog(f.nats, f.streamName, f.streamOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delivery log: %w", err)
+	}
+
+	f.mu.Lock()
+	f.closers = append(f.closers, w)
+	f.mu.Unlock()
+
+	return newTaskConsumer(f.nats, w, f.streamName, numWorkers, f.metrics, pubsub.WithDeadLetterQueue(dlq), pubsub.WithDeliveryLimiter(limiter), pubsub.WithDeliveryLog(deliveries), pubsub.WithStreamOptions(f.streamOpts))
+}
+
+// deliveryRouter returns a worker delivering each task with the sink of its
+// URL scheme. Collection targets and webhook write-backs need the document
+// store.
+func (f *defaultTriggerFactory) deliveryRouter() *worker.Router {
+	var opts worker.HTTPClientOptions
+	if f.store != nil {
+		opts.WriteBack = worker.NewWriteBack(f.store)
+	}
+	httpSink := worker.NewDeliveryWorker(f.auth, f.secrets, opts, f.metrics)
+	grpcSink := worker.NewGRPCSink(f.auth, f.secrets, f.metrics)
+	sinks := map[string]worker.DeliveryWorker{
+		types.SchemeHTTP:  httpSink,
+		types.SchemeHTTPS: httpSink,
+		types.SchemeNATS:  worker.NewNATSSink(f.nats, f.natsSinkPrefix, f.metrics),
+		types.SchemeGRPC:  grpcSink,
+		types.SchemeGRPCS: grpcSink,
+	}
+	if f.store != nil {
+		sinks[types.SchemeCollection] = worker.NewCollectionSink(f.store, f.metrics)
+	}
+	return worker.NewRouter(sinks, f.metrics)
+}
+
+// DeadLetters returns a service over the dead letters of the consumer.
+func (f *defaultTriggerFactory) DeadLetters() (types.DeadLetterService, error) {
+	if f.nats == nil {
+		return nil, fmt.Errorf("nats connection is required for dead letters")
+	}
 
-	return newTaskConsumer(f.nats, w, f.streamName, numWorkers, f.metrics)
+	dlq, err := newDeadLetterQueue(f.nats, f.streamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead letter queue: %w", err)
+	}
+	pub, err := newTaskPublisher(f.nats, f.streamName, f.metrics, pubsub.WithPublisherStreamOptions(f.streamOpts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create publisher: %w", err)
+	}
+
+	return &deadLetterService{queue: dlq, publisher: pub}, nil
 }
 
-// Close releases resources held by the factory.
+// Deliveries returns a service over the delivery log of the consumer.
+func (f *defaultTriggerFactory) Deliveries() (types.DeliveryLogService, error) {
+	if f.nats == nil {
+		return nil, fmt.Errorf("nats connection is required for the delivery log")
+	}
+
+	deliveries, err := newDeliveryLog(f.nats, f.streamName, f.streamOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delivery log: %w", err)
+	}
+	return &deliveryLogService{log: deliveries}, nil
+}
+
+// Close releases resources held by the factory: the connections of the
+// delivery sinks.
 // Note: The factory does NOT own the NATS connection - it is the caller's
 // responsibility to manage the NATS connection lifecycle. This design allows
 // the NATS connection to be shared across multiple components.
 func (f *defaultTriggerFactory) Close() error {
-	// Factory does not own any resources that need explicit cleanup.
-	// The NATS connection is managed by the caller (ServiceManager).
-	return nil
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var errs []error
+	for _, c := range f.closers {
+		errs = append(errs, c.Close())
+	}
+	f.closers = nil
+	return errors.Join(errs...)
 }
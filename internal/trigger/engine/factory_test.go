@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/codetrek/syntrix/internal/trigger/internal/pubsub"
@@ -10,6 +11,7 @@ import (
 	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockTaskConsumer
@@ -29,6 +31,18 @@ func TestNewFactory(t *testing.T) {
 	assert.NotNil(t, f)
 }
 
+func TestNewFactory_NATSSinkPrefix(t *testing.T) {
+	t.Parallel()
+	f, err := NewFactory(nil, nil, nil, WithNATSSinkPrefix("hooks.out"))
+	require.NoError(t, err)
+	assert.Equal(t, "hooks.out", f.(*defaultTriggerFactory).natsSinkPrefix)
+
+	for _, prefix := range []string{"hooks.*", "syntrix.hooks", "TRIGGERS", "TRIGGERS_DLQ", "JOBS.out"} {
+		_, err := NewFactory(nil, nil, nil, WithStreamName("JOBS"), WithNATSSinkPrefix(prefix))
+		assert.ErrorContains(t, err, "invalid nats sink prefix", prefix)
+	}
+}
+
 func TestFactoryOptions(t *testing.T) {
 	t.Parallel()
 	f := &defaultTriggerFactory{}
@@ -69,9 +83,10 @@ func TestFactory_Engine_WithNATS(t *testing.T) {
 	defer func() { newTaskPublisher = originalNewTaskPublisher }()
 
 	mockPub := new(MockPublisher)
-	newTaskPublisher = func(nc *nats.Conn, streamName string, metrics types.Metrics) (pubsub.TaskPublisher, error) {
+	newTaskPublisher = func(nc *nats.Conn, streamName string, metrics types.Metrics, opts ...pubsub.PublisherOption) (pubsub.TaskPublisher, error) {
 		return mockPub, nil
 	}
+	stubScheduler(t, nil, nil)
 
 	// Pass a dummy nats conn (can be nil if our mock doesn't check, but factory checks f.nats != nil)
 	// We need f.nats != nil to trigger the branch.
@@ -83,6 +98,39 @@ func TestFactory_Engine_WithNATS(t *testing.T) {
 	e, err := f.Engine()
 	assert.NoError(t, err)
 	assert.NotNil(t, e)
+	assert.NotNil(t, e.(*defaultTriggerEngine).scheduler)
+}
+
+// stubScheduler replaces the scheduler constructors for the test, failing
+// with the given errors.
+func stubScheduler(t *testing.T, storeErr, electorErr error) {
+	origStore, origElector := newScheduleStore, newLeaderElector
+	t.Cleanup(func() { newScheduleStore, newLeaderElector = origStore, origElector })
+	newScheduleStore = func(nc *nats.Conn, streamName string, opts types.StreamOptions) (pubsub.ScheduleStore, error) {
+		return pubsub.NewScheduleStoreFromBucket(pubsub.NewMemoryBucket()), storeErr
+	}
+	newLeaderElector = func(nc *nats.Conn, streamName, tenant string) (pubsub.LeaderElector, error) {
+		return &stubElector{leading: true}, electorErr
+	}
+}
+
+func TestFactory_Engine_SchedulerError(t *testing.T) {
+	originalNewTaskPublisher := newTaskPublisher
+	defer func() { newTaskPublisher = originalNewTaskPublisher }()
+	newTaskPublisher = func(nc *nats.Conn, streamName string, metrics types.Metrics, opts ...pubsub.PublisherOption) (pubsub.TaskPublisher, error) {
+		return new(MockPublisher), nil
+	}
+
+	f, err := NewFactory(nil, &nats.Conn{}, nil)
+	require.NoError(t, err)
+
+	stubScheduler(t, errors.New("no bucket"), nil)
+	_, err = f.Engine()
+	assert.ErrorContains(t, err, "failed to create schedule store")
+
+	stubScheduler(t, nil, errors.New("no bucket"))
+	_, err = f.Engine()
+	assert.ErrorContains(t, err, "failed to create scheduler election")
 }
 
 func TestFactory_Consumer_Fail(t *testing.T) {
@@ -100,10 +148,30 @@ func TestFactory_Consumer_Success(t *testing.T) {
 	originalNewTaskConsumer := newTaskConsumer
 	defer func() { newTaskConsumer = originalNewTaskConsumer }()
 
+	originalNewDeadLetterQueue := newDeadLetterQueue
+	defer func() { newDeadLetterQueue = originalNewDeadLetterQueue }()
+	originalNewDeliveryLimiter := newDeliveryLimiter
+	defer func() { newDeliveryLimiter = originalNewDeliveryLimiter }()
+	originalNewDeliveryLog := newDeliveryLog
+	defer func() { newDeliveryLog = originalNewDeliveryLog }()
+
 	mockConsumer := new(MockTaskConsumer)
+	var gotOpts []pubsub.ConsumerOption
+	var gotWorker worker.DeliveryWorker
 	newTaskConsumer = func(nc *nats.Conn, w worker.DeliveryWorker, streamName string, numWorkers int, metrics types.Metrics, opts ...pubsub.ConsumerOption) (pubsub.TaskConsumer, error) {
+		gotOpts = opts
+		gotWorker = w
 		return mockConsumer, nil
 	}
+	newDeadLetterQueue = func(nc *nats.Conn, streamName string) (pubsub.DeadLetterQueue, error) {
+		return new(MockDeadLetterQueue), nil
+	}
+	newDeliveryLimiter = func(nc *nats.Conn, streamName string) (pubsub.DeliveryLimiter, error) {
+		return new(MockDeliveryLimiter), nil
+	}
+	newDeliveryLog = func(nc *nats.Conn, streamName string, opts types.StreamOptions) (pubsub.DeliveryLog, error) {
+		return &stubDeliveryLog{}, nil
+	}
 
 	f, err := NewFactory(nil, &nats.Conn{}, nil)
 	assert.NoError(t, err)
@@ -111,6 +179,163 @@ func TestFactory_Consumer_Success(t *testing.T) {
 	c, err := f.Consumer(1)
 	assert.NoError(t, err)
 	assert.NotNil(t, c)
+	assert.Len(t, gotOpts, 4, "dead letter queue, limiter, delivery log and stream options")
+	assert.IsType(t, &worker.Router{}, gotWorker)
+	assert.NoError(t, f.Close(), "closes the sinks")
+}
+
+func TestFactory_Consumer_DeliveryLimiterError(t *testing.T) {
+	originalNewDeadLetterQueue := newDeadLetterQueue
+	originalNewDeliveryLimiter := newDeliveryLimiter
+	defer func() {
+		newDeadLetterQueue = originalNewDeadLetterQueue
+		newDeliveryLimiter = originalNewDeliveryLimiter
+	}()
+
+	newDeadLetterQueue = func(nc *nats.Conn, streamName string) (pubsub.DeadLetterQueue, error) {
+		return new(MockDeadLetterQueue), nil
+	}
+	newDeliveryLimiter = func(nc *nats.Conn, streamName string) (pubsub.DeliveryLimiter, error) {
+		return nil, errors.New("no bucket")
+	}
+
+	f, err := NewFactory(nil, &nats.Conn{}, nil)
+	assert.NoError(t, err)
+
+	c, err := f.Consumer(1)
+	assert.ErrorContains(t, err, "delivery limiter")
+	assert.Nil(t, c)
+}
+
+func TestFactory_Consumer_DeadLetterQueueError(t *testing.T) {
+	originalNewDeadLetterQueue := newDeadLetterQueue
+	defer func() { newDeadLetterQueue = originalNewDeadLetterQueue }()
+
+	newDeadLetterQueue = func(nc *nats.Conn, streamName string) (pubsub.DeadLetterQueue, error) {
+		return nil, errors.New("no stream")
+	}
+
+	f, err := NewFactory(nil, &nats.Conn{}, nil)
+	assert.NoError(t, err)
+
+	c, err := f.Consumer(1)
+	assert.ErrorContains(t, err, "dead letter queue")
+	assert.Nil(t, c)
+}
+
+func TestFactory_Consumer_DeliveryLogError(t *testing.T) {
+	originalNewDeadLetterQueue := newDeadLetterQueue
+	originalNewDeliveryLimiter := newDeliveryLimiter
+	originalNewDeliveryLog := newDeliveryLog
+	defer func() {
+		newDeadLetterQueue = originalNewDeadLetterQueue
+		newDeliveryLimiter = originalNewDeliveryLimiter
+		newDeliveryLog = originalNewDeliveryLog
+	}()
+
+	newDeadLetterQueue = func(nc *nats.Conn, streamName string) (pubsub.DeadLetterQueue, error) {
+		return new(MockDeadLetterQueue), nil
+	}
+	newDeliveryLimiter = func(nc *nats.Conn, streamName string) (pubsub.DeliveryLimiter, error) {
+		return new(MockDeliveryLimiter), nil
+	}
+	newDeliveryLog = func(nc *nats.Conn, streamName string, opts types.StreamOptions) (pubsub.DeliveryLog, error) {
+		return nil, errors.New("no stream")
+	}
+
+	f, err := NewFactory(nil, &nats.Conn{}, nil)
+	assert.NoError(t, err)
+
+	c, err := f.Consumer(1)
+	assert.ErrorContains(t, err, "delivery log")
+	assert.Nil(t, c)
+}
+
+// stubDeliveryLog is a pubsub.DeliveryLog holding fixed records.
+type stubDeliveryLog struct {
+	pubsub.DeliveryLog
+	records []*types.DeliveryRecord
+}
+
+func (l *stubDeliveryLog) List(ctx context.Context, tenant, triggerID string, filter types.DeliveryLogFilter) ([]*types.DeliveryRecord, error) {
+	return l.records, nil
+}
+
+func (l *stubDeliveryLog) Tail(ctx context.Context, tenant, triggerID string) (<-chan *types.DeliveryRecord, error) {
+	ch := make(chan *types.DeliveryRecord, len(l.records))
+	for _, rec := range l.records {
+		ch <- rec
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestFactory_Deliveries(t *testing.T) {
+	originalNewDeliveryLog := newDeliveryLog
+	defer func() { newDeliveryLog = originalNewDeliveryLog }()
+
+	var gotStream string
+	var gotOpts types.StreamOptions
+	dl := &stubDeliveryLog{records: []*types.DeliveryRecord{{ID: 1, TaskID: "task-1"}}}
+	newDeliveryLog = func(nc *nats.Conn, streamName string, opts types.StreamOptions) (pubsub.DeliveryLog, error) {
+		gotStream, gotOpts = streamName, opts
+		return dl, nil
+	}
+
+	f, err := NewFactory(nil, nil, nil)
+	require.NoError(t, err)
+	_, err = f.Deliveries()
+	assert.Error(t, err, "requires nats")
+
+	f, err = NewFactory(nil, &nats.Conn{}, nil, WithStreamName("JOBS"), WithStreamOptions(types.StreamOptions{Storage: types.StreamStorageFile}))
+	require.NoError(t, err)
+	s, err := f.Deliveries()
+	require.NoError(t, err)
+	assert.Equal(t, "JOBS", gotStream)
+	assert.Equal(t, types.StreamStorageFile, gotOpts.Storage)
+
+	records, err := s.ListDeliveries(context.Background(), "acme", "t1", types.DeliveryLogFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, dl.records, records)
+	tail, err := s.TailDeliveries(context.Background(), "acme", "t1")
+	require.NoError(t, err)
+	assert.Equal(t, "task-1", (<-tail).TaskID)
+
+	newDeliveryLog = func(nc *nats.Conn, streamName string, opts types.StreamOptions) (pubsub.DeliveryLog, error) {
+		return nil, errors.New("no stream")
+	}
+	_, err = f.Deliveries()
+	assert.ErrorContains(t, err, "failed to create delivery log")
+}
+
+func TestFactory_DeadLetters(t *testing.T) {
+	originalNewDeadLetterQueue := newDeadLetterQueue
+	originalNewTaskPublisher := newTaskPublisher
+	defer func() {
+		newDeadLetterQueue = originalNewDeadLetterQueue
+		newTaskPublisher = originalNewTaskPublisher
+	}()
+
+	var gotStream string
+	newDeadLetterQueue = func(nc *nats.Conn, streamName string) (pubsub.DeadLetterQueue, error) {
+		gotStream = streamName
+		return new(MockDeadLetterQueue), nil
+	}
+	newTaskPublisher = func(nc *nats.Conn, streamName string, metrics types.Metrics, opts ...pubsub.PublisherOption) (pubsub.TaskPublisher, error) {
+		return new(MockPublisher), nil
+	}
+
+	f, err := NewFactory(nil, nil, nil)
+	assert.NoError(t, err)
+	_, err = f.DeadLetters()
+	assert.Error(t, err, "requires nats")
+
+	f, err = NewFactory(nil, &nats.Conn{}, nil, WithStreamName("JOBS"))
+	assert.NoError(t, err)
+	s, err := f.DeadLetters()
+	assert.NoError(t, err)
+	assert.NotNil(t, s)
+	assert.Equal(t, "JOBS", gotStream)
 }
 
 func TestFactory_Close(t *testing.T) {
@@ -35,6 +35,14 @@ type TriggerFactory interface {
 	// Consumer returns a new TaskConsumer.
 	Consumer(numWorkers int) (TaskConsumer, error)
 
+	// DeadLetters returns a service to inspect and replay the tasks the
+	// consumer gave up on.
+	DeadLetters() (trigger.DeadLetterService, error)
+
+	// Deliveries returns a service to read the log of delivery attempts
+	// the consumer keeps per trigger.
+	Deliveries() (trigger.DeliveryLogService, error)
+
 	// Close releases any resources held by the factory.
 	Close() error
 }
@@ -56,3 +56,14 @@ func (m *MockPublisher) Close() error {
 	args := m.Called()
 	return args.Error(0)
 }
+
+// MockDeliveryLimiter
+type MockDeliveryLimiter struct {
+	mock.Mock
+}
+
+func (m *MockDeliveryLimiter) Acquire(ctx context.Context, task *types.DeliveryTask) (func(), error) {
+	args := m.Called(ctx, task)
+	release, _ := args.Get(0).(func())
+	return release, args.Error(1)
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+)
+
+// eventPosition returns the change stream position of evt: the resume token
+// as a string, or the commit time when the backend gives no token.
+func eventPosition(evt *storage.Event) string {
+	switch token := evt.ResumeToken.(type) {
+	case nil:
+	case string:
+		return token
+	default:
+		if doc, ok := token.(map[string]interface{}); ok {
+			if data, ok := doc["_data"].(string); ok {
+				return data
+			}
+		}
+		// Tokens are documents; their JSON form is as stable as the token.
+		if data, err := json.Marshal(token); err == nil {
+			return string(data)
+		}
+	}
+	if !evt.ClusterTime.IsZero() {
+		return fmt.Sprintf("%d.%d", evt.ClusterTime.T, evt.ClusterTime.I)
+	}
+	return fmt.Sprintf("ts.%d", evt.Timestamp)
+}
+
+// eventSeq returns the commit time of evt as a number that grows with it, or
+// 0 if the backend gives none.
+func eventSeq(evt *storage.Event) int64 {
+	return int64(evt.ClusterTime.T)<<32 | int64(evt.ClusterTime.I)
+}
+
+// taskID derives the ID of the task a trigger publishes for a change. It only
+// depends on the trigger and the change, so a change replayed after a
+// restart yields the same ID and the stream drops the duplicate.
+func taskID(triggerID, position, collection, documentID string) string {
+	h := sha256.New()
+	for _, part := range []string{triggerID, position, collection, documentID} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// eventTime is the commit time of evt, or now if the backend gives none.
+func eventTime(evt *storage.Event) time.Time {
+	if evt.ClusterTime.IsZero() {
+		return time.Now()
+	}
+	return time.Unix(int64(evt.ClusterTime.T), 0)
+}
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventPosition(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "tok", eventPosition(&storage.Event{ResumeToken: "tok"}))
+	assert.Equal(t, "8263", eventPosition(&storage.Event{ResumeToken: map[string]interface{}{"_data": "8263"}}))
+	assert.Equal(t, `{"a":1}`, eventPosition(&storage.Event{ResumeToken: map[string]interface{}{"a": 1}}))
+	assert.Equal(t, `["a",1]`, eventPosition(&storage.Event{ResumeToken: []interface{}{"a", 1}}))
+	assert.Equal(t, "5.2", eventPosition(&storage.Event{ClusterTime: storage.ClusterTime{T: 5, I: 2}}))
+	assert.Equal(t, "ts.42", eventPosition(&storage.Event{Timestamp: 42}))
+}
+
+func TestEventSeq(t *testing.T) {
+	t.Parallel()
+	assert.Zero(t, eventSeq(&storage.Event{}))
+	earlier := eventSeq(&storage.Event{ClusterTime: storage.ClusterTime{T: 5, I: 9}})
+	later := eventSeq(&storage.Event{ClusterTime: storage.ClusterTime{T: 6, I: 1}})
+	assert.Less(t, earlier, later)
+}
+
+func TestTaskID(t *testing.T) {
+	t.Parallel()
+	id := taskID("t1", "tok", "users", "u1")
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, taskID("t1", "tok", "users", "u1"), "same change, same ID")
+	assert.NotEqual(t, id, taskID("t2", "tok", "users", "u1"))
+	assert.NotEqual(t, id, taskID("t1", "tok2", "users", "u1"))
+	assert.NotEqual(t, id, taskID("t1", "tok", "users", "u2"))
+	// Parts are separated, so shifting characters between them changes the ID.
+	assert.NotEqual(t, taskID("ab", "c", "", ""), taskID("a", "bc", "", ""))
+}
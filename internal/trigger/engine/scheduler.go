@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger"
+	"github.com/codetrek/syntrix/internal/trigger/internal/pubsub"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+)
+
+// schedulerInterval is how often the scheduler looks for due work.
+const schedulerInterval = time.Second
+
+// scheduler fires the tasks of scheduled triggers and publishes delayed
+// tasks once due. Every engine holds its delayed tasks in the shared store,
+// but only the elected leader of its tenant publishes, so each run fires
+// once. Engines of other tenants share the store and elect their own leader.
+type scheduler struct {
+	tenant    string
+	store     pubsub.ScheduleStore
+	elector   pubsub.LeaderElector
+	publisher pubsub.TaskPublisher
+	interval  time.Duration
+	now       func() time.Time
+}
+
+func newScheduler(tenant string, store pubsub.ScheduleStore, elector pubsub.LeaderElector, publisher pubsub.TaskPublisher) *scheduler {
+	return &scheduler{
+		tenant:    tenant,
+		store:     store,
+		elector:   elector,
+		publisher: publisher,
+		interval:  schedulerInterval,
+		now:       time.Now,
+	}
+}
+
+// run ticks until ctx ends, then gives up the leadership.
+func (s *scheduler) run(ctx context.Context, triggers func() []*trigger.Trigger) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	defer func() {
+		resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.elector.Resign(resignCtx); err != nil {
+			log.Printf("[Warning] Failed to resign trigger scheduler leadership: %v", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx, triggers())
+		}
+	}
+}
+
+// tick fires what is due, if this instance leads.
+func (s *scheduler) tick(ctx context.Context, triggers []*trigger.Trigger) {
+	leading, err := s.elector.Campaign(ctx)
+	if err != nil {
+		log.Printf("[Error] Trigger scheduler election failed: %v", err)
+		return
+	}
+	if !leading {
+		return
+	}
+
+	now := s.now().UTC()
+	active := make(map[string]bool, len(triggers))
+	tenants := []string{s.tenant}
+	for _, t := range triggers {
+		key := triggerKey(t.Tenant, t.ID)
+		if !active[key] && !slices.Contains(tenants, t.Tenant) {
+			tenants = append(tenants, t.Tenant)
+		}
+		active[key] = true
+		if t.Schedule != "" {
+			s.fireSchedule(ctx, t, now)
+		}
+	}
+	for _, tenant := range tenants {
+		s.publishDelayed(ctx, tenant, active, now)
+	}
+}
+
+// triggerKey identifies a trigger across tenants.
+func triggerKey(tenant, triggerID string) string {
+	return tenant + "/" + triggerID
+}
+
+// fireSchedule publishes the latest run of t due at now. A new schedule
+// starts from now, and after downtime only the latest missed run fires.
+func (s *scheduler) fireSchedule(ctx context.Context, t *trigger.Trigger, now time.Time) {
+	sched, err := types.ParseSchedule(t.Schedule)
+	if err != nil {
+		log.Printf("[Error] Invalid schedule of trigger %s: %v", t.ID, err)
+		return
+	}
+	last, rev, err := s.store.LastRun(ctx, t.Tenant, t.ID)
+	if err != nil {
+		log.Printf("[Error] Failed to read last run of trigger %s: %v", t.ID, err)
+		return
+	}
+	if last.IsZero() {
+		if err := s.store.SetLastRun(ctx, t.Tenant, t.ID, now, rev); err != nil && !errors.Is(err, pubsub.ErrKVConflict) {
+			log.Printf("[Error] Failed to start schedule of trigger %s: %v", t.ID, err)
+		}
+		return
+	}
+
+	run := sched.Next(last)
+	if run.IsZero() || run.After(now) {
+		return
+	}
+	for next := sched.Next(run); !next.IsZero() && !next.After(now); next = sched.Next(next) {
+		run = next
+	}
+
+	// The run is recorded after publishing, so a failed publish is retried
+	// on the next tick; the task ID keeps a repeat from being delivered.
+	if err := s.publisher.Publish(ctx, scheduledTask(t, run)); err != nil {
+		log.Printf("[Error] Failed to publish scheduled task for trigger %s: %v", t.ID, err)
+		return
+	}
+	if err := s.store.SetLastRun(ctx, t.Tenant, t.ID, run, rev); err != nil && !errors.Is(err, pubsub.ErrKVConflict) {
+		log.Printf("[Error] Failed to record run of trigger %s: %v", t.ID, err)
+	}
+}
+
+// publishDelayed publishes the delayed tasks of tenant due at now, in due
+// order. Tasks of triggers that were removed or disabled meanwhile are
+// dropped, but only in the scheduler's own tenant: of another tenant it
+// knows just the triggers it loaded, so it leaves the rest to that tenant's
+// scheduler.
+func (s *scheduler) publishDelayed(ctx context.Context, tenant string, active map[string]bool, now time.Time) {
+	due, err := s.store.DueDelayed(ctx, tenant, now)
+	if err != nil {
+		log.Printf("[Error] Failed to list delayed tasks of tenant %s: %v", tenant, err)
+		return
+	}
+	for _, d := range due {
+		if !active[triggerKey(tenant, d.Task.TriggerID)] {
+			if tenant != s.tenant {
+				continue
+			}
+			log.Printf("[Info] Dropping delayed task %s of inactive trigger %s", d.Task.ID, d.Task.TriggerID)
+		} else if err := s.publisher.Publish(ctx, d.Task); err != nil {
+			log.Printf("[Error] Failed to publish delayed task for trigger %s: %v", d.Task.TriggerID, err)
+			return
+		}
+		if err := s.store.RemoveDelayed(ctx, d); err != nil {
+			log.Printf("[Error] Failed to remove delayed task %s: %v", d.Task.ID, err)
+		}
+	}
+}
+
+// scheduledTask is the task of the run of t at the given time. Its position
+// is the run time, so every scheduler computes the same task ID for a run.
+func scheduledTask(t *trigger.Trigger, run time.Time) *trigger.DeliveryTask {
+	position := "schedule." + strconv.FormatInt(run.Unix(), 10)
+	task := newTask(t)
+	task.ID = taskID(t.ID, position, t.Collection, "")
+	task.Event = types.EventSchedule
+	task.Collection = t.Collection
+	task.LSN = position
+	task.Seq = run.Unix() << 32
+	task.Timestamp = run.Unix()
+	task.Payload = map[string]interface{}{"scheduledAt": run.Format(time.RFC3339)}
+	return task
+}
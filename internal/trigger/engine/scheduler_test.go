@@ -0,0 +1,240 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/internal/trigger"
+	"github.com/codetrek/syntrix/internal/trigger/internal/pubsub"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type stubElector struct {
+	leading  bool
+	resigned bool
+}
+
+func (e *stubElector) Campaign(ctx context.Context) (bool, error) { return e.leading, nil }
+
+func (e *stubElector) Resign(ctx context.Context) error {
+	e.resigned = true
+	return nil
+}
+
+func newTestScheduler(pub *MockPublisher, now time.Time) (*scheduler, pubsub.ScheduleStore, *stubElector) {
+	store := pubsub.NewScheduleStoreFromBucket(pubsub.NewMemoryBucket())
+	elector := &stubElector{leading: true}
+	s := newScheduler("acme", store, elector, pub)
+	s.now = func() time.Time { return now }
+	return s, store, elector
+}
+
+// delayedIDs returns the IDs of the delayed tasks of tenant still held,
+// earliest first.
+func delayedIDs(t *testing.T, store pubsub.ScheduleStore, tenant string) []string {
+	t.Helper()
+	due, err := store.DueDelayed(context.Background(), tenant, time.Now().Add(365*24*time.Hour))
+	require.NoError(t, err)
+	ids := []string{}
+	for _, d := range due {
+		ids = append(ids, d.Task.ID)
+	}
+	return ids
+}
+
+func TestScheduler_FireSchedule(t *testing.T) {
+	pub := new(MockPublisher)
+	var published []*types.DeliveryTask
+	pub.On("Publish", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).(*types.DeliveryTask))
+	})
+
+	now := time.Date(2026, 3, 14, 10, 0, 30, 0, time.UTC)
+	s, store, _ := newTestScheduler(pub, now)
+	trig := &trigger.Trigger{ID: "nightly", Tenant: "acme", Schedule: "*/5 * * * *", URL: "http://example.com"}
+
+	// A new schedule starts from now without firing.
+	s.tick(context.Background(), []*trigger.Trigger{trig})
+	assert.Empty(t, published)
+	last, _, _ := store.LastRun(context.Background(), "acme", "nightly")
+	assert.Equal(t, now, last)
+
+	// After downtime only the latest missed run fires.
+	s.now = func() time.Time { return now.Add(17 * time.Minute) }
+	s.tick(context.Background(), []*trigger.Trigger{trig})
+	require.Len(t, published, 1)
+	run := time.Date(2026, 3, 14, 10, 15, 0, 0, time.UTC)
+	task := published[0]
+	assert.Equal(t, types.EventSchedule, task.Event)
+	assert.Equal(t, "nightly", task.TriggerID)
+	assert.Equal(t, "http://example.com", task.URL)
+	assert.Equal(t, run.Format(time.RFC3339), task.Payload["scheduledAt"])
+	assert.Equal(t, trigger.Duration(types.DefaultTaskTimeout), task.Timeout)
+	assert.Equal(t, scheduledTask(trig, run).ID, task.ID)
+	last, _, _ = store.LastRun(context.Background(), "acme", "nightly")
+	assert.Equal(t, run, last)
+
+	// Nothing more is due until the next run.
+	s.tick(context.Background(), []*trigger.Trigger{trig})
+	assert.Len(t, published, 1)
+}
+
+func TestScheduler_FireSchedule_PublishError(t *testing.T) {
+	pub := new(MockPublisher)
+	pub.On("Publish", mock.Anything, mock.Anything).Return(errors.New("stream down")).Once()
+	pub.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+	now := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	s, store, _ := newTestScheduler(pub, now.Add(time.Hour))
+	trig := &trigger.Trigger{ID: "hourly", Tenant: "acme", Schedule: "@hourly", URL: "http://example.com"}
+	require.NoError(t, store.SetLastRun(context.Background(), "acme", "hourly", now, 0))
+
+	// A failed publish leaves the run unrecorded and is retried.
+	s.tick(context.Background(), []*trigger.Trigger{trig})
+	last, _, _ := store.LastRun(context.Background(), "acme", "hourly")
+	assert.Equal(t, now, last)
+
+	s.tick(context.Background(), []*trigger.Trigger{trig})
+	last, _, _ = store.LastRun(context.Background(), "acme", "hourly")
+	assert.Equal(t, now.Add(time.Hour), last)
+	pub.AssertNumberOfCalls(t, "Publish", 2)
+}
+
+func TestScheduler_PublishDelayed(t *testing.T) {
+	pub := new(MockPublisher)
+	var published []string
+	pub.On("Publish", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).(*types.DeliveryTask).ID)
+	})
+
+	now := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	s, store, _ := newTestScheduler(pub, now)
+	ctx := context.Background()
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "late", Tenant: "acme", TriggerID: "t1"}, now.Add(-time.Second)))
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "early", Tenant: "acme", TriggerID: "t1"}, now.Add(-time.Minute)))
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "future", Tenant: "acme", TriggerID: "t1"}, now.Add(time.Minute)))
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "orphan", Tenant: "acme", TriggerID: "gone"}, now.Add(-time.Minute)))
+
+	s.tick(ctx, []*trigger.Trigger{{ID: "t1", Tenant: "acme"}})
+	assert.Equal(t, []string{"early", "late"}, published)
+	assert.Equal(t, []string{"future"}, delayedIDs(t, store, "acme"))
+}
+
+func TestScheduler_PublishDelayed_OtherTenants(t *testing.T) {
+	pub := new(MockPublisher)
+	var published []string
+	pub.On("Publish", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).(*types.DeliveryTask).ID)
+	})
+
+	now := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	s, store, _ := newTestScheduler(pub, now)
+	ctx := context.Background()
+	due := now.Add(-time.Minute)
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "own", Tenant: "acme", TriggerID: "t1"}, due))
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "same-id", Tenant: "globex", TriggerID: "t1"}, due))
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "loaded", Tenant: "initech", TriggerID: "t2"}, due))
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "unknown", Tenant: "initech", TriggerID: "t3"}, due))
+
+	// Trigger t1 of acme says nothing about t1 of globex, whose tasks are
+	// left to its own scheduler. Of initech only the loaded trigger fires,
+	// and the tasks of its other triggers are kept.
+	s.tick(ctx, []*trigger.Trigger{{ID: "t1", Tenant: "acme"}, {ID: "t2", Tenant: "initech"}})
+	assert.ElementsMatch(t, []string{"own", "loaded"}, published)
+	assert.Empty(t, delayedIDs(t, store, "acme"))
+	assert.Equal(t, []string{"same-id"}, delayedIDs(t, store, "globex"))
+	assert.Equal(t, []string{"unknown"}, delayedIDs(t, store, "initech"))
+}
+
+func TestScheduler_PublishDelayed_Error(t *testing.T) {
+	pub := new(MockPublisher)
+	pub.On("Publish", mock.Anything, mock.Anything).Return(errors.New("stream down"))
+
+	now := time.Now()
+	s, store, _ := newTestScheduler(pub, now)
+	require.NoError(t, store.AddDelayed(context.Background(), &types.DeliveryTask{ID: "a", Tenant: "acme", TriggerID: "t1"}, now))
+
+	s.tick(context.Background(), []*trigger.Trigger{{ID: "t1", Tenant: "acme"}})
+	assert.Equal(t, []string{"a"}, delayedIDs(t, store, "acme"), "kept for the next tick")
+}
+
+func TestScheduler_Follower(t *testing.T) {
+	pub := new(MockPublisher)
+	now := time.Now()
+	s, store, elector := newTestScheduler(pub, now)
+	elector.leading = false
+	require.NoError(t, store.AddDelayed(context.Background(), &types.DeliveryTask{ID: "a", Tenant: "acme", TriggerID: "t1"}, now))
+
+	s.tick(context.Background(), []*trigger.Trigger{{ID: "t1", Tenant: "acme", Schedule: "* * * * *"}})
+	pub.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	assert.Equal(t, []string{"a"}, delayedIDs(t, store, "acme"))
+	last, _, err := store.LastRun(context.Background(), "acme", "t1")
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+}
+
+func TestScheduler_RunResigns(t *testing.T) {
+	s, _, elector := newTestScheduler(new(MockPublisher), time.Now())
+	s.interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx, func() []*trigger.Trigger { return nil })
+		close(done)
+	}()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	<-done
+	assert.True(t, elector.resigned)
+}
+
+func TestStart_DelayedTrigger(t *testing.T) {
+	mockEvaluator := new(MockEvaluator)
+	mockWatcher := new(MockWatcher)
+	mockPublisher := new(MockPublisher)
+	sched, store, _ := newTestScheduler(mockPublisher, time.Now())
+	sched.interval = time.Hour
+
+	e := &defaultTriggerEngine{
+		evaluator: mockEvaluator,
+		watcher:   mockWatcher,
+		publisher: mockPublisher,
+		scheduler: sched,
+	}
+	trig := &trigger.Trigger{ID: "remind", Tenant: "acme", Collection: "orders", Events: []string{"create"}, URL: "http://example.com", Delay: trigger.Duration(30 * time.Minute)}
+	require.NoError(t, e.LoadTriggers([]*trigger.Trigger{trig}))
+
+	eventCh := make(chan storage.Event)
+	mockWatcher.On("Watch", mock.Anything).Return((<-chan storage.Event)(eventCh), nil)
+	mockEvaluator.On("Evaluate", mock.Anything, trig, mock.Anything).Return(true, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		errCh <- e.Start(ctx)
+	}()
+	created := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	eventCh <- storage.Event{
+		Type:        storage.EventCreate,
+		Document:    &storage.Document{Id: "o1", Collection: "orders"},
+		ClusterTime: storage.ClusterTime{T: uint32(created.Unix()), I: 1},
+	}
+	close(eventCh)
+	assert.NoError(t, <-errCh)
+	cancel()
+
+	due, err := store.DueDelayed(context.Background(), "acme", created.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, created.Add(30*time.Minute), due[0].Due.UTC())
+	assert.Equal(t, "remind", due[0].Task.TriggerID)
+	assert.Equal(t, "o1", due[0].Task.DocumentID)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
@@ -32,9 +32,7 @@ type celeEvaluator struct {
 
 func NewEvaluator() (Evaluator, error) {
 	// Define the CEL environment with an 'event' variable
-	env, err := celNewEnv(
-		cel.Variable("event", cel.MapType(cel.StringType, cel.DynType)),
-	)
+	env, err := celNewEnv(trigger.ConditionEnvOptions()...)
 	if err != nil {
 		return nil, err
 	}
@@ -78,26 +76,72 @@ func (e *celeEvaluator) Evaluate(ctx context.Context, t *trigger.Trigger, event
 		}
 	}
 
-	// 3. Evaluate CEL condition
-	if t.Condition == "" {
+	// 3. Evaluate the CEL condition and filters; all must hold.
+	if t.Condition == "" && len(t.Filters) == 0 {
 		return true, nil
 	}
 
-	prg, err := e.getProgram(t.Condition)
+	input := conditionInput(event)
+	if t.Condition != "" {
+		if match, err := e.evalBool(t.Condition, input); err != nil || !match {
+			return false, err
+		}
+	}
+	for _, filter := range t.Filters {
+		match, err := e.evalBool(filter, input)
+		if err != nil {
+			return false, fmt.Errorf("filter %q: %w", filter, err)
+		}
+		if !match {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// evalBool evaluates a boolean CEL expression against input.
+func (e *celeEvaluator) evalBool(expr string, input map[string]interface{}) (bool, error) {
+	prg, err := e.getProgram(expr)
 	if err != nil {
 		return false, fmt.Errorf("failed to get CEL program: %w", err)
 	}
 
-	// Prepare input
+	out, _, err := prg.Eval(input)
+	if err != nil {
+		return false, fmt.Errorf("CEL evaluation error: %w", err)
+	}
+
+	match, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("CEL condition must return boolean, got %T", out.Value())
+	}
+
+	return match, nil
+}
+
+// conditionInput builds the variables a condition sees from event.
+func conditionInput(event *storage.Event) map[string]interface{} {
 	input := map[string]interface{}{
 		"event": map[string]interface{}{
 			"type":      string(event.Type),
 			"timestamp": event.Timestamp,
 			"document":  nil,
 			"before":    nil,
+			"origin":    nil,
 		},
 	}
 
+	// Writes made for a trigger delivery name it, so conditions can skip
+	// changes their own trigger caused.
+	if event.Origin != nil {
+		input["event"].(map[string]interface{})["origin"] = map[string]interface{}{
+			"triggerId": event.Origin.TriggerID,
+			"taskId":    event.Origin.TaskID,
+			"depth":     event.Origin.Depth,
+		}
+	}
+
 	// If Document is struct, we might need to convert it to map or rely on CEL's reflection if configured.
 	// For simplicity, let's manually construct the map for the document part we care about.
 	if event.Document != nil {
@@ -128,17 +172,7 @@ func (e *celeEvaluator) Evaluate(ctx context.Context, t *trigger.Trigger, event
 		input["event"].(map[string]interface{})["before"] = docMap
 	}
 
-	out, _, err := prg.Eval(input)
-	if err != nil {
-		return false, fmt.Errorf("CEL evaluation error: %w", err)
-	}
-
-	match, ok := out.Value().(bool)
-	if !ok {
-		return false, fmt.Errorf("CEL condition must return boolean, got %T", out.Value())
-	}
-
-	return match, nil
+	return input
 }
 
 func (e *celeEvaluator) getProgram(condition string) (cel.Program, error) {
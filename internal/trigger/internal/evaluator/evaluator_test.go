@@ -398,3 +398,73 @@ func TestCELEvaluator_CacheHit(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, match2)
 }
+
+func TestCELEvaluator_Filters(t *testing.T) {
+	evaluator, err := NewEvaluator()
+	require.NoError(t, err)
+
+	trig := &trigger.Trigger{
+		Events:     []string{"update"},
+		Collection: "orders",
+		Condition:  `event.document.status == "paid"`,
+		Filters:    []string{`event.document.total > 100`, `event.before.status != "paid"`},
+	}
+	event := func(total int, before string) *storage.Event {
+		return &storage.Event{
+			Type:     storage.EventUpdate,
+			Document: &storage.Document{Collection: "orders", Data: map[string]interface{}{"status": "paid", "total": total}},
+			Before:   &storage.Document{Collection: "orders", Data: map[string]interface{}{"status": before}},
+		}
+	}
+
+	match, err := evaluator.Evaluate(context.Background(), trig, event(150, "open"))
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	match, err = evaluator.Evaluate(context.Background(), trig, event(50, "open"))
+	require.NoError(t, err)
+	assert.False(t, match, "first filter fails")
+
+	match, err = evaluator.Evaluate(context.Background(), trig, event(150, "paid"))
+	require.NoError(t, err)
+	assert.False(t, match, "second filter fails")
+
+	// Filters apply without a condition too.
+	trig.Condition = ""
+	match, err = evaluator.Evaluate(context.Background(), trig, event(50, "open"))
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	trig.Filters = []string{`event.document.missing > 1`}
+	_, err = evaluator.Evaluate(context.Background(), trig, event(150, "open"))
+	assert.ErrorContains(t, err, "filter")
+}
+
+func TestCELEvaluator_Origin(t *testing.T) {
+	evaluator, err := NewEvaluator()
+	require.NoError(t, err)
+
+	trig := &trigger.Trigger{
+		Events:     []string{"update"},
+		Collection: "orders",
+		Condition:  `event.origin == null || event.origin.triggerId != "t1"`,
+	}
+	event := &storage.Event{
+		Type:     storage.EventUpdate,
+		Document: &storage.Document{Collection: "orders", Data: map[string]interface{}{}},
+	}
+
+	match, err := evaluator.Evaluate(context.Background(), trig, event)
+	require.NoError(t, err)
+	assert.True(t, match, "client writes have no origin")
+
+	event.Origin = &storage.Origin{TriggerID: "t1", TaskID: "task-1", Depth: 1}
+	match, err = evaluator.Evaluate(context.Background(), trig, event)
+	require.NoError(t, err)
+	assert.False(t, match, "own write-backs are excluded")
+
+	trig.Condition = `event.origin.depth < 2`
+	match, err = evaluator.Evaluate(context.Background(), trig, event)
+	require.NoError(t, err)
+	assert.True(t, match)
+}
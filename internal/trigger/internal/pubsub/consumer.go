@@ -3,6 +3,7 @@ package pubsub
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"log"
@@ -19,6 +20,22 @@ import (
 // DefaultChannelBufferSize is the default buffer size for worker channels.
 const DefaultChannelBufferSize = 100
 
+// deadLetterRetryDelay is how long a terminated task waits for redelivery
+// when it cannot be written to the dead-letter queue.
+const deadLetterRetryDelay = 5 * time.Second
+
+// maxLimitDelay caps how long a delivery held back by a limit waits before
+// asking again, well within the ack wait of its message.
+const maxLimitDelay = time.Second
+
+// deliveryLogBufferSize bounds the records waiting to be written to the
+// delivery log. When the log falls behind, further records are dropped
+// rather than slowing deliveries down.
+const deliveryLogBufferSize = 1024
+
+// deliveryLogWriteTimeout bounds writing one record to the delivery log.
+const deliveryLogWriteTimeout = 5 * time.Second
+
 // natsConsumer consumes delivery tasks from NATS and dispatches them to the worker.
 type natsConsumer struct {
 	js             jetstream.JetStream
@@ -29,10 +46,18 @@ type natsConsumer struct {
 	workerChans    []chan jetstream.Msg
 	wg             sync.WaitGroup
 	metrics        types.Metrics
+	deadLetters    DeadLetterQueue
+	limiter        DeliveryLimiter
+	streamOpts     types.StreamOptions
+	deliveryLog    DeliveryLog
+	logRecords     chan *types.DeliveryRecord
+	logDropped     atomic.Int64
 
 	// Shutdown coordination
-	closing         atomic.Bool  // Marks closing state
-	inFlightCount   atomic.Int32 // Count of messages currently in dispatch()
+	closing         atomic.Bool   // Marks closing state
+	inFlightCount   atomic.Int32  // Count of messages currently in dispatch()
+	stopped         chan struct{} // Closed before the worker channels are
+	chansMu         sync.RWMutex  // Held for writing while closing the worker channels
 	drainTimeout    time.Duration
 	shutdownTimeout time.Duration
 }
@@ -69,6 +94,37 @@ func WithShutdownTimeout(d time.Duration) ConsumerOption {
 	}
 }
 
+// WithDeadLetterQueue keeps the tasks the consumer gives up on in q instead
+// of dropping them.
+func WithDeadLetterQueue(q DeadLetterQueue) ConsumerOption {
+	return func(c *natsConsumer) {
+		c.deadLetters = q
+	}
+}
+
+// WithDeliveryLimiter enforces the Concurrency and RateLimit of triggers
+// with l.
+func WithDeliveryLimiter(l DeliveryLimiter) ConsumerOption {
+	return func(c *natsConsumer) {
+		c.limiter = l
+	}
+}
+
+// WithDeliveryLog records every delivery attempt in l. Records are written
+// in the background.
+func WithDeliveryLog(l DeliveryLog) ConsumerOption {
+	return func(c *natsConsumer) {
+		c.deliveryLog = l
+	}
+}
+
+// WithStreamOptions sets the options the task stream is ensured with.
+func WithStreamOptions(opts types.StreamOptions) ConsumerOption {
+	return func(c *natsConsumer) {
+		c.streamOpts = opts
+	}
+}
+
 // NewTaskConsumer creates a new TaskConsumer.
 func NewTaskConsumer(nc *nats.Conn, w worker.DeliveryWorker, streamName string, numWorkers int, metrics types.Metrics, opts ...ConsumerOption) (TaskConsumer, error) {
 	if nc == nil {
@@ -109,6 +165,9 @@ func NewTaskConsumerFromJS(js jetstream.JetStream, w worker.DeliveryWorker, stre
 	for _, opt := range opts {
 		opt(c)
 	}
+	if c.deliveryLog != nil {
+		c.logRecords = make(chan *types.DeliveryRecord, deliveryLogBufferSize)
+	}
 
 	return c, nil
 }
@@ -116,19 +175,15 @@ func NewTaskConsumerFromJS(js jetstream.JetStream, w worker.DeliveryWorker, stre
 // Start begins consuming messages. It blocks until the context is cancelled.
 func (c *natsConsumer) Start(ctx context.Context) error {
 	// Ensure Stream exists
-	_, err := c.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
-		Name:     c.stream,
-		Subjects: []string{fmt.Sprintf("%s.>", c.stream)},
-		Storage:  jetstream.MemoryStorage,
-	})
-	if err != nil {
+	if err := ensureStream(ctx, c.js, StreamConfig(c.stream, c.streamOpts)); err != nil {
 		return fmt.Errorf("failed to ensure stream: %w", err)
 	}
 
 	// Create Consumer
 	consumer, err := c.js.CreateOrUpdateConsumer(ctx, c.stream, jetstream.ConsumerConfig{
-		Durable:       "TriggerDeliveryWorker",
+		Durable:       ConsumerName,
 		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       types.DefaultAckWait,
 		FilterSubject: fmt.Sprintf("%s.>", c.stream),
 	})
 	if err != nil {
@@ -136,6 +191,7 @@ func (c *natsConsumer) Start(ctx context.Context) error {
 	}
 
 	// Initialize Worker Pool
+	c.stopped = make(chan struct{})
 	c.workerChans = make([]chan jetstream.Msg, c.numWorkers)
 	for i := 0; i < c.numWorkers; i++ {
 		c.workerChans[i] = make(chan jetstream.Msg, c.channelBufSize)
@@ -152,6 +208,17 @@ func (c *natsConsumer) Start(ctx context.Context) error {
 	}
 	defer cc.Stop()
 
+	stopLog := make(chan struct{})
+	logDone := make(chan struct{})
+	if c.logRecords != nil {
+		go func() {
+			defer close(logDone)
+			c.writeDeliveryLog(stopLog)
+		}()
+	} else {
+		close(logDone)
+	}
+
 	log.Printf("Trigger Consumer started with %d workers, waiting for messages...", c.numWorkers)
 
 	<-ctx.Done()
@@ -166,10 +233,14 @@ func (c *natsConsumer) Start(ctx context.Context) error {
 	defer drainCancel()
 	c.waitForDrain(drainCtx)
 
-	// Phase 3: Close worker channels
+	// Phase 3: Close worker channels. Hand-offs still waiting, e.g. of
+	// delayed messages, NAK their message once stopped is closed.
+	close(c.stopped)
+	c.chansMu.Lock()
 	for _, ch := range c.workerChans {
 		close(ch)
 	}
+	c.chansMu.Unlock()
 
 	// Phase 4: Wait for workers with timeout
 	done := make(chan struct{})
@@ -188,6 +259,14 @@ func (c *natsConsumer) Start(ctx context.Context) error {
 		log.Printf("[Warn] Shutdown timeout exceeded, some workers may still be running")
 	}
 
+	// Phase 5: Write the delivery records still queued
+	close(stopLog)
+	select {
+	case <-logDone:
+	case <-shutdownCtx.Done():
+		log.Printf("[Warn] Shutdown timeout exceeded, some delivery records were not written")
+	}
+
 	return nil
 }
 
@@ -239,17 +318,43 @@ func (c *natsConsumer) dispatch(msg jetstream.Msg) {
 	hash := h.Sum32()
 	workerIdx := int(hash % uint32(c.numWorkers))
 
-	c.workerChans[workerIdx] <- msg
+	c.handOff(workerIdx, msg)
+}
+
+// handOff queues msg for worker id. Once the consumer stops, the message is
+// NAKed for redelivery instead, so a late hand-off never sends on a closed
+// worker channel.
+func (c *natsConsumer) handOff(id int, msg jetstream.Msg) {
+	c.chansMu.RLock()
+	defer c.chansMu.RUnlock()
+
+	select {
+	case <-c.stopped:
+		msg.Nak()
+		return
+	default:
+	}
+	select {
+	case c.workerChans[id] <- msg:
+	case <-c.stopped:
+		msg.Nak()
+	}
 }
 
 func (c *natsConsumer) workerLoop(ctx context.Context, id int) {
 	defer c.wg.Done()
 
 	for msg := range c.workerChans[id] {
-		if err := c.processMsg(ctx, msg); err != nil {
+		release, ok := c.admit(ctx, id, msg)
+		if !ok {
+			continue
+		}
+		err := c.processMsg(ctx, msg)
+		release()
+		if err != nil {
 			if types.IsFatal(err) {
 				log.Printf("[Error] [Worker %d] Fatal error processing message: %v. Terminating.", id, err)
-				msg.Term()
+				c.terminate(ctx, msg, err)
 				continue
 			}
 			log.Printf("[Error] [Worker %d] Failed to process message: %v", id, err)
@@ -268,14 +373,10 @@ func (c *natsConsumer) workerLoop(ctx context.Context, id int) {
 				continue
 			}
 
-			maxAttempts := task.RetryPolicy.MaxAttempts
-			if maxAttempts == 0 {
-				maxAttempts = 3
-			}
-
+			maxAttempts := attemptLimit(&task)
 			if int(md.NumDelivered) >= maxAttempts {
 				log.Printf("[Error] Max attempts (%d) reached for trigger %s. Terminating.", maxAttempts, task.TriggerID)
-				msg.Term()
+				c.terminate(ctx, msg, err)
 				continue
 			}
 
@@ -300,6 +401,91 @@ func (c *natsConsumer) workerLoop(ctx context.Context, id int) {
 	}
 }
 
+// admit asks the limiter to deliver msg now. A delivery over a limit of its
+// trigger is set aside and handed to the worker again later, so it neither
+// holds the worker nor uses up a delivery attempt.
+func (c *natsConsumer) admit(ctx context.Context, id int, msg jetstream.Msg) (func(), bool) {
+	noop := func() {}
+	if c.limiter == nil {
+		return noop, true
+	}
+	var task types.DeliveryTask
+	if err := json.Unmarshal(msg.Data(), &task); err != nil {
+		// processMsg reports the payload.
+		return noop, true
+	}
+	if task.Concurrency <= 0 && task.RateLimit <= 0 {
+		return noop, true
+	}
+
+	release, err := c.limiter.Acquire(ctx, &task)
+	if err == nil {
+		return release, true
+	}
+	var limited *LimitError
+	if !errors.As(err, &limited) {
+		log.Printf("[Warning] Failed to check limits of trigger %s: %v", task.TriggerID, err)
+		limited = &LimitError{Limit: LimitUnavailable, RetryAfter: maxLimitDelay}
+	}
+	c.metrics.IncDeliveryDelayed(task.Tenant, task.Collection, limited.Limit)
+	c.delay(id, msg, limited.RetryAfter)
+	return nil, false
+}
+
+// delay hands msg to worker id again after d. The message counts as in
+// flight meanwhile, so shutdown waits for it and NAKs it instead.
+func (c *natsConsumer) delay(id int, msg jetstream.Msg, d time.Duration) {
+	if d > maxLimitDelay {
+		d = maxLimitDelay
+	}
+	_ = msg.InProgress()
+	c.inFlightCount.Add(1)
+	time.AfterFunc(d, func() {
+		defer c.inFlightCount.Add(-1)
+		if c.closing.Load() {
+			msg.Nak()
+			return
+		}
+		c.handOff(id, msg)
+	})
+}
+
+// terminate gives up on msg after it failed with cause. With a dead-letter
+// queue the task is kept there first; if that fails the message is
+// redelivered later rather than lost.
+func (c *natsConsumer) terminate(ctx context.Context, msg jetstream.Msg, cause error) {
+	if c.deadLetters == nil {
+		msg.Term()
+		return
+	}
+
+	var task types.DeliveryTask
+	if err := json.Unmarshal(msg.Data(), &task); err != nil {
+		msg.Term()
+		return
+	}
+	task.PreIssuedToken = ""
+
+	letter := &types.DeadLetter{
+		Task:     task,
+		Error:    cause.Error(),
+		Status:   types.StatusOf(cause),
+		Fatal:    types.IsFatal(cause),
+		FailedAt: time.Now().UnixMilli(),
+	}
+	if md, err := msg.Metadata(); err == nil {
+		letter.Attempts = int(md.NumDelivered)
+	}
+
+	if err := c.deadLetters.Add(ctx, letter); err != nil {
+		log.Printf("[Error] Failed to dead-letter task of trigger %s: %v. Redelivering in %v.", task.TriggerID, err, deadLetterRetryDelay)
+		msg.NakWithDelay(deadLetterRetryDelay)
+		return
+	}
+	log.Printf("[Warning] Dead-lettered task of trigger %s as %d after %d attempts", task.TriggerID, letter.ID, letter.Attempts)
+	msg.Term()
+}
+
 func (c *natsConsumer) processMsg(ctx context.Context, msg jetstream.Msg) error {
 	start := time.Now()
 	var task types.DeliveryTask
@@ -317,6 +503,12 @@ func (c *natsConsumer) processMsg(ctx context.Context, msg jetstream.Msg) error
 	taskCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	var resp *types.DeliveryResponse
+	if c.logRecords != nil {
+		resp = &types.DeliveryResponse{}
+		taskCtx = types.WithDeliveryResponse(taskCtx, resp)
+	}
+
 	err := c.worker.ProcessTask(taskCtx, &task)
 	if err != nil {
 		c.metrics.IncConsumeFailure(task.Tenant, task.Collection, err.Error())
@@ -324,5 +516,86 @@ func (c *natsConsumer) processMsg(ctx context.Context, msg jetstream.Msg) error
 		c.metrics.IncConsumeSuccess(task.Tenant, task.Collection, task.SubjectHashed)
 	}
 	c.metrics.ObserveConsumeLatency(task.Tenant, task.Collection, time.Since(start))
+	if resp != nil {
+		c.recordDelivery(msg, &task, resp, start, err)
+	}
 	return err
 }
+
+// attemptLimit returns how often task is delivered before it is given up on.
+func attemptLimit(task *types.DeliveryTask) int {
+	if task.RetryPolicy.MaxAttempts == 0 {
+		return 3
+	}
+	return task.RetryPolicy.MaxAttempts
+}
+
+// recordDelivery queues the record of one delivery attempt of task for the
+// delivery log. It never blocks; records the log cannot take are dropped.
+func (c *natsConsumer) recordDelivery(msg jetstream.Msg, task *types.DeliveryTask, resp *types.DeliveryResponse, start time.Time, err error) {
+	rec := &types.DeliveryRecord{
+		TaskID:      task.ID,
+		Tenant:      task.Tenant,
+		TriggerID:   task.TriggerID,
+		Event:       task.Event,
+		Collection:  task.Collection,
+		DocumentID:  task.DocumentID,
+		Attempt:     1,
+		Outcome:     types.DeliverySucceeded,
+		Status:      resp.Status,
+		LatencyMs:   time.Since(start).Milliseconds(),
+		Response:    string(resp.Body),
+		Truncated:   resp.Truncated,
+		DeliveredAt: time.Now().UnixMilli(),
+	}
+	if md, mdErr := msg.Metadata(); mdErr == nil {
+		rec.Attempt = int(md.NumDelivered)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+		if rec.Status == 0 {
+			rec.Status = types.StatusOf(err)
+		}
+		rec.Outcome = types.DeliveryRetrying
+		if types.IsFatal(err) || rec.Attempt >= attemptLimit(task) {
+			rec.Outcome = types.DeliveryFailed
+		}
+	}
+
+	select {
+	case c.logRecords <- rec:
+	default:
+		c.logDropped.Add(1)
+	}
+}
+
+// writeDeliveryLog writes queued delivery records until stop is closed, then
+// the ones still queued.
+func (c *natsConsumer) writeDeliveryLog(stop <-chan struct{}) {
+	for {
+		select {
+		case rec := <-c.logRecords:
+			c.appendDelivery(rec)
+		case <-stop:
+			for {
+				select {
+				case rec := <-c.logRecords:
+					c.appendDelivery(rec)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *natsConsumer) appendDelivery(rec *types.DeliveryRecord) {
+	if n := c.logDropped.Swap(0); n > 0 {
+		log.Printf("[Warning] Delivery log fell behind, dropped %d records", n)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryLogWriteTimeout)
+	defer cancel()
+	if err := c.deliveryLog.Append(ctx, rec); err != nil {
+		log.Printf("[Warning] Failed to write delivery record of trigger %s: %v", rec.TriggerID, err)
+	}
+}
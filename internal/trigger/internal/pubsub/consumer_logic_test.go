@@ -402,6 +402,10 @@ func (m *MockMetrics) IncHashCollision(tenant, collection string) {
 	m.Called(tenant, collection)
 }
 
+func (m *MockMetrics) IncDeliveryDelayed(tenant, collection, limit string) {
+	m.Called(tenant, collection, limit)
+}
+
 func (m *MockMetrics) IncDeliverySuccess(tenant, collection string) {
 	m.Called(tenant, collection)
 }
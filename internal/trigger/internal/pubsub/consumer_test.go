@@ -97,6 +97,11 @@ func (m *MockMsg) Metadata() (*jetstream.MsgMetadata, error) {
 	return args.Get(0).(*jetstream.MsgMetadata), args.Error(1)
 }
 
+func (m *MockMsg) InProgress() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func (m *MockMsg) Term() error {
 	args := m.Called()
 	return args.Error(0)
@@ -125,7 +130,7 @@ func TestConsumer_Start_Success(t *testing.T) {
 
 	// 2. Create Consumer
 	js.On("CreateOrUpdateConsumer", ctx, "TestConsumer_Start_Success", mock.MatchedBy(func(cfg jetstream.ConsumerConfig) bool {
-		return cfg.Durable == "TriggerDeliveryWorker"
+		return cfg.Durable == "TriggerDeliveryWorker" && cfg.AckWait == 60*time.Second
 	})).Return(consumer, nil)
 
 	// 3. Consume Messages
@@ -0,0 +1,171 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DeadLetterQueue keeps the delivery tasks the consumer gave up on, per
+// tenant and trigger.
+type DeadLetterQueue interface {
+	// Add stores letter and sets its ID.
+	Add(ctx context.Context, letter *types.DeadLetter) error
+
+	// List returns the dead letters of a trigger selected by filter, oldest
+	// first.
+	List(ctx context.Context, tenant, triggerID string, filter types.DeadLetterFilter) ([]*types.DeadLetter, error)
+
+	// Get returns one dead letter, or types.ErrDeadLetterNotFound.
+	Get(ctx context.Context, tenant, triggerID string, id uint64) (*types.DeadLetter, error)
+
+	// Delete removes one dead letter, or returns types.ErrDeadLetterNotFound.
+	Delete(ctx context.Context, tenant, triggerID string, id uint64) error
+
+	// Purge removes all dead letters of a trigger.
+	Purge(ctx context.Context, tenant, triggerID string) error
+}
+
+// DeadLetterStreamName returns the name of the stream holding the dead
+// letters of the task stream streamName.
+func DeadLetterStreamName(streamName string) string {
+	if streamName == "" {
+		streamName = "TRIGGERS"
+	}
+	return streamName + "_DLQ"
+}
+
+// natsDeadLetterQueue implements DeadLetterQueue with a JetStream stream.
+// Dead letters are published to <stream>_DLQ.<tenant>.<triggerId> and
+// identified by their stream sequence.
+type natsDeadLetterQueue struct {
+	js     jetstream.JetStream
+	stream jetstream.Stream
+	name   string
+}
+
+// NewDeadLetterQueue creates a DeadLetterQueue next to the task stream
+// streamName, creating its stream if needed.
+func NewDeadLetterQueue(nc *nats.Conn, streamName string) (DeadLetterQueue, error) {
+	if nc == nil {
+		return nil, fmt.Errorf("nats connection cannot be nil")
+	}
+	js, err := jetStreamNew(nc)
+	if err != nil {
+		return nil, err
+	}
+	return NewDeadLetterQueueFromJS(js, streamName)
+}
+
+// NewDeadLetterQueueFromJS creates a DeadLetterQueue using an existing
+// JetStream context.
+func NewDeadLetterQueueFromJS(js jetstream.JetStream, streamName string) (DeadLetterQueue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	name := DeadLetterStreamName(streamName)
+	// Dead letters outlive restarts, unlike the tasks in the memory stream.
+	stream, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     name,
+		Subjects: []string{fmt.Sprintf("%s.>", name)},
+		Storage:  jetstream.FileStorage,
+		MaxAge:   types.DefaultDeadLetterMaxAge,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure dead letter stream: %w", err)
+	}
+	return &natsDeadLetterQueue{js: js, stream: stream, name: name}, nil
+}
+
+func (q *natsDeadLetterQueue) subject(tenant, triggerID string) string {
+	return fmt.Sprintf("%s.%s.%s", q.name, tenant, triggerID)
+}
+
+func (q *natsDeadLetterQueue) Add(ctx context.Context, letter *types.DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	ack, err := q.js.Publish(ctx, q.subject(letter.Task.Tenant, letter.Task.TriggerID), data, jetstream.WithExpectStream(q.name))
+	if err != nil {
+		return err
+	}
+	letter.ID = ack.Sequence
+	return nil
+}
+
+func (q *natsDeadLetterQueue) List(ctx context.Context, tenant, triggerID string, filter types.DeadLetterFilter) ([]*types.DeadLetter, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = types.DefaultDeadLetterPageSize
+	}
+	if limit > types.MaxDeadLetterPageSize {
+		limit = types.MaxDeadLetterPageSize
+	}
+
+	subject := q.subject(tenant, triggerID)
+	var letters []*types.DeadLetter
+	for seq := filter.After + 1; len(letters) < limit; {
+		raw, err := q.stream.GetMsg(ctx, seq, jetstream.WithGetMsgSubject(subject))
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		letter, err := decodeDeadLetter(raw)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Matches(letter) {
+			letters = append(letters, letter)
+		}
+		seq = raw.Sequence + 1
+	}
+	return letters, nil
+}
+
+func (q *natsDeadLetterQueue) Get(ctx context.Context, tenant, triggerID string, id uint64) (*types.DeadLetter, error) {
+	raw, err := q.stream.GetMsg(ctx, id)
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return nil, types.ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Sequences are shared by all triggers of all tenants.
+	if raw.Subject != q.subject(tenant, triggerID) {
+		return nil, types.ErrDeadLetterNotFound
+	}
+	return decodeDeadLetter(raw)
+}
+
+func (q *natsDeadLetterQueue) Delete(ctx context.Context, tenant, triggerID string, id uint64) error {
+	if _, err := q.Get(ctx, tenant, triggerID, id); err != nil {
+		return err
+	}
+	err := q.stream.DeleteMsg(ctx, id)
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return types.ErrDeadLetterNotFound
+	}
+	return err
+}
+
+func (q *natsDeadLetterQueue) Purge(ctx context.Context, tenant, triggerID string) error {
+	return q.stream.Purge(ctx, jetstream.WithPurgeSubject(q.subject(tenant, triggerID)))
+}
+
+func decodeDeadLetter(raw *jetstream.RawStreamMsg) (*types.DeadLetter, error) {
+	var letter types.DeadLetter
+	if err := json.Unmarshal(raw.Data, &letter); err != nil {
+		return nil, fmt.Errorf("invalid dead letter %d: %w", raw.Sequence, err)
+	}
+	letter.ID = raw.Sequence
+	return &letter, nil
+}
@@ -0,0 +1,214 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDLQStream keeps dead letters in memory, as a JetStream stream would.
+type fakeDLQStream struct {
+	jetstream.Stream
+	msgs   map[uint64]*jetstream.RawStreamMsg
+	last   uint64
+	purged string
+}
+
+func newFakeDLQStream() *fakeDLQStream {
+	return &fakeDLQStream{msgs: make(map[uint64]*jetstream.RawStreamMsg)}
+}
+
+func (s *fakeDLQStream) add(subject string, letter types.DeadLetter) {
+	s.last++
+	data, _ := json.Marshal(letter)
+	s.msgs[s.last] = &jetstream.RawStreamMsg{Subject: subject, Sequence: s.last, Data: data}
+}
+
+func (s *fakeDLQStream) GetMsg(ctx context.Context, seq uint64, opts ...jetstream.GetMsgOpt) (*jetstream.RawStreamMsg, error) {
+	if len(opts) == 0 {
+		if msg, ok := s.msgs[seq]; ok {
+			return msg, nil
+		}
+		return nil, jetstream.ErrMsgNotFound
+	}
+	// Only the subject option is used; the tests below pin the subject.
+	for ; seq <= s.last; seq++ {
+		if msg, ok := s.msgs[seq]; ok && msg.Subject == "TRIGGERS_DLQ.acme.t1" {
+			return msg, nil
+		}
+	}
+	return nil, jetstream.ErrMsgNotFound
+}
+
+func (s *fakeDLQStream) DeleteMsg(ctx context.Context, seq uint64) error {
+	if _, ok := s.msgs[seq]; !ok {
+		return jetstream.ErrMsgNotFound
+	}
+	delete(s.msgs, seq)
+	return nil
+}
+
+func (s *fakeDLQStream) Purge(ctx context.Context, opts ...jetstream.StreamPurgeOpt) error {
+	req := &jetstream.StreamPurgeRequest{}
+	for _, opt := range opts {
+		if err := opt(req); err != nil {
+			return err
+		}
+	}
+	s.purged = req.Subject
+	return nil
+}
+
+func TestNewDeadLetterQueueFromJS(t *testing.T) {
+	js := new(MockJetStream)
+	js.On("CreateOrUpdateStream", mock.Anything, mock.MatchedBy(func(cfg jetstream.StreamConfig) bool {
+		return cfg.Name == "JOBS_DLQ" && cfg.Subjects[0] == "JOBS_DLQ.>" &&
+			cfg.Storage == jetstream.FileStorage && cfg.MaxAge == types.DefaultDeadLetterMaxAge
+	})).Return(newFakeDLQStream(), nil)
+
+	q, err := NewDeadLetterQueueFromJS(js, "JOBS")
+	require.NoError(t, err)
+	assert.NotNil(t, q)
+	js.AssertExpectations(t)
+
+	failing := new(MockJetStream)
+	failing.On("CreateOrUpdateStream", mock.Anything, mock.Anything).Return(nil, errors.New("no jetstream"))
+	_, err = NewDeadLetterQueueFromJS(failing, "")
+	assert.ErrorContains(t, err, "dead letter stream")
+}
+
+func TestNewDeadLetterQueue_NilConn(t *testing.T) {
+	_, err := NewDeadLetterQueue(nil, "TRIGGERS")
+	assert.Error(t, err)
+}
+
+func TestDeadLetterQueue_Add(t *testing.T) {
+	js := new(MockJetStream)
+	q := &natsDeadLetterQueue{js: js, name: "TRIGGERS_DLQ"}
+	js.On("Publish", mock.Anything, "TRIGGERS_DLQ.acme.t1", mock.Anything, mock.Anything).
+		Return(&jetstream.PubAck{Stream: "TRIGGERS_DLQ", Sequence: 42}, nil)
+
+	letter := &types.DeadLetter{Task: types.DeliveryTask{Tenant: "acme", TriggerID: "t1"}, Error: "boom"}
+	require.NoError(t, q.Add(context.Background(), letter))
+	assert.Equal(t, uint64(42), letter.ID)
+}
+
+func TestDeadLetterQueue_ListGetDeletePurge(t *testing.T) {
+	ctx := context.Background()
+	stream := newFakeDLQStream()
+	now := time.Now().UnixMilli()
+	stream.add("TRIGGERS_DLQ.acme.t1", types.DeadLetter{Task: types.DeliveryTask{Event: "create"}, Status: 500, FailedAt: now})
+	stream.add("TRIGGERS_DLQ.other.t1", types.DeadLetter{Status: 500})
+	stream.add("TRIGGERS_DLQ.acme.t1", types.DeadLetter{Task: types.DeliveryTask{Event: "update"}, Status: 404, FailedAt: now})
+	stream.add("TRIGGERS_DLQ.acme.t1", types.DeadLetter{Task: types.DeliveryTask{Event: "create"}, Status: 500, FailedAt: now})
+	q := &natsDeadLetterQueue{stream: stream, name: "TRIGGERS_DLQ"}
+
+	letters, err := q.List(ctx, "acme", "t1", types.DeadLetterFilter{})
+	require.NoError(t, err)
+	require.Len(t, letters, 3)
+	assert.Equal(t, []uint64{1, 3, 4}, []uint64{letters[0].ID, letters[1].ID, letters[2].ID})
+
+	letters, err = q.List(ctx, "acme", "t1", types.DeadLetterFilter{Status: 500, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, uint64(1), letters[0].ID)
+
+	letters, err = q.List(ctx, "acme", "t1", types.DeadLetterFilter{Status: 500, After: 1})
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, uint64(4), letters[0].ID)
+
+	letter, err := q.Get(ctx, "acme", "t1", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 404, letter.Status)
+
+	// Sequences of other tenants are not visible.
+	_, err = q.Get(ctx, "acme", "t1", 2)
+	assert.ErrorIs(t, err, types.ErrDeadLetterNotFound)
+	assert.ErrorIs(t, q.Delete(ctx, "acme", "t1", 2), types.ErrDeadLetterNotFound)
+	_, err = q.Get(ctx, "acme", "t1", 99)
+	assert.ErrorIs(t, err, types.ErrDeadLetterNotFound)
+
+	require.NoError(t, q.Delete(ctx, "acme", "t1", 3))
+	_, err = q.Get(ctx, "acme", "t1", 3)
+	assert.ErrorIs(t, err, types.ErrDeadLetterNotFound)
+
+	require.NoError(t, q.Purge(ctx, "acme", "t1"))
+	assert.Equal(t, "TRIGGERS_DLQ.acme.t1", stream.purged)
+}
+
+type MockDeadLetterQueue struct {
+	mock.Mock
+	DeadLetterQueue
+}
+
+func (m *MockDeadLetterQueue) Add(ctx context.Context, letter *types.DeadLetter) error {
+	return m.Called(ctx, letter).Error(0)
+}
+
+func TestConsumer_Worker_DeadLetters(t *testing.T) {
+	tests := []struct {
+		name       string
+		processErr error
+		delivered  uint64
+		addErr     error
+		status     int
+		fatal      bool
+	}{
+		{"Fatal", &types.FatalError{Err: &types.StatusError{Status: 404}}, 1, nil, 404, true},
+		{"MaxAttempts", &types.StatusError{Status: 503}, 3, nil, 503, false},
+		{"QueueError", &types.FatalError{Err: errors.New("bad secret")}, 1, errors.New("nats down"), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockWorker := new(MockWorker)
+			dlq := new(MockDeadLetterQueue)
+			c := &natsConsumer{
+				worker:      mockWorker,
+				numWorkers:  1,
+				workerChans: []chan jetstream.Msg{make(chan jetstream.Msg, 1)},
+				metrics:     &types.NoopMetrics{},
+				deadLetters: dlq,
+			}
+			c.wg.Add(1)
+
+			data, _ := json.Marshal(&types.DeliveryTask{TriggerID: "t1", Tenant: "acme", RetryPolicy: types.RetryPolicy{MaxAttempts: 3}})
+			msg := new(MockMsg)
+			msg.On("Data").Return(data)
+			msg.On("Metadata").Return(&jetstream.MsgMetadata{NumDelivered: tt.delivered}, nil)
+			mockWorker.On("ProcessTask", mock.Anything, mock.Anything).Return(tt.processErr)
+			dlq.On("Add", mock.Anything, mock.MatchedBy(func(l *types.DeadLetter) bool {
+				return l.Task.TriggerID == "t1" && l.Status == tt.status && l.Fatal == tt.fatal &&
+					l.Attempts == int(tt.delivered) && l.Error == tt.processErr.Error() && l.FailedAt > 0
+			})).Return(tt.addErr)
+			if tt.addErr != nil {
+				msg.On("NakWithDelay", deadLetterRetryDelay).Return(nil)
+			} else {
+				msg.On("Term").Return(nil)
+			}
+
+			c.workerChans[0] <- msg
+			close(c.workerChans[0])
+			c.workerLoop(context.Background(), 0)
+
+			msg.AssertExpectations(t)
+			dlq.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWithDeadLetterQueue(t *testing.T) {
+	dlq := new(MockDeadLetterQueue)
+	c, err := NewTaskConsumerFromJS(new(MockJetStream), nil, "TRIGGERS", 1, nil, WithDeadLetterQueue(dlq))
+	require.NoError(t, err)
+	assert.Equal(t, dlq, c.(*natsConsumer).deadLetters)
+}
@@ -0,0 +1,200 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// tailBufferSize is how many records a tail holds for a slow reader.
+const tailBufferSize = 64
+
+// DeliveryLog keeps the latest delivery records of each trigger.
+type DeliveryLog interface {
+	// Append stores rec and sets its ID.
+	Append(ctx context.Context, rec *types.DeliveryRecord) error
+
+	// List returns the records of a trigger selected by filter, oldest
+	// first.
+	List(ctx context.Context, tenant, triggerID string, filter types.DeliveryLogFilter) ([]*types.DeliveryRecord, error)
+
+	// Tail returns the records of a trigger appended from now on, until ctx
+	// ends.
+	Tail(ctx context.Context, tenant, triggerID string) (<-chan *types.DeliveryRecord, error)
+}
+
+// DeliveryLogStreamName returns the name of the stream holding the delivery
+// log of the task stream streamName.
+func DeliveryLogStreamName(streamName string) string {
+	if streamName == "" {
+		streamName = "TRIGGERS"
+	}
+	return streamName + "_DELIVERIES"
+}
+
+// natsDeliveryLog implements DeliveryLog with a JetStream stream. Records
+// are published to <stream>_DELIVERIES.<tenant>.<triggerId>; the stream keeps
+// the last DefaultDeliveryLogSize of each subject.
+type natsDeliveryLog struct {
+	js     jetstream.JetStream
+	stream jetstream.Stream
+	name   string
+}
+
+// NewDeliveryLog creates a DeliveryLog next to the task stream streamName,
+// creating its stream if needed.
+func NewDeliveryLog(nc *nats.Conn, streamName string, opts types.StreamOptions) (DeliveryLog, error) {
+	if nc == nil {
+		return nil, fmt.Errorf("nats connection cannot be nil")
+	}
+	js, err := jetStreamNew(nc)
+	if err != nil {
+		return nil, err
+	}
+	return NewDeliveryLogFromJS(js, streamName, opts)
+}
+
+// NewDeliveryLogFromJS creates a DeliveryLog using an existing JetStream
+// context. The log uses the storage and replicas of the task stream.
+func NewDeliveryLogFromJS(js jetstream.JetStream, streamName string, opts types.StreamOptions) (DeliveryLog, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	name := DeliveryLogStreamName(streamName)
+	cfg := jetstream.StreamConfig{
+		Name:              name,
+		Subjects:          []string{fmt.Sprintf("%s.>", name)},
+		Storage:           jetstream.MemoryStorage,
+		Replicas:          opts.Replicas,
+		MaxMsgsPerSubject: types.DefaultDeliveryLogSize,
+		MaxAge:            types.DefaultDeliveryLogMaxAge,
+	}
+	if opts.Storage == types.StreamStorageFile {
+		cfg.Storage = jetstream.FileStorage
+	}
+	if cfg.Replicas <= 0 {
+		cfg.Replicas = 1
+	}
+	stream, err := js.CreateOrUpdateStream(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure delivery log stream: %w", err)
+	}
+	return &natsDeliveryLog{js: js, stream: stream, name: name}, nil
+}
+
+func (l *natsDeliveryLog) subject(tenant, triggerID string) string {
+	return fmt.Sprintf("%s.%s.%s", l.name, tenant, triggerID)
+}
+
+func (l *natsDeliveryLog) Append(ctx context.Context, rec *types.DeliveryRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	ack, err := l.js.Publish(ctx, l.subject(rec.Tenant, rec.TriggerID), data, jetstream.WithExpectStream(l.name))
+	if err != nil {
+		return err
+	}
+	rec.ID = ack.Sequence
+	return nil
+}
+
+func (l *natsDeliveryLog) List(ctx context.Context, tenant, triggerID string, filter types.DeliveryLogFilter) ([]*types.DeliveryRecord, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = types.DefaultDeliveryLogPageSize
+	}
+	if limit > types.MaxDeliveryLogPageSize {
+		limit = types.MaxDeliveryLogPageSize
+	}
+
+	subject := l.subject(tenant, triggerID)
+	var records []*types.DeliveryRecord
+	for seq := filter.After + 1; len(records) < limit; {
+		raw, err := l.stream.GetMsg(ctx, seq, jetstream.WithGetMsgSubject(subject))
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec, err := decodeDeliveryRecord(raw.Data, raw.Sequence)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Matches(rec) {
+			records = append(records, rec)
+		}
+		seq = raw.Sequence + 1
+	}
+	return records, nil
+}
+
+func (l *natsDeliveryLog) Tail(ctx context.Context, tenant, triggerID string) (<-chan *types.DeliveryRecord, error) {
+	cons, err := l.js.OrderedConsumer(ctx, l.name, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{l.subject(tenant, triggerID)},
+		DeliverPolicy:  jetstream.DeliverNewPolicy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to tail delivery log: %w", err)
+	}
+	iter, err := cons.Messages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to tail delivery log: %w", err)
+	}
+
+	out := make(chan *types.DeliveryRecord, tailBufferSize)
+	done := make(chan struct{})
+	go func() {
+		// Stopping the iterator ends Next below.
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		iter.Stop()
+	}()
+	go func() {
+		defer close(out)
+		defer close(done)
+		for {
+			msg, err := iter.Next()
+			if err != nil {
+				if !errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+					log.Printf("[Warning] Delivery log tail of trigger %s ended: %v", triggerID, err)
+				}
+				return
+			}
+			var seq uint64
+			if md, err := msg.Metadata(); err == nil {
+				seq = md.Sequence.Stream
+			}
+			rec, err := decodeDeliveryRecord(msg.Data(), seq)
+			if err != nil {
+				log.Printf("[Warning] Skipping delivery record: %v", err)
+				continue
+			}
+			select {
+			case out <- rec:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func decodeDeliveryRecord(data []byte, seq uint64) (*types.DeliveryRecord, error) {
+	var rec types.DeliveryRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("invalid delivery record %d: %w", seq, err)
+	}
+	rec.ID = seq
+	return &rec, nil
+}
@@ -0,0 +1,270 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *MockJetStream) OrderedConsumer(ctx context.Context, stream string, cfg jetstream.OrderedConsumerConfig) (jetstream.Consumer, error) {
+	args := m.Called(ctx, stream, cfg)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(jetstream.Consumer), args.Error(1)
+}
+
+// fakeLogStream keeps records in memory, as a JetStream stream would.
+type fakeLogStream struct {
+	jetstream.Stream
+	msgs []*jetstream.RawStreamMsg
+}
+
+func (s *fakeLogStream) add(subject string, rec types.DeliveryRecord) {
+	data, _ := json.Marshal(rec)
+	s.msgs = append(s.msgs, &jetstream.RawStreamMsg{Subject: subject, Sequence: uint64(len(s.msgs) + 1), Data: data})
+}
+
+func (s *fakeLogStream) GetMsg(ctx context.Context, seq uint64, opts ...jetstream.GetMsgOpt) (*jetstream.RawStreamMsg, error) {
+	// Only the subject option is used; the tests below pin the subject.
+	for _, msg := range s.msgs {
+		if msg.Sequence >= seq && msg.Subject == "TRIGGERS_DELIVERIES.acme.t1" {
+			return msg, nil
+		}
+	}
+	return nil, jetstream.ErrMsgNotFound
+}
+
+// fakeMessages is a MessagesContext fed through a channel.
+type fakeMessages struct {
+	jetstream.MessagesContext
+	msgs    chan jetstream.Msg
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newFakeMessages() *fakeMessages {
+	return &fakeMessages{msgs: make(chan jetstream.Msg, 4), stopped: make(chan struct{})}
+}
+
+func (m *fakeMessages) Next(opts ...jetstream.NextOpt) (jetstream.Msg, error) {
+	select {
+	case msg := <-m.msgs:
+		return msg, nil
+	case <-m.stopped:
+		return nil, jetstream.ErrMsgIteratorClosed
+	}
+}
+
+func (m *fakeMessages) Stop() {
+	m.once.Do(func() { close(m.stopped) })
+}
+
+func TestNewDeliveryLogFromJS(t *testing.T) {
+	js := new(MockJetStream)
+	js.On("CreateOrUpdateStream", mock.Anything, mock.MatchedBy(func(cfg jetstream.StreamConfig) bool {
+		return cfg.Name == "JOBS_DELIVERIES" && cfg.Subjects[0] == "JOBS_DELIVERIES.>" &&
+			cfg.Storage == jetstream.FileStorage && cfg.Replicas == 1 &&
+			cfg.MaxMsgsPerSubject == types.DefaultDeliveryLogSize && cfg.MaxAge == types.DefaultDeliveryLogMaxAge
+	})).Return(&fakeLogStream{}, nil)
+
+	l, err := NewDeliveryLogFromJS(js, "JOBS", types.StreamOptions{Storage: types.StreamStorageFile})
+	require.NoError(t, err)
+	assert.NotNil(t, l)
+	js.AssertExpectations(t)
+}
+
+func TestNewDeliveryLogFromJS_Error(t *testing.T) {
+	js := new(MockJetStream)
+	js.On("CreateOrUpdateStream", mock.Anything, mock.Anything).Return(nil, errors.New("no jetstream"))
+
+	_, err := NewDeliveryLogFromJS(js, "", types.StreamOptions{})
+	assert.ErrorContains(t, err, "failed to ensure delivery log stream")
+}
+
+func TestNewDeliveryLog_NilConn(t *testing.T) {
+	_, err := NewDeliveryLog(nil, "", types.StreamOptions{})
+	assert.Error(t, err)
+}
+
+func TestNATSDeliveryLog_Append(t *testing.T) {
+	js := new(MockJetStream)
+	js.On("Publish", mock.Anything, "TRIGGERS_DELIVERIES.acme.t1", mock.Anything, mock.Anything).
+		Return(&jetstream.PubAck{Sequence: 7}, nil)
+	l := &natsDeliveryLog{js: js, name: DeliveryLogStreamName("")}
+
+	rec := &types.DeliveryRecord{Tenant: "acme", TriggerID: "t1", TaskID: "task-1"}
+	require.NoError(t, l.Append(context.Background(), rec))
+	assert.Equal(t, uint64(7), rec.ID)
+}
+
+func TestNATSDeliveryLog_List(t *testing.T) {
+	stream := &fakeLogStream{}
+	stream.add("TRIGGERS_DELIVERIES.acme.t1", types.DeliveryRecord{TaskID: "a", Outcome: types.DeliverySucceeded, Status: 200})
+	stream.add("TRIGGERS_DELIVERIES.acme.t2", types.DeliveryRecord{TaskID: "other"})
+	stream.add("TRIGGERS_DELIVERIES.acme.t1", types.DeliveryRecord{TaskID: "b", Outcome: types.DeliveryRetrying, Status: 503})
+	stream.add("TRIGGERS_DELIVERIES.acme.t1", types.DeliveryRecord{TaskID: "c", Outcome: types.DeliveryFailed, Status: 404})
+	l := &natsDeliveryLog{stream: stream, name: DeliveryLogStreamName("")}
+	ctx := context.Background()
+
+	records, err := l.List(ctx, "acme", "t1", types.DeliveryLogFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "a", records[0].TaskID)
+	assert.Equal(t, uint64(1), records[0].ID)
+	assert.Equal(t, uint64(4), records[2].ID)
+
+	records, err = l.List(ctx, "acme", "t1", types.DeliveryLogFilter{After: 1, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "b", records[0].TaskID)
+
+	records, err = l.List(ctx, "acme", "t1", types.DeliveryLogFilter{Outcome: types.DeliveryFailed})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 404, records[0].Status)
+}
+
+func TestNATSDeliveryLog_Tail(t *testing.T) {
+	iter := newFakeMessages()
+	cons := new(MockConsumer)
+	cons.On("Messages", mock.Anything).Return(iter, nil)
+	js := new(MockJetStream)
+	js.On("OrderedConsumer", mock.Anything, "TRIGGERS_DELIVERIES", mock.MatchedBy(func(cfg jetstream.OrderedConsumerConfig) bool {
+		return cfg.FilterSubjects[0] == "TRIGGERS_DELIVERIES.acme.t1" && cfg.DeliverPolicy == jetstream.DeliverNewPolicy
+	})).Return(cons, nil)
+	l := &natsDeliveryLog{js: js, name: DeliveryLogStreamName("")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	records, err := l.Tail(ctx, "acme", "t1")
+	require.NoError(t, err)
+
+	data, _ := json.Marshal(types.DeliveryRecord{TaskID: "task-1"})
+	msg := new(MockMsg)
+	msg.On("Data").Return(data)
+	msg.On("Metadata").Return(&jetstream.MsgMetadata{Sequence: jetstream.SequencePair{Stream: 42}}, nil)
+	iter.msgs <- msg
+
+	select {
+	case rec := <-records:
+		assert.Equal(t, "task-1", rec.TaskID)
+		assert.Equal(t, uint64(42), rec.ID)
+	case <-time.After(time.Second):
+		t.Fatal("no record tailed")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-records:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("tail not closed")
+	}
+}
+
+func TestNATSDeliveryLog_Tail_Error(t *testing.T) {
+	js := new(MockJetStream)
+	js.On("OrderedConsumer", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("no stream"))
+	l := &natsDeliveryLog{js: js, name: DeliveryLogStreamName("")}
+
+	_, err := l.Tail(context.Background(), "acme", "t1")
+	assert.ErrorContains(t, err, "failed to tail delivery log")
+}
+
+// memoryDeliveryLog is a DeliveryLog that keeps appended records.
+type memoryDeliveryLog struct {
+	DeliveryLog
+	mu      sync.Mutex
+	records []*types.DeliveryRecord
+}
+
+func (l *memoryDeliveryLog) Append(ctx context.Context, rec *types.DeliveryRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, rec)
+	return nil
+}
+
+func TestConsumer_RecordsDeliveries(t *testing.T) {
+	tests := []struct {
+		name       string
+		processErr error
+		delivered  uint64
+		outcome    string
+		status     int
+	}{
+		{"Succeeded", nil, 1, types.DeliverySucceeded, 200},
+		{"Retrying", &types.StatusError{Status: 503}, 1, types.DeliveryRetrying, 503},
+		{"Exhausted", &types.StatusError{Status: 503}, 3, types.DeliveryFailed, 503},
+		{"Fatal", &types.FatalError{Err: &types.StatusError{Status: 404}}, 1, types.DeliveryFailed, 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dl := &memoryDeliveryLog{}
+			mockWorker := new(MockWorker)
+			c, err := NewTaskConsumerFromJS(nil, mockWorker, "", 1, nil, WithDeliveryLog(dl))
+			require.NoError(t, err)
+			nc := c.(*natsConsumer)
+
+			data, _ := json.Marshal(&types.DeliveryTask{ID: "task-1", TriggerID: "t1", Tenant: "acme", DocumentID: "d1"})
+			msg := new(MockMsg)
+			msg.On("Data").Return(data)
+			msg.On("Metadata").Return(&jetstream.MsgMetadata{NumDelivered: tt.delivered}, nil)
+			mockWorker.On("ProcessTask", mock.Anything, mock.Anything).Return(tt.processErr).Run(func(args mock.Arguments) {
+				if tt.processErr == nil {
+					resp := types.DeliveryResponseFrom(args.Get(0).(context.Context))
+					require.NotNil(t, resp)
+					resp.Status, resp.Body = 200, []byte("ok")
+				}
+			})
+
+			_ = nc.processMsg(context.Background(), msg)
+
+			stop := make(chan struct{})
+			close(stop)
+			nc.writeDeliveryLog(stop)
+
+			require.Len(t, dl.records, 1)
+			rec := dl.records[0]
+			assert.Equal(t, "task-1", rec.TaskID)
+			assert.Equal(t, "d1", rec.DocumentID)
+			assert.Equal(t, int(tt.delivered), rec.Attempt)
+			assert.Equal(t, tt.outcome, rec.Outcome)
+			assert.Equal(t, tt.status, rec.Status)
+			assert.NotZero(t, rec.DeliveredAt)
+			if tt.processErr != nil {
+				assert.Equal(t, tt.processErr.Error(), rec.Error)
+			} else {
+				assert.Equal(t, "ok", rec.Response)
+			}
+		})
+	}
+}
+
+func TestConsumer_RecordDelivery_Full(t *testing.T) {
+	c := &natsConsumer{logRecords: make(chan *types.DeliveryRecord, 1), deliveryLog: &memoryDeliveryLog{}}
+	msg := new(MockMsg)
+	msg.On("Metadata").Return(nil, errors.New("no metadata"))
+	task := &types.DeliveryTask{TriggerID: "t1"}
+
+	// The second record does not fit and is dropped without blocking.
+	c.recordDelivery(msg, task, &types.DeliveryResponse{}, time.Now(), nil)
+	c.recordDelivery(msg, task, &types.DeliveryResponse{}, time.Now(), nil)
+	assert.Equal(t, int64(1), c.logDropped.Load())
+
+	stop := make(chan struct{})
+	close(stop)
+	c.writeDeliveryLog(stop)
+	assert.Len(t, c.deliveryLog.(*memoryDeliveryLog).records, 1)
+	assert.Zero(t, c.logDropped.Load())
+}
@@ -0,0 +1,190 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// ErrKVConflict is returned by a compare-and-set write to a KVBucket when
+// the key changed since it was read, e.g. by SetLastRun when another
+// scheduler recorded a run first.
+var ErrKVConflict = errors.New("key changed concurrently")
+
+// KVBucket is the part of a key-value bucket the scheduler, the leader
+// election and the delivery limiter keep their state in. Writes are
+// compare-and-set on the revision; revision 0 creates the key.
+type KVBucket interface {
+	// Get returns the value and revision of key, nil and 0 if missing.
+	Get(ctx context.Context, key string) (value []byte, revision uint64, err error)
+
+	// Put writes key if it is still at revision and returns the new one.
+	Put(ctx context.Context, key string, value []byte, revision uint64) (uint64, error)
+
+	// Delete removes key if it is still at revision, or anyway if revision
+	// is 0.
+	Delete(ctx context.Context, key string, revision uint64) error
+
+	// Keys returns the keys matching filter, a subject with wildcards.
+	Keys(ctx context.Context, filter string) ([]string, error)
+}
+
+// jsBucket implements KVBucket with a JetStream key-value bucket.
+type jsBucket struct {
+	kv jetstream.KeyValue
+}
+
+func (b *jsBucket) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	entry, err := b.kv.Get(ctx, key)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return entry.Value(), entry.Revision(), nil
+}
+
+func (b *jsBucket) Put(ctx context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	var rev uint64
+	var err error
+	if revision == 0 {
+		rev, err = b.kv.Create(ctx, key, value)
+	} else {
+		rev, err = b.kv.Update(ctx, key, value, revision)
+	}
+	if errors.Is(err, jetstream.ErrKeyExists) {
+		return 0, ErrKVConflict
+	}
+	return rev, err
+}
+
+func (b *jsBucket) Delete(ctx context.Context, key string, revision uint64) error {
+	if revision == 0 {
+		return b.kv.Purge(ctx, key)
+	}
+	return b.kv.Purge(ctx, key, jetstream.LastRevision(revision))
+}
+
+func (b *jsBucket) Keys(ctx context.Context, filter string) ([]string, error) {
+	lister, err := b.kv.ListKeysFiltered(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for key := range lister.Keys() {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ensureBucket creates or updates the key-value bucket described by cfg.
+func ensureBucket(nc *nats.Conn, cfg jetstream.KeyValueConfig) (KVBucket, error) {
+	if nc == nil {
+		return nil, fmt.Errorf("nats connection cannot be nil")
+	}
+	js, err := jetStreamNew(nc)
+	if err != nil {
+		return nil, err
+	}
+	return ensureBucketFromJS(js, cfg)
+}
+
+// ensureBucketFromJS creates or updates the key-value bucket described by
+// cfg using an existing JetStream context.
+func ensureBucketFromJS(js jetstream.JetStream, cfg jetstream.KeyValueConfig) (KVBucket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	kv, err := js.CreateOrUpdateKeyValue(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure bucket %s: %w", cfg.Bucket, err)
+	}
+	return &jsBucket{kv: kv}, nil
+}
+
+// MemoryBucket is a KVBucket in memory with one revision counter, like a
+// JetStream bucket. Its state is not shared between processes, so it only
+// serves a single instance, e.g. in tests.
+type MemoryBucket struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	seq     uint64
+
+	err       error // returned by every call while set
+	conflicts int   // the next puts lose their compare-and-set
+}
+
+type memoryEntry struct {
+	value    []byte
+	revision uint64
+}
+
+// NewMemoryBucket creates an empty MemoryBucket.
+func NewMemoryBucket() *MemoryBucket {
+	return &MemoryBucket{entries: map[string]memoryEntry{}}
+}
+
+func (b *MemoryBucket) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return nil, 0, b.err
+	}
+	e := b.entries[key]
+	return e.value, e.revision, nil
+}
+
+func (b *MemoryBucket) Put(ctx context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.conflicts > 0 {
+		b.conflicts--
+		return 0, ErrKVConflict
+	}
+	if b.entries[key].revision != revision {
+		return 0, ErrKVConflict
+	}
+	b.seq++
+	b.entries[key] = memoryEntry{value: value, revision: b.seq}
+	return b.seq, nil
+}
+
+func (b *MemoryBucket) Delete(ctx context.Context, key string, revision uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	if revision != 0 && b.entries[key].revision != revision {
+		return ErrKVConflict
+	}
+	delete(b.entries, key)
+	return nil
+}
+
+// Keys supports filters ending in ">" or naming one key.
+func (b *MemoryBucket) Keys(ctx context.Context, filter string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return nil, b.err
+	}
+	var keys []string
+	for k := range b.entries {
+		if prefix, ok := strings.CutSuffix(filter, ">"); (ok && strings.HasPrefix(k, prefix)) || k == filter {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
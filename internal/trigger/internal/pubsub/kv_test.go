@@ -0,0 +1,41 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBucket(t *testing.T) {
+	b := NewMemoryBucket()
+	ctx := context.Background()
+
+	value, rev, err := b.Get(ctx, "a.1")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	assert.Zero(t, rev)
+
+	rev, err = b.Put(ctx, "a.1", []byte("x"), 0)
+	require.NoError(t, err)
+	_, err = b.Put(ctx, "a.1", []byte("y"), 0)
+	assert.ErrorIs(t, err, ErrKVConflict, "create of an existing key")
+	_, err = b.Put(ctx, "b.1", []byte("z"), 0)
+	require.NoError(t, err)
+
+	keys, err := b.Keys(ctx, "a.>")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.1"}, keys)
+
+	assert.ErrorIs(t, b.Delete(ctx, "a.1", rev+1), ErrKVConflict)
+	require.NoError(t, b.Delete(ctx, "a.1", rev))
+	require.NoError(t, b.Delete(ctx, "b.1", 0))
+	keys, _ = b.Keys(ctx, ">")
+	assert.Empty(t, keys)
+
+	b.err = errors.New("bucket down")
+	_, _, err = b.Get(ctx, "a.1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,244 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Limits a delivery can be delayed by.
+const (
+	LimitConcurrency = "concurrency"
+	LimitRate        = "rate_limit"
+	LimitUnavailable = "limiter_error"
+)
+
+const (
+	// concurrencyPollInterval is how long a delivery waits for a free slot
+	// before asking again.
+	concurrencyPollInterval = 250 * time.Millisecond
+
+	// leaseGrace is added to the task timeout for the lease of a slot, so a
+	// consumer that dies mid-delivery frees it soon after.
+	leaseGrace = 5 * time.Second
+
+	// maxLimitUpdates bounds the compare-and-set rounds of one acquire or
+	// release under contention.
+	maxLimitUpdates = 10
+
+	// limitKeyTTL expires the state of triggers that stopped delivering.
+	limitKeyTTL = time.Hour
+)
+
+// LimitError reports a delivery held back by a limit of its trigger.
+type LimitError struct {
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("delivery delayed by %s for %v", e.Limit, e.RetryAfter)
+}
+
+// DeliveryLimiter admits the deliveries of a trigger within its Concurrency
+// and RateLimit. Instances sharing a limiter's state enforce the limits
+// together.
+type DeliveryLimiter interface {
+	// Acquire admits one delivery of task and returns the func to call when
+	// it ends. A delivery over a limit gets a *LimitError.
+	Acquire(ctx context.Context, task *types.DeliveryTask) (release func(), err error)
+}
+
+// LimitBucketName returns the name of the key-value bucket holding the
+// limiter state of the task stream streamName.
+func LimitBucketName(streamName string) string {
+	if streamName == "" {
+		streamName = "TRIGGERS"
+	}
+	return streamName + "_LIMITS"
+}
+
+// kvLimiter implements DeliveryLimiter on shared state, so the limits hold
+// across all consumers of the stream. Concurrency is a semaphore of leased
+// slots under concurrency.<tenant>.<triggerId>; RateLimit is a token bucket
+// refilled at RateLimit tokens per second under rate.<tenant>.<triggerId>.
+type kvLimiter struct {
+	bucket   KVBucket
+	instance string
+	leases   atomic.Uint64
+	now      func() time.Time
+}
+
+// NewDeliveryLimiter creates a DeliveryLimiter for the task stream
+// streamName, creating its bucket if needed.
+func NewDeliveryLimiter(nc *nats.Conn, streamName string) (DeliveryLimiter, error) {
+	if nc == nil {
+		return nil, fmt.Errorf("nats connection cannot be nil")
+	}
+	js, err := jetStreamNew(nc)
+	if err != nil {
+		return nil, err
+	}
+	return NewDeliveryLimiterFromJS(js, streamName)
+}
+
+// NewDeliveryLimiterFromJS creates a DeliveryLimiter using an existing
+// JetStream context.
+func NewDeliveryLimiterFromJS(js jetstream.JetStream, streamName string) (DeliveryLimiter, error) {
+	bucket, err := ensureBucketFromJS(js, jetstream.KeyValueConfig{
+		Bucket:  LimitBucketName(streamName),
+		History: 1,
+		TTL:     limitKeyTTL,
+		Storage: jetstream.MemoryStorage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newKVLimiter(bucket), nil
+}
+
+func newKVLimiter(bucket KVBucket) *kvLimiter {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &kvLimiter{bucket: bucket, instance: hex.EncodeToString(id), now: time.Now}
+}
+
+// slots are the leased slots of a trigger: lease ID to expiry in Unix
+// milliseconds.
+type slots map[string]int64
+
+// bucket is the token bucket of a trigger.
+type bucket struct {
+	Tokens    float64 `json:"tokens"`
+	UpdatedAt int64   `json:"updatedAt"` // Unix milliseconds
+}
+
+func (l *kvLimiter) Acquire(ctx context.Context, task *types.DeliveryTask) (func(), error) {
+	release := func() {}
+	if task.Concurrency > 0 {
+		lease, err := l.acquireSlot(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+		release = func() { l.releaseSlot(task, lease) }
+	}
+	if task.RateLimit > 0 {
+		if err := l.takeToken(ctx, task); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// update applies fn to the state under key until the write wins. fn returns
+// the new state, or an error to leave it unchanged.
+func (l *kvLimiter) update(ctx context.Context, key string, fn func(data []byte) ([]byte, error)) error {
+	for i := 0; i < maxLimitUpdates; i++ {
+		data, rev, err := l.bucket.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		next, err := fn(data)
+		if err != nil {
+			return err
+		}
+		_, err = l.bucket.Put(ctx, key, next, rev)
+		if !errors.Is(err, ErrKVConflict) {
+			return err
+		}
+	}
+	return ErrKVConflict
+}
+
+func (l *kvLimiter) acquireSlot(ctx context.Context, task *types.DeliveryTask) (string, error) {
+	lease := fmt.Sprintf("%s-%d", l.instance, l.leases.Add(1))
+	timeout := time.Duration(task.Timeout)
+	if timeout <= 0 {
+		timeout = types.DefaultTaskTimeout
+	}
+
+	err := l.update(ctx, limitKey(LimitConcurrency, task), func(data []byte) ([]byte, error) {
+		held := slots{}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &held); err != nil {
+				return nil, err
+			}
+		}
+		now := l.now()
+		for id, expires := range held {
+			if expires <= now.UnixMilli() {
+				delete(held, id)
+			}
+		}
+		if len(held) >= task.Concurrency {
+			return nil, &LimitError{Limit: LimitConcurrency, RetryAfter: concurrencyPollInterval}
+		}
+		held[lease] = now.Add(timeout + leaseGrace).UnixMilli()
+		return json.Marshal(held)
+	})
+	return lease, err
+}
+
+// releaseSlot frees a slot after the delivery, even if its context ended.
+// A slot that cannot be freed expires with its lease.
+func (l *kvLimiter) releaseSlot(task *types.DeliveryTask, lease string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_ = l.update(ctx, limitKey(LimitConcurrency, task), func(data []byte) ([]byte, error) {
+		held := slots{}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &held); err != nil {
+				return nil, err
+			}
+		}
+		delete(held, lease)
+		return json.Marshal(held)
+	})
+}
+
+func (l *kvLimiter) takeToken(ctx context.Context, task *types.DeliveryTask) error {
+	rate := float64(task.RateLimit)
+	return l.update(ctx, limitKey(LimitRate, task), func(data []byte) ([]byte, error) {
+		now := l.now().UnixMilli()
+		b := bucket{Tokens: rate, UpdatedAt: now}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &b); err != nil {
+				return nil, err
+			}
+		}
+		if elapsed := now - b.UpdatedAt; elapsed > 0 {
+			b.Tokens += rate * float64(elapsed) / 1000
+		}
+		if b.Tokens > rate {
+			b.Tokens = rate
+		}
+		b.UpdatedAt = now
+		if b.Tokens < 1 {
+			wait := time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+			return nil, &LimitError{Limit: LimitRate, RetryAfter: wait}
+		}
+		b.Tokens--
+		return json.Marshal(b)
+	})
+}
+
+// limitKey returns the key of a limit's state. Tenants and trigger IDs are
+// restricted to characters valid in keys.
+func limitKey(limit string, task *types.DeliveryTask) string {
+	prefix := "rate"
+	if limit == LimitConcurrency {
+		prefix = "concurrency"
+	}
+	return fmt.Sprintf("%s.%s.%s", prefix, task.Tenant, task.TriggerID)
+}
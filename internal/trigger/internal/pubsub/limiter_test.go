@@ -0,0 +1,263 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func limitedBy(t *testing.T, err error) *LimitError {
+	t.Helper()
+	var limited *LimitError
+	require.ErrorAs(t, err, &limited)
+	return limited
+}
+
+func TestKVLimiter_Concurrency(t *testing.T) {
+	store := NewMemoryBucket()
+	now := time.Unix(1000, 0)
+	// Two consumer instances share the state.
+	a, b := newKVLimiter(store), newKVLimiter(store)
+	a.now = func() time.Time { return now }
+	b.now = a.now
+	task := &types.DeliveryTask{Tenant: "acme", TriggerID: "t1", Concurrency: 2}
+
+	releaseA, err := a.Acquire(context.Background(), task)
+	require.NoError(t, err)
+	_, err = b.Acquire(context.Background(), task)
+	require.NoError(t, err)
+
+	_, err = a.Acquire(context.Background(), task)
+	limited := limitedBy(t, err)
+	assert.Equal(t, LimitConcurrency, limited.Limit)
+	assert.Equal(t, concurrencyPollInterval, limited.RetryAfter)
+
+	releaseA()
+	_, err = b.Acquire(context.Background(), task)
+	require.NoError(t, err)
+
+	// Leases of consumers that died expire.
+	now = now.Add(types.DefaultTaskTimeout + leaseGrace)
+	_, err = a.Acquire(context.Background(), task)
+	assert.NoError(t, err)
+
+	other := &types.DeliveryTask{Tenant: "acme", TriggerID: "t2", Concurrency: 1}
+	_, err = a.Acquire(context.Background(), other)
+	assert.NoError(t, err, "limits are per trigger")
+}
+
+func TestKVLimiter_RateLimit(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newKVLimiter(NewMemoryBucket())
+	l.now = func() time.Time { return now }
+	task := &types.DeliveryTask{Tenant: "acme", TriggerID: "t1", RateLimit: 2}
+
+	for i := 0; i < 2; i++ {
+		_, err := l.Acquire(context.Background(), task)
+		require.NoError(t, err)
+	}
+	_, err := l.Acquire(context.Background(), task)
+	limited := limitedBy(t, err)
+	assert.Equal(t, LimitRate, limited.Limit)
+	assert.Equal(t, 500*time.Millisecond, limited.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	_, err = l.Acquire(context.Background(), task)
+	assert.NoError(t, err)
+
+	// The bucket holds at most one second of tokens.
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		_, err := l.Acquire(context.Background(), task)
+		require.NoError(t, err)
+	}
+	_, err = l.Acquire(context.Background(), task)
+	assert.Error(t, err)
+}
+
+func TestKVLimiter_RateLimitReleasesSlot(t *testing.T) {
+	store := NewMemoryBucket()
+	l := newKVLimiter(store)
+	task := &types.DeliveryTask{Tenant: "acme", TriggerID: "t1", Concurrency: 1, RateLimit: 1}
+
+	release, err := l.Acquire(context.Background(), task)
+	require.NoError(t, err)
+	release()
+
+	_, err = l.Acquire(context.Background(), task)
+	assert.Equal(t, LimitRate, limitedBy(t, err).Limit)
+
+	var held slots
+	require.NoError(t, json.Unmarshal(store.entries[limitKey(LimitConcurrency, task)].value, &held))
+	assert.Empty(t, held, "a delivery delayed by the rate gives its slot back")
+}
+
+func TestKVLimiter_Contention(t *testing.T) {
+	store := NewMemoryBucket()
+	l := newKVLimiter(store)
+	task := &types.DeliveryTask{Tenant: "acme", TriggerID: "t1", RateLimit: 5}
+
+	store.conflicts = 3
+	_, err := l.Acquire(context.Background(), task)
+	assert.NoError(t, err, "retries lost compare-and-sets")
+
+	store.conflicts = maxLimitUpdates
+	_, err = l.Acquire(context.Background(), task)
+	assert.ErrorIs(t, err, ErrKVConflict)
+}
+
+func TestKVLimiter_ParallelAcquire(t *testing.T) {
+	store := NewMemoryBucket()
+	task := &types.DeliveryTask{Tenant: "acme", TriggerID: "t1", Concurrency: 3}
+
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := newKVLimiter(store)
+			for j := 0; j < 5; j++ {
+				if _, err := l.Acquire(context.Background(), task); err == nil {
+					admitted.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), admitted.Load())
+}
+
+func TestLimitBucketName(t *testing.T) {
+	assert.Equal(t, "TRIGGERS_LIMITS", LimitBucketName(""))
+	assert.Equal(t, "JOBS_LIMITS", LimitBucketName("JOBS"))
+}
+
+type MockDeliveryLimiter struct {
+	mock.Mock
+}
+
+func (m *MockDeliveryLimiter) Acquire(ctx context.Context, task *types.DeliveryTask) (func(), error) {
+	args := m.Called(ctx, task)
+	release, _ := args.Get(0).(func())
+	return release, args.Error(1)
+}
+
+func TestConsumer_Worker_DelaysLimitedDelivery(t *testing.T) {
+	mockWorker := new(MockWorker)
+	limiter := new(MockDeliveryLimiter)
+	metrics := new(MockMetrics)
+	c := &natsConsumer{
+		worker:      mockWorker,
+		numWorkers:  1,
+		workerChans: []chan jetstream.Msg{make(chan jetstream.Msg, 1)},
+		metrics:     metrics,
+		limiter:     limiter,
+	}
+	c.wg.Add(1)
+
+	data, _ := json.Marshal(&types.DeliveryTask{TriggerID: "t1", Tenant: "acme", Collection: "orders", RateLimit: 1})
+	var released atomic.Bool
+	limiter.On("Acquire", mock.Anything, mock.Anything).
+		Return(nil, &LimitError{Limit: LimitRate, RetryAfter: 10 * time.Millisecond}).Once()
+	limiter.On("Acquire", mock.Anything, mock.Anything).
+		Return(nil, errors.New("bucket unavailable")).Once()
+	limiter.On("Acquire", mock.Anything, mock.Anything).
+		Return(func() { released.Store(true) }, nil).Once()
+	metrics.On("IncDeliveryDelayed", "acme", "orders", LimitRate).Once()
+	metrics.On("IncDeliveryDelayed", "acme", "orders", LimitUnavailable).Once()
+	metrics.On("IncConsumeSuccess", "acme", "orders", false)
+	metrics.On("ObserveConsumeLatency", "acme", "orders", mock.Anything)
+	mockWorker.On("ProcessTask", mock.Anything, mock.Anything).Return(nil).Once()
+
+	acked := make(chan struct{})
+	msg := new(MockMsg)
+	msg.On("Data").Return(data)
+	msg.On("InProgress").Return(nil).Twice()
+	msg.On("Ack").Return(nil).Run(func(mock.Arguments) { close(acked) })
+
+	go c.workerLoop(context.Background(), 0)
+	c.workerChans[0] <- msg
+
+	select {
+	case <-acked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delayed delivery was not retried")
+	}
+	close(c.workerChans[0])
+	c.wg.Wait()
+
+	assert.True(t, released.Load())
+	assert.Zero(t, c.inFlightCount.Load())
+	msg.AssertExpectations(t)
+	limiter.AssertExpectations(t)
+	metrics.AssertExpectations(t)
+}
+
+func TestConsumer_Delay_NakOnClose(t *testing.T) {
+	c := &natsConsumer{workerChans: []chan jetstream.Msg{make(chan jetstream.Msg)}}
+	c.closing.Store(true)
+
+	naked := make(chan struct{})
+	msg := new(MockMsg)
+	msg.On("InProgress").Return(nil)
+	msg.On("Nak").Return(nil).Run(func(mock.Arguments) { close(naked) })
+
+	c.delay(0, msg, time.Millisecond)
+	assert.Equal(t, int32(1), c.inFlightCount.Load(), "shutdown waits for delayed deliveries")
+	<-naked
+	assert.Eventually(t, func() bool { return c.inFlightCount.Load() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestConsumer_Delay_NakOnStop(t *testing.T) {
+	// Nobody reads the worker channel, so the hand-off waits until the
+	// consumer stops and its channels are closed.
+	c := &natsConsumer{workerChans: []chan jetstream.Msg{make(chan jetstream.Msg)}, stopped: make(chan struct{})}
+
+	naked := make(chan struct{}, 2)
+	msg := new(MockMsg)
+	msg.On("InProgress").Return(nil)
+	msg.On("Nak").Return(nil).Run(func(mock.Arguments) { naked <- struct{}{} })
+
+	c.delay(0, msg, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	close(c.stopped)
+	c.chansMu.Lock()
+	close(c.workerChans[0])
+	c.chansMu.Unlock()
+
+	select {
+	case <-naked:
+	case <-time.After(time.Second):
+		t.Fatal("delayed message was not NAKed")
+	}
+	assert.Eventually(t, func() bool { return c.inFlightCount.Load() == 0 }, time.Second, time.Millisecond)
+
+	// A hand-off after the channels are closed does not panic.
+	c.handOff(0, msg)
+	assert.Len(t, naked, 1)
+}
+
+func TestConsumer_Admit_Unlimited(t *testing.T) {
+	limiter := new(MockDeliveryLimiter)
+	c := &natsConsumer{limiter: limiter}
+	msg := new(MockMsg)
+	data, _ := json.Marshal(&types.DeliveryTask{TriggerID: "t1"})
+	msg.On("Data").Return(data)
+
+	release, ok := c.admit(context.Background(), 0, msg)
+	assert.True(t, ok)
+	release()
+	limiter.AssertNotCalled(t, "Acquire", mock.Anything, mock.Anything)
+}
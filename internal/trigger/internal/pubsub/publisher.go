@@ -21,7 +21,22 @@ type natsPublisher struct {
 	prefix  string
 }
 
-func NewTaskPublisher(nc *nats.Conn, streamName string, metrics types.Metrics) (TaskPublisher, error) {
+// PublisherOption configures the publisher.
+type PublisherOption func(*publisherOptions)
+
+type publisherOptions struct {
+	stream types.StreamOptions
+}
+
+// WithPublisherStreamOptions sets the options the task stream is ensured
+// with.
+func WithPublisherStreamOptions(opts types.StreamOptions) PublisherOption {
+	return func(o *publisherOptions) {
+		o.stream = opts
+	}
+}
+
+func NewTaskPublisher(nc *nats.Conn, streamName string, metrics types.Metrics, opts ...PublisherOption) (TaskPublisher, error) {
 	if nc == nil {
 		return nil, fmt.Errorf("nats connection cannot be nil")
 	}
@@ -30,31 +45,19 @@ func NewTaskPublisher(nc *nats.Conn, streamName string, metrics types.Metrics) (
 		return nil, err
 	}
 
+	var o publisherOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// Ensure stream exists
-	if err := EnsureStream(js, streamName); err != nil {
+	if err := EnsureStream(js, streamName, o.stream); err != nil {
 		return nil, fmt.Errorf("failed to ensure stream: %w", err)
 	}
 
 	return NewTaskPublisherFromJS(js, streamName, metrics), nil
 }
 
-func EnsureStream(js jetstream.JetStream, streamName string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	if streamName == "" {
-		streamName = "TRIGGERS"
-	}
-	subjects := []string{fmt.Sprintf("%s.>", streamName)}
-
-	_, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
-		Name:     streamName,
-		Subjects: subjects,
-		Storage:  jetstream.MemoryStorage,
-	})
-	return err
-}
-
 func NewTaskPublisherFromJS(js jetstream.JetStream, streamName string, metrics types.Metrics) TaskPublisher {
 	if metrics == nil {
 		metrics = &types.NoopMetrics{}
@@ -89,7 +92,11 @@ func (p *natsPublisher) Publish(ctx context.Context, task *types.DeliveryTask) e
 		return err
 	}
 
-	_, err = p.js.Publish(ctx, subject, data, jetstream.WithExpectStream(p.prefix), jetstream.WithRetryAttempts(3))
+	opts := []jetstream.PublishOpt{jetstream.WithExpectStream(p.prefix), jetstream.WithRetryAttempts(3)}
+	if id := msgID(task); id != "" {
+		opts = append(opts, jetstream.WithMsgID(id))
+	}
+	_, err = p.js.Publish(ctx, subject, data, opts...)
 	if err != nil {
 		p.metrics.IncPublishFailure(task.Tenant, task.Collection, err.Error())
 		return err
@@ -100,6 +107,15 @@ func (p *natsPublisher) Publish(ctx context.Context, task *types.DeliveryTask) e
 	return nil
 }
 
+// msgID returns the Nats-Msg-Id the stream deduplicates task by. A replay
+// of a dead letter is a new message, so it gets an ID of its own.
+func msgID(task *types.DeliveryTask) string {
+	if task.ID == "" || task.Replay == 0 {
+		return task.ID
+	}
+	return fmt.Sprintf("%s-replay-%d", task.ID, task.Replay)
+}
+
 // Close releases resources held by the publisher.
 // The publisher does not own the NATS connection, so this is a no-op.
 func (p *natsPublisher) Close() error {
@@ -24,6 +24,12 @@ func (m *MockJetStreamCoverage) CreateOrUpdateStream(ctx context.Context, cfg je
 	return args.Get(0).(jetstream.Stream), args.Error(1)
 }
 
+// Stream finds no streams: neither an old task stream to migrate nor an
+// interrupted migration.
+func (m *MockJetStreamCoverage) Stream(ctx context.Context, name string) (jetstream.Stream, error) {
+	return nil, jetstream.ErrStreamNotFound
+}
+
 func TestNewTaskPublisher_Coverage(t *testing.T) {
 	// Save original jetStreamNew and restore after test
 	originalJetStreamNew := jetStreamNew
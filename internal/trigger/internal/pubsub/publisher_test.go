@@ -50,6 +50,26 @@ func TestNatsPublisher_Publish(t *testing.T) {
 
 	mockJS.AssertExpectations(t)
 }
+func TestMsgID(t *testing.T) {
+	assert.Empty(t, msgID(&trigger.DeliveryTask{}))
+	assert.Equal(t, "abc", msgID(&trigger.DeliveryTask{ID: "abc"}))
+	assert.Equal(t, "abc-replay-7", msgID(&trigger.DeliveryTask{ID: "abc", Replay: 7}))
+}
+
+func TestNatsPublisher_Publish_DedupID(t *testing.T) {
+	mockJS := new(MockJetStream)
+	publisher := NewTaskPublisherFromJS(mockJS, "TRIGGERS", nil)
+
+	// The expected stream, the retries and the message ID.
+	mockJS.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(opts []jetstream.PublishOpt) bool {
+		return len(opts) == 3
+	})).Return(&jetstream.PubAck{Duplicate: true}, nil)
+
+	err := publisher.Publish(context.Background(), &trigger.DeliveryTask{ID: "abc", Tenant: "acme", Collection: "users", DocumentID: "u1"})
+	assert.NoError(t, err)
+	mockJS.AssertExpectations(t)
+}
+
 func TestNatsPublisher_Publish_HashedSubject(t *testing.T) {
 	mockJS := new(MockJetStream)
 	publisher := NewTaskPublisherFromJS(mockJS, "TRIGGERS", nil)
@@ -124,6 +144,12 @@ func (m *MockJetStream) CreateOrUpdateStream(ctx context.Context, cfg jetstream.
 	return args.Get(0).(jetstream.Stream), args.Error(1)
 }
 
+// Stream finds no streams: neither an old task stream to migrate nor an
+// interrupted migration.
+func (m *MockJetStream) Stream(ctx context.Context, name string) (jetstream.Stream, error) {
+	return nil, jetstream.ErrStreamNotFound
+}
+
 func (m *MockJetStream) CreateOrUpdateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (jetstream.Consumer, error) {
 	args := m.Called(ctx, stream, cfg)
 	if args.Get(0) == nil {
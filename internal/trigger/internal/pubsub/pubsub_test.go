@@ -2,6 +2,7 @@ package pubsub
 
 import (
 	"testing"
+	"time"
 
 	"github.com/codetrek/syntrix/internal/trigger/types"
 	"github.com/nats-io/nats.go"
@@ -59,10 +60,15 @@ func TestEnsureStream(t *testing.T) {
 
 	// Expect CreateOrUpdateStream to be called
 	mockJS.On("CreateOrUpdateStream", mock.Anything, mock.MatchedBy(func(cfg jetstream.StreamConfig) bool {
-		return cfg.Name == "TRIGGERS" && len(cfg.Subjects) > 0 && cfg.Subjects[0] == "TRIGGERS.>"
+		return cfg.Name == "TRIGGERS" && len(cfg.Subjects) > 0 && cfg.Subjects[0] == "TRIGGERS.>" &&
+			cfg.Storage == jetstream.FileStorage && cfg.MaxAge == time.Hour && cfg.Discard == jetstream.DiscardNew && cfg.Replicas == 1
 	})).Return(nil, nil)
 
-	err := EnsureStream(mockJS, "TRIGGERS")
+	err := EnsureStream(mockJS, "TRIGGERS", types.StreamOptions{
+		Storage: types.StreamStorageFile,
+		MaxAge:  time.Hour,
+		Discard: types.StreamDiscardNew,
+	})
 	assert.NoError(t, err)
 	mockJS.AssertExpectations(t)
 }
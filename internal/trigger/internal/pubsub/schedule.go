@@ -0,0 +1,279 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	// leaderTTL is how long a scheduler leads without renewing. A leader
+	// that dies is replaced within this time.
+	leaderTTL = 15 * time.Second
+
+	// leaderPrefix keys the instance ID of the leading scheduler of each
+	// tenant, under scheduler.<tenant>.
+	leaderPrefix = "scheduler."
+
+	delayedPrefix = "delayed."
+	lastRunPrefix = "cron."
+)
+
+// ScheduleBucketName returns the name of the key-value bucket holding the
+// scheduler state of the task stream streamName.
+func ScheduleBucketName(streamName string) string {
+	if streamName == "" {
+		streamName = "TRIGGERS"
+	}
+	return streamName + "_SCHEDULE"
+}
+
+// LeaderBucketName returns the name of the key-value bucket the schedulers
+// of the task stream streamName elect their leader in.
+func LeaderBucketName(streamName string) string {
+	if streamName == "" {
+		streamName = "TRIGGERS"
+	}
+	return streamName + "_LEADER"
+}
+
+// DelayedTask is a task held back until Due.
+type DelayedTask struct {
+	Task *types.DeliveryTask
+	Due  time.Time
+	key  string
+}
+
+// ScheduleStore keeps the state of the trigger scheduler: tasks waiting out
+// the delay of their trigger and the last run of each schedule.
+type ScheduleStore interface {
+	// AddDelayed keeps task until due. A task added twice is kept once.
+	AddDelayed(ctx context.Context, task *types.DeliveryTask, due time.Time) error
+
+	// DueDelayed returns the tasks of tenant due at now, earliest first.
+	DueDelayed(ctx context.Context, tenant string, now time.Time) ([]*DelayedTask, error)
+
+	// RemoveDelayed drops a task once it is published.
+	RemoveDelayed(ctx context.Context, d *DelayedTask) error
+
+	// LastRun returns when the schedule of a trigger last fired, zero if
+	// never, and the revision to pass to SetLastRun.
+	LastRun(ctx context.Context, tenant, triggerID string) (time.Time, uint64, error)
+
+	// SetLastRun records a run if the record is still at revision, else
+	// returns ErrKVConflict.
+	SetLastRun(ctx context.Context, tenant, triggerID string, at time.Time, revision uint64) error
+}
+
+// LeaderElector elects one scheduler among the trigger service instances.
+type LeaderElector interface {
+	// Campaign takes or keeps the leadership and reports whether this
+	// instance holds it. Call it more often than every leaderTTL/3.
+	Campaign(ctx context.Context) (bool, error)
+
+	// Resign gives up the leadership if held.
+	Resign(ctx context.Context) error
+}
+
+// kvScheduleStore implements ScheduleStore on a key-value bucket. Delayed
+// tasks are kept under delayed.<tenant>.<due unix ms>.<task id>, so due tasks
+// of a tenant are found from their keys; runs under cron.<tenant>.<triggerId>.
+type kvScheduleStore struct {
+	bucket KVBucket
+}
+
+// NewScheduleStore creates a ScheduleStore for the task stream streamName,
+// creating its bucket if needed. The bucket uses the storage and replicas of
+// the stream, so delayed tasks are as durable as published ones.
+func NewScheduleStore(nc *nats.Conn, streamName string, opts types.StreamOptions) (ScheduleStore, error) {
+	cfg := jetstream.KeyValueConfig{
+		Bucket:   ScheduleBucketName(streamName),
+		History:  1,
+		Storage:  jetstream.MemoryStorage,
+		Replicas: opts.Replicas,
+	}
+	if opts.Storage == types.StreamStorageFile {
+		cfg.Storage = jetstream.FileStorage
+	}
+	bucket, err := ensureBucket(nc, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewScheduleStoreFromBucket(bucket), nil
+}
+
+// NewScheduleStoreFromBucket creates a ScheduleStore on an existing bucket.
+func NewScheduleStoreFromBucket(bucket KVBucket) ScheduleStore {
+	return &kvScheduleStore{bucket: bucket}
+}
+
+func (s *kvScheduleStore) AddDelayed(ctx context.Context, task *types.DeliveryTask, due time.Time) error {
+	if task.ID == "" || task.Tenant == "" {
+		return errors.New("delayed task has no ID or tenant")
+	}
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	key := fmt.Sprintf("%s%s.%d.%s", delayedPrefix, task.Tenant, due.UnixMilli(), task.ID)
+	if _, err := s.bucket.Put(ctx, key, data, 0); err != nil && !errors.Is(err, ErrKVConflict) {
+		return err
+	}
+	return nil
+}
+
+func (s *kvScheduleStore) DueDelayed(ctx context.Context, tenant string, now time.Time) ([]*DelayedTask, error) {
+	prefix := delayedPrefix + tenant + "."
+	keys, err := s.bucket.Keys(ctx, prefix+">")
+	if err != nil {
+		return nil, err
+	}
+
+	var due []*DelayedTask
+	for _, key := range keys {
+		parts := strings.SplitN(strings.TrimPrefix(key, prefix), ".", 2)
+		ms, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || len(parts) != 2 {
+			log.Printf("[Warning] Ignoring malformed delayed task key %s", key)
+			continue
+		}
+		at := time.UnixMilli(ms)
+		if at.After(now) {
+			continue
+		}
+
+		data, _, err := s.bucket.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue // published by another scheduler meanwhile
+		}
+		var task types.DeliveryTask
+		if err := json.Unmarshal(data, &task); err != nil {
+			log.Printf("[Error] Dropping undecodable delayed task %s: %v", key, err)
+			_ = s.bucket.Delete(ctx, key, 0)
+			continue
+		}
+		due = append(due, &DelayedTask{Task: &task, Due: at, key: key})
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Due.Before(due[j].Due) })
+	return due, nil
+}
+
+func (s *kvScheduleStore) RemoveDelayed(ctx context.Context, d *DelayedTask) error {
+	return s.bucket.Delete(ctx, d.key, 0)
+}
+
+func (s *kvScheduleStore) LastRun(ctx context.Context, tenant, triggerID string) (time.Time, uint64, error) {
+	data, rev, err := s.bucket.Get(ctx, lastRunKey(tenant, triggerID))
+	if err != nil || data == nil {
+		return time.Time{}, rev, err
+	}
+	ms, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return time.Time{}, rev, fmt.Errorf("invalid last run of trigger %s: %w", triggerID, err)
+	}
+	return time.UnixMilli(ms).UTC(), rev, nil
+}
+
+func (s *kvScheduleStore) SetLastRun(ctx context.Context, tenant, triggerID string, at time.Time, revision uint64) error {
+	_, err := s.bucket.Put(ctx, lastRunKey(tenant, triggerID), []byte(strconv.FormatInt(at.UnixMilli(), 10)), revision)
+	return err
+}
+
+// lastRunKey returns the key of the last run of a trigger. Tenants and
+// trigger IDs are restricted to characters valid in keys.
+func lastRunKey(tenant, triggerID string) string {
+	return lastRunPrefix + tenant + "." + triggerID
+}
+
+// kvLeaderElector implements LeaderElector with a key in a bucket whose
+// entries expire after leaderTTL. The leader owns the key and renews it;
+// the others take it once it expires.
+type kvLeaderElector struct {
+	bucket   KVBucket
+	key      string
+	instance string
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	revision uint64 // of the leader key while leading, else 0
+	renewed  time.Time
+}
+
+// NewLeaderElector creates a LeaderElector for the schedulers of tenant on
+// the task stream streamName, creating its bucket if needed.
+func NewLeaderElector(nc *nats.Conn, streamName, tenant string) (LeaderElector, error) {
+	bucket, err := ensureBucket(nc, jetstream.KeyValueConfig{
+		Bucket:  LeaderBucketName(streamName),
+		History: 1,
+		TTL:     leaderTTL,
+		Storage: jetstream.MemoryStorage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newKVLeaderElector(bucket, tenant, leaderTTL), nil
+}
+
+func newKVLeaderElector(bucket KVBucket, tenant string, ttl time.Duration) *kvLeaderElector {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &kvLeaderElector{bucket: bucket, key: leaderPrefix + tenant, instance: hex.EncodeToString(id), ttl: ttl, now: time.Now}
+}
+
+func (l *kvLeaderElector) Campaign(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if l.revision != 0 && now.Sub(l.renewed) < l.ttl/3 {
+		return true, nil
+	}
+
+	leading := l.revision != 0
+	rev, err := l.bucket.Put(ctx, l.key, []byte(l.instance), l.revision)
+	if err != nil {
+		// A leader that cannot renew steps down before its key expires.
+		l.revision = 0
+		if leading {
+			log.Printf("[Warning] Trigger scheduler %s lost leadership: %v", l.instance, err)
+		}
+		if errors.Is(err, ErrKVConflict) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !leading {
+		log.Printf("[Info] Trigger scheduler %s is now the leader", l.instance)
+	}
+	l.revision, l.renewed = rev, now
+	return true, nil
+}
+
+func (l *kvLeaderElector) Resign(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.revision == 0 {
+		return nil
+	}
+	rev := l.revision
+	l.revision = 0
+	return l.bucket.Delete(ctx, l.key, rev)
+}
@@ -0,0 +1,162 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVScheduleStore_Delayed(t *testing.T) {
+	bucket := NewMemoryBucket()
+	store := &kvScheduleStore{bucket: bucket}
+	ctx := context.Background()
+	now := time.UnixMilli(1_700_000_000_000)
+
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "b", Tenant: "acme", TriggerID: "t1"}, now.Add(-time.Second)))
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "a", Tenant: "acme", TriggerID: "t1"}, now.Add(-time.Minute)))
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "c", Tenant: "acme", TriggerID: "t1"}, now.Add(time.Minute)))
+	// Adding a task again keeps it once.
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "a", Tenant: "acme", TriggerID: "t1"}, now.Add(-time.Minute)))
+	assert.Error(t, store.AddDelayed(ctx, &types.DeliveryTask{}, now))
+
+	due, err := store.DueDelayed(ctx, "acme", now)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "a", due[0].Task.ID)
+	assert.Equal(t, "b", due[1].Task.ID)
+	assert.Equal(t, now.Add(-time.Minute), due[0].Due)
+
+	require.NoError(t, store.RemoveDelayed(ctx, due[0]))
+	due, err = store.DueDelayed(ctx, "acme", now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "b", due[0].Task.ID)
+	assert.Equal(t, "c", due[1].Task.ID)
+}
+
+func TestKVScheduleStore_Delayed_Tenants(t *testing.T) {
+	store := &kvScheduleStore{bucket: NewMemoryBucket()}
+	ctx := context.Background()
+	now := time.UnixMilli(1_700_000_000_000)
+
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "a", Tenant: "acme", TriggerID: "t1"}, now))
+	require.NoError(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "b", Tenant: "globex", TriggerID: "t1"}, now))
+	assert.Error(t, store.AddDelayed(ctx, &types.DeliveryTask{ID: "c", TriggerID: "t1"}, now), "no tenant")
+
+	due, err := store.DueDelayed(ctx, "acme", now)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, "a", due[0].Task.ID)
+}
+
+func TestKVScheduleStore_DueDelayed_Malformed(t *testing.T) {
+	bucket := NewMemoryBucket()
+	store := &kvScheduleStore{bucket: bucket}
+	ctx := context.Background()
+	_, _ = bucket.Put(ctx, "delayed.acme.x.y", []byte("{}"), 0)
+	_, _ = bucket.Put(ctx, "delayed.acme.1.bad", []byte("not json"), 0)
+
+	due, err := store.DueDelayed(ctx, "acme", time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	assert.NotContains(t, bucket.entries, "delayed.acme.1.bad", "undecodable tasks are dropped")
+
+	bucket.err = errors.New("bucket down")
+	_, err = store.DueDelayed(ctx, "acme", time.Now())
+	assert.Error(t, err)
+}
+
+func TestKVScheduleStore_LastRun(t *testing.T) {
+	store := &kvScheduleStore{bucket: NewMemoryBucket()}
+	ctx := context.Background()
+
+	last, rev, err := store.LastRun(ctx, "acme", "nightly")
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+
+	at := time.Date(2026, 3, 14, 3, 0, 0, 0, time.UTC)
+	require.NoError(t, store.SetLastRun(ctx, "acme", "nightly", at, rev))
+	assert.ErrorIs(t, store.SetLastRun(ctx, "acme", "nightly", at, rev), ErrKVConflict)
+
+	last, _, err = store.LastRun(ctx, "acme", "nightly")
+	require.NoError(t, err)
+	assert.Equal(t, at, last)
+}
+
+func TestKVLeaderElector(t *testing.T) {
+	bucket := NewMemoryBucket()
+	ctx := context.Background()
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	a := newKVLeaderElector(bucket, "acme", 15*time.Second)
+	b := newKVLeaderElector(bucket, "acme", 15*time.Second)
+	a.now, b.now = clock, clock
+
+	leading, err := a.Campaign(ctx)
+	require.NoError(t, err)
+	assert.True(t, leading)
+	leading, err = b.Campaign(ctx)
+	require.NoError(t, err)
+	assert.False(t, leading)
+
+	// The leader renews its key after a third of the TTL.
+	rev := a.revision
+	now = now.Add(6 * time.Second)
+	leading, _ = a.Campaign(ctx)
+	assert.True(t, leading)
+	assert.NotEqual(t, rev, a.revision)
+
+	// Once the key expires, another instance takes over and the old
+	// leader steps down.
+	delete(bucket.entries, "scheduler.acme")
+	leading, _ = b.Campaign(ctx)
+	assert.True(t, leading)
+	now = now.Add(6 * time.Second)
+	leading, _ = a.Campaign(ctx)
+	assert.False(t, leading)
+
+	// Resigning frees the key at once.
+	require.NoError(t, b.Resign(ctx))
+	require.NoError(t, b.Resign(ctx))
+	leading, _ = a.Campaign(ctx)
+	assert.True(t, leading)
+}
+
+func TestKVLeaderElector_Tenants(t *testing.T) {
+	bucket := NewMemoryBucket()
+	ctx := context.Background()
+
+	// Each tenant elects its own leader.
+	leading, err := newKVLeaderElector(bucket, "acme", 15*time.Second).Campaign(ctx)
+	require.NoError(t, err)
+	assert.True(t, leading)
+	leading, err = newKVLeaderElector(bucket, "globex", 15*time.Second).Campaign(ctx)
+	require.NoError(t, err)
+	assert.True(t, leading)
+	leading, err = newKVLeaderElector(bucket, "acme", 15*time.Second).Campaign(ctx)
+	require.NoError(t, err)
+	assert.False(t, leading)
+}
+
+func TestKVLeaderElector_Error(t *testing.T) {
+	bucket := NewMemoryBucket()
+	l := newKVLeaderElector(bucket, "acme", 15*time.Second)
+	bucket.err = errors.New("bucket down")
+
+	leading, err := l.Campaign(context.Background())
+	assert.Error(t, err)
+	assert.False(t, leading)
+}
+
+func TestScheduleBucketNames(t *testing.T) {
+	assert.Equal(t, "TRIGGERS_SCHEDULE", ScheduleBucketName(""))
+	assert.Equal(t, "JOBS_SCHEDULE", ScheduleBucketName("JOBS"))
+	assert.Equal(t, "TRIGGERS_LEADER", LeaderBucketName(""))
+	assert.Equal(t, "JOBS_LEADER", LeaderBucketName("JOBS"))
+}
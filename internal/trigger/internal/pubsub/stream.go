@@ -0,0 +1,269 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// ConsumerName is the durable consumer delivering the task stream.
+const ConsumerName = "TriggerDeliveryWorker"
+
+const (
+	// streamSetupTimeout bounds creating or updating the task stream.
+	streamSetupTimeout = 10 * time.Second
+
+	// migrateTimeout bounds moving the tasks of the stream to new storage.
+	migrateTimeout = 5 * time.Minute
+
+	// migratePollInterval is how often a migration checks its copy.
+	migratePollInterval = 100 * time.Millisecond
+
+	// migrateExpectedKey is the stream metadata key holding the number of
+	// tasks a migration copies.
+	migrateExpectedKey = "syntrix.migrate.expected"
+)
+
+// MigrationStreamName returns the name of the stream holding the tasks of
+// streamName while it is recreated.
+func MigrationStreamName(streamName string) string {
+	return streamName + "_MIGRATE"
+}
+
+// StreamConfig returns the JetStream configuration of the task stream
+// streamName.
+func StreamConfig(streamName string, opts types.StreamOptions) jetstream.StreamConfig {
+	if streamName == "" {
+		streamName = "TRIGGERS"
+	}
+	cfg := jetstream.StreamConfig{
+		Name:       streamName,
+		Subjects:   []string{fmt.Sprintf("%s.>", streamName)},
+		Storage:    jetstream.MemoryStorage,
+		MaxAge:     opts.MaxAge,
+		MaxBytes:   opts.MaxBytes,
+		MaxMsgs:    opts.MaxMsgs,
+		Discard:    jetstream.DiscardOld,
+		Replicas:   opts.Replicas,
+		Duplicates: opts.DuplicateWindow,
+	}
+	if opts.Storage == types.StreamStorageFile {
+		cfg.Storage = jetstream.FileStorage
+	}
+	if opts.Discard == types.StreamDiscardNew {
+		cfg.Discard = jetstream.DiscardNew
+	}
+	if cfg.Replicas <= 0 {
+		cfg.Replicas = 1
+	}
+	return cfg
+}
+
+// EnsureStream creates the task stream streamName with opts, or brings an
+// existing one to them. Limits, discard policy and replicas are updated in
+// place. JetStream cannot change the storage of a stream, so a stream on
+// other storage is migrated: its undelivered tasks are moved to a new one.
+func EnsureStream(js jetstream.JetStream, streamName string, opts types.StreamOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), streamSetupTimeout)
+	defer cancel()
+	return ensureStream(ctx, js, StreamConfig(streamName, opts))
+}
+
+func ensureStream(ctx context.Context, js jetstream.JetStream, cfg jetstream.StreamConfig) error {
+	_, updateErr := js.CreateOrUpdateStream(ctx, cfg)
+	if updateErr != nil {
+		stream, err := js.Stream(ctx, cfg.Name)
+		if err != nil {
+			return updateErr
+		}
+		current := stream.CachedInfo().Config
+		if current.Storage == cfg.Storage && !current.Sealed {
+			return updateErr
+		}
+	} else if _, err := js.Stream(ctx, MigrationStreamName(cfg.Name)); errors.Is(err, jetstream.ErrStreamNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// A migration is needed, or one was interrupted.
+	migrateCtx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+	if err := migrateStream(migrateCtx, js, cfg); err != nil {
+		return fmt.Errorf("failed to migrate stream %s: %w", cfg.Name, err)
+	}
+	return nil
+}
+
+// migrateStream moves the tasks of the stream cfg.Name to a stream with cfg.
+// Each step can be repeated, so a migration interrupted by a restart
+// resumes where it stopped:
+//
+//  1. Seal the old stream, so no task is accepted and then lost, and copy
+//     its undelivered tasks into the migration stream.
+//  2. Delete the old stream and create it anew from cfg, sourcing the
+//     migration stream.
+//  3. Drop the source once copied, and delete the migration stream.
+//
+// Publishers fail while the stream is sealed or missing. Tasks already
+// delivered are not copied; the consumer is recreated on the new stream.
+func migrateStream(ctx context.Context, js jetstream.JetStream, cfg jetstream.StreamConfig) error {
+	tmpName := MigrationStreamName(cfg.Name)
+	log.Printf("[Warning] Migrating stream %s to %s storage", cfg.Name, cfg.Storage)
+
+	tmp, err := js.Stream(ctx, tmpName)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		if tmp, err = copyPending(ctx, js, cfg, tmpName); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	expected, _ := strconv.ParseUint(tmp.CachedInfo().Config.Metadata[migrateExpectedKey], 10, 64)
+	sourced := cfg
+	sourced.Sources = []*jetstream.StreamSource{{Name: tmpName}}
+
+	stream, err := js.Stream(ctx, cfg.Name)
+	switch {
+	case errors.Is(err, jetstream.ErrStreamNotFound):
+		if err := waitForCopy(ctx, tmp, expected); err != nil {
+			return err
+		}
+		if stream, err = js.CreateStream(ctx, sourced); err != nil {
+			return fmt.Errorf("failed to create stream: %w", err)
+		}
+	case err != nil:
+		return err
+	case stream.CachedInfo().Config.Storage != cfg.Storage:
+		if err := waitForCopy(ctx, tmp, expected); err != nil {
+			return err
+		}
+		if err := js.DeleteStream(ctx, cfg.Name); err != nil {
+			return fmt.Errorf("failed to delete old stream: %w", err)
+		}
+		if stream, err = js.CreateStream(ctx, sourced); err != nil {
+			return fmt.Errorf("failed to create stream: %w", err)
+		}
+	default:
+		// Created before an interruption; make sure it sources the tasks.
+		// Its message count tells nothing about the copy: it may hold tasks
+		// published or copied before, minus those already acknowledged.
+		if stream, err = js.UpdateStream(ctx, sourced); err != nil {
+			return fmt.Errorf("failed to source migrated tasks: %w", err)
+		}
+	}
+
+	if err := waitForSource(ctx, stream, tmpName, expected); err != nil {
+		return err
+	}
+	if _, err := js.UpdateStream(ctx, cfg); err != nil {
+		return fmt.Errorf("failed to finish stream: %w", err)
+	}
+	if err := js.DeleteStream(ctx, tmpName); err != nil && !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return fmt.Errorf("failed to delete migration stream: %w", err)
+	}
+	log.Printf("[Info] Migrated stream %s with %d undelivered tasks", cfg.Name, expected)
+	return nil
+}
+
+// copyPending seals the old stream and creates the migration stream tmpName
+// on the new storage, sourcing the tasks the consumer has not acknowledged.
+func copyPending(ctx context.Context, js jetstream.JetStream, cfg jetstream.StreamConfig, tmpName string) (jetstream.Stream, error) {
+	old, err := js.Stream(ctx, cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+	info := old.CachedInfo()
+	if !info.Config.Sealed {
+		sealed := info.Config
+		sealed.Sealed = true
+		if _, err := js.UpdateStream(ctx, sealed); err != nil {
+			return nil, fmt.Errorf("failed to seal old stream: %w", err)
+		}
+	}
+
+	// Sealed, the stream no longer changes and the count below holds.
+	if info, err = old.Info(ctx); err != nil {
+		return nil, err
+	}
+	start, expected := info.State.FirstSeq, info.State.Msgs
+	if consumer, err := js.Consumer(ctx, cfg.Name, ConsumerName); err == nil {
+		ci := consumer.CachedInfo()
+		start = ci.AckFloor.Stream + 1
+		expected = ci.NumPending + uint64(ci.NumAckPending)
+	} else if !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return nil, err
+	}
+
+	tmp, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     tmpName,
+		Storage:  cfg.Storage,
+		Replicas: cfg.Replicas,
+		Sources:  []*jetstream.StreamSource{{Name: cfg.Name, OptStartSeq: start}},
+		Metadata: map[string]string{migrateExpectedKey: strconv.FormatUint(expected, 10)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration stream: %w", err)
+	}
+	return tmp, nil
+}
+
+// waitForCopy waits until stream holds at least expected messages from its
+// source.
+func waitForCopy(ctx context.Context, stream jetstream.Stream, expected uint64) error {
+	ticker := time.NewTicker(migratePollInterval)
+	defer ticker.Stop()
+	for {
+		info, err := stream.Info(ctx)
+		if err != nil {
+			return err
+		}
+		if info.State.Msgs >= expected {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("copied %d of %d tasks to %s: %w", info.State.Msgs, expected, info.Config.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitForSource waits until stream has copied all expected messages of its
+// source name. JetStream reports the source caught up with a lag of 0 once it
+// has copied a message; the number of messages in stream is no measure, as
+// the consumer may remove tasks meanwhile.
+func waitForSource(ctx context.Context, stream jetstream.Stream, name string, expected uint64) error {
+	if expected == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(migratePollInterval)
+	defer ticker.Stop()
+	for {
+		info, err := stream.Info(ctx)
+		if err != nil {
+			return err
+		}
+		lag := expected
+		for _, src := range info.Sources {
+			if src.Name == name && src.Active >= 0 {
+				lag = src.Lag
+			}
+		}
+		if lag == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d of %d tasks from %s not yet in %s: %w", lag, expected, name, info.Config.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
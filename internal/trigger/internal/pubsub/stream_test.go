@@ -0,0 +1,312 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJetStream keeps streams in memory. Like JetStream it refuses to change
+// the storage of a stream and to update a sealed one, and streams copy their
+// sources when asked for their info, unless stalled.
+type fakeJetStream struct {
+	jetstream.JetStream
+	streams   map[string]*fakeStream
+	consumers map[string]*jetstream.ConsumerInfo
+}
+
+func newFakeJetStream() *fakeJetStream {
+	return &fakeJetStream{streams: map[string]*fakeStream{}, consumers: map[string]*jetstream.ConsumerInfo{}}
+}
+
+// addStream creates a stream holding messages first..last.
+func (js *fakeJetStream) addStream(cfg jetstream.StreamConfig, first, last uint64) *fakeStream {
+	s := &fakeStream{js: js, cfg: cfg, copied: map[string]uint64{}}
+	for seq := first; seq <= last && last > 0; seq++ {
+		s.seqs = append(s.seqs, seq)
+	}
+	s.last = last
+	js.streams[cfg.Name] = s
+	return s
+}
+
+func (js *fakeJetStream) Stream(ctx context.Context, name string) (jetstream.Stream, error) {
+	s, ok := js.streams[name]
+	if !ok {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	return s, nil
+}
+
+func (js *fakeJetStream) CreateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	if _, ok := js.streams[cfg.Name]; ok {
+		return nil, jetstream.ErrStreamNameAlreadyInUse
+	}
+	return js.addStream(cfg, 0, 0), nil
+}
+
+func (js *fakeJetStream) UpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	s, ok := js.streams[cfg.Name]
+	switch {
+	case !ok:
+		return nil, jetstream.ErrStreamNotFound
+	case s.cfg.Storage != cfg.Storage:
+		return nil, errors.New("stream configuration update can not change storage type")
+	case s.cfg.Sealed:
+		return nil, errors.New("stream configuration for sealed stream can not be updated")
+	}
+	s.cfg = cfg
+	return s, nil
+}
+
+func (js *fakeJetStream) CreateOrUpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	if _, ok := js.streams[cfg.Name]; !ok {
+		return js.CreateStream(ctx, cfg)
+	}
+	return js.UpdateStream(ctx, cfg)
+}
+
+func (js *fakeJetStream) DeleteStream(ctx context.Context, name string) error {
+	if _, ok := js.streams[name]; !ok {
+		return jetstream.ErrStreamNotFound
+	}
+	delete(js.streams, name)
+	delete(js.consumers, name)
+	return nil
+}
+
+func (js *fakeJetStream) Consumer(ctx context.Context, stream, name string) (jetstream.Consumer, error) {
+	info, ok := js.consumers[stream]
+	if !ok || name != ConsumerName {
+		return nil, jetstream.ErrConsumerNotFound
+	}
+	return &fakeConsumer{info: info}, nil
+}
+
+type fakeStream struct {
+	jetstream.Stream
+	js     *fakeJetStream
+	cfg    jetstream.StreamConfig
+	seqs   []uint64
+	last   uint64
+	copied map[string]uint64 // last sequence copied per source
+	stall  bool
+}
+
+func (s *fakeStream) sync() {
+	if s.stall {
+		return
+	}
+	for _, src := range s.cfg.Sources {
+		from, ok := s.js.streams[src.Name]
+		if !ok {
+			continue
+		}
+		for _, seq := range from.seqs {
+			if seq >= src.OptStartSeq && seq > s.copied[src.Name] {
+				s.last++
+				s.seqs = append(s.seqs, s.last)
+				s.copied[src.Name] = seq
+			}
+		}
+	}
+}
+
+func (s *fakeStream) CachedInfo() *jetstream.StreamInfo {
+	info := &jetstream.StreamInfo{Config: s.cfg}
+	info.State.Msgs = uint64(len(s.seqs))
+	info.State.LastSeq = s.last
+	if len(s.seqs) > 0 {
+		info.State.FirstSeq = s.seqs[0]
+	}
+	for _, src := range s.cfg.Sources {
+		si := &jetstream.StreamSourceInfo{Name: src.Name, Active: -1}
+		if from, ok := s.js.streams[src.Name]; ok {
+			for _, seq := range from.seqs {
+				if seq >= src.OptStartSeq && seq > s.copied[src.Name] {
+					si.Lag++
+				}
+			}
+		}
+		if _, ok := s.copied[src.Name]; ok {
+			si.Active = 0
+		}
+		info.Sources = append(info.Sources, si)
+	}
+	return info
+}
+
+func (s *fakeStream) Info(ctx context.Context, opts ...jetstream.StreamInfoOpt) (*jetstream.StreamInfo, error) {
+	s.sync()
+	return s.CachedInfo(), nil
+}
+
+type fakeConsumer struct {
+	jetstream.Consumer
+	info *jetstream.ConsumerInfo
+}
+
+func (c *fakeConsumer) CachedInfo() *jetstream.ConsumerInfo {
+	return c.info
+}
+
+func TestStreamConfig(t *testing.T) {
+	cfg := StreamConfig("", types.StreamOptions{})
+	assert.Equal(t, "TRIGGERS", cfg.Name)
+	assert.Equal(t, []string{"TRIGGERS.>"}, cfg.Subjects)
+	assert.Equal(t, jetstream.MemoryStorage, cfg.Storage)
+	assert.Equal(t, jetstream.DiscardOld, cfg.Discard)
+	assert.Equal(t, 1, cfg.Replicas)
+
+	cfg = StreamConfig("JOBS", types.StreamOptions{
+		Storage:  types.StreamStorageFile,
+		MaxAge:   time.Hour,
+		MaxBytes: 1 << 20,
+		MaxMsgs:  1000,
+		Discard:  types.StreamDiscardNew,
+		Replicas: 3,
+	})
+	assert.Equal(t, jetstream.FileStorage, cfg.Storage)
+	assert.Equal(t, time.Hour, cfg.MaxAge)
+	assert.Equal(t, int64(1<<20), cfg.MaxBytes)
+	assert.Equal(t, int64(1000), cfg.MaxMsgs)
+	assert.Equal(t, jetstream.DiscardNew, cfg.Discard)
+	assert.Equal(t, 3, cfg.Replicas)
+}
+
+func TestEnsureStream_UpdatesLimitsInPlace(t *testing.T) {
+	js := newFakeJetStream()
+	js.addStream(StreamConfig("TRIGGERS", types.StreamOptions{Storage: types.StreamStorageFile}), 1, 5)
+
+	require.NoError(t, EnsureStream(js, "TRIGGERS", types.StreamOptions{Storage: types.StreamStorageFile, MaxAge: time.Hour}))
+
+	s := js.streams["TRIGGERS"]
+	assert.Equal(t, time.Hour, s.cfg.MaxAge)
+	assert.Len(t, s.seqs, 5)
+	assert.NotContains(t, js.streams, "TRIGGERS_MIGRATE")
+}
+
+func TestEnsureStream_MigratesStorage(t *testing.T) {
+	js := newFakeJetStream()
+	js.addStream(StreamConfig("TRIGGERS", types.StreamOptions{}), 1, 10)
+	// Tasks up to 6 are acknowledged; 7 is being delivered, 8-10 wait.
+	js.consumers["TRIGGERS"] = &jetstream.ConsumerInfo{
+		AckFloor:      jetstream.SequenceInfo{Stream: 6},
+		NumAckPending: 1,
+		NumPending:    3,
+	}
+
+	opts := types.StreamOptions{Storage: types.StreamStorageFile, MaxMsgs: 100}
+	require.NoError(t, EnsureStream(js, "TRIGGERS", opts))
+
+	s := js.streams["TRIGGERS"]
+	assert.Equal(t, StreamConfig("TRIGGERS", opts), s.cfg, "no sources and no seal left")
+	assert.Len(t, s.seqs, 4, "only undelivered tasks are moved")
+	assert.NotContains(t, js.streams, "TRIGGERS_MIGRATE")
+	assert.NotContains(t, js.consumers, "TRIGGERS", "the consumer is recreated on start")
+}
+
+func TestEnsureStream_MigratesWithoutConsumer(t *testing.T) {
+	js := newFakeJetStream()
+	js.addStream(StreamConfig("JOBS", types.StreamOptions{Storage: types.StreamStorageFile}), 3, 7)
+
+	require.NoError(t, EnsureStream(js, "JOBS", types.StreamOptions{}))
+
+	s := js.streams["JOBS"]
+	assert.Equal(t, jetstream.MemoryStorage, s.cfg.Storage)
+	assert.Len(t, s.seqs, 5)
+}
+
+func TestEnsureStream_ResumesMigration(t *testing.T) {
+	// The old stream was deleted before the new one was created.
+	js := newFakeJetStream()
+	js.addStream(jetstream.StreamConfig{
+		Name:     "TRIGGERS_MIGRATE",
+		Storage:  jetstream.FileStorage,
+		Metadata: map[string]string{migrateExpectedKey: "4"},
+	}, 1, 4)
+
+	opts := types.StreamOptions{Storage: types.StreamStorageFile}
+	require.NoError(t, EnsureStream(js, "TRIGGERS", opts))
+
+	s := js.streams["TRIGGERS"]
+	assert.Equal(t, StreamConfig("TRIGGERS", opts), s.cfg)
+	assert.Len(t, s.seqs, 4)
+	assert.NotContains(t, js.streams, "TRIGGERS_MIGRATE")
+}
+
+func TestEnsureStream_ResumesCreatedStream(t *testing.T) {
+	// The new stream was created and had copied the tasks, and the consumer
+	// acknowledged one of them, before the migration stream was deleted.
+	js := newFakeJetStream()
+	tmp := js.addStream(jetstream.StreamConfig{
+		Name:     "TRIGGERS_MIGRATE",
+		Storage:  jetstream.FileStorage,
+		Metadata: map[string]string{migrateExpectedKey: "4"},
+	}, 1, 4)
+	opts := types.StreamOptions{Storage: types.StreamStorageFile}
+	sourced := StreamConfig("TRIGGERS", opts)
+	sourced.Sources = []*jetstream.StreamSource{{Name: tmp.cfg.Name}}
+	s := js.addStream(sourced, 0, 0)
+	s.sync()
+	s.seqs = s.seqs[1:]
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, ensureStream(ctx, js, StreamConfig("TRIGGERS", opts)))
+	assert.Equal(t, StreamConfig("TRIGGERS", opts), s.cfg)
+	assert.Len(t, s.seqs, 3)
+	assert.NotContains(t, js.streams, "TRIGGERS_MIGRATE")
+}
+
+func TestEnsureStream_ResumesSealedStream(t *testing.T) {
+	// The old stream was sealed before the migration stream was created.
+	js := newFakeJetStream()
+	old := StreamConfig("TRIGGERS", types.StreamOptions{})
+	old.Sealed = true
+	js.addStream(old, 1, 3)
+
+	require.NoError(t, EnsureStream(js, "TRIGGERS", types.StreamOptions{Storage: types.StreamStorageFile}))
+	assert.Len(t, js.streams["TRIGGERS"].seqs, 3)
+	assert.False(t, js.streams["TRIGGERS"].cfg.Sealed)
+}
+
+func TestEnsureStream_Error(t *testing.T) {
+	// No stream on other storage: the update error is returned as is.
+	mock := &MockJetStream{}
+	mock.On("CreateOrUpdateStream", context.Background(), StreamConfig("TRIGGERS", types.StreamOptions{})).
+		Return(nil, errors.New("replicas > 1 not supported in non-clustered mode"))
+	err := ensureStream(context.Background(), mock, StreamConfig("TRIGGERS", types.StreamOptions{}))
+	assert.ErrorContains(t, err, "replicas")
+}
+
+func TestWaitForSource_Timeout(t *testing.T) {
+	js := newFakeJetStream()
+	js.addStream(jetstream.StreamConfig{Name: "TRIGGERS_MIGRATE"}, 1, 3)
+	s := js.addStream(jetstream.StreamConfig{Name: "TRIGGERS", Sources: []*jetstream.StreamSource{{Name: "TRIGGERS_MIGRATE"}}}, 0, 0)
+	s.stall = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := waitForSource(ctx, s, "TRIGGERS_MIGRATE", 3)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "3 of 3 tasks from TRIGGERS_MIGRATE not yet in TRIGGERS")
+	assert.NoError(t, waitForSource(ctx, s, "TRIGGERS_MIGRATE", 0), "nothing to copy")
+}
+
+func TestWaitForCopy_Timeout(t *testing.T) {
+	js := newFakeJetStream()
+	s := js.addStream(jetstream.StreamConfig{Name: "TRIGGERS_MIGRATE"}, 1, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := waitForCopy(ctx, s, 3)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "copied 2 of 3 tasks")
+}
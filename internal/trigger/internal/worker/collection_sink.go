@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/codetrek/syntrix/pkg/model"
+)
+
+// CollectionSink writes tasks as documents into the collection of their
+// collection:// URL, in the tenant of the trigger: a materialized outbox
+// that clients query or watch like any collection. The document ID is the
+// task ID, so a retried task is written once.
+type CollectionSink struct {
+	store   storage.DocumentStore
+	timeout time.Duration
+	metrics types.Metrics
+}
+
+// NewCollectionSink creates a CollectionSink writing to store.
+func NewCollectionSink(store storage.DocumentStore, metrics types.Metrics) *CollectionSink {
+	if metrics == nil {
+		metrics = &types.NoopMetrics{}
+	}
+	return &CollectionSink{store: store, timeout: types.DefaultHTTPTimeout, metrics: metrics}
+}
+
+// ProcessTask creates the outbox document of the task. A document that
+// exists already is success.
+func (s *CollectionSink) ProcessTask(ctx context.Context, task *types.DeliveryTask) error {
+	start := time.Now()
+	u, err := url.Parse(task.URL)
+	if err != nil {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return &types.FatalError{Err: fmt.Errorf("invalid url: %w", err)}
+	}
+	if task.ID == "" {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return &types.FatalError{Err: errors.New("task has no ID to name its document")}
+	}
+
+	collection := types.URLTarget(u)
+	doc := storage.NewDocument(task.Tenant, collection+"/"+task.ID, collection, outboxData(task))
+
+	writeCtx, cancel := context.WithTimeout(ctx, taskTimeout(task, s.timeout))
+	defer cancel()
+	// The outbox write is itself a change triggers may watch.
+	writeCtx = storage.WithOrigin(writeCtx, &storage.Origin{TriggerID: task.TriggerID, TaskID: task.ID, Depth: task.Depth + 1})
+	if err := s.store.Create(writeCtx, task.Tenant, doc); err != nil && !errors.Is(err, model.ErrExists) {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, false)
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	s.metrics.IncDeliverySuccess(task.Tenant, task.Collection)
+	s.metrics.ObserveDeliveryLatency(task.Tenant, task.Collection, time.Since(start))
+	return nil
+}
+
+// outboxData is the document written for a task. Its keys stay clear of the
+// reserved fields of documents.
+func outboxData(task *types.DeliveryTask) map[string]interface{} {
+	data := map[string]interface{}{
+		"taskId":           task.ID,
+		"triggerId":        task.TriggerID,
+		"event":            task.Event,
+		"sourceCollection": task.Collection,
+		"documentId":       task.DocumentID,
+		"lsn":              task.LSN,
+		"seq":              task.Seq,
+		"payload":          task.Payload,
+	}
+	if task.Before != nil {
+		data["before"] = task.Before
+	}
+	if task.After != nil {
+		data["after"] = task.After
+	}
+	return data
+}
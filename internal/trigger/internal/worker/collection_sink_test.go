@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDocumentStore records created documents and refuses duplicates.
+type fakeDocumentStore struct {
+	storage.DocumentStore
+	docs map[string]*storage.Document
+	err  error
+}
+
+func (s *fakeDocumentStore) Create(ctx context.Context, tenant string, doc *storage.Document) error {
+	if s.err != nil {
+		return s.err
+	}
+	if _, ok := s.docs[doc.Fullpath]; ok {
+		return model.ErrExists
+	}
+	doc.Origin = storage.OriginFrom(ctx)
+	s.docs[doc.Fullpath] = doc
+	return nil
+}
+
+func TestCollectionSink_ProcessTask(t *testing.T) {
+	store := &fakeDocumentStore{docs: map[string]*storage.Document{}}
+	sink := NewCollectionSink(store, nil)
+
+	task := &types.DeliveryTask{
+		ID:         "task-1",
+		TriggerID:  "t1",
+		Tenant:     "acme",
+		Event:      "update",
+		Collection: "orders",
+		DocumentID: "o1",
+		URL:        "collection://rooms/r1/outbox",
+		Payload:    map[string]interface{}{"total": 3},
+		Before:     map[string]interface{}{"total": 2},
+	}
+	require.NoError(t, sink.ProcessTask(context.Background(), task))
+
+	doc := store.docs["rooms/r1/outbox/task-1"]
+	require.NotNil(t, doc)
+	assert.Equal(t, "acme", doc.TenantID)
+	assert.Equal(t, "rooms/r1/outbox", doc.Collection)
+	assert.Equal(t, "orders", doc.Data["sourceCollection"])
+	assert.Equal(t, "o1", doc.Data["documentId"])
+	assert.Equal(t, task.Before, doc.Data["before"])
+	assert.NotContains(t, doc.Data, "after")
+	assert.Equal(t, &storage.Origin{TriggerID: "t1", TaskID: "task-1", Depth: 1}, doc.Origin)
+
+	// A retry finds its document and succeeds.
+	assert.NoError(t, sink.ProcessTask(context.Background(), task))
+	assert.Len(t, store.docs, 1)
+}
+
+func TestCollectionSink_Errors(t *testing.T) {
+	sink := NewCollectionSink(&fakeDocumentStore{docs: map[string]*storage.Document{}}, nil)
+	err := sink.ProcessTask(context.Background(), &types.DeliveryTask{URL: "collection://outbox"})
+	assert.True(t, types.IsFatal(err), "no ID to name the document")
+
+	sink = NewCollectionSink(&fakeDocumentStore{err: errors.New("store down")}, nil)
+	err = sink.ProcessTask(context.Background(), &types.DeliveryTask{ID: "task-1", URL: "collection://outbox"})
+	assert.ErrorContains(t, err, "store down")
+	assert.False(t, types.IsFatal(err))
+}
@@ -0,0 +1,153 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	triggerv1 "github.com/codetrek/syntrix/api/trigger/v1"
+	"github.com/codetrek/syntrix/internal/identity"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fatalCodes are the status codes a retry cannot fix.
+var fatalCodes = map[codes.Code]bool{
+	codes.InvalidArgument:    true,
+	codes.NotFound:           true,
+	codes.PermissionDenied:   true,
+	codes.Unauthenticated:    true,
+	codes.FailedPrecondition: true,
+	codes.OutOfRange:         true,
+	codes.Unimplemented:      true,
+}
+
+// GRPCSink delivers tasks by calling TriggerSink.Deliver on the endpoint of
+// their grpc:// (plaintext) or grpcs:// (TLS) URL. Connections are kept per
+// endpoint until Close.
+type GRPCSink struct {
+	auth    identity.AuthN
+	secrets SecretProvider
+	timeout time.Duration
+	metrics types.Metrics
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewGRPCSink creates a GRPCSink.
+func NewGRPCSink(auth identity.AuthN, secrets SecretProvider, metrics types.Metrics) *GRPCSink {
+	if metrics == nil {
+		metrics = &types.NoopMetrics{}
+	}
+	return &GRPCSink{
+		auth:    auth,
+		secrets: secrets,
+		timeout: types.DefaultHTTPTimeout,
+		metrics: metrics,
+		conns:   map[string]*grpc.ClientConn{},
+	}
+}
+
+// ProcessTask calls the endpoint with the task. OK and ALREADY_EXISTS are
+// success; the codes in fatalCodes fail the task; anything else is retried.
+func (s *GRPCSink) ProcessTask(ctx context.Context, task *types.DeliveryTask) error {
+	start := time.Now()
+	u, err := url.Parse(task.URL)
+	if err != nil {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return &types.FatalError{Err: fmt.Errorf("invalid url: %w", err)}
+	}
+	conn, err := s.conn(u)
+	if err != nil {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return &types.FatalError{Err: err}
+	}
+
+	if err := issueToken(s.auth, task); err != nil {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, false)
+		return err
+	}
+	payload, err := json.Marshal(task)
+	if err != nil {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	signature, err := signTask(ctx, s.secrets, task, payload)
+	if err != nil {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, types.IsFatal(err))
+		return err
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, taskTimeout(task, s.timeout))
+	defer cancel()
+	if signature != "" {
+		callCtx = metadata.AppendToOutgoingContext(callCtx, "x-syntrix-signature", signature)
+	}
+
+	_, err = triggerv1.NewTriggerSinkClient(conn).Deliver(callCtx, &triggerv1.DeliverRequest{
+		Id:         task.ID,
+		TriggerId:  task.TriggerID,
+		Tenant:     task.Tenant,
+		Event:      task.Event,
+		Collection: task.Collection,
+		DocumentId: task.DocumentID,
+		Task:       payload,
+	})
+	if code := status.Code(err); code != codes.OK && code != codes.AlreadyExists {
+		fatal := fatalCodes[code]
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, fatal)
+		if fatal {
+			return &types.FatalError{Err: err}
+		}
+		return fmt.Errorf("call failed: %w", err)
+	}
+
+	s.metrics.IncDeliverySuccess(task.Tenant, task.Collection)
+	s.metrics.ObserveDeliveryLatency(task.Tenant, task.Collection, time.Since(start))
+	return nil
+}
+
+// conn returns the connection to the endpoint of u, creating it on first
+// use. Connections reconnect by themselves, so they are never replaced.
+func (s *GRPCSink) conn(u *url.URL) (*grpc.ClientConn, error) {
+	key := u.Scheme + "://" + u.Host
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conn, ok := s.conns[key]; ok {
+		return conn, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if u.Scheme == types.SchemeGRPCS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("invalid grpc target %s: %w", u.Host, err)
+	}
+	s.conns[key] = conn
+	return conn, nil
+}
+
+// Close closes all connections.
+func (s *GRPCSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for key, conn := range s.conns {
+		errs = append(errs, conn.Close())
+		delete(s.conns, key)
+	}
+	return errors.Join(errs...)
+}
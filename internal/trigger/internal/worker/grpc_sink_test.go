@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	triggerv1 "github.com/codetrek/syntrix/api/trigger/v1"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeTriggerSink struct {
+	triggerv1.UnimplementedTriggerSinkServer
+	requests   chan *triggerv1.DeliverRequest
+	signatures chan string
+	err        error
+}
+
+func (s *fakeTriggerSink) Deliver(ctx context.Context, req *triggerv1.DeliverRequest) (*triggerv1.DeliverResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.requests <- req
+	s.signatures <- first(md.Get("x-syntrix-signature"))
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &triggerv1.DeliverResponse{}, nil
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// serveTriggerSink runs srv and returns its grpc:// URL.
+func serveTriggerSink(t *testing.T, srv *fakeTriggerSink) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	triggerv1.RegisterTriggerSinkServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return "grpc://" + lis.Addr().String()
+}
+
+func newFakeTriggerSink(err error) *fakeTriggerSink {
+	return &fakeTriggerSink{requests: make(chan *triggerv1.DeliverRequest, 1), signatures: make(chan string, 1), err: err}
+}
+
+func TestGRPCSink_ProcessTask(t *testing.T) {
+	srv := newFakeTriggerSink(nil)
+	url := serveTriggerSink(t, srv)
+
+	sink := NewGRPCSink(nil, &MockSecretProvider{secrets: map[string]string{"ref": "s3cret"}}, nil)
+	defer sink.Close()
+
+	task := &types.DeliveryTask{ID: "task-1", TriggerID: "t1", Tenant: "acme", Event: "create", Collection: "orders", DocumentID: "o1", URL: url, SecretsRef: "ref"}
+	require.NoError(t, sink.ProcessTask(context.Background(), task))
+
+	req := <-srv.requests
+	assert.Equal(t, "task-1", req.Id)
+	assert.Equal(t, "t1", req.TriggerId)
+	assert.Equal(t, "o1", req.DocumentId)
+	var got types.DeliveryTask
+	require.NoError(t, json.Unmarshal(req.Task, &got))
+	assert.Equal(t, "orders", got.Collection)
+	assert.Contains(t, <-srv.signatures, ",v1=")
+
+	// The connection is reused.
+	require.NoError(t, sink.ProcessTask(context.Background(), task))
+	<-srv.requests
+	<-srv.signatures
+	assert.Len(t, sink.conns, 1)
+}
+
+func TestGRPCSink_Classification(t *testing.T) {
+	tests := []struct {
+		code    codes.Code
+		wantErr bool
+		fatal   bool
+	}{
+		{codes.AlreadyExists, false, false},
+		{codes.InvalidArgument, true, true},
+		{codes.PermissionDenied, true, true},
+		{codes.Unimplemented, true, true},
+		{codes.Unavailable, true, false},
+		{codes.ResourceExhausted, true, false},
+		{codes.Internal, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			srv := newFakeTriggerSink(status.Error(tt.code, "sink says no"))
+			sink := NewGRPCSink(nil, nil, nil)
+			defer sink.Close()
+
+			err := sink.ProcessTask(context.Background(), &types.DeliveryTask{ID: "task-1", URL: serveTriggerSink(t, srv)})
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+			assert.Equal(t, tt.fatal, types.IsFatal(err))
+		})
+	}
+}
+
+func TestGRPCSink_NoSecretProvider(t *testing.T) {
+	sink := NewGRPCSink(nil, nil, nil)
+	defer sink.Close()
+	err := sink.ProcessTask(context.Background(), &types.DeliveryTask{URL: "grpc://127.0.0.1:1", SecretsRef: "ref"})
+	assert.True(t, types.IsFatal(err))
+}
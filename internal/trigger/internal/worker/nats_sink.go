@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go"
+)
+
+// natsConn is the part of *nats.Conn the NATS sink uses.
+type natsConn interface {
+	PublishMsg(m *nats.Msg) error
+	FlushWithContext(ctx context.Context) error
+}
+
+// NATSSink publishes tasks to the subject of their nats:// URL. A delivery
+// succeeds once the server has the message; subscribers are not waited for.
+// A JetStream stream bound to the subject deduplicates retries by the
+// Nats-Msg-Id header, which carries the task ID.
+//
+// The sink shares its connection with the task streams, so it only publishes
+// to subjects under <prefix>.<tenant>. of the tenant of the task.
+type NATSSink struct {
+	nc      natsConn
+	prefix  string
+	timeout time.Duration
+	metrics types.Metrics
+}
+
+// NewNATSSink creates a NATSSink publishing on nc under prefix, or under
+// types.DefaultNATSSinkPrefix if prefix is empty.
+func NewNATSSink(nc *nats.Conn, prefix string, metrics types.Metrics) *NATSSink {
+	if prefix == "" {
+		prefix = types.DefaultNATSSinkPrefix
+	}
+	if metrics == nil {
+		metrics = &types.NoopMetrics{}
+	}
+	return &NATSSink{nc: nc, prefix: prefix, timeout: types.DefaultHTTPTimeout, metrics: metrics}
+}
+
+// ProcessTask publishes the task as JSON.
+func (s *NATSSink) ProcessTask(ctx context.Context, task *types.DeliveryTask) error {
+	start := time.Now()
+	u, err := url.Parse(task.URL)
+	if err != nil {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return &types.FatalError{Err: fmt.Errorf("invalid url: %w", err)}
+	}
+	subject := types.URLTarget(u)
+	if !types.NATSSinkSubject(s.prefix, task.Tenant, subject) {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return &types.FatalError{Err: fmt.Errorf("subject %s is outside %s.%s.>", subject, s.prefix, task.Tenant)}
+	}
+
+	// Anyone subscribed may read the message, so it carries no token.
+	out := *task
+	out.PreIssuedToken = ""
+	data, err := json.Marshal(&out)
+	if err != nil {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set("Content-Type", "application/json")
+	if task.ID != "" {
+		msg.Header.Set(nats.MsgIdHdr, task.ID)
+		msg.Header.Set("Idempotency-Key", task.ID)
+	}
+
+	if err := s.nc.PublishMsg(msg); err != nil {
+		// A bad subject or an oversized task fails the same way every time.
+		fatal := errors.Is(err, nats.ErrBadSubject) || errors.Is(err, nats.ErrMaxPayload)
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, fatal)
+		if fatal {
+			return &types.FatalError{Err: err}
+		}
+		return fmt.Errorf("publish failed: %w", err)
+	}
+
+	flushCtx, cancel := context.WithTimeout(ctx, taskTimeout(task, s.timeout))
+	defer cancel()
+	if err := s.nc.FlushWithContext(flushCtx); err != nil {
+		s.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, false)
+		return fmt.Errorf("publish not confirmed: %w", err)
+	}
+
+	s.metrics.IncDeliverySuccess(task.Tenant, task.Collection)
+	s.metrics.ObserveDeliveryLatency(task.Tenant, task.Collection, time.Since(start))
+	return nil
+}
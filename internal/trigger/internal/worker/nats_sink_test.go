@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNATSConn struct {
+	msgs       []*nats.Msg
+	publishErr error
+	flushErr   error
+}
+
+func (c *fakeNATSConn) PublishMsg(m *nats.Msg) error {
+	if c.publishErr != nil {
+		return c.publishErr
+	}
+	c.msgs = append(c.msgs, m)
+	return nil
+}
+
+func (c *fakeNATSConn) FlushWithContext(ctx context.Context) error {
+	return c.flushErr
+}
+
+func TestNATSSink_ProcessTask(t *testing.T) {
+	conn := &fakeNATSConn{}
+	sink := NewNATSSink(nil, "", nil)
+	sink.nc = conn
+
+	task := &types.DeliveryTask{ID: "task-1", TriggerID: "t1", Tenant: "acme", URL: "nats://sinks.acme.orders.created", PreIssuedToken: "secret"}
+	require.NoError(t, sink.ProcessTask(context.Background(), task))
+
+	require.Len(t, conn.msgs, 1)
+	msg := conn.msgs[0]
+	assert.Equal(t, "sinks.acme.orders.created", msg.Subject)
+	assert.Equal(t, "task-1", msg.Header.Get(nats.MsgIdHdr))
+	assert.Equal(t, "task-1", msg.Header.Get("Idempotency-Key"))
+
+	var got types.DeliveryTask
+	require.NoError(t, json.Unmarshal(msg.Data, &got))
+	assert.Equal(t, "t1", got.TriggerID)
+	assert.Empty(t, got.PreIssuedToken, "subscribers get no token")
+}
+
+func TestNATSSink_Errors(t *testing.T) {
+	task := &types.DeliveryTask{ID: "task-1", Tenant: "acme", URL: "nats://sinks.acme.orders.created"}
+
+	sink := &NATSSink{nc: &fakeNATSConn{publishErr: nats.ErrMaxPayload}, prefix: "sinks", metrics: &types.NoopMetrics{}}
+	assert.True(t, types.IsFatal(sink.ProcessTask(context.Background(), task)), "too large for any retry")
+
+	sink = &NATSSink{nc: &fakeNATSConn{publishErr: nats.ErrConnectionReconnecting}, prefix: "sinks", metrics: &types.NoopMetrics{}}
+	err := sink.ProcessTask(context.Background(), task)
+	assert.Error(t, err)
+	assert.False(t, types.IsFatal(err))
+
+	sink = &NATSSink{nc: &fakeNATSConn{flushErr: errors.New("timeout")}, prefix: "sinks", metrics: &types.NoopMetrics{}}
+	err = sink.ProcessTask(context.Background(), task)
+	assert.ErrorContains(t, err, "publish not confirmed")
+	assert.False(t, types.IsFatal(err))
+}
+
+func TestNATSSink_OutsideTenant(t *testing.T) {
+	conn := &fakeNATSConn{}
+	sink := &NATSSink{nc: conn, prefix: "sinks", metrics: &types.NoopMetrics{}}
+
+	for _, url := range []string{"nats://sinks.other.orders", "nats://TRIGGERS.acme.t1", "nats://syntrix.realtime.presence"} {
+		err := sink.ProcessTask(context.Background(), &types.DeliveryTask{ID: "task-1", Tenant: "acme", URL: url})
+		assert.True(t, types.IsFatal(err), url)
+		assert.ErrorContains(t, err, "is outside sinks.acme.>")
+	}
+	assert.Empty(t, conn.msgs)
+}
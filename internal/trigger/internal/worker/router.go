@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+)
+
+// Router delivers each task with the sink registered for the scheme of its
+// URL. Tasks for a scheme without a sink fail fatally.
+type Router struct {
+	sinks   map[string]DeliveryWorker
+	metrics types.Metrics
+}
+
+// NewRouter creates a Router over sinks keyed by URL scheme.
+func NewRouter(sinks map[string]DeliveryWorker, metrics types.Metrics) *Router {
+	if metrics == nil {
+		metrics = &types.NoopMetrics{}
+	}
+	return &Router{sinks: sinks, metrics: metrics}
+}
+
+// ProcessTask hands the task to the sink of its URL scheme.
+func (r *Router) ProcessTask(ctx context.Context, task *types.DeliveryTask) error {
+	u, err := url.Parse(task.URL)
+	if err != nil {
+		r.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return &types.FatalError{Err: fmt.Errorf("invalid url: %w", err)}
+	}
+	sink, ok := r.sinks[u.Scheme]
+	if !ok {
+		r.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
+		return &types.FatalError{Err: fmt.Errorf("no sink for %q urls", u.Scheme)}
+	}
+	return sink.ProcessTask(ctx, task)
+}
+
+// Close closes the sinks that hold connections.
+func (r *Router) Close() error {
+	var errs []error
+	closed := map[DeliveryWorker]bool{}
+	for _, sink := range r.sinks {
+		if c, ok := sink.(io.Closer); ok && !closed[sink] {
+			closed[sink] = true
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingSink records the tasks it gets.
+type recordingSink struct {
+	tasks  []*types.DeliveryTask
+	closed int
+	err    error
+}
+
+func (s *recordingSink) ProcessTask(ctx context.Context, task *types.DeliveryTask) error {
+	s.tasks = append(s.tasks, task)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.closed++
+	return s.err
+}
+
+func TestRouter_ProcessTask(t *testing.T) {
+	web, bus := &recordingSink{}, &recordingSink{}
+	r := NewRouter(map[string]DeliveryWorker{"http": web, "https": web, "nats": bus}, nil)
+
+	assert.NoError(t, r.ProcessTask(context.Background(), &types.DeliveryTask{URL: "https://example.com/hook"}))
+	assert.NoError(t, r.ProcessTask(context.Background(), &types.DeliveryTask{URL: "nats://orders.created"}))
+	assert.Len(t, web.tasks, 1)
+	assert.Len(t, bus.tasks, 1)
+
+	err := r.ProcessTask(context.Background(), &types.DeliveryTask{URL: "grpc://sink:9000"})
+	assert.True(t, types.IsFatal(err), "no sink for the scheme")
+	assert.ErrorContains(t, err, `no sink for "grpc" urls`)
+
+	err = r.ProcessTask(context.Background(), &types.DeliveryTask{URL: "://bad"})
+	assert.True(t, types.IsFatal(err))
+}
+
+func TestRouter_Close(t *testing.T) {
+	shared := &recordingSink{err: errors.New("close failed")}
+	r := NewRouter(map[string]DeliveryWorker{"grpc": shared, "grpcs": shared, "http": NewDeliveryWorker(nil, nil, HTTPClientOptions{}, nil)}, nil)
+
+	assert.ErrorContains(t, r.Close(), "close failed")
+	assert.Equal(t, 1, shared.closed, "a sink serving two schemes is closed once")
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"time"
@@ -16,17 +17,23 @@ import (
 	"github.com/codetrek/syntrix/internal/trigger/types"
 )
 
-// HTTPClientOptions configures the HTTP client.
+// HTTPClientOptions configures the HTTP client and what it does with
+// responses.
 type HTTPClientOptions struct {
 	Timeout time.Duration
+
+	// WriteBack applies the writes webhooks return; nil ignores them.
+	WriteBack *WriteBack
 }
 
 // HTTPWorker handles the execution of delivery tasks via HTTP.
 type HTTPWorker struct {
-	client  *http.Client
-	auth    identity.AuthN
-	secrets SecretProvider
-	metrics types.Metrics
+	client    *http.Client
+	timeout   time.Duration
+	auth      identity.AuthN
+	secrets   SecretProvider
+	metrics   types.Metrics
+	writeBack *WriteBack
 }
 
 // NewDeliveryWorker creates a new HTTPWorker.
@@ -39,26 +46,22 @@ func NewDeliveryWorker(auth identity.AuthN, secrets SecretProvider, opts HTTPCli
 		metrics = &types.NoopMetrics{}
 	}
 	return &HTTPWorker{
-		client: &http.Client{
-			Timeout: timeout,
-		},
-		auth:    auth,
-		secrets: secrets,
-		metrics: metrics,
+		// Requests are bounded by their task's timeout instead of a client-wide one.
+		client:    &http.Client{},
+		timeout:   timeout,
+		auth:      auth,
+		secrets:   secrets,
+		metrics:   metrics,
+		writeBack: opts.WriteBack,
 	}
 }
 
 // ProcessTask executes a single delivery task.
 func (w *HTTPWorker) ProcessTask(ctx context.Context, task *types.DeliveryTask) error {
 	start := time.Now()
-	// Add System Token
-	if w.auth != nil {
-		token, err := w.auth.GenerateSystemToken("trigger-worker")
-		if err != nil {
-			w.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, false)
-			return fmt.Errorf("failed to generate system token: %w", err)
-		}
-		task.PreIssuedToken = token
+	if err := issueToken(w.auth, task); err != nil {
+		w.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, false)
+		return err
 	}
 
 	payload, err := json.Marshal(task)
@@ -67,7 +70,10 @@ func (w *HTTPWorker) ProcessTask(ctx context.Context, task *types.DeliveryTask)
 		return fmt.Errorf("failed to marshal task: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", task.URL, bytes.NewReader(payload))
+	reqCtx, cancel := context.WithTimeout(ctx, taskTimeout(task, w.timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, "POST", task.URL, bytes.NewReader(payload))
 	if err != nil {
 		w.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
 		return fmt.Errorf("failed to create request: %w", err)
@@ -76,29 +82,23 @@ func (w *HTTPWorker) ProcessTask(ctx context.Context, task *types.DeliveryTask)
 	// Add Headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "Syntrix-Trigger-Service/1.0")
+	if task.ID != "" {
+		// Retries and replays of a task carry the same key.
+		req.Header.Set("Idempotency-Key", task.ID)
+	}
 	for k, v := range task.Headers {
 		req.Header.Set(k, v)
 	}
 
 	// Add Signature (only if SecretsRef is configured)
-	if task.SecretsRef != "" {
-		if w.secrets == nil {
-			log.Printf("[Warning] SecretsRef %s specified but no SecretProvider configured", task.SecretsRef)
-			w.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, true)
-			return &types.FatalError{Err: fmt.Errorf("no secret provider configured for SecretsRef %s", task.SecretsRef)}
-		}
-		secret, err := w.secrets.GetSecret(ctx, task.SecretsRef)
-		if err != nil {
-			// If we can't get the secret, should we fail fatally or retry?
-			// Probably retry, as it might be a temporary issue with secret store.
-			w.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, false)
-			return fmt.Errorf("failed to resolve secret %s: %w", task.SecretsRef, err)
-		}
-		timestamp := time.Now().Unix()
-		signature := w.signPayload(payload, secret, timestamp)
+	signature, err := signTask(ctx, w.secrets, task, payload)
+	if err != nil {
+		w.metrics.IncDeliveryFailure(task.Tenant, task.Collection, 0, types.IsFatal(err))
+		return err
+	}
+	if signature != "" {
 		req.Header.Set("X-Syntrix-Signature", signature)
 	}
-	// If SecretsRef is empty, skip signature header entirely (webhook may not require it)
 
 	resp, err := w.client.Do(req)
 	if err != nil {
@@ -106,8 +106,14 @@ func (w *HTTPWorker) ProcessTask(ctx context.Context, task *types.DeliveryTask)
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	recordResponse(ctx, resp)
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := w.applyWrites(ctx, task, resp); err != nil {
+			log.Printf("[Warning] Write-back of task %s for trigger %s failed: %v", task.ID, task.TriggerID, err)
+			w.metrics.IncDeliveryFailure(task.Tenant, task.Collection, resp.StatusCode, types.IsFatal(err))
+			return err
+		}
 		w.metrics.IncDeliverySuccess(task.Tenant, task.Collection)
 		w.metrics.ObserveDeliveryLatency(task.Tenant, task.Collection, time.Since(start))
 		return nil
@@ -117,14 +123,88 @@ func (w *HTTPWorker) ProcessTask(ctx context.Context, task *types.DeliveryTask)
 	w.metrics.IncDeliveryFailure(task.Tenant, task.Collection, resp.StatusCode, fatal)
 
 	// 4xx errors are fatal, 5xx are retryable.
+	statusErr := &types.StatusError{Status: resp.StatusCode}
 	if fatal {
-		return &types.FatalError{Err: fmt.Errorf("webhook failed with status: %d", resp.StatusCode)}
+		return &types.FatalError{Err: statusErr}
+	}
+
+	return statusErr
+}
+
+// applyWrites applies the writes a successful response returns, if the
+// worker has a WriteBack.
+func (w *HTTPWorker) applyWrites(ctx context.Context, task *types.DeliveryTask, resp *http.Response) error {
+	if w.writeBack == nil {
+		return nil
+	}
+	writes, err := readWrites(resp)
+	if err != nil || len(writes) == 0 {
+		return err
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, taskTimeout(task, w.timeout))
+	defer cancel()
+	return w.writeBack.Apply(writeCtx, task, writes)
+}
+
+// recordResponse fills in the status and the start of the body of resp for
+// the delivery log, if ctx asks for them. The body stays readable in full.
+func recordResponse(ctx context.Context, resp *http.Response) {
+	rec := types.DeliveryResponseFrom(ctx)
+	if rec == nil {
+		return
+	}
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, types.MaxDeliveryLogBodySize+1))
+	rec.Status = resp.StatusCode
+	rec.Body = head
+	if len(head) > types.MaxDeliveryLogBodySize {
+		rec.Body, rec.Truncated = head[:types.MaxDeliveryLogBodySize], true
 	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+}
 
-	return fmt.Errorf("webhook failed with status: %d", resp.StatusCode)
+// taskTimeout bounds one delivery of task, fallback if it sets none.
+func taskTimeout(task *types.DeliveryTask, fallback time.Duration) time.Duration {
+	if timeout := time.Duration(task.Timeout); timeout > 0 {
+		return timeout
+	}
+	return fallback
+}
+
+// issueToken adds the system token receivers call back with to the task.
+func issueToken(auth identity.AuthN, task *types.DeliveryTask) error {
+	if auth == nil {
+		return nil
+	}
+	token, err := auth.GenerateSystemToken("trigger-worker")
+	if err != nil {
+		return fmt.Errorf("failed to generate system token: %w", err)
+	}
+	task.PreIssuedToken = token
+	return nil
+}
+
+// signTask signs payload with the secret the task refers to. It returns ""
+// when the task has no SecretsRef; webhooks may not require a signature.
+func signTask(ctx context.Context, secrets SecretProvider, task *types.DeliveryTask, payload []byte) (string, error) {
+	if task.SecretsRef == "" {
+		return "", nil
+	}
+	if secrets == nil {
+		log.Printf("[Warning] SecretsRef %s specified but no SecretProvider configured", task.SecretsRef)
+		return "", &types.FatalError{Err: fmt.Errorf("no secret provider configured for SecretsRef %s", task.SecretsRef)}
+	}
+	secret, err := secrets.GetSecret(ctx, task.SecretsRef)
+	if err != nil {
+		// The secret store may be down for a moment, so this is retried.
+		return "", fmt.Errorf("failed to resolve secret %s: %w", task.SecretsRef, err)
+	}
+	return signPayload(payload, secret, time.Now().Unix()), nil
 }
 
-func (w *HTTPWorker) signPayload(body []byte, secret string, timestamp int64) string {
+func signPayload(body []byte, secret string, timestamp int64) string {
 	// Signature format: t={ts},v1={hex(hmac)}
 	mac := hmac.New(sha256.New, []byte(secret))
 	mac.Write([]byte(fmt.Sprintf("%d.", timestamp)))
@@ -55,6 +55,7 @@ func TestDeliveryWorker_ProcessTask(t *testing.T) {
 		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
 		assert.Equal(t, "Syntrix-Trigger-Service/1.0", r.Header.Get("User-Agent"))
 		assert.Equal(t, "bar", r.Header.Get("X-Custom-Header"))
+		assert.Equal(t, "task-1", r.Header.Get("Idempotency-Key"))
 		// No signature when SecretsRef is empty
 		assert.Empty(t, r.Header.Get("X-Syntrix-Signature"))
 
@@ -73,6 +74,7 @@ func TestDeliveryWorker_ProcessTask(t *testing.T) {
 
 	// 3. Create Task (no SecretsRef, so no signature)
 	task := &types.DeliveryTask{
+		ID:        "task-1",
 		TriggerID: "trig-1",
 		URL:       server.URL,
 		Headers:   map[string]string{"X-Custom-Header": "bar"},
@@ -318,3 +320,52 @@ func TestDeliveryWorker_ProcessTask_SecretError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to resolve secret")
 }
+
+func TestDeliveryWorker_ProcessTask_TaskTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	worker := NewDeliveryWorker(nil, nil, HTTPClientOptions{Timeout: 50 * time.Millisecond}, nil)
+
+	// The client default applies to tasks without a timeout.
+	err := worker.ProcessTask(context.Background(), &types.DeliveryTask{TriggerID: "trig-1", URL: server.URL})
+	assert.ErrorContains(t, err, "request failed")
+
+	// A task's timeout replaces it.
+	err = worker.ProcessTask(context.Background(), &types.DeliveryTask{
+		TriggerID: "trig-1",
+		URL:       server.URL,
+		Timeout:   types.Duration(time.Second),
+	})
+	assert.NoError(t, err)
+}
+
+func TestDeliveryWorker_ProcessTask_RecordsResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Idempotency-Key") == "long" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(strings.Repeat("x", types.MaxDeliveryLogBodySize+10)))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	worker := NewDeliveryWorker(nil, nil, HTTPClientOptions{}, nil)
+
+	var resp types.DeliveryResponse
+	ctx := types.WithDeliveryResponse(context.Background(), &resp)
+	assert.NoError(t, worker.ProcessTask(ctx, &types.DeliveryTask{ID: "short", URL: server.URL}))
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, "ok", string(resp.Body))
+	assert.False(t, resp.Truncated)
+
+	resp = types.DeliveryResponse{}
+	assert.Error(t, worker.ProcessTask(ctx, &types.DeliveryTask{ID: "long", URL: server.URL}))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Status)
+	assert.Len(t, resp.Body, types.MaxDeliveryLogBodySize)
+	assert.True(t, resp.Truncated)
+}
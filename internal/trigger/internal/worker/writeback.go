@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/codetrek/syntrix/pkg/model"
+)
+
+var writePathRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+(/[a-zA-Z0-9_-]+)*$`)
+
+// WriteBack applies the writes webhooks return in their responses. The writes
+// of one response commit together and are tagged with the delivery as their
+// origin, one hop deeper than the change that fired it.
+type WriteBack struct {
+	store storage.DocumentStore
+}
+
+// NewWriteBack creates a WriteBack writing to store. The store must support
+// transactions for responses to carry more than one write.
+func NewWriteBack(store storage.DocumentStore) *WriteBack {
+	return &WriteBack{store: store}
+}
+
+// readWrites returns the writes of a webhook response. Bodies that are not
+// JSON carry none.
+func readWrites(resp *http.Response) ([]types.WriteOp, error) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, types.MaxWriteBackBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > types.MaxWriteBackBodySize {
+		return nil, &types.FatalError{Err: fmt.Errorf("response body exceeds %d bytes", types.MaxWriteBackBodySize)}
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil, nil
+	}
+	var out types.WebhookResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, &types.FatalError{Err: fmt.Errorf("invalid response body: %w", err)}
+	}
+	return out.Writes, nil
+}
+
+// Apply validates writes and applies them for task. Invalid writes and
+// writes the store rejects are fatal; failures of the store are retried.
+func (wb *WriteBack) Apply(ctx context.Context, task *types.DeliveryTask, writes []types.WriteOp) error {
+	if len(writes) == 0 {
+		return nil
+	}
+	if len(writes) > types.MaxWriteBackOps {
+		return &types.FatalError{Err: fmt.Errorf("response returns %d writes, limit is %d", len(writes), types.MaxWriteBackOps)}
+	}
+	for i := range writes {
+		if err := validateWrite(&writes[i]); err != nil {
+			return &types.FatalError{Err: fmt.Errorf("write %d: %w", i, err)}
+		}
+	}
+
+	if wb.applied(ctx, task, writes) {
+		return nil
+	}
+
+	ctx = storage.WithOrigin(ctx, &storage.Origin{TriggerID: task.TriggerID, TaskID: task.ID, Depth: task.Depth + 1})
+	apply := func(ctx context.Context) error {
+		for i := range writes {
+			if err := wb.applyOne(ctx, task.Tenant, &writes[i]); err != nil {
+				return fmt.Errorf("write %d (%s %s): %w", i, writes[i].Type, writes[i].Path, err)
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if tx, ok := wb.store.(storage.Transactor); ok {
+		err = tx.RunTransaction(ctx, task.Tenant, apply)
+	} else if len(writes) == 1 {
+		err = apply(ctx)
+	} else {
+		err = storage.ErrTransactionsUnsupported
+	}
+	if err == nil {
+		return nil
+	}
+	if isRejected(err) {
+		return &types.FatalError{Err: err}
+	}
+	return fmt.Errorf("write-back failed: %w", err)
+}
+
+// applied reports whether an earlier delivery of task committed its writes
+// already. The writes commit together, so the first document written that
+// still names task as its origin answers for all of them.
+func (wb *WriteBack) applied(ctx context.Context, task *types.DeliveryTask, writes []types.WriteOp) bool {
+	if task.ID == "" {
+		return false
+	}
+	for i := range writes {
+		if writes[i].Type == "delete" {
+			continue
+		}
+		doc, err := wb.store.Get(ctx, task.Tenant, writes[i].Path)
+		return err == nil && doc.Origin != nil && doc.Origin.TaskID == task.ID
+	}
+	return false
+}
+
+func (wb *WriteBack) applyOne(ctx context.Context, tenant string, op *types.WriteOp) error {
+	idx := strings.LastIndex(op.Path, "/")
+	collection := op.Path[:idx]
+	data := model.Document(op.Data)
+	if data == nil {
+		data = make(model.Document)
+	}
+	data.StripProtectedFields()
+	delete(data, "id")
+
+	switch op.Type {
+	case "create":
+		return wb.store.Create(ctx, tenant, storage.NewDocument(tenant, op.Path, collection, data))
+	case "update":
+		return wb.store.Patch(ctx, tenant, op.Path, data, op.IfMatch)
+	case "replace":
+		if _, err := wb.store.Get(ctx, tenant, op.Path); errors.Is(err, model.ErrNotFound) {
+			return wb.store.Create(ctx, tenant, storage.NewDocument(tenant, op.Path, collection, data))
+		} else if err != nil {
+			return err
+		}
+		return wb.store.Update(ctx, tenant, op.Path, data, op.IfMatch)
+	default: // delete, checked by validateWrite
+		return wb.store.Delete(ctx, tenant, op.Path, op.IfMatch)
+	}
+}
+
+// validateWrite checks a write a receiver returned. System collections are
+// out of reach of write-backs.
+func validateWrite(op *types.WriteOp) error {
+	switch op.Type {
+	case "create", "update", "replace", "delete":
+	default:
+		return fmt.Errorf("invalid write type %q", op.Type)
+	}
+	if !writePathRegex.MatchString(op.Path) || len(strings.Split(op.Path, "/"))%2 != 0 {
+		return fmt.Errorf("invalid document path %q", op.Path)
+	}
+	if op.Path == "sys" || strings.HasPrefix(op.Path, "sys/") {
+		return fmt.Errorf("cannot write to system collection: %s", op.Path)
+	}
+	return nil
+}
+
+// isRejected reports whether the store refused a write for its content, so
+// retrying the delivery cannot succeed.
+func isRejected(err error) bool {
+	return errors.Is(err, model.ErrExists) ||
+		errors.Is(err, model.ErrNotFound) ||
+		errors.Is(err, model.ErrPreconditionFailed) ||
+		errors.Is(err, storage.ErrTransactionsUnsupported)
+}
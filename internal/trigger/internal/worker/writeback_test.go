@@ -0,0 +1,255 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/internal/trigger/types"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// txDocumentStore is an in-memory store whose transactions apply all writes
+// or none.
+type txDocumentStore struct {
+	storage.DocumentStore
+	docs    map[string]*storage.Document
+	origins []*storage.Origin
+	err     error
+}
+
+func newTxDocumentStore() *txDocumentStore {
+	return &txDocumentStore{docs: map[string]*storage.Document{}}
+}
+
+func (s *txDocumentStore) RunTransaction(ctx context.Context, tenant string, fn func(ctx context.Context) error) error {
+	if s.err != nil {
+		return s.err
+	}
+	saved := make(map[string]*storage.Document, len(s.docs))
+	for k, v := range s.docs {
+		saved[k] = v
+	}
+	if err := fn(ctx); err != nil {
+		s.docs = saved
+		return err
+	}
+	return nil
+}
+
+func (s *txDocumentStore) Get(ctx context.Context, tenant string, path string) (*storage.Document, error) {
+	doc, ok := s.docs[path]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return doc, nil
+}
+
+func (s *txDocumentStore) Create(ctx context.Context, tenant string, doc *storage.Document) error {
+	if _, ok := s.docs[doc.Fullpath]; ok {
+		return model.ErrExists
+	}
+	s.origins = append(s.origins, storage.OriginFrom(ctx))
+	doc.Origin = storage.OriginFrom(ctx)
+	s.docs[doc.Fullpath] = doc
+	return nil
+}
+
+func (s *txDocumentStore) Update(ctx context.Context, tenant string, path string, data map[string]interface{}, pred model.Filters) error {
+	doc, ok := s.docs[path]
+	if !ok {
+		return model.ErrNotFound
+	}
+	s.origins = append(s.origins, storage.OriginFrom(ctx))
+	s.docs[path] = &storage.Document{Fullpath: path, Collection: doc.Collection, Data: data, Origin: storage.OriginFrom(ctx)}
+	return nil
+}
+
+func (s *txDocumentStore) Patch(ctx context.Context, tenant string, path string, data map[string]interface{}, pred model.Filters) error {
+	doc, ok := s.docs[path]
+	if !ok {
+		return model.ErrNotFound
+	}
+	merged := map[string]interface{}{}
+	for k, v := range doc.Data {
+		merged[k] = v
+	}
+	for k, v := range data {
+		merged[k] = v
+	}
+	return s.Update(ctx, tenant, path, merged, pred)
+}
+
+func (s *txDocumentStore) Delete(ctx context.Context, tenant string, path string, pred model.Filters) error {
+	if _, ok := s.docs[path]; !ok {
+		return model.ErrNotFound
+	}
+	s.origins = append(s.origins, storage.OriginFrom(ctx))
+	delete(s.docs, path)
+	return nil
+}
+
+func TestWriteBack_Apply(t *testing.T) {
+	store := newTxDocumentStore()
+	store.docs["orders/o1"] = &storage.Document{Fullpath: "orders/o1", Collection: "orders", Data: map[string]interface{}{"status": "new"}}
+	store.docs["carts/c1"] = &storage.Document{Fullpath: "carts/c1", Collection: "carts"}
+	wb := NewWriteBack(store)
+
+	task := &types.DeliveryTask{ID: "task-1", TriggerID: "t1", Tenant: "acme", Depth: 2}
+	err := wb.Apply(context.Background(), task, []types.WriteOp{
+		{Type: "create", Path: "invoices/i1", Data: map[string]interface{}{"total": 3, "version": 9}},
+		{Type: "update", Path: "orders/o1", Data: map[string]interface{}{"status": "billed"}},
+		{Type: "replace", Path: "stats/s1", Data: map[string]interface{}{"n": 1}},
+		{Type: "delete", Path: "carts/c1"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{"total": 3}, store.docs["invoices/i1"].Data)
+	assert.Equal(t, "billed", store.docs["orders/o1"].Data["status"])
+	assert.Equal(t, "stats", store.docs["stats/s1"].Collection)
+	assert.NotContains(t, store.docs, "carts/c1")
+
+	require.Len(t, store.origins, 4)
+	for _, origin := range store.origins {
+		assert.Equal(t, &storage.Origin{TriggerID: "t1", TaskID: "task-1", Depth: 3}, origin)
+	}
+}
+
+func TestWriteBack_Apply_Atomic(t *testing.T) {
+	store := newTxDocumentStore()
+	wb := NewWriteBack(store)
+
+	err := wb.Apply(context.Background(), &types.DeliveryTask{ID: "task-1"}, []types.WriteOp{
+		{Type: "create", Path: "invoices/i1"},
+		{Type: "update", Path: "orders/missing"},
+	})
+	assert.True(t, types.IsFatal(err))
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	assert.Empty(t, store.docs)
+}
+
+func TestWriteBack_Apply_StoreError(t *testing.T) {
+	store := newTxDocumentStore()
+	store.err = errors.New("no primary")
+	wb := NewWriteBack(store)
+
+	err := wb.Apply(context.Background(), &types.DeliveryTask{}, []types.WriteOp{{Type: "create", Path: "invoices/i1"}})
+	assert.Error(t, err)
+	assert.False(t, types.IsFatal(err))
+}
+
+func TestWriteBack_Apply_NoTransactions(t *testing.T) {
+	store := &fakeDocumentStore{docs: map[string]*storage.Document{}}
+	wb := NewWriteBack(store)
+
+	// A single write needs no transaction.
+	err := wb.Apply(context.Background(), &types.DeliveryTask{}, []types.WriteOp{{Type: "create", Path: "invoices/i1"}})
+	require.NoError(t, err)
+	assert.Contains(t, store.docs, "invoices/i1")
+
+	err = wb.Apply(context.Background(), &types.DeliveryTask{}, []types.WriteOp{
+		{Type: "create", Path: "invoices/i2"},
+		{Type: "create", Path: "invoices/i3"},
+	})
+	assert.True(t, types.IsFatal(err))
+	assert.ErrorIs(t, err, storage.ErrTransactionsUnsupported)
+	assert.NotContains(t, store.docs, "invoices/i2")
+}
+
+func TestWriteBack_Apply_Invalid(t *testing.T) {
+	wb := NewWriteBack(newTxDocumentStore())
+	tests := []struct {
+		name   string
+		writes []types.WriteOp
+	}{
+		{"bad type", []types.WriteOp{{Type: "upsert", Path: "a/b"}}},
+		{"collection path", []types.WriteOp{{Type: "create", Path: "a"}}},
+		{"bad characters", []types.WriteOp{{Type: "create", Path: "a/b c"}}},
+		{"system collection", []types.WriteOp{{Type: "delete", Path: "sys/triggers"}}},
+		{"too many", make([]types.WriteOp, types.MaxWriteBackOps+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wb.Apply(context.Background(), &types.DeliveryTask{}, tt.writes)
+			assert.True(t, types.IsFatal(err))
+		})
+	}
+}
+
+func TestReadWrites(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		writes      int
+		fatal       bool
+	}{
+		{"plain text", "text/plain", "OK", 0, false},
+		{"empty json", "application/json", "", 0, false},
+		{"no writes", "application/json; charset=utf-8", `{"ok":true}`, 0, false},
+		{"writes", "application/json", `{"writes":[{"type":"delete","path":"a/b"}]}`, 1, false},
+		{"malformed", "application/json", `{"writes":`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Content-Type", tt.contentType)
+			rec.WriteString(tt.body)
+
+			writes, err := readWrites(rec.Result())
+			assert.Len(t, writes, tt.writes)
+			if tt.fatal {
+				assert.True(t, types.IsFatal(err))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeliveryWorker_ProcessTask_WriteBack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"writes":[{"type":"create","path":"audit/a1","data":{"by":"hook"}}]}`))
+	}))
+	defer server.Close()
+
+	store := newTxDocumentStore()
+	worker := NewDeliveryWorker(nil, nil, HTTPClientOptions{WriteBack: NewWriteBack(store)}, nil)
+
+	// Recording the response for the delivery log leaves the writes readable.
+	var resp types.DeliveryResponse
+	task := &types.DeliveryTask{ID: "task-1", TriggerID: "t1", Tenant: "acme", URL: server.URL}
+	require.NoError(t, worker.ProcessTask(types.WithDeliveryResponse(context.Background(), &resp), task))
+	require.Contains(t, store.docs, "audit/a1")
+	assert.Equal(t, "hook", store.docs["audit/a1"].Data["by"])
+	assert.Contains(t, string(resp.Body), "audit/a1")
+
+	// A retry finds its writes committed and does not apply them again.
+	store.origins = nil
+	require.NoError(t, worker.ProcessTask(context.Background(), task))
+	assert.Empty(t, store.origins)
+
+	// Another task returning the same writes conflicts.
+	task.ID = "task-2"
+	err := worker.ProcessTask(context.Background(), task)
+	assert.True(t, types.IsFatal(err))
+	assert.ErrorIs(t, err, model.ErrExists)
+}
+
+func TestDeliveryWorker_ProcessTask_WriteBackIgnored(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"writes":[{"type":"create","path":"audit/a1"}]}`))
+	}))
+	defer server.Close()
+
+	// Without a WriteBack the writes of a response are ignored.
+	worker := NewDeliveryWorker(nil, nil, HTTPClientOptions{}, nil)
+	assert.NoError(t, worker.ProcessTask(context.Background(), &types.DeliveryTask{URL: server.URL}))
+}
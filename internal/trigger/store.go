@@ -0,0 +1,212 @@
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+)
+
+// DefinitionsCollection is the sys collection holding the trigger
+// definitions of a tenant, one document per trigger at sys/triggers/<id>.
+const DefinitionsCollection = "sys/triggers"
+
+var (
+	// ErrTriggerNotFound is returned when a trigger definition does not exist.
+	ErrTriggerNotFound = errors.New("trigger not found")
+
+	// ErrTriggerExists is returned when creating a trigger whose ID is taken.
+	ErrTriggerExists = errors.New("trigger already exists")
+
+	// ErrInvalidTrigger wraps the reason a trigger definition is rejected.
+	ErrInvalidTrigger = errors.New("invalid trigger")
+)
+
+// Store persists trigger definitions per tenant. Running trigger engines
+// follow the changes through Watch.
+type Store interface {
+	// List returns the triggers of a tenant, enabled or not.
+	List(ctx context.Context, tenant string) ([]*Trigger, error)
+
+	// Get returns one trigger, or ErrTriggerNotFound.
+	Get(ctx context.Context, tenant, id string) (*Trigger, error)
+
+	// Create validates and stores a new trigger.
+	Create(ctx context.Context, t *Trigger) error
+
+	// Update validates and replaces an existing trigger.
+	Update(ctx context.Context, t *Trigger) error
+
+	// SetDisabled enables or disables a trigger without changing it otherwise.
+	SetDisabled(ctx context.Context, tenant, id string, disabled bool) error
+
+	// Delete removes a trigger.
+	Delete(ctx context.Context, tenant, id string) error
+
+	// Watch reports changes to the triggers of a tenant.
+	Watch(ctx context.Context, tenant string) (<-chan storage.Event, error)
+}
+
+// ValidateDefinition checks a trigger before it is stored: ValidateTrigger
+// plus a test compile of its condition and filters.
+func ValidateDefinition(t *Trigger) error {
+	if err := ValidateTrigger(t); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTrigger, err)
+	}
+	if err := CompileCondition(t.Condition); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTrigger, err)
+	}
+	if err := CompileFilters(t.Filters); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTrigger, err)
+	}
+	return nil
+}
+
+// documentStore implements Store on top of a storage.DocumentStore.
+type documentStore struct {
+	docs storage.DocumentStore
+}
+
+// NewStore returns a Store keeping definitions in the sys collection of docs.
+func NewStore(docs storage.DocumentStore) Store {
+	return &documentStore{docs: docs}
+}
+
+func definitionPath(id string) string {
+	return DefinitionsCollection + "/" + id
+}
+
+// definitionData wraps the trigger so its fields cannot clash with the
+// reserved fields of documents.
+func definitionData(t *Trigger) (map[string]interface{}, error) {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"trigger":   fields,
+		"updatedAt": time.Now().UnixMilli(),
+	}, nil
+}
+
+func decodeDefinition(doc *storage.Document) (*Trigger, error) {
+	raw, err := json.Marshal(doc.Data["trigger"])
+	if err != nil {
+		return nil, err
+	}
+	var t Trigger
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, fmt.Errorf("invalid trigger definition %s: %w", doc.Fullpath, err)
+	}
+	return &t, nil
+}
+
+func (s *documentStore) List(ctx context.Context, tenant string) ([]*Trigger, error) {
+	docs, err := s.docs.Query(ctx, tenant, model.Query{Collection: DefinitionsCollection})
+	if err != nil {
+		return nil, err
+	}
+	triggers := make([]*Trigger, 0, len(docs))
+	for _, doc := range docs {
+		t, err := decodeDefinition(doc)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, nil
+}
+
+func (s *documentStore) Get(ctx context.Context, tenant, id string) (*Trigger, error) {
+	t, _, err := s.get(ctx, tenant, id)
+	return t, err
+}
+
+// get returns a trigger with the version of its document.
+func (s *documentStore) get(ctx context.Context, tenant, id string) (*Trigger, int64, error) {
+	doc, err := s.docs.Get(ctx, tenant, definitionPath(id))
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, 0, ErrTriggerNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	t, err := decodeDefinition(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	return t, doc.Version, nil
+}
+
+func (s *documentStore) Create(ctx context.Context, t *Trigger) error {
+	if err := ValidateDefinition(t); err != nil {
+		return err
+	}
+	data, err := definitionData(t)
+	if err != nil {
+		return err
+	}
+	err = s.docs.Create(ctx, t.Tenant, storage.NewDocument(t.Tenant, definitionPath(t.ID), DefinitionsCollection, data))
+	if errors.Is(err, model.ErrExists) {
+		return ErrTriggerExists
+	}
+	return err
+}
+
+func (s *documentStore) Update(ctx context.Context, t *Trigger) error {
+	if err := ValidateDefinition(t); err != nil {
+		return err
+	}
+	data, err := definitionData(t)
+	if err != nil {
+		return err
+	}
+	err = s.docs.Update(ctx, t.Tenant, definitionPath(t.ID), data, model.Filters{})
+	if errors.Is(err, model.ErrNotFound) {
+		return ErrTriggerNotFound
+	}
+	return err
+}
+
+func (s *documentStore) SetDisabled(ctx context.Context, tenant, id string, disabled bool) error {
+	t, version, err := s.get(ctx, tenant, id)
+	if err != nil {
+		return err
+	}
+	if t.Disabled == disabled {
+		return nil
+	}
+	t.Disabled = disabled
+	data, err := definitionData(t)
+	if err != nil {
+		return err
+	}
+	// Fails with model.ErrPreconditionFailed if the trigger changed since it was read.
+	err = s.docs.Update(ctx, tenant, definitionPath(id), data, model.Filters{
+		{Field: "version", Op: "==", Value: version},
+	})
+	if errors.Is(err, model.ErrNotFound) {
+		return ErrTriggerNotFound
+	}
+	return err
+}
+
+func (s *documentStore) Delete(ctx context.Context, tenant, id string) error {
+	err := s.docs.Delete(ctx, tenant, definitionPath(id), model.Filters{})
+	if errors.Is(err, model.ErrNotFound) {
+		return ErrTriggerNotFound
+	}
+	return err
+}
+
+func (s *documentStore) Watch(ctx context.Context, tenant string) (<-chan storage.Event, error) {
+	return s.docs.Watch(ctx, tenant, DefinitionsCollection, nil, storage.WatchOptions{})
+}
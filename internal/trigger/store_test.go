@@ -0,0 +1,151 @@
+package trigger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/codetrek/syntrix/internal/storage"
+	"github.com/codetrek/syntrix/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryDocumentStore keeps documents in memory for one test.
+type memoryDocumentStore struct {
+	storage.DocumentStore
+	docs    map[string]*storage.Document
+	watched string
+}
+
+func newMemoryDocumentStore() *memoryDocumentStore {
+	return &memoryDocumentStore{docs: make(map[string]*storage.Document)}
+}
+
+func (m *memoryDocumentStore) key(tenant, path string) string { return tenant + ":" + path }
+
+func (m *memoryDocumentStore) Get(ctx context.Context, tenant, path string) (*storage.Document, error) {
+	doc, ok := m.docs[m.key(tenant, path)]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return doc, nil
+}
+
+func (m *memoryDocumentStore) Create(ctx context.Context, tenant string, doc *storage.Document) error {
+	if _, ok := m.docs[m.key(tenant, doc.Fullpath)]; ok {
+		return model.ErrExists
+	}
+	doc.Version = 1
+	m.docs[m.key(tenant, doc.Fullpath)] = doc
+	return nil
+}
+
+func (m *memoryDocumentStore) Update(ctx context.Context, tenant, path string, data map[string]interface{}, pred model.Filters) error {
+	doc, ok := m.docs[m.key(tenant, path)]
+	if !ok {
+		return model.ErrNotFound
+	}
+	for _, f := range pred {
+		if f.Field == "version" && f.Value != doc.Version {
+			return model.ErrPreconditionFailed
+		}
+	}
+	doc.Data = data
+	doc.Version++
+	return nil
+}
+
+func (m *memoryDocumentStore) Delete(ctx context.Context, tenant, path string, pred model.Filters) error {
+	if _, ok := m.docs[m.key(tenant, path)]; !ok {
+		return model.ErrNotFound
+	}
+	delete(m.docs, m.key(tenant, path))
+	return nil
+}
+
+func (m *memoryDocumentStore) Query(ctx context.Context, tenant string, q model.Query) ([]*storage.Document, error) {
+	var docs []*storage.Document
+	for key, doc := range m.docs {
+		if strings.HasPrefix(key, tenant+":") && doc.Collection == q.Collection {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+func (m *memoryDocumentStore) Watch(ctx context.Context, tenant, collection string, resumeToken interface{}, opts storage.WatchOptions) (<-chan storage.Event, error) {
+	m.watched = tenant + ":" + collection
+	return make(chan storage.Event), nil
+}
+
+func validDefinition(id string) *Trigger {
+	return &Trigger{
+		ID:         id,
+		Tenant:     "acme",
+		Collection: "orders",
+		Events:     []string{"create"},
+		Condition:  `event.document.total > 100`,
+		URL:        "https://example.com/hook",
+	}
+}
+
+func TestValidateDefinition(t *testing.T) {
+	assert.NoError(t, ValidateDefinition(validDefinition("t1")))
+
+	noURL := validDefinition("t1")
+	noURL.URL = ""
+	assert.ErrorIs(t, ValidateDefinition(noURL), ErrInvalidTrigger)
+
+	badCondition := validDefinition("t1")
+	badCondition.Condition = "event.document.total >"
+	assert.ErrorIs(t, ValidateDefinition(badCondition), ErrInvalidTrigger)
+}
+
+func TestStore_CRUD(t *testing.T) {
+	ctx := context.Background()
+	docs := newMemoryDocumentStore()
+	s := NewStore(docs)
+
+	require.NoError(t, s.Create(ctx, validDefinition("t1")))
+	assert.ErrorIs(t, s.Create(ctx, validDefinition("t1")), ErrTriggerExists)
+	invalid := validDefinition("t2")
+	invalid.Events = []string{"upsert"}
+	assert.ErrorIs(t, s.Create(ctx, invalid), ErrInvalidTrigger)
+
+	doc, err := docs.Get(ctx, "acme", "sys/triggers/t1")
+	require.NoError(t, err)
+	assert.Equal(t, DefinitionsCollection, doc.Collection)
+	assert.Contains(t, doc.Data, "trigger")
+
+	got, err := s.Get(ctx, "acme", "t1")
+	require.NoError(t, err)
+	assert.Equal(t, validDefinition("t1"), got)
+	_, err = s.Get(ctx, "other", "t1")
+	assert.ErrorIs(t, err, ErrTriggerNotFound)
+
+	updated := validDefinition("t1")
+	updated.URL = "https://example.com/v2"
+	require.NoError(t, s.Update(ctx, updated))
+	assert.ErrorIs(t, s.Update(ctx, validDefinition("missing")), ErrTriggerNotFound)
+
+	require.NoError(t, s.SetDisabled(ctx, "acme", "t1", true))
+	got, err = s.Get(ctx, "acme", "t1")
+	require.NoError(t, err)
+	assert.True(t, got.Disabled)
+	assert.Equal(t, "https://example.com/v2", got.URL)
+	require.NoError(t, s.SetDisabled(ctx, "acme", "t1", true), "no-op")
+	assert.ErrorIs(t, s.SetDisabled(ctx, "acme", "missing", false), ErrTriggerNotFound)
+
+	require.NoError(t, s.Create(ctx, validDefinition("t2")))
+	list, err := s.List(ctx, "acme")
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	require.NoError(t, s.Delete(ctx, "acme", "t1"))
+	assert.ErrorIs(t, s.Delete(ctx, "acme", "t1"), ErrTriggerNotFound)
+
+	_, err = s.Watch(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme:sys/triggers", docs.watched)
+}
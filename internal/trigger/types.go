@@ -11,5 +11,17 @@ type Evaluator = types.Evaluator
 type EventPublisher = types.EventPublisher
 type Duration = types.Duration
 type FatalError = types.FatalError
+type StatusError = types.StatusError
+type DeadLetter = types.DeadLetter
+type DeadLetterFilter = types.DeadLetterFilter
+type DeadLetterService = types.DeadLetterService
+type DeliveryRecord = types.DeliveryRecord
+type DeliveryLogFilter = types.DeliveryLogFilter
+type DeliveryLogService = types.DeliveryLogService
 
 var IsFatal = types.IsFatal
+var StatusOf = types.StatusOf
+var ErrDeadLetterNotFound = types.ErrDeadLetterNotFound
+
+const DefaultDeadLetterPageSize = types.DefaultDeadLetterPageSize
+const DefaultDeliveryLogPageSize = types.DefaultDeliveryLogPageSize
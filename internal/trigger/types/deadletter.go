@@ -0,0 +1,92 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDeadLetterNotFound is returned when a dead letter does not exist.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// StatusError is a webhook response with a non-2xx status.
+type StatusError struct {
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook failed with status: %d", e.Status)
+}
+
+// StatusOf returns the HTTP status a delivery failed with, or 0 if it failed
+// before a response arrived.
+func StatusOf(err error) int {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Status
+	}
+	return 0
+}
+
+// DeadLetter is a delivery task the consumer gave up on, either because it
+// failed fatally or because its retry policy was exhausted.
+type DeadLetter struct {
+	// ID is the sequence of the dead letter in its queue.
+	ID       uint64       `json:"id"`
+	Task     DeliveryTask `json:"task"`
+	Error    string       `json:"error"`
+	Status   int          `json:"status,omitempty"`
+	Attempts int          `json:"attempts"`
+	Fatal    bool         `json:"fatal"`
+	FailedAt int64        `json:"failedAt"` // Unix milliseconds
+}
+
+// DeadLetterFilter selects dead letters of a trigger. Zero fields match all.
+type DeadLetterFilter struct {
+	Event  string `json:"event,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Since  int64  `json:"since,omitempty"` // FailedAt lower bound, Unix milliseconds
+	Until  int64  `json:"until,omitempty"` // FailedAt upper bound, Unix milliseconds
+	After  uint64 `json:"after,omitempty"` // only IDs greater than After, for paging
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// Matches reports whether d is selected by the filter, ignoring paging.
+func (f DeadLetterFilter) Matches(d *DeadLetter) bool {
+	if f.Event != "" && d.Task.Event != f.Event {
+		return false
+	}
+	if f.Status != 0 && d.Status != f.Status {
+		return false
+	}
+	if f.Since != 0 && d.FailedAt < f.Since {
+		return false
+	}
+	if f.Until != 0 && d.FailedAt > f.Until {
+		return false
+	}
+	return true
+}
+
+// DeadLetterService inspects and replays the dead letters of triggers.
+type DeadLetterService interface {
+	// ListDeadLetters returns the dead letters of a trigger selected by filter.
+	ListDeadLetters(ctx context.Context, tenant, triggerID string, filter DeadLetterFilter) ([]*DeadLetter, error)
+
+	// GetDeadLetter returns one dead letter, or ErrDeadLetterNotFound.
+	GetDeadLetter(ctx context.Context, tenant, triggerID string, id uint64) (*DeadLetter, error)
+
+	// ReplayDeadLetter publishes the task of a dead letter again and removes
+	// the dead letter.
+	ReplayDeadLetter(ctx context.Context, tenant, triggerID string, id uint64) error
+
+	// ReplayDeadLetters replays the dead letters selected by filter, up to
+	// filter.Limit if set, and returns how many were replayed.
+	ReplayDeadLetters(ctx context.Context, tenant, triggerID string, filter DeadLetterFilter) (int, error)
+
+	// DeleteDeadLetter removes one dead letter without replaying it.
+	DeleteDeadLetter(ctx context.Context, tenant, triggerID string, id uint64) error
+
+	// PurgeDeadLetters removes all dead letters of a trigger.
+	PurgeDeadLetters(ctx context.Context, tenant, triggerID string) error
+}
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusOf(t *testing.T) {
+	err := &StatusError{Status: 503}
+	assert.Equal(t, "webhook failed with status: 503", err.Error())
+	assert.Equal(t, 503, StatusOf(err))
+	assert.Equal(t, 404, StatusOf(&FatalError{Err: &StatusError{Status: 404}}))
+	assert.Equal(t, 500, StatusOf(fmt.Errorf("wrapped: %w", &StatusError{Status: 500})))
+	assert.Equal(t, 0, StatusOf(errors.New("request failed")))
+	assert.Equal(t, 0, StatusOf(nil))
+}
+
+func TestDeadLetterFilter_Matches(t *testing.T) {
+	d := &DeadLetter{Task: DeliveryTask{Event: "update"}, Status: 500, FailedAt: 1000}
+
+	assert.True(t, DeadLetterFilter{}.Matches(d))
+	assert.True(t, DeadLetterFilter{Event: "update", Status: 500, Since: 1000, Until: 1000}.Matches(d))
+	assert.False(t, DeadLetterFilter{Event: "create"}.Matches(d))
+	assert.False(t, DeadLetterFilter{Status: 404}.Matches(d))
+	assert.False(t, DeadLetterFilter{Since: 1001}.Matches(d))
+	assert.False(t, DeadLetterFilter{Until: 999}.Matches(d))
+}
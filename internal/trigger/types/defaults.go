@@ -7,6 +7,11 @@ const (
 	// DefaultTaskTimeout is the default timeout for processing a single task.
 	DefaultTaskTimeout = 10 * time.Second
 
+	// DefaultAckWait is how long the stream waits for a task to be
+	// acknowledged before it redelivers the task. Task timeouts stay below it,
+	// so a slow delivery is not handed to a second worker meanwhile.
+	DefaultAckWait = 60 * time.Second
+
 	// DefaultHTTPTimeout is the default timeout for HTTP requests to webhooks.
 	DefaultHTTPTimeout = 5 * time.Second
 
@@ -16,3 +21,52 @@ const (
 	// DefaultShutdownTimeout is the default timeout for waiting workers to finish during shutdown.
 	DefaultShutdownTimeout = 10 * time.Second
 )
+
+// Dead letter defaults.
+const (
+	// DefaultDeadLetterMaxAge is how long dead letters are kept before they expire.
+	DefaultDeadLetterMaxAge = 7 * 24 * time.Hour
+
+	// DefaultDeadLetterPageSize is the number of dead letters listed when no limit is given.
+	DefaultDeadLetterPageSize = 100
+
+	// MaxDeadLetterPageSize caps the number of dead letters listed at once.
+	MaxDeadLetterPageSize = 1000
+)
+
+// Write-back limits.
+const (
+	// MaxTriggerDepth caps trigger chains: changes written back at this depth
+	// fire no triggers.
+	MaxTriggerDepth = 8
+
+	// MaxWriteBackOps is the most writes one webhook response may return.
+	MaxWriteBackOps = 100
+
+	// MaxWriteBackBodySize is the largest webhook response body read for writes.
+	MaxWriteBackBodySize = 1 << 20
+)
+
+// Scheduling limits.
+const (
+	// MaxTriggerDelay caps how long a trigger may hold its tasks back.
+	MaxTriggerDelay = 7 * 24 * time.Hour
+)
+
+// Delivery log defaults.
+const (
+	// DefaultDeliveryLogSize is how many delivery records are kept per trigger.
+	DefaultDeliveryLogSize = 1000
+
+	// DefaultDeliveryLogMaxAge is how long delivery records are kept.
+	DefaultDeliveryLogMaxAge = 24 * time.Hour
+
+	// MaxDeliveryLogBodySize is how much of a response body a record keeps.
+	MaxDeliveryLogBodySize = 1024
+
+	// DefaultDeliveryLogPageSize is the number of records listed when no limit is given.
+	DefaultDeliveryLogPageSize = 100
+
+	// MaxDeliveryLogPageSize caps the number of records listed at once.
+	MaxDeliveryLogPageSize = 1000
+)
@@ -0,0 +1,88 @@
+package types
+
+import "context"
+
+// Outcomes of a delivery attempt.
+const (
+	// DeliverySucceeded means the sink accepted the task.
+	DeliverySucceeded = "succeeded"
+
+	// DeliveryRetrying means the attempt failed and the task is retried.
+	DeliveryRetrying = "retrying"
+
+	// DeliveryFailed means the attempt failed and the task is given up on.
+	DeliveryFailed = "failed"
+)
+
+// DeliveryRecord is one delivery attempt of a task, as kept in the delivery
+// log of its trigger.
+type DeliveryRecord struct {
+	// ID is the sequence of the record in the delivery log.
+	ID          uint64 `json:"id"`
+	TaskID      string `json:"taskId"`
+	Tenant      string `json:"tenant"`
+	TriggerID   string `json:"triggerId"`
+	Event       string `json:"event"`
+	Collection  string `json:"collection"`
+	DocumentID  string `json:"documentId"`
+	Attempt     int    `json:"attempt"`
+	Outcome     string `json:"outcome"`
+	Status      int    `json:"status,omitempty"`
+	Error       string `json:"error,omitempty"`
+	LatencyMs   int64  `json:"latencyMs"`
+	Response    string `json:"response,omitempty"` // the first MaxDeliveryLogBodySize bytes
+	Truncated   bool   `json:"truncated,omitempty"`
+	DeliveredAt int64  `json:"deliveredAt"` // Unix milliseconds
+}
+
+// DeliveryLogFilter selects delivery records of a trigger. Zero fields match
+// all.
+type DeliveryLogFilter struct {
+	Outcome string `json:"outcome,omitempty"`
+	Status  int    `json:"status,omitempty"`
+	After   uint64 `json:"after,omitempty"` // only IDs greater than After, for paging
+	Limit   int    `json:"limit,omitempty"`
+}
+
+// Matches reports whether r is selected by the filter, ignoring paging.
+func (f DeliveryLogFilter) Matches(r *DeliveryRecord) bool {
+	if f.Outcome != "" && r.Outcome != f.Outcome {
+		return false
+	}
+	if f.Status != 0 && r.Status != f.Status {
+		return false
+	}
+	return true
+}
+
+// DeliveryLogService reads the delivery log of triggers.
+type DeliveryLogService interface {
+	// ListDeliveries returns the delivery records of a trigger selected by
+	// filter, oldest first.
+	ListDeliveries(ctx context.Context, tenant, triggerID string, filter DeliveryLogFilter) ([]*DeliveryRecord, error)
+
+	// TailDeliveries returns the records of a trigger written from now on.
+	// The channel is closed when ctx ends or the log fails.
+	TailDeliveries(ctx context.Context, tenant, triggerID string) (<-chan *DeliveryRecord, error)
+}
+
+// DeliveryResponse is what a sink received in answer to a delivery.
+type DeliveryResponse struct {
+	Status    int
+	Body      []byte
+	Truncated bool
+}
+
+type deliveryResponseKey struct{}
+
+// WithDeliveryResponse returns a context asking sinks to fill in resp.
+func WithDeliveryResponse(ctx context.Context, resp *DeliveryResponse) context.Context {
+	return context.WithValue(ctx, deliveryResponseKey{}, resp)
+}
+
+// DeliveryResponseFrom returns the response the delivery of ctx is to fill
+// in, or nil if none is asked for.
+func DeliveryResponseFrom(ctx context.Context) *DeliveryResponse {
+	resp, _ := ctx.Value(deliveryResponseKey{}).(*DeliveryResponse)
+	return resp
+}
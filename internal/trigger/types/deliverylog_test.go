@@ -0,0 +1,25 @@
+package types
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryLogFilter_Matches(t *testing.T) {
+	r := &DeliveryRecord{Outcome: DeliveryRetrying, Status: 503}
+
+	assert.True(t, DeliveryLogFilter{}.Matches(r))
+	assert.True(t, DeliveryLogFilter{Outcome: DeliveryRetrying, Status: 503}.Matches(r))
+	assert.False(t, DeliveryLogFilter{Outcome: DeliverySucceeded}.Matches(r))
+	assert.False(t, DeliveryLogFilter{Status: 200}.Matches(r))
+}
+
+func TestDeliveryResponse_Context(t *testing.T) {
+	assert.Nil(t, DeliveryResponseFrom(context.Background()))
+
+	resp := &DeliveryResponse{}
+	ctx := WithDeliveryResponse(context.Background(), resp)
+	assert.Same(t, resp, DeliveryResponseFrom(ctx))
+}
@@ -14,6 +14,9 @@ type Metrics interface {
 	IncConsumeFailure(tenant, collection string, reason string)
 	ObserveConsumeLatency(tenant, collection string, duration time.Duration)
 	IncHashCollision(tenant, collection string)
+	// IncDeliveryDelayed counts deliveries held back by a limit of their
+	// trigger; limit is "concurrency", "rate_limit" or "limiter_error".
+	IncDeliveryDelayed(tenant, collection string, limit string)
 
 	// Worker metrics
 	IncDeliverySuccess(tenant, collection string)
@@ -47,6 +50,9 @@ func (m *NoopMetrics) ObserveConsumeLatency(tenant, collection string, duration
 func (m *NoopMetrics) IncHashCollision(tenant, collection string) {
 	_ = tenant
 }
+func (m *NoopMetrics) IncDeliveryDelayed(tenant, collection string, limit string) {
+	_ = tenant
+}
 
 func (m *NoopMetrics) IncDeliverySuccess(tenant, collection string) {
 	_ = tenant
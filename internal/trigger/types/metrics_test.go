@@ -17,6 +17,7 @@ func TestNoopMetrics(t *testing.T) {
 	m.IncConsumeFailure("tenant", "collection", "reason")
 	m.ObserveConsumeLatency("tenant", "collection", time.Second)
 	m.IncHashCollision("tenant", "collection")
+	m.IncDeliveryDelayed("tenant", "collection", "concurrency")
 
 	m.IncDeliverySuccess("tenant", "collection")
 	m.IncDeliveryFailure("tenant", "collection", 500, true)
@@ -0,0 +1,51 @@
+package types
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Schemes of trigger URLs. Each is delivered by its own sink.
+const (
+	SchemeHTTP       = "http"
+	SchemeHTTPS      = "https"
+	SchemeNATS       = "nats"       // nats://<subject>
+	SchemeGRPC       = "grpc"       // grpc://<host:port>, plaintext
+	SchemeGRPCS      = "grpcs"      // grpcs://<host:port>, TLS
+	SchemeCollection = "collection" // collection://<collection path>
+)
+
+// DefaultNATSSinkPrefix is the subject prefix nats targets publish under: a
+// trigger of tenant t may only publish to <prefix>.<t>.<...>.
+const DefaultNATSSinkPrefix = "sinks"
+
+// defaultStreamName is the name of the task stream when none is configured.
+const defaultStreamName = "TRIGGERS"
+
+// NATSSinkSubject reports whether subject is one a trigger of tenant may
+// publish to under prefix.
+func NATSSinkSubject(prefix, tenant, subject string) bool {
+	return strings.HasPrefix(subject, prefix+"."+tenant+".")
+}
+
+// IsReservedSubject reports whether subject belongs to Syntrix itself: the
+// task, dead letter and delivery log streams, the syntrix.* subjects of the
+// realtime bus, and the $-prefixed system subjects.
+func IsReservedSubject(subject string) bool {
+	first, _, _ := strings.Cut(subject, ".")
+	switch {
+	case first == "syntrix", strings.HasPrefix(first, "$"):
+		return true
+	case first == defaultStreamName, strings.HasPrefix(first, defaultStreamName+"_"):
+		return true
+	case strings.HasSuffix(first, "_DLQ"), strings.HasSuffix(first, "_DELIVERIES"):
+		return true
+	}
+	return false
+}
+
+// URLTarget returns what a nats or collection URL names: the subject or the
+// collection path.
+func URLTarget(u *url.URL) string {
+	return strings.TrimSuffix(u.Host+u.Path, "/")
+}
@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+// Storage types of the task stream.
+const (
+	StreamStorageFile   = "file"
+	StreamStorageMemory = "memory"
+)
+
+// Discard policies of the task stream once it reaches a limit.
+const (
+	StreamDiscardOld = "old" // drop the oldest tasks
+	StreamDiscardNew = "new" // reject new tasks
+)
+
+// StreamOptions configures the JetStream stream holding delivery tasks.
+// Zero limits are unlimited.
+type StreamOptions struct {
+	Storage  string // StreamStorageFile or StreamStorageMemory (default)
+	MaxAge   time.Duration
+	MaxBytes int64
+	MaxMsgs  int64
+	Discard  string // StreamDiscardOld (default) or StreamDiscardNew
+	Replicas int    // default 1
+
+	// DuplicateWindow is how long the stream remembers task IDs to drop
+	// republished tasks; JetStream defaults it to 2 minutes.
+	DuplicateWindow time.Duration
+}
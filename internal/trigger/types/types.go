@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	stypes "github.com/codetrek/syntrix/internal/storage/types"
+	"github.com/codetrek/syntrix/pkg/model"
 )
 
 // FatalError represents an error that should not be retried.
@@ -44,6 +45,9 @@ type Trigger struct {
 	RetryPolicy   RetryPolicy       `json:"retryPolicy" yaml:"retryPolicy"`
 	Filters       []string          `json:"filters" yaml:"filters"`
 	Timeout       Duration          `json:"timeout" yaml:"timeout"`
+	Disabled      bool              `json:"disabled,omitempty" yaml:"disabled"`
+	Schedule      string            `json:"schedule,omitempty" yaml:"schedule"` // cron expression; fires on time instead of events
+	Delay         Duration          `json:"delay,omitempty" yaml:"delay"`       // holds each task back after its change
 }
 
 // RetryPolicy defines how to handle delivery failures.
@@ -55,13 +59,16 @@ type RetryPolicy struct {
 
 // DeliveryTask represents the payload sent to the delivery worker via NATS.
 type DeliveryTask struct {
+	ID             string                 `json:"id"` // same for every publish of one trigger and change
 	TriggerID      string                 `json:"triggerId"`
 	Tenant         string                 `json:"tenant"`
 	Event          string                 `json:"event"`
 	Collection     string                 `json:"collection"`
 	DocumentID     string                 `json:"documentId"`
-	LSN            string                 `json:"lsn"`
-	Seq            int64                  `json:"seq"`
+	LSN            string                 `json:"lsn"`              // change stream position of the event
+	Seq            int64                  `json:"seq"`              // commit time of the event, 0 if unknown
+	Replay         uint64                 `json:"replay,omitempty"` // dead letter ID the task was replayed from
+	Depth          int                    `json:"depth,omitempty"`  // trigger hops behind the change, 0 for a client write
 	Before         map[string]interface{} `json:"before,omitempty"`
 	After          map[string]interface{} `json:"after,omitempty"`
 	Timestamp      int64                  `json:"ts"`
@@ -69,12 +76,28 @@ type DeliveryTask struct {
 	Headers        map[string]string      `json:"headers"`
 	SecretsRef     string                 `json:"secretsRef"`
 	RetryPolicy    RetryPolicy            `json:"retryPolicy"`
+	Concurrency    int                    `json:"concurrency,omitempty"` // max deliveries in flight, 0 = unlimited
+	RateLimit      int                    `json:"rateLimit,omitempty"`   // max deliveries per second, 0 = unlimited
 	Timeout        Duration               `json:"timeout"`
 	PreIssuedToken string                 `json:"preIssuedToken,omitempty"`
 	Payload        map[string]interface{} `json:"payload,omitempty"` // Added Payload field for compatibility
 	SubjectHashed  bool                   `json:"subjectHashed,omitempty"`
 }
 
+// WriteOp is a write a receiver asks Syntrix to apply on its behalf.
+type WriteOp struct {
+	Type    string                 `json:"type"` // create, update, replace, delete
+	Path    string                 `json:"path"` // collection/docId
+	Data    map[string]interface{} `json:"data,omitempty"`
+	IfMatch model.Filters          `json:"ifMatch,omitempty"`
+}
+
+// WebhookResponse is the optional body of a successful webhook response.
+// Its writes are applied atomically, tagged with the delivery as origin.
+type WebhookResponse struct {
+	Writes []WriteOp `json:"writes,omitempty"`
+}
+
 // Evaluator evaluates trigger conditions.
 type Evaluator interface {
 	Evaluate(ctx context.Context, t *Trigger, event *stypes.Event) (bool, error)
@@ -4,11 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"path"
 	"regexp"
+	"strings"
+	"time"
+
+	"github.com/codetrek/syntrix/internal/trigger/types"
 )
 
 var (
-	validNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	validNameRegex    = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	validSubjectRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)*$`)
+	validPathRegex    = regexp.MustCompile(`^[a-zA-Z0-9_-]+(/[a-zA-Z0-9_-]+)*$`)
 )
 
 // ValidateTrigger checks if the trigger configuration is valid.
@@ -30,6 +37,48 @@ func ValidateTrigger(t *Trigger) error {
 		return fmt.Errorf("tenant name too long: %s", t.Tenant)
 	}
 
+	if t.Schedule != "" {
+		if err := validateSchedule(t); err != nil {
+			return err
+		}
+	} else if err := validateEvents(t); err != nil {
+		return err
+	}
+
+	if t.Delay < 0 || time.Duration(t.Delay) > types.MaxTriggerDelay {
+		return fmt.Errorf("delay must be between 0 and %v: %v", types.MaxTriggerDelay, time.Duration(t.Delay))
+	}
+
+	if t.Timeout < 0 || time.Duration(t.Timeout) >= types.DefaultAckWait {
+		return fmt.Errorf("timeout must be at least 0 and less than %v: %v", types.DefaultAckWait, time.Duration(t.Timeout))
+	}
+
+	if t.URL == "" {
+		return errors.New("url is required")
+	}
+
+	// Validate URL format
+	parsedURL, err := url.Parse(t.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if err := validateTarget(t, parsedURL); err != nil {
+		return err
+	}
+
+	if t.Concurrency < 0 {
+		return fmt.Errorf("concurrency must not be negative: %d", t.Concurrency)
+	}
+	if t.RateLimit < 0 {
+		return fmt.Errorf("rateLimit must not be negative: %d", t.RateLimit)
+	}
+
+	return nil
+}
+
+// validateEvents checks the collection and events of a trigger fired by
+// document changes.
+func validateEvents(t *Trigger) error {
 	if t.Collection == "" {
 		return errors.New("collection is required")
 	}
@@ -49,22 +98,60 @@ func ValidateTrigger(t *Trigger) error {
 			return fmt.Errorf("invalid event type: %s", evt)
 		}
 	}
+	return nil
+}
 
-	if t.URL == "" {
-		return errors.New("url is required")
-	}
-
-	// Validate URL format
-	parsedURL, err := url.Parse(t.URL)
-	if err != nil {
-		return fmt.Errorf("invalid url: %w", err)
+// validateSchedule checks a trigger fired by its schedule. Such a trigger
+// sees no document, so the options about changes do not apply.
+func validateSchedule(t *Trigger) error {
+	if _, err := types.ParseSchedule(t.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
 	}
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return fmt.Errorf("url must use http or https scheme: %s", t.URL)
+	if len(t.Collection) > 128 {
+		return fmt.Errorf("collection name too long: %s", t.Collection)
 	}
-	if parsedURL.Host == "" {
-		return fmt.Errorf("url must have a host: %s", t.URL)
+	switch {
+	case len(t.Events) > 0:
+		return errors.New("scheduled trigger must not have events")
+	case t.Condition != "" || len(t.Filters) > 0:
+		return errors.New("scheduled trigger must not have a condition or filters")
+	case t.IncludeBefore:
+		return errors.New("scheduled trigger must not set includeBefore")
+	case t.Delay != 0:
+		return errors.New("scheduled trigger must not have a delay")
 	}
+	return nil
+}
 
+// validateTarget checks the URL of a trigger against the sink of its scheme.
+func validateTarget(t *Trigger, u *url.URL) error {
+	switch u.Scheme {
+	case types.SchemeHTTP, types.SchemeHTTPS, types.SchemeGRPC, types.SchemeGRPCS:
+		if u.Host == "" {
+			return fmt.Errorf("url must have a host: %s", t.URL)
+		}
+	case types.SchemeNATS:
+		if u.Path != "" || !validSubjectRegex.MatchString(u.Host) {
+			return fmt.Errorf("nats url must name a subject without wildcards: %s", t.URL)
+		}
+		if types.IsReservedSubject(u.Host) {
+			return fmt.Errorf("nats url must not target a subject reserved by syntrix: %s", t.URL)
+		}
+	case types.SchemeCollection:
+		collection := types.URLTarget(u)
+		if !validPathRegex.MatchString(collection) || strings.Count(collection, "/")%2 != 0 {
+			return fmt.Errorf("collection url must name a collection path: %s", t.URL)
+		}
+		if collection == "sys" || strings.HasPrefix(collection, "sys/") {
+			return fmt.Errorf("collection url must not target a sys collection: %s", t.URL)
+		}
+		// A trigger writing into a collection it watches would fire on its
+		// own writes.
+		if matched, _ := path.Match(t.Collection, collection); matched {
+			return fmt.Errorf("collection url must not target the watched collection: %s", t.URL)
+		}
+	default:
+		return fmt.Errorf("url scheme must be http, https, nats, grpc, grpcs or collection: %s", t.URL)
+	}
 	return nil
 }
@@ -3,6 +3,7 @@ package trigger
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -138,7 +139,7 @@ func TestValidateTrigger(t *testing.T) {
 				Events:     []string{"create"},
 				URL:        "ftp://example.com",
 			},
-			wantErr: "url must use http or https scheme",
+			wantErr: "url scheme must be http, https, nats, grpc, grpcs or collection",
 		},
 		{
 			name: "url without host",
@@ -151,6 +152,30 @@ func TestValidateTrigger(t *testing.T) {
 			},
 			wantErr: "url must have a host",
 		},
+		{
+			name: "negative concurrency",
+			trigger: &Trigger{
+				ID:          "valid-id",
+				Tenant:      "valid-tenant",
+				Collection:  "users",
+				Events:      []string{"create"},
+				URL:         "http://example.com",
+				Concurrency: -1,
+			},
+			wantErr: "concurrency must not be negative",
+		},
+		{
+			name: "negative rate limit",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "http://example.com",
+				RateLimit:  -1,
+			},
+			wantErr: "rateLimit must not be negative",
+		},
 		{
 			name: "valid https url",
 			trigger: &Trigger{
@@ -173,6 +198,229 @@ func TestValidateTrigger(t *testing.T) {
 			},
 			wantErr: "invalid url",
 		},
+		{
+			name: "valid nats url",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "nats://sinks.valid-tenant.orders.created",
+			},
+			wantErr: "",
+		},
+		{
+			name: "nats url on a task stream",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "nats://TRIGGERS_DLQ.other.t1",
+			},
+			wantErr: "nats url must not target a subject reserved by syntrix",
+		},
+		{
+			name: "nats url on the realtime bus",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "nats://syntrix.realtime.revocations",
+			},
+			wantErr: "nats url must not target a subject reserved by syntrix",
+		},
+		{
+			name: "nats url with wildcard",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "nats://orders.*",
+			},
+			wantErr: "nats url must name a subject without wildcards",
+		},
+		{
+			name: "nats url with path",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "nats://orders/created",
+			},
+			wantErr: "nats url must name a subject without wildcards",
+		},
+		{
+			name: "valid grpc url",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "grpcs://sink.internal:443",
+			},
+			wantErr: "",
+		},
+		{
+			name: "grpc url without host",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "grpc://",
+			},
+			wantErr: "url must have a host",
+		},
+		{
+			name: "valid collection url",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "collection://rooms/r1/outbox",
+			},
+			wantErr: "",
+		},
+		{
+			name: "collection url to a document",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "collection://rooms/r1",
+			},
+			wantErr: "collection url must name a collection path",
+		},
+		{
+			name: "collection url to sys",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				URL:        "collection://sys/audit/entries",
+			},
+			wantErr: "collection url must not target a sys collection",
+		},
+		{
+			name: "collection url to watched collection",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "rooms/*/outbox",
+				Events:     []string{"create"},
+				URL:        "collection://rooms/r1/outbox",
+			},
+			wantErr: "collection url must not target the watched collection",
+		},
+		{
+			name: "valid schedule",
+			trigger: &Trigger{
+				ID:       "valid-id",
+				Tenant:   "valid-tenant",
+				Schedule: "0 3 * * *",
+				URL:      "http://example.com",
+			},
+			wantErr: "",
+		},
+		{
+			name: "invalid schedule",
+			trigger: &Trigger{
+				ID:       "valid-id",
+				Tenant:   "valid-tenant",
+				Schedule: "0 25 * * *",
+				URL:      "http://example.com",
+			},
+			wantErr: "invalid schedule",
+		},
+		{
+			name: "schedule with events",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				Schedule:   "@daily",
+				URL:        "http://example.com",
+			},
+			wantErr: "scheduled trigger must not have events",
+		},
+		{
+			name: "schedule with condition",
+			trigger: &Trigger{
+				ID:        "valid-id",
+				Tenant:    "valid-tenant",
+				Schedule:  "@daily",
+				Condition: "true",
+				URL:       "http://example.com",
+			},
+			wantErr: "scheduled trigger must not have a condition",
+		},
+		{
+			name: "schedule with delay",
+			trigger: &Trigger{
+				ID:       "valid-id",
+				Tenant:   "valid-tenant",
+				Schedule: "@daily",
+				Delay:    Duration(time.Minute),
+				URL:      "http://example.com",
+			},
+			wantErr: "scheduled trigger must not have a delay",
+		},
+		{
+			name: "valid delay",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				Delay:      Duration(15 * time.Minute),
+				URL:        "http://example.com",
+			},
+			wantErr: "",
+		},
+		{
+			name: "delay too long",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				Delay:      Duration(8 * 24 * time.Hour),
+				URL:        "http://example.com",
+			},
+			wantErr: "delay must be between",
+		},
+		{
+			name: "negative timeout",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				Timeout:    Duration(-time.Second),
+				URL:        "http://example.com",
+			},
+			wantErr: "timeout must be at least 0",
+		},
+		{
+			name: "timeout reaches ack wait",
+			trigger: &Trigger{
+				ID:         "valid-id",
+				Tenant:     "valid-tenant",
+				Collection: "users",
+				Events:     []string{"create"},
+				Timeout:    Duration(time.Minute),
+				URL:        "http://example.com",
+			},
+			wantErr: "less than 1m0s",
+		},
 	}
 
 	for _, tt := range tests {
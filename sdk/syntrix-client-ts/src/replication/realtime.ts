@@ -10,6 +10,8 @@ export const MessageType = {
   UnsubscribeAck: 'unsubscribe_ack',
   Event: 'event',
   Snapshot: 'snapshot',
+  Reset: 'reset',
+  Change: 'change',
   Error: 'error',
   Heartbeat: 'heartbeat',
 } as const;
@@ -23,12 +25,16 @@ export interface BaseMessage {
 export interface SubscribeQuery {
   collection: string;
   filters?: Array<{ field: string; op: string; value: any }>;
+  orderBy?: Array<{ field: string; direction: 'asc' | 'desc' }>;
+  limit?: number;
 }
 
 export interface SubscribeOptions {
   query: SubscribeQuery;
   includeData?: boolean;
   sendSnapshot?: boolean;
+  /** Maintain the result window on the server and receive change messages. */
+  view?: boolean;
 }
 
 export interface RealtimeEvent {
@@ -38,12 +44,39 @@ export interface RealtimeEvent {
     id: string;
     document?: Record<string, any>;
     timestamp: number;
+    /** Opaque position; sent back as resumeAfter when resubscribing. */
+    resumeToken?: string;
   };
 }
 
+export interface ChangeEvent {
+  subId: string;
+  type: 'added' | 'modified' | 'removed';
+  id: string;
+  document?: Record<string, any>;
+  /** Position before the change; -1 for added documents. */
+  oldIndex: number;
+  /** Position after the change; -1 for removed documents. */
+  newIndex: number;
+  timestamp: number;
+  resumeToken?: string;
+}
+
+export interface ResetEvent {
+  subId: string;
+  /** Why the subscription could not be resumed, e.g. "expired". */
+  reason: string;
+}
+
 export interface SnapshotEvent {
   subId: string;
   documents: Record<string, any>[];
+  /** Zero-based page index; large snapshots arrive in several pages. */
+  page?: number;
+  /** True on the last page of the snapshot. */
+  done?: boolean;
+  /** True when the server capped the result size. */
+  truncated?: boolean;
 }
 
 export type ConnectionState = 'disconnected' | 'connecting' | 'connected' | 'error';
@@ -54,6 +87,9 @@ export interface RealtimeCallbacks {
   onError?: (error: Error) => void;
   onEvent?: (event: RealtimeEvent) => void;
   onSnapshot?: (snapshot: SnapshotEvent) => void;
+  onChange?: (change: ChangeEvent) => void;
+  /** Local state for the subscription must be replaced by the following snapshot. */
+  onReset?: (reset: ResetEvent) => void;
   onStateChange?: (state: ConnectionState) => void;
 }
 
@@ -69,6 +105,7 @@ export class RealtimeClient {
   private tokenProvider: TokenProvider;
   private callbacks: RealtimeCallbacks = {};
   private subscriptions: Map<string, SubscribeOptions> = new Map();
+  private resumeTokens: Map<string, string> = new Map();
   private messageHandlers: Map<string, (msg: BaseMessage) => void> = new Map();
   private subIdCounter = 0;
   private state: ConnectionState = 'disconnected';
@@ -261,6 +298,9 @@ export class RealtimeClient {
           const event: RealtimeEvent = typeof msg.payload === 'string'
             ? JSON.parse(msg.payload)
             : msg.payload;
+          if (event.delta?.resumeToken && this.subscriptions.has(event.subId)) {
+            this.resumeTokens.set(event.subId, event.delta.resumeToken);
+          }
           this.callbacks.onEvent?.(event);
         }
         break;
@@ -273,6 +313,23 @@ export class RealtimeClient {
           this.callbacks.onSnapshot?.(snapshot);
         }
         break;
+      case MessageType.Change:
+        if (msg.payload) {
+          const change: ChangeEvent = typeof msg.payload === 'string'
+            ? JSON.parse(msg.payload)
+            : msg.payload;
+          this.callbacks.onChange?.(change);
+        }
+        break;
+      case MessageType.Reset:
+        if (msg.payload) {
+          const reset: ResetEvent = typeof msg.payload === 'string'
+            ? JSON.parse(msg.payload)
+            : msg.payload;
+          this.resumeTokens.delete(reset.subId);
+          this.callbacks.onReset?.(reset);
+        }
+        break;
       case MessageType.Error:
         if (msg.payload) {
           const error = typeof msg.payload === 'string'
@@ -311,6 +368,7 @@ export class RealtimeClient {
   }
 
   private sendSubscribe(subId: string, options: SubscribeOptions): void {
+    const resumeAfter = this.resumeTokens.get(subId);
     this.sendMessage({
       id: subId,
       type: MessageType.Subscribe,
@@ -318,12 +376,15 @@ export class RealtimeClient {
         query: options.query,
         includeData: options.includeData ?? true,
         sendSnapshot: options.sendSnapshot ?? false,
+        ...(options.view ? { view: true } : {}),
+        ...(resumeAfter ? { resumeAfter } : {}),
       },
     });
   }
 
   unsubscribe(subId: string): void {
     this.subscriptions.delete(subId);
+    this.resumeTokens.delete(subId);
     this.sendMessage({
       id: `unsub-${subId}`,
       type: MessageType.Unsubscribe,